@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/cilium"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"github.com/replicatedhq/embedded-cluster/pkg/release"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+var ciliumRepo = &repo.Entry{
+	Name: "cilium",
+	URL:  "https://helm.cilium.io",
+}
+
+var ciliumImageComponents = map[string]addonComponent{
+	"quay.io/cilium/cilium": {
+		name:             "cilium",
+		useUpstreamImage: true,
+	},
+	"quay.io/cilium/operator-generic": {
+		name:             "cilium-operator",
+		useUpstreamImage: true,
+	},
+}
+
+var updateCiliumAddonCommand = &cli.Command{
+	Name:      "cilium",
+	Usage:     "Updates the Cilium addon",
+	UsageText: environmentUsageText,
+	Action: func(c *cli.Context) error {
+		logrus.Infof("updating cilium addon")
+
+		hcli, err := NewHelm()
+		if err != nil {
+			return fmt.Errorf("failed to create helm client: %w", err)
+		}
+		defer hcli.Close()
+
+		nextChartVersion := os.Getenv("INPUT_CILIUM_CHART_VERSION")
+		if nextChartVersion != "" {
+			logrus.Infof("using input override from INPUT_CILIUM_CHART_VERSION: %s", nextChartVersion)
+		} else {
+			logrus.Infof("fetching the latest cilium chart version")
+			latest, err := LatestChartVersion(hcli, ciliumRepo, "cilium")
+			if err != nil {
+				return fmt.Errorf("failed to get the latest cilium chart version: %v", err)
+			}
+			nextChartVersion = latest
+			logrus.Printf("latest cilium chart version: %s", latest)
+		}
+		nextChartVersion = strings.TrimPrefix(nextChartVersion, "v")
+
+		current := cilium.Metadata
+		if current.Version == nextChartVersion && !c.Bool("force") {
+			logrus.Infof("cilium chart version is already up-to-date")
+			return nil
+		}
+
+		logrus.Infof("mirroring cilium chart version %s", nextChartVersion)
+		if err := MirrorChart(hcli, ciliumRepo, "cilium", nextChartVersion); err != nil {
+			return fmt.Errorf("failed to mirror cilium chart: %v", err)
+		}
+
+		upstream := fmt.Sprintf("%s/cilium", os.Getenv("CHARTS_DESTINATION"))
+		withproto := fmt.Sprintf("oci://proxy.replicated.com/anonymous/%s", upstream)
+
+		logrus.Infof("updating cilium images")
+
+		err = updateCiliumAddonImages(c.Context, hcli, withproto, nextChartVersion)
+		if err != nil {
+			return fmt.Errorf("failed to update cilium images: %w", err)
+		}
+
+		logrus.Infof("successfully updated cilium addon")
+
+		return nil
+	},
+}
+
+var updateCiliumImagesCommand = &cli.Command{
+	Name:      "cilium",
+	Usage:     "Updates the cilium images",
+	UsageText: environmentUsageText,
+	Action: func(c *cli.Context) error {
+		logrus.Infof("updating cilium images")
+
+		hcli, err := NewHelm()
+		if err != nil {
+			return fmt.Errorf("failed to create helm client: %w", err)
+		}
+		defer hcli.Close()
+
+		current := cilium.Metadata
+
+		err = updateCiliumAddonImages(c.Context, hcli, current.Location, current.Version)
+		if err != nil {
+			return fmt.Errorf("failed to update cilium images: %w", err)
+		}
+
+		logrus.Infof("successfully updated cilium images")
+
+		return nil
+	},
+}
+
+func updateCiliumAddonImages(ctx context.Context, hcli helm.Client, chartURL string, chartVersion string) error {
+	newmeta := release.AddonMetadata{
+		Version:  chartVersion,
+		Location: chartURL,
+		Images:   make(map[string]release.AddonImage),
+	}
+
+	values, err := release.GetValuesWithOriginalImages("cilium")
+	if err != nil {
+		return fmt.Errorf("failed to get cilium values: %v", err)
+	}
+
+	logrus.Infof("extracting images from chart version %s", chartVersion)
+	images, err := helm.ExtractImagesFromChart(hcli, chartURL, chartVersion, values)
+	if err != nil {
+		return fmt.Errorf("failed to get images from cilium chart: %w", err)
+	}
+
+	metaImages, err := UpdateImages(ctx, ciliumImageComponents, cilium.Metadata.Images, images)
+	if err != nil {
+		return fmt.Errorf("failed to update images: %w", err)
+	}
+	newmeta.Images = metaImages
+
+	logrus.Infof("saving addon manifest")
+	if err := newmeta.Save("cilium"); err != nil {
+		return fmt.Errorf("failed to save metadata: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/longhorn"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"github.com/replicatedhq/embedded-cluster/pkg/helpers"
+	"github.com/replicatedhq/embedded-cluster/pkg/release"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+	"helm.sh/helm/v3/pkg/chart/loader"
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+var longhornRepo = &repo.Entry{
+	Name: "longhorn",
+	URL:  "https://charts.longhorn.io",
+}
+
+var longhornImageComponents = map[string]addonComponent{
+	"docker.io/longhornio/backing-image-manager": {
+		name:             "backing-image-manager",
+		useUpstreamImage: true,
+	},
+	"docker.io/longhornio/csi-attacher": {
+		name:             "csi-attacher",
+		useUpstreamImage: true,
+	},
+	"docker.io/longhornio/livenessprobe": {
+		name:             "csi-livenessprobe",
+		useUpstreamImage: true,
+	},
+	"docker.io/longhornio/csi-node-driver-registrar": {
+		name:             "csi-node-driver-registrar",
+		useUpstreamImage: true,
+	},
+	"docker.io/longhornio/csi-provisioner": {
+		name:             "csi-provisioner",
+		useUpstreamImage: true,
+	},
+	"docker.io/longhornio/csi-resizer": {
+		name:             "csi-resizer",
+		useUpstreamImage: true,
+	},
+	"docker.io/longhornio/csi-snapshotter": {
+		name:             "csi-snapshotter",
+		useUpstreamImage: true,
+	},
+	"docker.io/longhornio/longhorn-engine": {
+		name:             "longhorn-engine",
+		useUpstreamImage: true,
+	},
+	"docker.io/longhornio/longhorn-instance-manager": {
+		name:             "longhorn-instance-manager",
+		useUpstreamImage: true,
+	},
+	"docker.io/longhornio/longhorn-manager": {
+		name:             "longhorn-manager",
+		useUpstreamImage: true,
+	},
+	"docker.io/longhornio/longhorn-share-manager": {
+		name:             "longhorn-share-manager",
+		useUpstreamImage: true,
+	},
+	"docker.io/longhornio/longhorn-ui": {
+		name:             "longhorn-ui",
+		useUpstreamImage: true,
+	},
+	"docker.io/longhornio/support-bundle-kit": {
+		name:             "support-bundle-kit",
+		useUpstreamImage: true,
+	},
+}
+
+var updateLonghornAddonCommand = &cli.Command{
+	Name:      "longhorn",
+	Usage:     "Updates the Longhorn addon",
+	UsageText: environmentUsageText,
+	Action: func(c *cli.Context) error {
+		logrus.Infof("updating longhorn addon")
+
+		hcli, err := NewHelm()
+		if err != nil {
+			return fmt.Errorf("failed to create helm client: %w", err)
+		}
+		defer hcli.Close()
+
+		nextChartVersion := os.Getenv("INPUT_LONGHORN_CHART_VERSION")
+		if nextChartVersion != "" {
+			logrus.Infof("using input override from INPUT_LONGHORN_CHART_VERSION: %s", nextChartVersion)
+		} else {
+			logrus.Infof("fetching the latest longhorn chart version")
+			latest, err := LatestChartVersion(hcli, longhornRepo, "longhorn")
+			if err != nil {
+				return fmt.Errorf("failed to get the latest longhorn chart version: %v", err)
+			}
+			nextChartVersion = latest
+			logrus.Printf("latest longhorn chart version: %s", latest)
+		}
+		nextChartVersion = strings.TrimPrefix(nextChartVersion, "v")
+
+		current := longhorn.Metadata
+		if current.Version == nextChartVersion && !c.Bool("force") {
+			logrus.Infof("longhorn chart version is already up-to-date")
+			return nil
+		}
+
+		logrus.Infof("mirroring longhorn chart version %s", nextChartVersion)
+		if err := MirrorChart(hcli, longhornRepo, "longhorn", nextChartVersion); err != nil {
+			return fmt.Errorf("failed to mirror longhorn chart: %v", err)
+		}
+
+		upstream := fmt.Sprintf("%s/longhorn", os.Getenv("CHARTS_DESTINATION"))
+		withproto := fmt.Sprintf("oci://proxy.replicated.com/anonymous/%s", upstream)
+
+		logrus.Infof("updating longhorn images")
+
+		err = updateLonghornAddonImages(c.Context, hcli, withproto, nextChartVersion)
+		if err != nil {
+			return fmt.Errorf("failed to update longhorn images: %w", err)
+		}
+
+		logrus.Infof("successfully updated longhorn addon")
+
+		return nil
+	},
+}
+
+var updateLonghornImagesCommand = &cli.Command{
+	Name:      "longhorn",
+	Usage:     "Updates the longhorn images",
+	UsageText: environmentUsageText,
+	Action: func(c *cli.Context) error {
+		logrus.Infof("updating longhorn images")
+
+		hcli, err := NewHelm()
+		if err != nil {
+			return fmt.Errorf("failed to create helm client: %w", err)
+		}
+		defer hcli.Close()
+
+		current := longhorn.Metadata
+
+		err = updateLonghornAddonImages(c.Context, hcli, current.Location, current.Version)
+		if err != nil {
+			return fmt.Errorf("failed to update longhorn images: %w", err)
+		}
+
+		logrus.Infof("successfully updated longhorn images")
+
+		return nil
+	},
+}
+
+func updateLonghornAddonImages(ctx context.Context, hcli helm.Client, chartURL string, chartVersion string) error {
+	newmeta := release.AddonMetadata{
+		Version:  chartVersion,
+		Location: chartURL,
+		Images:   make(map[string]release.AddonImage),
+	}
+
+	values, err := release.GetValuesWithOriginalImages("longhorn")
+	if err != nil {
+		return fmt.Errorf("failed to get longhorn values: %v", err)
+	}
+
+	logrus.Infof("extracting images from chart version %s", chartVersion)
+	images, err := helm.ExtractImagesFromChart(hcli, chartURL, chartVersion, values)
+	if err != nil {
+		return fmt.Errorf("failed to get images from longhorn chart: %w", err)
+	}
+
+	runtimeImages, err := longhornRuntimeImages(hcli, chartURL, chartVersion)
+	if err != nil {
+		return fmt.Errorf("failed to get runtime images from longhorn chart: %w", err)
+	}
+	images = helpers.UniqueStringSlice(append(images, runtimeImages...))
+
+	metaImages, err := UpdateImages(ctx, longhornImageComponents, longhorn.Metadata.Images, images)
+	if err != nil {
+		return fmt.Errorf("failed to update images: %w", err)
+	}
+	newmeta.Images = metaImages
+
+	logrus.Infof("saving addon manifest")
+	if err := newmeta.Save("longhorn"); err != nil {
+		return fmt.Errorf("failed to save metadata: %w", err)
+	}
+
+	return nil
+}
+
+// longhornRuntimeImages returns the images the longhorn manager starts at runtime, the engine,
+// instance manager and csi sidecars among others. They are passed to the manager as arguments
+// and environment variables so they are not found in the rendered chart.
+func longhornRuntimeImages(hcli helm.Client, chartURL string, chartVersion string) ([]string, error) {
+	chartPath, err := hcli.PullByRef(chartURL, chartVersion)
+	if err != nil {
+		return nil, fmt.Errorf("pull chart: %w", err)
+	}
+	defer os.RemoveAll(chartPath)
+
+	chrt, err := loader.Load(chartPath)
+	if err != nil {
+		return nil, fmt.Errorf("load chart: %w", err)
+	}
+
+	var images []string
+	for _, group := range []string{"longhorn", "csi"} {
+		components, err := chartutil.Values(chrt.Values).Table("image." + group)
+		if err != nil {
+			return nil, fmt.Errorf("get image.%s values: %w", group, err)
+		}
+		for name := range components {
+			component, err := components.Table(name)
+			if err != nil {
+				return nil, fmt.Errorf("get image.%s.%s values: %w", group, name, err)
+			}
+			repo, _ := component["repository"].(string)
+			tag, _ := component["tag"].(string)
+			if repo == "" || tag == "" {
+				continue
+			}
+			images = append(images, fmt.Sprintf("docker.io/%s:%s", repo, tag))
+		}
+	}
+	return images, nil
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/monitoring"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"github.com/replicatedhq/embedded-cluster/pkg/release"
+	"github.com/sirupsen/logrus"
+	"github.com/urfave/cli/v2"
+	"helm.sh/helm/v3/pkg/repo"
+)
+
+var prometheusRepo = &repo.Entry{
+	Name: "prometheus-community",
+	URL:  "https://prometheus-community.github.io/helm-charts",
+}
+
+var monitoringImageComponents = map[string]addonComponent{
+	"quay.io/prometheus/prometheus": {
+		name:             "prometheus",
+		useUpstreamImage: true,
+	},
+	"quay.io/prometheus-operator/prometheus-config-reloader": {
+		name:             "prometheus-config-reloader",
+		useUpstreamImage: true,
+	},
+	"registry.k8s.io/kube-state-metrics/kube-state-metrics": {
+		name:             "kube-state-metrics",
+		useUpstreamImage: true,
+	},
+	"quay.io/prometheus/node-exporter": {
+		name:             "node-exporter",
+		useUpstreamImage: true,
+	},
+}
+
+var updateMonitoringAddonCommand = &cli.Command{
+	Name:      "monitoring",
+	Usage:     "Updates the Monitoring addon",
+	UsageText: environmentUsageText,
+	Action: func(c *cli.Context) error {
+		logrus.Infof("updating prometheus addon")
+
+		hcli, err := NewHelm()
+		if err != nil {
+			return fmt.Errorf("failed to create helm client: %w", err)
+		}
+		defer hcli.Close()
+
+		nextChartVersion := os.Getenv("INPUT_PROMETHEUS_CHART_VERSION")
+		if nextChartVersion != "" {
+			logrus.Infof("using input override from INPUT_PROMETHEUS_CHART_VERSION: %s", nextChartVersion)
+		} else {
+			logrus.Infof("fetching the latest prometheus chart version")
+			latest, err := LatestChartVersion(hcli, prometheusRepo, "prometheus")
+			if err != nil {
+				return fmt.Errorf("failed to get the latest prometheus chart version: %v", err)
+			}
+			nextChartVersion = latest
+			logrus.Printf("latest prometheus chart version: %s", latest)
+		}
+		nextChartVersion = strings.TrimPrefix(nextChartVersion, "v")
+
+		current := monitoring.Metadata
+		if current.Version == nextChartVersion && !c.Bool("force") {
+			logrus.Infof("prometheus chart version is already up-to-date")
+			return nil
+		}
+
+		logrus.Infof("mirroring prometheus chart version %s", nextChartVersion)
+		if err := MirrorChart(hcli, prometheusRepo, "prometheus", nextChartVersion); err != nil {
+			return fmt.Errorf("failed to mirror prometheus chart: %v", err)
+		}
+
+		upstream := fmt.Sprintf("%s/prometheus", os.Getenv("CHARTS_DESTINATION"))
+		withproto := fmt.Sprintf("oci://proxy.replicated.com/anonymous/%s", upstream)
+
+		logrus.Infof("updating prometheus images")
+
+		err = updateMonitoringAddonImages(c.Context, hcli, withproto, nextChartVersion)
+		if err != nil {
+			return fmt.Errorf("failed to update prometheus images: %w", err)
+		}
+
+		logrus.Infof("successfully updated prometheus addon")
+
+		return nil
+	},
+}
+
+var updateMonitoringImagesCommand = &cli.Command{
+	Name:      "monitoring",
+	Usage:     "Updates the monitoring images",
+	UsageText: environmentUsageText,
+	Action: func(c *cli.Context) error {
+		logrus.Infof("updating prometheus images")
+
+		hcli, err := NewHelm()
+		if err != nil {
+			return fmt.Errorf("failed to create helm client: %w", err)
+		}
+		defer hcli.Close()
+
+		current := monitoring.Metadata
+
+		err = updateMonitoringAddonImages(c.Context, hcli, current.Location, current.Version)
+		if err != nil {
+			return fmt.Errorf("failed to update prometheus images: %w", err)
+		}
+
+		logrus.Infof("successfully updated prometheus images")
+
+		return nil
+	},
+}
+
+func updateMonitoringAddonImages(ctx context.Context, hcli helm.Client, chartURL string, chartVersion string) error {
+	newmeta := release.AddonMetadata{
+		Version:  chartVersion,
+		Location: chartURL,
+		Images:   make(map[string]release.AddonImage),
+	}
+
+	values, err := release.GetValuesWithOriginalImages("monitoring")
+	if err != nil {
+		return fmt.Errorf("failed to get prometheus values: %v", err)
+	}
+
+	logrus.Infof("extracting images from chart version %s", chartVersion)
+	images, err := helm.ExtractImagesFromChart(hcli, chartURL, chartVersion, values)
+	if err != nil {
+		return fmt.Errorf("failed to get images from prometheus chart: %w", err)
+	}
+
+	metaImages, err := UpdateImages(ctx, monitoringImageComponents, monitoring.Metadata.Images, images)
+	if err != nil {
+		return fmt.Errorf("failed to update images: %w", err)
+	}
+	newmeta.Images = metaImages
+
+	logrus.Infof("saving addon manifest")
+	if err := newmeta.Save("monitoring"); err != nil {
+		return fmt.Errorf("failed to save metadata: %w", err)
+	}
+
+	return nil
+}
@@ -24,11 +24,15 @@ var updateAddonCommand = &cli.Command{
 	},
 	Subcommands: []*cli.Command{
 		updateAdminConsoleAddonCommand,
+		updateCiliumAddonCommand,
 		updateOpenEBSAddonCommand,
+		updateLonghornAddonCommand,
 		updateOperatorAddonCommand,
 		updateRegistryAddonCommand,
 		updateVeleroAddonCommand,
 		updateSeaweedFSAddonCommand,
+		updateIngressAddonCommand,
+		updateMonitoringAddonCommand,
 	},
 }
 
@@ -37,9 +41,13 @@ var updateImagesCommand = &cli.Command{
 	Usage: "Update embedded cluster images",
 	Subcommands: []*cli.Command{
 		updateK0sImagesCommand,
+		updateCiliumImagesCommand,
 		updateOpenEBSImagesCommand,
+		updateLonghornImagesCommand,
 		updateOperatorImagesCommand,
 		updateSeaweedFSImagesCommand,
+		updateIngressImagesCommand,
+		updateMonitoringImagesCommand,
 		updateVeleroImagesCommand,
 	},
 }
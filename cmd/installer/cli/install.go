@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -23,6 +24,7 @@ import (
 	"github.com/replicatedhq/embedded-cluster/pkg/addons"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/adminconsole"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/embeddedclusteroperator"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/storage"
 	"github.com/replicatedhq/embedded-cluster/pkg/airgap"
 	"github.com/replicatedhq/embedded-cluster/pkg/config"
 	"github.com/replicatedhq/embedded-cluster/pkg/configutils"
@@ -30,7 +32,6 @@ import (
 	"github.com/replicatedhq/embedded-cluster/pkg/extensions"
 	"github.com/replicatedhq/embedded-cluster/pkg/helm"
 	"github.com/replicatedhq/embedded-cluster/pkg/helpers"
-	"github.com/replicatedhq/embedded-cluster/pkg/helpers/systemd"
 	"github.com/replicatedhq/embedded-cluster/pkg/k0s"
 	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
 	"github.com/replicatedhq/embedded-cluster/pkg/metrics"
@@ -67,13 +68,23 @@ type InstallCmdFlags struct {
 	privateCAs              []string
 	skipHostPreflights      bool
 	ignoreHostPreflights    bool
+	fixHostPreflights       bool
 	configValues            string
+	preflightReport         preflightReportFlags
+	ingressEnabled          bool
+	storageProvider         string
+	cniProvider             string
+	registryAddress         string
+	registryUsername        string
+	registryPassword        string
+	registryCA              string
 
 	networkInterface string
 
-	license *kotsv1beta1.License
-	proxy   *ecv1beta1.ProxySpec
-	cidrCfg *CIDRConfig
+	license          *kotsv1beta1.License
+	proxy            *ecv1beta1.ProxySpec
+	cidrCfg          *CIDRConfig
+	externalRegistry *ecv1beta1.ExternalRegistrySpec
 }
 
 // InstallCmd returns a cobra command for installing the embedded cluster.
@@ -115,6 +126,9 @@ func InstallCmd(ctx context.Context, name string) *cobra.Command {
 	if err := addInstallAdminConsoleFlags(cmd, &flags); err != nil {
 		panic(err)
 	}
+	if err := addInstallRegistryFlags(cmd, &flags); err != nil {
+		panic(err)
+	}
 
 	cmd.AddCommand(InstallRunPreflightsCmd(ctx, name))
 
@@ -151,6 +165,7 @@ func addInstallFlags(cmd *cobra.Command, flags *InstallCmdFlags) error {
 		return err
 	}
 	cmd.Flags().BoolVar(&flags.ignoreHostPreflights, "ignore-host-preflights", false, "Allow bypassing host preflight failures")
+	cmd.Flags().BoolVar(&flags.fixHostPreflights, "fix-preflights", false, "Fix known host preflight failures after confirmation and run the host preflights again")
 
 	return nil
 }
@@ -167,6 +182,15 @@ func addInstallAdminConsoleFlags(cmd *cobra.Command, flags *InstallCmdFlags) err
 	return nil
 }
 
+func addInstallRegistryFlags(cmd *cobra.Command, flags *InstallCmdFlags) error {
+	cmd.Flags().StringVar(&flags.registryAddress, "registry-address", "", "Address of an existing registry the air gap images are pushed to instead of the bundled registry")
+	cmd.Flags().StringVar(&flags.registryUsername, "registry-username", "", "Username for the registry set with --registry-address")
+	cmd.Flags().StringVar(&flags.registryPassword, "registry-password", "", "Password for the registry set with --registry-address")
+	cmd.Flags().StringVar(&flags.registryCA, "registry-ca", "", "Path to the CA certificate file used to verify the registry set with --registry-address")
+
+	return nil
+}
+
 func preRunInstall(cmd *cobra.Command, flags *InstallCmdFlags) error {
 	if os.Getuid() != 0 {
 		return fmt.Errorf("install command must be run as root")
@@ -224,6 +248,27 @@ func preRunInstall(cmd *cobra.Command, flags *InstallCmdFlags) error {
 
 	flags.isAirgap = flags.airgapBundle != ""
 
+	externalRegistry, err := getExternalRegistrySpec(flags)
+	if err != nil {
+		return err
+	}
+	flags.externalRegistry = externalRegistry
+
+	embCfgSpec, euCfgSpec, err := getConfigSpecs(flags.overrides)
+	if err != nil {
+		return fmt.Errorf("unable to process overrides file: %w", err)
+	}
+	if err := config.ValidateCNIProvider(embCfgSpec); err != nil {
+		return fmt.Errorf("invalid embedded cluster config: %w", err)
+	}
+	storageSpec := addons.GetStorageSpec(embCfgSpec, euCfgSpec)
+	if err := storage.ValidateProvider(storageSpec); err != nil {
+		return fmt.Errorf("invalid embedded cluster config: %w", err)
+	}
+	flags.ingressEnabled = addons.GetIngressSpec(embCfgSpec, euCfgSpec).Enabled
+	flags.storageProvider = storage.Provider(storageSpec)
+	flags.cniProvider = config.CNIProvider(embCfgSpec)
+
 	runtimeconfig.ApplyFlags(cmd.Flags())
 	os.Setenv("KUBECONFIG", runtimeconfig.PathToKubeConfig()) // this is needed for restore as well since it shares this function
 	os.Setenv("TMPDIR", runtimeconfig.EmbeddedClusterTmpSubDir())
@@ -241,6 +286,33 @@ func preRunInstall(cmd *cobra.Command, flags *InstallCmdFlags) error {
 	return nil
 }
 
+// getExternalRegistrySpec returns the external registry set through the install flags, if
+// any. The credentials are not part of the spec, they are only stored in the registry secret.
+func getExternalRegistrySpec(flags *InstallCmdFlags) (*ecv1beta1.ExternalRegistrySpec, error) {
+	if flags.registryAddress == "" {
+		if flags.registryUsername != "" || flags.registryPassword != "" || flags.registryCA != "" {
+			return nil, fmt.Errorf("--registry-address is required when setting the registry credentials or CA")
+		}
+		return nil, nil
+	}
+	if !flags.isAirgap {
+		return nil, fmt.Errorf("--registry-address can only be used with --airgap-bundle")
+	}
+	if flags.registryUsername == "" || flags.registryPassword == "" {
+		return nil, fmt.Errorf("--registry-username and --registry-password are required when using --registry-address")
+	}
+
+	spec := &ecv1beta1.ExternalRegistrySpec{Address: flags.registryAddress}
+	if flags.registryCA != "" {
+		data, err := os.ReadFile(flags.registryCA)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read registry CA file: %w", err)
+		}
+		spec.CA = string(data)
+	}
+	return spec, nil
+}
+
 func runInstall(ctx context.Context, name string, flags InstallCmdFlags, metricsReporter preflights.MetricsReporter) error {
 	if err := runInstallVerifyAndPrompt(ctx, name, &flags); err != nil {
 		return err
@@ -272,12 +344,12 @@ func runInstall(ctx context.Context, name string, flags InstallCmdFlags, metrics
 	}
 
 	logrus.Debugf("configuring network manager")
-	if err := configureNetworkManager(ctx); err != nil {
+	if err := configureNetworkManager(ctx, flags.cniProvider); err != nil {
 		return fmt.Errorf("unable to configure network manager: %w", err)
 	}
 
 	logrus.Debugf("configuring firewalld")
-	if err := configureFirewalld(ctx, flags.cidrCfg.PodCIDR, flags.cidrCfg.ServiceCIDR); err != nil {
+	if err := configutils.ConfigureFirewalld(ctx, flags.cidrCfg.PodCIDR, flags.cidrCfg.ServiceCIDR, flags.networkInterface, flags.cniProvider, flags.ingressEnabled); err != nil {
 		logrus.Debugf("unable to configure firewalld: %v", err)
 	}
 
@@ -289,7 +361,14 @@ func runInstall(ctx context.Context, name string, flags InstallCmdFlags, metrics
 		return fmt.Errorf("unable to run install preflights: %w", err)
 	}
 
-	k0sCfg, err := installAndStartCluster(ctx, flags.networkInterface, flags.airgapBundle, flags.proxy, flags.cidrCfg, flags.overrides, nil)
+	if flags.externalRegistry != nil {
+		logrus.Debugf("configuring external registry")
+		if err := airgap.AddExternalRegistry(*flags.externalRegistry); err != nil {
+			return fmt.Errorf("unable to configure external registry: %w", err)
+		}
+	}
+
+	k0sCfg, err := installAndStartCluster(ctx, flags.networkInterface, flags.airgapBundle, flags.proxy, flags.cidrCfg, flags.cniProvider, flags.overrides, nil)
 	if err != nil {
 		return fmt.Errorf("unable to install cluster: %w", err)
 	}
@@ -299,7 +378,7 @@ func runInstall(ctx context.Context, name string, flags InstallCmdFlags, metrics
 		return fmt.Errorf("unable to create kube client: %w", err)
 	}
 
-	errCh := kubeutils.WaitForKubernetes(ctx, kcli)
+	errCh := kubeutils.WaitForKubernetes(ctx, kcli, flags.cniProvider)
 	defer logKubernetesErrors(errCh)
 
 	disasterRecoveryEnabled, err := helpers.DisasterRecoveryEnabled(flags.license)
@@ -353,15 +432,18 @@ func runInstall(ctx context.Context, name string, flags InstallCmdFlags, metrics
 
 	logrus.Debugf("installing addons")
 	if err := addons.Install(ctx, hcli, addons.InstallOptions{
-		AdminConsolePwd:         flags.adminConsolePassword,
-		License:                 flags.license,
-		IsAirgap:                flags.airgapBundle != "",
-		Proxy:                   flags.proxy,
-		PrivateCAs:              flags.privateCAs,
-		ServiceCIDR:             flags.cidrCfg.ServiceCIDR,
-		DisasterRecoveryEnabled: disasterRecoveryEnabled,
-		EmbeddedConfigSpec:      embCfgSpec,
-		EndUserConfigSpec:       euCfgSpec,
+		AdminConsolePwd:          flags.adminConsolePassword,
+		License:                  flags.license,
+		IsAirgap:                 flags.airgapBundle != "",
+		Proxy:                    flags.proxy,
+		PrivateCAs:               flags.privateCAs,
+		ServiceCIDR:              flags.cidrCfg.ServiceCIDR,
+		DisasterRecoveryEnabled:  disasterRecoveryEnabled,
+		EmbeddedConfigSpec:       embCfgSpec,
+		EndUserConfigSpec:        euCfgSpec,
+		ExternalRegistry:         flags.externalRegistry,
+		ExternalRegistryUsername: flags.registryUsername,
+		ExternalRegistryPassword: flags.registryPassword,
 		KotsInstaller: func(msg *spinner.MessageWriter) error {
 			opts := kotscli.InstallOptions{
 				AppSlug:               flags.license.Spec.AppSlug,
@@ -614,18 +696,18 @@ func materializeFiles(airgapBundle string) error {
 	return nil
 }
 
-func installAndStartCluster(ctx context.Context, networkInterface string, airgapBundle string, proxy *ecv1beta1.ProxySpec, cidrCfg *CIDRConfig, overrides string, mutate func(*k0sv1beta1.ClusterConfig) error) (*k0sv1beta1.ClusterConfig, error) {
+func installAndStartCluster(ctx context.Context, networkInterface string, airgapBundle string, proxy *ecv1beta1.ProxySpec, cidrCfg *CIDRConfig, cniProvider string, overrides string, mutate func(*k0sv1beta1.ClusterConfig) error) (*k0sv1beta1.ClusterConfig, error) {
 	loading := spinner.Start()
 	defer loading.Close()
 	loading.Infof("Installing %s node", runtimeconfig.BinaryName())
 	logrus.Debugf("creating k0s configuration file")
 
-	cfg, err := k0s.WriteK0sConfig(ctx, networkInterface, airgapBundle, cidrCfg.PodCIDR, cidrCfg.ServiceCIDR, overrides, mutate)
+	cfg, err := k0s.WriteK0sConfig(ctx, networkInterface, airgapBundle, cidrCfg.PodCIDR, cidrCfg.ServiceCIDR, cniProvider, overrides, mutate)
 	if err != nil {
 		return nil, fmt.Errorf("create config file: %w", err)
 	}
 	logrus.Debugf("creating systemd unit files")
-	if err := createSystemdUnitFiles(ctx, false, proxy); err != nil {
+	if err := createSystemdUnitFiles(ctx, false, proxy, networkInterface); err != nil {
 		return nil, fmt.Errorf("create systemd unit files: %w", err)
 	}
 
@@ -641,9 +723,13 @@ func installAndStartCluster(ctx context.Context, networkInterface string, airgap
 		return nil, fmt.Errorf("wait for k0s: %w", err)
 	}
 
-	logrus.Debugf("waiting for node to be ready")
-	if err := waitForNode(ctx); err != nil {
-		return nil, fmt.Errorf("wait for node: %w", err)
+	// the node only becomes ready once the pod network is up, a cni not deployed by k0s is
+	// installed with the other addons.
+	if cfg.Spec.Network.Provider != config.CustomNetworkProvider {
+		logrus.Debugf("waiting for node to be ready")
+		if err := waitForNode(ctx); err != nil {
+			return nil, fmt.Errorf("wait for node: %w", err)
+		}
 	}
 
 	loading.Infof("Node installation finished!")
@@ -651,9 +737,9 @@ func installAndStartCluster(ctx context.Context, networkInterface string, airgap
 }
 
 // configureNetworkManager configures the network manager (if the host is using it) to ignore
-// the calico interfaces. This function restarts the NetworkManager service if the configuration
-// was changed.
-func configureNetworkManager(ctx context.Context) error {
+// the interfaces of the cni provider. This function restarts the NetworkManager service if the
+// configuration was changed.
+func configureNetworkManager(ctx context.Context, cniProvider string) error {
 	if active, err := helpers.IsSystemdServiceActive(ctx, "NetworkManager"); err != nil {
 		return fmt.Errorf("unable to check if NetworkManager is active: %w", err)
 	} else if !active {
@@ -669,7 +755,7 @@ func configureNetworkManager(ctx context.Context) error {
 
 	logrus.Debugf("creating NetworkManager config file")
 	materializer := goods.NewMaterializer()
-	if err := materializer.CalicoNetworkManagerConfig(); err != nil {
+	if err := materializer.NetworkManagerConfig(cniProvider); err != nil {
 		return fmt.Errorf("unable to materialize configuration: %w", err)
 	}
 
@@ -793,7 +879,7 @@ func validateAdminConsolePassword(password, passwordCheck string) bool {
 
 // createSystemdUnitFiles links the k0s systemd unit file. this also creates a new
 // systemd unit file for the local artifact mirror service.
-func createSystemdUnitFiles(ctx context.Context, isWorker bool, proxy *ecv1beta1.ProxySpec) error {
+func createSystemdUnitFiles(ctx context.Context, isWorker bool, proxy *ecv1beta1.ProxySpec, networkInterface string) error {
 	dst := systemdUnitFileName()
 	if _, err := os.Lstat(dst); err == nil {
 		if err := os.Remove(dst); err != nil {
@@ -817,7 +903,7 @@ func createSystemdUnitFiles(ctx context.Context, isWorker bool, proxy *ecv1beta1
 	if _, err := helpers.RunCommand("systemctl", "daemon-reload"); err != nil {
 		return fmt.Errorf("unable to get reload systemctl daemon: %w", err)
 	}
-	if err := installAndEnableLocalArtifactMirror(ctx); err != nil {
+	if err := installAndEnableLocalArtifactMirror(ctx, networkInterface); err != nil {
 		return fmt.Errorf("unable to install and enable local artifact mirror: %w", err)
 	}
 	return nil
@@ -851,13 +937,14 @@ Environment="NO_PROXY=%s"`, httpProxy, httpsProxy, noProxy)
 
 // installAndEnableLocalArtifactMirror installs and enables the local artifact mirror. This
 // service is responsible for serving on localhost, through http, all files that are used
-// during a cluster upgrade.
-func installAndEnableLocalArtifactMirror(ctx context.Context) error {
+// during a cluster upgrade. The airgap artifacts are also served to the other nodes on the
+// address of the provided network interface.
+func installAndEnableLocalArtifactMirror(ctx context.Context, networkInterface string) error {
 	materializer := goods.NewMaterializer()
 	if err := materializer.LocalArtifactMirrorUnitFile(); err != nil {
 		return fmt.Errorf("failed to materialize artifact mirror unit: %w", err)
 	}
-	if err := writeLocalArtifactMirrorDropInFile(); err != nil {
+	if err := writeLocalArtifactMirrorDropInFile(networkInterface); err != nil {
 		return fmt.Errorf("failed to write local artifact mirror environment file: %w", err)
 	}
 	if _, err := helpers.RunCommand("systemctl", "daemon-reload"); err != nil {
@@ -877,15 +964,19 @@ func installAndEnableLocalArtifactMirror(ctx context.Context) error {
 	return nil
 }
 
+// waitForLocalArtifactMirror waits until the local artifact mirror readiness endpoint reports
+// the service as ready for a few consecutive checks.
 func waitForLocalArtifactMirror(ctx context.Context) error {
 	consecutiveSuccesses := 0
 	requiredSuccesses := 3
 	maxAttempts := 30
 	checkInterval := 2 * time.Second
 
+	url := fmt.Sprintf("http://127.0.0.1:%d/readyz", runtimeconfig.LocalArtifactMirrorPort())
+
 	var lastErr error
 	for attempt := 0; attempt < maxAttempts; attempt++ {
-		_, err := helpers.RunCommand("systemctl", "status", "local-artifact-mirror")
+		err := checkLocalArtifactMirrorReady(ctx, url)
 		if err == nil {
 			consecutiveSuccesses++
 			if consecutiveSuccesses >= requiredSuccesses {
@@ -907,30 +998,33 @@ func waitForLocalArtifactMirror(ctx context.Context) error {
 	return lastErr
 }
 
-const (
-	localArtifactMirrorDropInFileContents = `[Service]
-Environment="LOCAL_ARTIFACT_MIRROR_PORT=%d"
-Environment="LOCAL_ARTIFACT_MIRROR_DATA_DIR=%s"
-# Empty ExecStart= will clear out the previous ExecStart value
-ExecStart=
-ExecStart=%s serve
-`
-)
+func checkLocalArtifactMirrorReady(ctx context.Context, url string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
 
-func writeLocalArtifactMirrorDropInFile() error {
-	contents := fmt.Sprintf(
-		localArtifactMirrorDropInFileContents,
-		runtimeconfig.LocalArtifactMirrorPort(),
-		runtimeconfig.EmbeddedClusterHomeDirectory(),
-		runtimeconfig.PathToEmbeddedClusterBinary("local-artifact-mirror"),
-	)
-	err := systemd.WriteDropInFile("local-artifact-mirror.service", "embedded-cluster.conf", []byte(contents))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("write drop-in file: %w", err)
+		return fmt.Errorf("get %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("local artifact mirror not ready: status code %d", resp.StatusCode)
 	}
 	return nil
 }
 
+func writeLocalArtifactMirrorDropInFile(networkInterface string) error {
+	peerAddress, err := netutils.FirstValidAddress(networkInterface)
+	if err != nil {
+		return fmt.Errorf("get node address: %w", err)
+	}
+	return configutils.WriteLocalArtifactMirrorDropInFile(peerAddress)
+}
+
 // waitForK0s waits for the k0s API to be available. We wait for the k0s socket to
 // appear in the system and until the k0s status command to finish.
 func waitForK0s() error {
@@ -997,6 +1091,7 @@ func recordInstallation(ctx context.Context, kcli client.Client, flags InstallCm
 	}
 
 	var euOverrides string
+	var euCfgSpec *ecv1beta1.ConfigSpec
 	if flags.overrides != "" {
 		eucfg, err := helpers.ParseEndUserConfig(flags.overrides)
 		if err != nil {
@@ -1004,6 +1099,7 @@ func recordInstallation(ctx context.Context, kcli client.Client, flags InstallCm
 		}
 		if eucfg != nil {
 			euOverrides = eucfg.Spec.UnsupportedOverrides.K0s
+			euCfgSpec = &eucfg.Spec
 		}
 	}
 
@@ -1019,11 +1115,13 @@ func recordInstallation(ctx context.Context, kcli client.Client, flags InstallCm
 			ClusterID:                 metrics.ClusterID().String(),
 			MetricsBaseURL:            metrics.BaseURL(flags.license),
 			AirGap:                    flags.isAirgap,
+			ExternalRegistry:          flags.externalRegistry,
 			Proxy:                     flags.proxy,
 			Network:                   networkSpecFromK0sConfig(k0sCfg),
 			Config:                    cfgspec,
 			RuntimeConfig:             runtimeconfig.Get(),
 			EndUserK0sConfigOverrides: euOverrides,
+			EndUserConfig:             euCfgSpec,
 			BinaryName:                runtimeconfig.BinaryName(),
 			LicenseInfo: &ecv1beta1.LicenseInfo{
 				IsDisasterRecoverySupported: disasterRecoveryEnabled,
@@ -1199,7 +1297,16 @@ func gatherVersionMetadata(withChannelRelease bool) (*types.ReleaseMetadata, err
 		Repositories:     append(repconfig, additionalRepos...),
 	}
 
-	k0sCfg := config.RenderK0sConfig()
+	// the k0s images depend on the cni, calico is only deployed by k0s when selected.
+	embCfg, err := release.GetEmbeddedClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get embedded cluster config: %w", err)
+	}
+	var embCfgSpec *ecv1beta1.ConfigSpec
+	if embCfg != nil {
+		embCfgSpec = &embCfg.Spec
+	}
+	k0sCfg := config.RenderK0sConfig(config.CNIProvider(embCfgSpec))
 	meta.K0sImages = config.ListK0sImages(k0sCfg)
 	meta.K0sImages = append(meta.K0sImages, addons.GetAdditionalImages()...)
 	meta.K0sImages = helpers.UniqueStringSlice(meta.K0sImages)
@@ -1239,6 +1346,30 @@ func normalizeNoPromptToYes(f *pflag.FlagSet, name string) pflag.NormalizedName
 	return pflag.NormalizedName(name)
 }
 
+// getConfigSpecs returns the embedded config spec and the end user config spec from the
+// overrides file. Either is nil if not provided.
+func getConfigSpecs(overrides string) (*ecv1beta1.ConfigSpec, *ecv1beta1.ConfigSpec, error) {
+	embCfg, err := release.GetEmbeddedClusterConfig()
+	if err != nil {
+		return nil, nil, fmt.Errorf("get embedded cluster config: %w", err)
+	}
+	var embCfgSpec *ecv1beta1.ConfigSpec
+	if embCfg != nil {
+		embCfgSpec = &embCfg.Spec
+	}
+
+	euCfg, err := helpers.ParseEndUserConfig(overrides)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parse end user config: %w", err)
+	}
+	var euCfgSpec *ecv1beta1.ConfigSpec
+	if euCfg != nil {
+		euCfgSpec = &euCfg.Spec
+	}
+
+	return embCfgSpec, euCfgSpec, nil
+}
+
 func copyLicenseFileToDataDir(licenseFile, dataDir string) error {
 	if licenseFile == "" {
 		return nil
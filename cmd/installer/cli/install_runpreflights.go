@@ -23,6 +23,9 @@ func InstallRunPreflightsCmd(ctx context.Context, name string) *cobra.Command {
 			if err := preRunInstall(cmd, &flags); err != nil {
 				return err
 			}
+			if err := validatePreflightReportFlags(flags.preflightReport, flags.ignoreHostPreflights); err != nil {
+				return err
+			}
 
 			return nil
 		},
@@ -44,6 +47,7 @@ func InstallRunPreflightsCmd(ctx context.Context, name string) *cobra.Command {
 	if err := addInstallAdminConsoleFlags(cmd, &flags); err != nil {
 		panic(err)
 	}
+	addPreflightReportFlags(cmd, &flags.preflightReport)
 
 	return cmd
 }
@@ -93,6 +97,11 @@ func runInstallPreflights(ctx context.Context, flags InstallCmdFlags, metricsRep
 		return fmt.Errorf("unable to find first valid address: %w", err)
 	}
 
+	var reportFile string
+	if flags.preflightReport.format != "" {
+		reportFile = flags.preflightReport.path()
+	}
+
 	if err := preflights.PrepareAndRun(ctx, preflights.PrepareAndRunOptions{
 		ReplicatedAPIURL:     replicatedAPIURL,
 		ProxyRegistryURL:     proxyRegistryURL,
@@ -101,11 +110,18 @@ func runInstallPreflights(ctx context.Context, flags InstallCmdFlags, metricsRep
 		ServiceCIDR:          flags.cidrCfg.ServiceCIDR,
 		GlobalCIDR:           flags.cidrCfg.GlobalCIDR,
 		NodeIP:               nodeIP,
+		NetworkInterface:     flags.networkInterface,
 		PrivateCAs:           flags.privateCAs,
 		IsAirgap:             flags.isAirgap,
 		SkipHostPreflights:   flags.skipHostPreflights,
 		IgnoreHostPreflights: flags.ignoreHostPreflights,
+		FixHostPreflights:    flags.fixHostPreflights,
 		AssumeYes:            flags.assumeYes,
+		IngressEnabled:       flags.ingressEnabled,
+		StorageProvider:      flags.storageProvider,
+		CNIProvider:          flags.cniProvider,
+		ReportFormat:         flags.preflightReport.format,
+		ReportFile:           reportFile,
 		MetricsReporter:      metricsReported,
 	}); err != nil {
 		return err
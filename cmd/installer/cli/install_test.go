@@ -10,6 +10,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
 	"github.com/replicatedhq/embedded-cluster/pkg/prompts"
 	"github.com/replicatedhq/embedded-cluster/pkg/prompts/plain"
 	"github.com/replicatedhq/embedded-cluster/pkg/release"
@@ -520,3 +521,58 @@ versionLabel: testversion
 		})
 	}
 }
+
+func Test_getExternalRegistrySpec(t *testing.T) {
+	caFile := filepath.Join(t.TempDir(), "ca.crt")
+	require.NoError(t, os.WriteFile(caFile, []byte("-----BEGIN CERTIFICATE-----"), 0644))
+
+	tests := []struct {
+		name    string
+		flags   InstallCmdFlags
+		want    *ecv1beta1.ExternalRegistrySpec
+		wantErr string
+	}{
+		{
+			name:  "no external registry",
+			flags: InstallCmdFlags{isAirgap: true},
+			want:  nil,
+		},
+		{
+			name: "external registry with ca",
+			flags: InstallCmdFlags{
+				isAirgap:         true,
+				registryAddress:  "harbor.example.com",
+				registryUsername: "robot",
+				registryPassword: "secret",
+				registryCA:       caFile,
+			},
+			want: &ecv1beta1.ExternalRegistrySpec{Address: "harbor.example.com", CA: "-----BEGIN CERTIFICATE-----"},
+		},
+		{
+			name:    "credentials without address",
+			flags:   InstallCmdFlags{isAirgap: true, registryUsername: "robot"},
+			wantErr: "--registry-address is required when setting the registry credentials or CA",
+		},
+		{
+			name:    "online installation",
+			flags:   InstallCmdFlags{registryAddress: "harbor.example.com", registryUsername: "robot", registryPassword: "secret"},
+			wantErr: "--registry-address can only be used with --airgap-bundle",
+		},
+		{
+			name:    "missing password",
+			flags:   InstallCmdFlags{isAirgap: true, registryAddress: "harbor.example.com", registryUsername: "robot"},
+			wantErr: "--registry-username and --registry-password are required when using --registry-address",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getExternalRegistrySpec(&tt.flags)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -39,6 +39,7 @@ type JoinCmdFlags struct {
 	assumeYes              bool
 	skipHostPreflights     bool
 	ignoreHostPreflights   bool
+	preflightReport        preflightReportFlags
 }
 
 // This is the upcoming version of join without the operator and where
@@ -148,8 +149,10 @@ func runJoin(ctx context.Context, name string, flags JoinCmdFlags, jcmd *kotsadm
 		logrus.Debugf("unable to configure kernel modules: %v", err)
 	}
 
+	cniProvider := config.CNIProvider(jcmd.InstallationSpec.Config)
+
 	logrus.Debugf("configuring network manager")
-	if err := configureNetworkManager(ctx); err != nil {
+	if err := configureNetworkManager(ctx, cniProvider); err != nil {
 		return fmt.Errorf("unable to configure network manager: %w", err)
 	}
 
@@ -159,7 +162,8 @@ func runJoin(ctx context.Context, name string, flags JoinCmdFlags, jcmd *kotsadm
 	}
 
 	logrus.Debugf("configuring firewalld")
-	if err := configureFirewalld(ctx, cidrCfg.PodCIDR, cidrCfg.ServiceCIDR); err != nil {
+	ingressEnabled := addons.GetIngressSpec(jcmd.InstallationSpec.Config, jcmd.InstallationSpec.EndUserConfig).Enabled
+	if err := configutils.ConfigureFirewalld(ctx, cidrCfg.PodCIDR, cidrCfg.ServiceCIDR, flags.networkInterface, cniProvider, ingressEnabled); err != nil {
 		logrus.Debugf("unable to configure firewalld: %v", err)
 	}
 
@@ -211,7 +215,7 @@ func runJoin(ctx context.Context, name string, flags JoinCmdFlags, jcmd *kotsadm
 	}
 
 	if flags.enableHighAvailability {
-		if err := maybeEnableHA(ctx, kcli, hcli, flags.isAirgap, cidrCfg.ServiceCIDR, jcmd.InstallationSpec.Proxy, jcmd.InstallationSpec.Config); err != nil {
+		if err := maybeEnableHA(ctx, kcli, hcli, flags.isAirgap, cidrCfg.ServiceCIDR, jcmd.InstallationSpec.Proxy, jcmd.InstallationSpec.Config, jcmd.InstallationSpec.EndUserConfig); err != nil {
 			return fmt.Errorf("unable to enable high availability: %w", err)
 		}
 	}
@@ -307,7 +311,11 @@ func installAndJoinCluster(ctx context.Context, jcmd *kotsadm.JoinCommandRespons
 		return fmt.Errorf("unable to install k0s binary: %w", err)
 	}
 
-	if jcmd.AirgapRegistryAddress != "" {
+	if jcmd.InstallationSpec.ExternalRegistry != nil {
+		if err := airgap.AddExternalRegistry(*jcmd.InstallationSpec.ExternalRegistry); err != nil {
+			return fmt.Errorf("unable to add external registry: %w", err)
+		}
+	} else if jcmd.AirgapRegistryAddress != "" {
 		if err := airgap.AddInsecureRegistry(jcmd.AirgapRegistryAddress); err != nil {
 			return fmt.Errorf("unable to add insecure registry: %w", err)
 		}
@@ -316,7 +324,7 @@ func installAndJoinCluster(ctx context.Context, jcmd *kotsadm.JoinCommandRespons
 	logrus.Debugf("creating systemd unit files")
 	// both controller and worker nodes will have 'worker' in the join command
 	isWorker := !strings.Contains(jcmd.K0sJoinCommand, "controller")
-	if err := createSystemdUnitFiles(ctx, isWorker, jcmd.InstallationSpec.Proxy); err != nil {
+	if err := createSystemdUnitFiles(ctx, isWorker, jcmd.InstallationSpec.Proxy, flags.networkInterface); err != nil {
 		return fmt.Errorf("unable to create systemd unit files: %w", err)
 	}
 
@@ -366,7 +374,7 @@ func installK0sBinary() error {
 
 func applyNetworkConfiguration(networkInterface string, jcmd *kotsadm.JoinCommandResponse) error {
 	if jcmd.InstallationSpec.Network != nil {
-		clusterSpec := config.RenderK0sConfig()
+		clusterSpec := config.RenderK0sConfig(config.CNIProvider(jcmd.InstallationSpec.Config))
 
 		address, err := netutils.FirstValidAddress(networkInterface)
 		if err != nil {
@@ -482,7 +490,7 @@ func waitForNodeToJoin(ctx context.Context, kcli client.Client, hostname string)
 	return nil
 }
 
-func maybeEnableHA(ctx context.Context, kcli client.Client, hcli helm.Client, isAirgap bool, serviceCIDR string, proxy *ecv1beta1.ProxySpec, cfgspec *ecv1beta1.ConfigSpec) error {
+func maybeEnableHA(ctx context.Context, kcli client.Client, hcli helm.Client, isAirgap bool, serviceCIDR string, proxy *ecv1beta1.ProxySpec, cfgspec *ecv1beta1.ConfigSpec, euCfgSpec *ecv1beta1.ConfigSpec) error {
 	canEnableHA, err := addons.CanEnableHA(ctx, kcli)
 	if err != nil {
 		return fmt.Errorf("unable to check if HA can be enabled: %w", err)
@@ -498,5 +506,5 @@ func maybeEnableHA(ctx context.Context, kcli client.Client, hcli helm.Client, is
 		return nil
 	}
 	logrus.Info("")
-	return addons.EnableHA(ctx, kcli, hcli, isAirgap, serviceCIDR, proxy, cfgspec)
+	return addons.EnableHA(ctx, kcli, hcli, isAirgap, serviceCIDR, proxy, cfgspec, euCfgSpec)
 }
@@ -5,6 +5,9 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/replicatedhq/embedded-cluster/pkg/addons"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/storage"
+	"github.com/replicatedhq/embedded-cluster/pkg/config"
 	"github.com/replicatedhq/embedded-cluster/pkg/configutils"
 	"github.com/replicatedhq/embedded-cluster/pkg/kotsadm"
 	"github.com/replicatedhq/embedded-cluster/pkg/netutils"
@@ -25,6 +28,9 @@ func JoinRunPreflightsCmd(ctx context.Context, name string) *cobra.Command {
 			if err := preRunJoin(&flags); err != nil {
 				return err
 			}
+			if err := validatePreflightReportFlags(flags.preflightReport, flags.ignoreHostPreflights); err != nil {
+				return err
+			}
 
 			return nil
 		},
@@ -48,6 +54,7 @@ func JoinRunPreflightsCmd(ctx context.Context, name string) *cobra.Command {
 	if err := addJoinFlags(cmd, &flags); err != nil {
 		panic(err)
 	}
+	addPreflightReportFlags(cmd, &flags.preflightReport)
 
 	return cmd
 }
@@ -96,6 +103,11 @@ func runJoinPreflights(ctx context.Context, jcmd *kotsadm.JoinCommandResponse, f
 		return fmt.Errorf("unable to find first valid address: %w", err)
 	}
 
+	var reportFile string
+	if flags.preflightReport.format != "" {
+		reportFile = flags.preflightReport.path()
+	}
+
 	if err := preflights.PrepareAndRun(ctx, preflights.PrepareAndRunOptions{
 		ReplicatedAPIURL:       jcmd.InstallationSpec.MetricsBaseURL, // MetricsBaseURL is the replicated.app endpoint url
 		ProxyRegistryURL:       fmt.Sprintf("https://%s", runtimeconfig.ProxyRegistryAddress),
@@ -103,12 +115,18 @@ func runJoinPreflights(ctx context.Context, jcmd *kotsadm.JoinCommandResponse, f
 		PodCIDR:                cidrCfg.PodCIDR,
 		ServiceCIDR:            cidrCfg.ServiceCIDR,
 		NodeIP:                 nodeIP,
+		NetworkInterface:       flags.networkInterface,
 		IsAirgap:               flags.isAirgap,
 		SkipHostPreflights:     flags.skipHostPreflights,
 		IgnoreHostPreflights:   flags.ignoreHostPreflights,
 		AssumeYes:              flags.assumeYes,
+		IngressEnabled:         addons.GetIngressSpec(jcmd.InstallationSpec.Config, jcmd.InstallationSpec.EndUserConfig).Enabled,
+		StorageProvider:        storage.Provider(addons.GetStorageSpec(jcmd.InstallationSpec.Config, jcmd.InstallationSpec.EndUserConfig)),
+		CNIProvider:            config.CNIProvider(jcmd.InstallationSpec.Config),
 		TCPConnectionsRequired: jcmd.TCPConnectionsRequired,
 		IsJoin:                 true,
+		ReportFormat:           flags.preflightReport.format,
+		ReportFile:             reportFile,
 	}); err != nil {
 		return err
 	}
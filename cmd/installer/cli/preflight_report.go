@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/preflights"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"github.com/spf13/cobra"
+)
+
+// preflightReportFlags holds the flags used to write a machine readable host preflight report.
+type preflightReportFlags struct {
+	format string
+	file   string
+}
+
+func addPreflightReportFlags(cmd *cobra.Command, flags *preflightReportFlags) {
+	cmd.Flags().StringVar(&flags.format, "output", "", fmt.Sprintf("Write a host preflight report in the given format (%s)", strings.Join(preflights.ReportFormats, ", ")))
+	cmd.Flags().StringVar(&flags.file, "output-file", "", "Path to write the host preflight report to. Defaults to a file in the support directory.")
+}
+
+// validatePreflightReportFlags validates the report flags. Automation gates on the exit code
+// when a report is requested so host preflight failures can not be ignored.
+func validatePreflightReportFlags(flags preflightReportFlags, ignoreHostPreflights bool) error {
+	if flags.format == "" {
+		if flags.file != "" {
+			return fmt.Errorf("--output-file requires --output")
+		}
+		return nil
+	}
+	if !slices.Contains(preflights.ReportFormats, flags.format) {
+		return fmt.Errorf("invalid --output %q, must be one of %s", flags.format, strings.Join(preflights.ReportFormats, ", "))
+	}
+	if ignoreHostPreflights {
+		return fmt.Errorf("--output can not be used with --ignore-host-preflights")
+	}
+	return nil
+}
+
+// path returns the path the report is written to. This must be called after the data directory
+// has been set as the default location is in the support directory.
+func (f preflightReportFlags) path() string {
+	if f.file != "" {
+		return f.file
+	}
+	return runtimeconfig.PathToEmbeddedClusterSupportFile(fmt.Sprintf("host-preflight-report.%s", preflights.ReportFileExtension(f.format)))
+}
@@ -22,6 +22,7 @@ import (
 	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons"
 	"github.com/replicatedhq/embedded-cluster/pkg/airgap"
+	"github.com/replicatedhq/embedded-cluster/pkg/config"
 	"github.com/replicatedhq/embedded-cluster/pkg/configutils"
 	"github.com/replicatedhq/embedded-cluster/pkg/constants"
 	"github.com/replicatedhq/embedded-cluster/pkg/disasterrecovery"
@@ -213,7 +214,7 @@ func runRestore(ctx context.Context, name string, flags InstallCmdFlags, s3Store
 			return fmt.Errorf("unable to set restore state: %w", err)
 		}
 
-		err = runRestoreECInstall(ctx, backupToRestore)
+		err = runRestoreECInstall(ctx, backupToRestore, flags.networkInterface)
 		if err != nil {
 			return err
 		}
@@ -370,12 +371,12 @@ func runRestoreStepNew(ctx context.Context, name string, flags InstallCmdFlags,
 	}
 
 	logrus.Debugf("configuring network manager")
-	if err := configureNetworkManager(ctx); err != nil {
+	if err := configureNetworkManager(ctx, flags.cniProvider); err != nil {
 		return fmt.Errorf("unable to configure network manager: %w", err)
 	}
 
 	logrus.Debugf("configuring firewalld")
-	if err := configureFirewalld(ctx, flags.cidrCfg.PodCIDR, flags.cidrCfg.ServiceCIDR); err != nil {
+	if err := configutils.ConfigureFirewalld(ctx, flags.cidrCfg.PodCIDR, flags.cidrCfg.ServiceCIDR, flags.networkInterface, flags.cniProvider, flags.ingressEnabled); err != nil {
 		logrus.Debugf("unable to configure firewalld: %v", err)
 	}
 
@@ -392,7 +393,7 @@ func runRestoreStepNew(ctx context.Context, name string, flags InstallCmdFlags,
 		return fmt.Errorf("unable to run install preflights: %w", err)
 	}
 
-	_, err = installAndStartCluster(ctx, flags.networkInterface, flags.airgapBundle, flags.proxy, flags.cidrCfg, flags.overrides, nil)
+	_, err = installAndStartCluster(ctx, flags.networkInterface, flags.airgapBundle, flags.proxy, flags.cidrCfg, flags.cniProvider, flags.overrides, nil)
 	if err != nil {
 		return err
 	}
@@ -417,18 +418,38 @@ func runRestoreStepNew(ctx context.Context, name string, flags InstallCmdFlags,
 	}
 	defer hcli.Close()
 
-	errCh := kubeutils.WaitForKubernetes(ctx, kcli)
+	embCfg, err := release.GetEmbeddedClusterConfig()
+	if err != nil {
+		return fmt.Errorf("unable to get release embedded cluster config: %w", err)
+	}
+	var embCfgSpec *ecv1beta1.ConfigSpec
+	if embCfg != nil {
+		embCfgSpec = &embCfg.Spec
+	}
+
+	errCh := kubeutils.WaitForKubernetes(ctx, kcli, config.CNIProvider(embCfgSpec))
 	defer logKubernetesErrors(errCh)
 
 	// TODO (@salah): update installation status to reflect what's happening
 
+	euCfg, err := helpers.ParseEndUserConfig(flags.overrides)
+	if err != nil {
+		return fmt.Errorf("unable to process overrides file: %w", err)
+	}
+	var euCfgSpec *ecv1beta1.ConfigSpec
+	if euCfg != nil {
+		euCfgSpec = &euCfg.Spec
+	}
+
 	logrus.Debugf("installing addons")
 	if err := addons.Install(ctx, hcli, addons.InstallOptions{
-		IsAirgap:    flags.airgapBundle != "",
-		Proxy:       flags.proxy,
-		PrivateCAs:  flags.privateCAs,
-		ServiceCIDR: flags.cidrCfg.ServiceCIDR,
-		IsRestore:   true,
+		IsAirgap:           flags.airgapBundle != "",
+		Proxy:              flags.proxy,
+		PrivateCAs:         flags.privateCAs,
+		ServiceCIDR:        flags.cidrCfg.ServiceCIDR,
+		EmbeddedConfigSpec: embCfgSpec,
+		EndUserConfigSpec:  euCfgSpec,
+		IsRestore:          true,
 		// TODO: pass in custom domain
 	}); err != nil {
 		return err
@@ -483,7 +504,7 @@ func runRestoreStepConfirmBackup(ctx context.Context, flags InstallCmdFlags) (*d
 	return backupToRestore, true, nil
 }
 
-func runRestoreECInstall(ctx context.Context, backupToRestore *disasterrecovery.ReplicatedBackup) error {
+func runRestoreECInstall(ctx context.Context, backupToRestore *disasterrecovery.ReplicatedBackup, networkInterface string) error {
 	logrus.Debugf("restoring embedded cluster installation from backup %q", backupToRestore.GetName())
 	if err := restoreFromReplicatedBackup(ctx, *backupToRestore, disasterRecoveryComponentECInstall, true); err != nil {
 		return fmt.Errorf("unable to restore from backup: %w", err)
@@ -495,7 +516,7 @@ func runRestoreECInstall(ctx context.Context, backupToRestore *disasterrecovery.
 	}
 
 	logrus.Debugf("updating local artifact mirror service from backup %q", backupToRestore.GetName())
-	if err := updateLocalArtifactMirrorService(); err != nil {
+	if err := updateLocalArtifactMirrorService(networkInterface); err != nil {
 		return fmt.Errorf("unable to update local artifact mirror service from backup: %w", err)
 	}
 
@@ -565,7 +586,7 @@ func runRestoreEnableAdminConsoleHA(ctx context.Context, flags InstallCmdFlags,
 	}
 	defer hcli.Close()
 
-	err = addons.EnableAdminConsoleHA(ctx, kcli, hcli, flags.isAirgap, flags.cidrCfg.ServiceCIDR, flags.proxy, in.Spec.Config)
+	err = addons.EnableAdminConsoleHA(ctx, kcli, hcli, flags.isAirgap, flags.cidrCfg.ServiceCIDR, flags.proxy, in.Spec.Config, in.Spec.EndUserConfig)
 	if err != nil {
 		return err
 	}
@@ -584,6 +605,14 @@ func runRestoreSeaweedFS(ctx context.Context, flags InstallCmdFlags, backupToRes
 		return nil
 	}
 
+	// seaweedfs only holds the data of the bundled registry.
+	externalRegistry, err := getRestoredExternalRegistry(ctx)
+	if err != nil {
+		return err
+	} else if externalRegistry != nil {
+		return nil
+	}
+
 	logrus.Debugf("restoring seaweedfs from backup %q", backupToRestore.GetName())
 	if err := restoreFromReplicatedBackup(ctx, *backupToRestore, disasterRecoveryComponentSeaweedFS, true); err != nil {
 		return err
@@ -598,6 +627,19 @@ func runRestoreRegistry(ctx context.Context, flags InstallCmdFlags, backupToRest
 		return nil
 	}
 
+	// the images are still in the external registry, containerd only needs to be configured to
+	// pull from it.
+	externalRegistry, err := getRestoredExternalRegistry(ctx)
+	if err != nil {
+		return err
+	} else if externalRegistry != nil {
+		logrus.Debugf("configuring external registry %q", externalRegistry.Address)
+		if err := airgap.AddExternalRegistry(*externalRegistry); err != nil {
+			return fmt.Errorf("failed to add external registry: %w", err)
+		}
+		return nil
+	}
+
 	logrus.Debugf("restoring embedded cluster registry from backup %q", backupToRestore.GetName())
 	if err := restoreFromReplicatedBackup(ctx, *backupToRestore, disasterRecoveryComponentRegistry, true); err != nil {
 		return err
@@ -615,6 +657,20 @@ func runRestoreRegistry(ctx context.Context, flags InstallCmdFlags, backupToRest
 	return nil
 }
 
+// getRestoredExternalRegistry returns the external registry of the installation restored from
+// the backup, nil if the bundled registry is used.
+func getRestoredExternalRegistry(ctx context.Context) (*ecv1beta1.ExternalRegistrySpec, error) {
+	kcli, err := kubeutils.KubeClient()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create kube client: %w", err)
+	}
+	in, err := kubeutils.GetLatestInstallation(ctx, kcli)
+	if err != nil {
+		return nil, fmt.Errorf("get latest installation: %w", err)
+	}
+	return in.Spec.ExternalRegistry, nil
+}
+
 func runRestoreECO(ctx context.Context, backupToRestore *disasterrecovery.ReplicatedBackup) error {
 	logrus.Debugf("restoring embedded cluster operator from backup %q", backupToRestore.GetName())
 	if err := restoreFromReplicatedBackup(ctx, *backupToRestore, disasterRecoveryComponentECO, true); err != nil {
@@ -1701,8 +1757,8 @@ func (e *invalidBackupsError) Error() string {
 }
 
 // updateLocalArtifactMirrorService updates the port on which the local artifact mirror is served.
-func updateLocalArtifactMirrorService() error {
-	if err := writeLocalArtifactMirrorDropInFile(); err != nil {
+func updateLocalArtifactMirrorService(networkInterface string) error {
+	if err := writeLocalArtifactMirrorDropInFile(networkInterface); err != nil {
 		return fmt.Errorf("failed to write local artifact mirror environment file: %w", err)
 	}
 
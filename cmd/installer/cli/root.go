@@ -104,6 +104,7 @@ func RootCmd(ctx context.Context, name string) *cobra.Command {
 	cmd.AddCommand(ResetCmd(ctx, name))
 	cmd.AddCommand(MaterializeCmd(ctx, name))
 	cmd.AddCommand(UpdateCmd(ctx, name))
+	cmd.AddCommand(UpgradeCmd(ctx, name))
 	cmd.AddCommand(RestoreCmd(ctx, name))
 	cmd.AddCommand(AdminConsoleCmd(ctx, name))
 	cmd.AddCommand(SupportBundleCmd(ctx, name))
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/operator/pkg/upgrade"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	rcutil "github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig/util"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func UpgradeCmd(ctx context.Context, name string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "upgrade",
+		Short: fmt.Sprintf("Manage an in-flight %s upgrade", name),
+	}
+
+	cmd.AddCommand(upgradeControlCmd(ctx, "pause", "Pause the upgrade after the step it is currently running", "Upgrade paused", upgrade.PauseUpgrade))
+	cmd.AddCommand(upgradeControlCmd(ctx, "resume", "Resume a paused upgrade from the last completed step", "Upgrade resumed", upgrade.ResumeUpgrade))
+	cmd.AddCommand(upgradeControlCmd(ctx, "cancel", "Cancel the upgrade after the step it is currently running and roll it back", "Upgrade cancelled", upgrade.CancelUpgrade))
+
+	return cmd
+}
+
+func upgradeControlCmd(ctx context.Context, use, short, done string, fn func(context.Context, client.Client, *ecv1beta1.Installation) error) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   use,
+		Short: short,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if os.Getuid() != 0 {
+				return fmt.Errorf("upgrade %s command must be run as root", use)
+			}
+
+			if err := rcutil.InitRuntimeConfigFromCluster(ctx); err != nil {
+				return fmt.Errorf("failed to init runtime config from cluster: %w", err)
+			}
+
+			os.Setenv("KUBECONFIG", runtimeconfig.PathToKubeConfig())
+
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kcli, err := kubeutils.KubeClient()
+			if err != nil {
+				return fmt.Errorf("unable to create kube client: %w", err)
+			}
+
+			in, err := kubeutils.GetLatestInstallation(ctx, kcli)
+			if err != nil {
+				return fmt.Errorf("unable to get latest installation: %w", err)
+			}
+
+			if err := fn(ctx, kcli, in); err != nil {
+				return err
+			}
+
+			logrus.Info(done)
+			return nil
+		},
+	}
+
+	return cmd
+}
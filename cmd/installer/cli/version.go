@@ -59,6 +59,7 @@ func VersionCmd(ctx context.Context, name string) *cobra.Command {
 	cmd.AddCommand(VersionMetadataCmd(ctx, name))
 	cmd.AddCommand(VersionEmbeddedDataCmd(ctx, name))
 	cmd.AddCommand(VersionListImagesCmd(ctx, name))
+	cmd.AddCommand(VersionHistoryCmd(ctx, name))
 
 	return cmd
 }
@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	rcutil "github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig/util"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func VersionHistoryCmd(ctx context.Context, name string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: fmt.Sprintf("Show the versions %s has been installed or upgraded to", name),
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if os.Getuid() != 0 {
+				return fmt.Errorf("version history command must be run as root")
+			}
+
+			if err := rcutil.InitRuntimeConfigFromCluster(ctx); err != nil {
+				return fmt.Errorf("failed to init runtime config from cluster: %w", err)
+			}
+
+			os.Setenv("KUBECONFIG", runtimeconfig.PathToKubeConfig())
+
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kcli, err := kubeutils.KubeClient()
+			if err != nil {
+				return fmt.Errorf("unable to create kube client: %w", err)
+			}
+
+			history, err := kubeutils.GetInstallationHistory(ctx, kcli)
+			if err != nil {
+				return fmt.Errorf("unable to get installation history: %w", err)
+			}
+
+			writer := table.NewWriter()
+			writer.AppendHeader(table.Row{"installation", "version", "started", "ended", "result", "reason"})
+			for _, entry := range history {
+				writer.AppendRow(table.Row{
+					entry.Name,
+					entry.Version,
+					formatHistoryTime(&entry.StartedAt),
+					formatHistoryTime(entry.EndedAt),
+					entry.Result,
+					entry.Reason,
+				})
+			}
+
+			fmt.Printf("%s\n", writer.Render())
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func formatHistoryTime(t *metav1.Time) string {
+	if t == nil || t.IsZero() {
+		return "-"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
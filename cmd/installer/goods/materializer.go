@@ -60,11 +60,11 @@ func (m *Materializer) LocalArtifactMirrorUnitFile() error {
 	return nil
 }
 
-// CalicoNetworkManagerConfig materializes a configuration file for the network manager. This
+// NetworkManagerConfig materializes a configuration file for the network manager. This
 // configuration file instructs the network manager to ignore any interface being managed by
-// the calico network cni.
-func (m *Materializer) CalicoNetworkManagerConfig() error {
-	content, err := systemdfs.ReadFile("systemd/calico-network-manager.conf")
+// the provided cni provider.
+func (m *Materializer) NetworkManagerConfig(cniProvider string) error {
+	content, err := systemdfs.ReadFile(fmt.Sprintf("systemd/%s-network-manager.conf", cniProvider))
 	if err != nil {
 		return fmt.Errorf("unable to open network manager config file: %w", err)
 	}
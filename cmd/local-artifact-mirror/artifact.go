@@ -6,6 +6,7 @@ import (
 	"os"
 
 	"github.com/replicatedhq/embedded-cluster/pkg/artifacts"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
 )
 
 func pullArtifact(ctx context.Context, from string) (string, error) {
@@ -14,7 +15,10 @@ func pullArtifact(ctx context.Context, from string) (string, error) {
 		return "", fmt.Errorf("create temp dir: %w", err)
 	}
 
-	opts := artifacts.PullOptions{}
+	opts := artifacts.PullOptions{
+		Peers:    peers,
+		CacheDir: runtimeconfig.EmbeddedClusterArtifactsSubDir(),
+	}
 	err = artifacts.Pull(ctx, kubecli, from, tmpdir, opts)
 	if err == nil {
 		return tmpdir, nil
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/artifacts"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// CleanupCmd removes the artifacts in the data directory that are no longer referenced by
+// the current or the previous installation. Only artifacts tracked while being pulled are
+// considered, anything else in the data directory is left untouched.
+func CleanupCmd(ctx context.Context, v *viper.Viper) *cobra.Command {
+	var (
+		keepVersions []string
+		dryRun       bool
+	)
+
+	cmd := &cobra.Command{
+		Use:   "cleanup",
+		Short: "Remove artifacts no longer referenced by the current or previous installation",
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			v.BindPFlag("data-dir", cmd.Flags().Lookup("data-dir"))
+
+			if os.Getuid() != 0 {
+				return fmt.Errorf("cleanup command must be run as root")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			v := viper.GetViper()
+
+			runtimeconfig.SetDataDir(v.GetString("data-dir"))
+			os.Setenv("TMPDIR", runtimeconfig.EmbeddedClusterTmpSubDir())
+
+			if len(keepVersions) == 0 {
+				versions, err := referencedVersions(ctx)
+				if err != nil {
+					return fmt.Errorf("unable to determine installation versions: %w", err)
+				}
+				keepVersions = versions
+			}
+			if len(keepVersions) == 0 {
+				return fmt.Errorf("no installation versions to keep")
+			}
+			logrus.Infof("keeping artifacts referenced by versions %v", keepVersions)
+
+			pruned, err := artifacts.PruneArtifacts(keepVersions, dryRun)
+			if err != nil {
+				return fmt.Errorf("unable to prune artifacts: %w", err)
+			}
+
+			if len(pruned) == 0 {
+				logrus.Info("no artifacts to remove")
+				return nil
+			}
+			for _, path := range pruned {
+				if dryRun {
+					fmt.Printf("would remove %s\n", path)
+				} else {
+					fmt.Printf("removed %s\n", path)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().String("data-dir", ecv1beta1.DefaultDataDir, "Path to the data directory")
+	cmd.Flags().StringSliceVar(&keepVersions, "keep-version", nil, "Installation versions whose artifacts must be kept, defaults to the current and previous installations")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show the artifacts that would be removed without removing them")
+
+	return cmd
+}
+
+// referencedVersions returns the versions of the current and previous installations.
+func referencedVersions(ctx context.Context) ([]string, error) {
+	kcli, err := kubeutils.KubeClient()
+	if err != nil {
+		return nil, fmt.Errorf("unable to create kube client: %w", err)
+	}
+
+	in, err := kubeutils.GetLatestInstallation(ctx, kcli)
+	if err != nil {
+		return nil, fmt.Errorf("get latest installation: %w", err)
+	}
+
+	var versions []string
+	if in.Spec.Config != nil && in.Spec.Config.Version != "" {
+		versions = append(versions, in.Spec.Config.Version)
+	}
+
+	previous, err := kubeutils.GetPreviousInstallation(ctx, kcli, in)
+	if errors.Is(err, kubeutils.ErrInstallationNotFound{}) {
+		return versions, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("get previous installation: %w", err)
+	}
+	if previous.Spec.Config != nil && previous.Spec.Config.Version != "" {
+		versions = append(versions, previous.Spec.Config.Version)
+	}
+
+	return versions, nil
+}
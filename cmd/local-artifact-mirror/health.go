@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"sync/atomic"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+)
+
+// shuttingDown is set once the server has been asked to stop. From this point on the
+// server is no longer reported as ready.
+var shuttingDown atomic.Bool
+
+// healthzHandler reports the server as healthy if the data directory can be read.
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	if err := checkDataDir(); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
+
+// readyzHandler reports the server as ready if the data directory can be read and the
+// server is not shutting down.
+func readyzHandler(w http.ResponseWriter, r *http.Request) {
+	if shuttingDown.Load() {
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	if err := checkDataDir(); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	fmt.Fprintln(w, "ok")
+}
+
+// checkDataDir verifies that the data directory exists and its content can be listed.
+func checkDataDir() error {
+	dir := runtimeconfig.EmbeddedClusterHomeDirectory()
+	f, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("open data dir: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Readdirnames(1); err != nil {
+		return fmt.Errorf("read data dir: %w", err)
+	}
+	return nil
+}
@@ -47,6 +47,7 @@ func RootCmd(ctx context.Context, v *viper.Viper, name string) *cobra.Command {
 
 	cmd.AddCommand(ServeCmd(ctx, v))
 	cmd.AddCommand(PullCmd(ctx, v))
+	cmd.AddCommand(CleanupCmd(ctx, v))
 
 	v.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
 
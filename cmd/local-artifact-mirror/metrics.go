@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+)
+
+// restartsFileName is the name of the file, inside the support directory, where we keep
+// track of the number of restarts triggered by changes to the binary. The counter must be
+// persisted as the process exits on every restart.
+const restartsFileName = "local-artifact-mirror-restarts"
+
+var (
+	requestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "local_artifact_mirror_requests_total",
+			Help: "Total number of requests, by served directory and status code.",
+		},
+		[]string{"dir", "code"},
+	)
+	requestErrorsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "local_artifact_mirror_request_errors_total",
+			Help: "Total number of requests that resulted in an error status code, by served directory.",
+		},
+		[]string{"dir"},
+	)
+	bytesServedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "local_artifact_mirror_bytes_served_total",
+			Help: "Total number of bytes served, by served directory.",
+		},
+		[]string{"dir"},
+	)
+	binaryRestartsTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "local_artifact_mirror_binary_restarts_total",
+			Help: "Total number of restarts triggered by changes to the local artifact mirror binary.",
+		},
+	)
+)
+
+// newMetricsRegistry returns a registry with all the local artifact mirror metrics plus
+// the default go and process collectors.
+func newMetricsRegistry() *prometheus.Registry {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		requestsTotal,
+		requestErrorsTotal,
+		bytesServedTotal,
+		binaryRestartsTotal,
+	)
+	return registry
+}
+
+// metricsHandler returns the handler for the metrics endpoint.
+func metricsHandler(registry *prometheus.Registry) http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
+
+// metricsResponseWriter wraps a http.ResponseWriter keeping track of the status code and
+// of the number of bytes written.
+type metricsResponseWriter struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (w *metricsResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *metricsResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// instrumentRequest is a middleware that records the request metrics, grouped by the
+// whitelisted directory being accessed.
+func instrumentRequest(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mw := &metricsResponseWriter{ResponseWriter: w}
+		handler.ServeHTTP(mw, r)
+
+		if mw.status == 0 {
+			mw.status = http.StatusOK
+		}
+		dir := servedDir(r.URL.Path)
+		requestsTotal.WithLabelValues(dir, strconv.Itoa(mw.status)).Inc()
+		bytesServedTotal.WithLabelValues(dir).Add(float64(mw.bytes))
+		if mw.status >= http.StatusBadRequest {
+			requestErrorsTotal.WithLabelValues(dir).Inc()
+		}
+	})
+}
+
+// servedDir returns the whitelisted directory the path belongs to or "none" if the path
+// is not within any of them.
+func servedDir(path string) string {
+	for _, dir := range whitelistServeDirs {
+		if strings.HasPrefix(path, "/"+dir+"/") {
+			return dir
+		}
+	}
+	return "none"
+}
+
+// loadBinaryRestarts initializes the binary restarts counter from the value persisted on
+// disk by the previous processes.
+func loadBinaryRestarts() {
+	binaryRestartsTotal.Add(float64(readBinaryRestarts()))
+}
+
+// recordBinaryRestart increments the binary restarts counter and persists it on disk so
+// it survives the restart.
+func recordBinaryRestart() {
+	binaryRestartsTotal.Inc()
+
+	count := readBinaryRestarts() + 1
+	fpath := runtimeconfig.PathToEmbeddedClusterSupportFile(restartsFileName)
+	if err := os.WriteFile(fpath, []byte(strconv.Itoa(count)), 0600); err != nil {
+		fmt.Println("Unable to persist binary restarts counter:", err)
+	}
+}
+
+// readBinaryRestarts returns the number of restarts persisted on disk.
+func readBinaryRestarts() int {
+	data, err := os.ReadFile(runtimeconfig.PathToEmbeddedClusterSupportFile(restartsFileName))
+	if err != nil {
+		return 0
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || count < 0 {
+		return 0
+	}
+	return count
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/configutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/helpers"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+)
+
+// migratePeerAddress configures the local artifact mirrors installed before they served the
+// artifacts to the other nodes. During air gap upgrades the artifacts job writes the node address
+// to a support file before replacing the binary, the restarted mirror then writes the drop-in file
+// and the firewalld rule as the installer does and returns the address so it is served right
+// away. An empty address is returned if there is nothing to migrate, the address is returned
+// along with the error if the drop-in file was written but the migration failed afterwards.
+func migratePeerAddress(ctx context.Context) (string, error) {
+	fpath := runtimeconfig.PathToEmbeddedClusterSupportFile(runtimeconfig.LocalArtifactMirrorPeerAddressFile)
+	data, err := os.ReadFile(fpath)
+	if os.IsNotExist(err) {
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("read peer address file: %w", err)
+	}
+
+	address := strings.TrimSpace(string(data))
+	if net.ParseIP(address) == nil {
+		return "", fmt.Errorf("invalid peer address %q", address)
+	}
+
+	if err := configutils.WriteLocalArtifactMirrorDropInFile(address); err != nil {
+		return "", fmt.Errorf("write drop-in file: %w", err)
+	}
+	if _, err := helpers.RunCommand("systemctl", "daemon-reload"); err != nil {
+		return "", fmt.Errorf("reload systemd: %w", err)
+	}
+
+	network, err := configutils.FirewalldAddressNetwork(address)
+	if err != nil {
+		return address, fmt.Errorf("get node network: %w", err)
+	}
+	if err := configutils.EnsureLocalArtifactMirrorFirewalldRule(ctx, network); err != nil {
+		return address, fmt.Errorf("configure firewalld: %w", err)
+	}
+
+	if err := os.Remove(fpath); err != nil {
+		return address, fmt.Errorf("remove peer address file: %w", err)
+	}
+	return address, nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/helpers"
+	"github.com/replicatedhq/embedded-cluster/pkg/helpers/firewalld"
+	"github.com/replicatedhq/embedded-cluster/pkg/helpers/systemd"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type inactiveFirewalld struct{}
+
+func (inactiveFirewalld) IsFirewalldActive(ctx context.Context) (bool, error) { return false, nil }
+func (inactiveFirewalld) FirewallCmdExists(ctx context.Context) (bool, error) { return false, nil }
+
+func Test_migratePeerAddress(t *testing.T) {
+	runtimeconfig.SetDataDir(t.TempDir())
+	runtimeconfig.SetLocalArtifactMirrorPort(50001)
+	t.Cleanup(func() { runtimeconfig.SetLocalArtifactMirrorPort(0) })
+
+	systemd.SetSystemDUnitBasePath(t.TempDir())
+	t.Cleanup(func() { systemd.SetSystemDUnitBasePath(systemd.DefaultSystemDUnitBasePath) })
+
+	mock := &helpers.MockHelpers{}
+	helpers.Set(mock)
+	t.Cleanup(func() { helpers.Set(&helpers.Helpers{}) })
+
+	firewalld.SetUtil(inactiveFirewalld{})
+	t.Cleanup(func() { firewalld.SetUtil(&firewalld.Util{}) })
+
+	// nothing to migrate without the peer address file.
+	address, err := migratePeerAddress(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, address)
+	assert.Empty(t, mock.Commands)
+
+	fpath := runtimeconfig.PathToEmbeddedClusterSupportFile(runtimeconfig.LocalArtifactMirrorPeerAddressFile)
+	require.NoError(t, os.WriteFile(fpath, []byte("127.0.0.1\n"), 0644))
+
+	address, err = migratePeerAddress(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "127.0.0.1", address)
+	assert.Equal(t, []string{"systemctl daemon-reload"}, mock.Commands)
+
+	dropIn, err := os.ReadFile(systemd.DropInFilePath("local-artifact-mirror.service", "embedded-cluster.conf"))
+	require.NoError(t, err)
+	assert.Contains(t, string(dropIn), `Environment="LOCAL_ARTIFACT_MIRROR_PORT=50001"`)
+	assert.Contains(t, string(dropIn), `Environment="LOCAL_ARTIFACT_MIRROR_PEER_ADDRESS=127.0.0.1"`)
+
+	assert.NoFileExists(t, fpath)
+}
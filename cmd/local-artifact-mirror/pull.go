@@ -5,9 +5,12 @@ import (
 	"encoding/base64"
 	"fmt"
 	"os"
+	"time"
 
 	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/artifacts"
 	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -27,6 +30,10 @@ const (
 // kubecli holds a global reference to a Kubernetes client.
 var kubecli client.Client
 
+// peers holds the base urls of the local artifact mirrors running in other nodes. Artifacts
+// are fetched from them before falling back to the registry.
+var peers []string
+
 func PullCmd(ctx context.Context, v *viper.Viper) *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "pull",
@@ -47,6 +54,8 @@ func PullCmd(ctx context.Context, v *viper.Viper) *cobra.Command {
 		},
 	}
 
+	cmd.PersistentFlags().StringSliceVar(&peers, "peers", nil, "Base urls of local artifact mirrors to fetch the artifacts from before falling back to the registry")
+
 	cmd.AddCommand(PullBinariesCmd(ctx, v))
 	cmd.AddCommand(PullImagesCmd(ctx, v))
 	cmd.AddCommand(PullHelmChartsCmd(ctx, v))
@@ -102,3 +111,18 @@ func decodeInstallation(ctx context.Context, data string) (*ecv1beta1.Installati
 
 	return in, nil
 }
+
+// trackArtifacts marks the artifacts modified, inside the provided directories, since the
+// pull started as referenced by the installation version. Tracked artifacts are pruned
+// by the cleanup command once no longer referenced. Failing to track the artifacts is not
+// fatal as it only means they won't be pruned.
+func trackArtifacts(in *ecv1beta1.Installation, since time.Time, dirs ...string) {
+	if in.Spec.Config == nil || in.Spec.Config.Version == "" {
+		logrus.Warnf("installation %s has no version, artifacts won't be tracked", in.Name)
+		return
+	}
+	dirs = append(dirs, runtimeconfig.EmbeddedClusterArtifactsSubDir())
+	if err := artifacts.TrackArtifacts(in.Spec.Config.Version, since, dirs...); err != nil {
+		logrus.Warnf("unable to track artifacts: %v", err)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"time"
 
 	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
 	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
@@ -55,6 +56,7 @@ func PullBinariesCmd(ctx context.Context, v *viper.Viper) *cobra.Command {
 
 			from := in.Spec.Artifacts.EmbeddedClusterBinary
 			logrus.Infof("fetching embedded cluster binary artifact from %s", from)
+			start := time.Now()
 			location, err := pullArtifact(ctx, from)
 			if err != nil {
 				return fmt.Errorf("unable to fetch artifact: %w", err)
@@ -87,6 +89,7 @@ func PullBinariesCmd(ctx context.Context, v *viper.Viper) *cobra.Command {
 			}
 
 			logrus.Infof("embedded cluster binaries materialized")
+			trackArtifacts(in, start, runtimeconfig.EmbeddedClusterBinsSubDir())
 
 			return nil
 		},
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
 	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
@@ -54,6 +55,7 @@ func PullHelmChartsCmd(ctx context.Context, v *viper.Viper) *cobra.Command {
 
 			from := in.Spec.Artifacts.HelmCharts
 			logrus.Infof("fetching helm charts artifact from %s", from)
+			start := time.Now()
 			location, err := pullArtifact(ctx, from)
 			if err != nil {
 				return fmt.Errorf("unable to fetch artifact: %w", err)
@@ -71,6 +73,7 @@ func PullHelmChartsCmd(ctx context.Context, v *viper.Viper) *cobra.Command {
 			}
 
 			logrus.Infof("helm charts materialized under %s", dst)
+			trackArtifacts(in, start, runtimeconfig.EmbeddedClusterChartsSubDir())
 			return nil
 		},
 	}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
 	"github.com/replicatedhq/embedded-cluster/pkg/helpers"
@@ -54,6 +55,7 @@ func PullImagesCmd(ctx context.Context, v *viper.Viper) *cobra.Command {
 
 			from := in.Spec.Artifacts.Images
 			logrus.Infof("fetching images artifact from %s", from)
+			start := time.Now()
 			location, err := pullArtifact(ctx, from)
 			if err != nil {
 				return fmt.Errorf("unable to fetch artifact: %w", err)
@@ -66,11 +68,16 @@ func PullImagesCmd(ctx context.Context, v *viper.Viper) *cobra.Command {
 			dst := filepath.Join(runtimeconfig.EmbeddedClusterImagesSubDir(), ImagesDstArtifactName)
 			src := filepath.Join(location, ImagesSrcArtifactName)
 			logrus.Infof("%s > %s", src, dst)
-			if err := helpers.MoveFile(src, dst); err != nil {
-				return fmt.Errorf("unable to move images bundle: %w", err)
+			// we attempt to rename first so the bundle keeps sharing its inode with the
+			// cached artifact that is served to other nodes.
+			if err := os.Rename(src, dst); err != nil {
+				if err := helpers.MoveFile(src, dst); err != nil {
+					return fmt.Errorf("unable to move images bundle: %w", err)
+				}
 			}
 
 			logrus.Infof("images materialized under %s", dst)
+			trackArtifacts(in, start, runtimeconfig.EmbeddedClusterImagesSubDir())
 			return nil
 		},
 	}
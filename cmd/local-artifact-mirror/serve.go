@@ -20,15 +20,22 @@ import (
 )
 
 var (
-	whitelistServeDirs = []string{"bin", "charts", "images"}
+	whitelistServeDirs = []string{"bin", "charts", "images", "artifacts"}
+	// whitelistPeerServeDirs are the directories that can be accessed by clients other than
+	// localhost. These are the verified airgap artifacts other nodes fetch during upgrades.
+	whitelistPeerServeDirs = []string{"artifacts"}
 )
 
-// serveCommand starts a http server that serves files from the data directory. This server listen
-// only on localhost and is used to serve files needed by the autopilot during an upgrade.
+// serveCommand starts a http server that serves files from the data directory. This server is
+// used to serve files needed by the autopilot during an upgrade. It listens on localhost and,
+// if a peer address is provided, on the node cluster address too. Requests coming from other
+// hosts are only allowed to read the verified airgap artifacts, used to distribute them among
+// the nodes in the cluster. The server also exposes /healthz, /readyz and /metrics endpoints.
 func ServeCmd(ctx context.Context, v *viper.Viper) *cobra.Command {
 	var (
-		dataDir string
-		port    int
+		dataDir     string
+		port        int
+		peerAddress string
 	)
 
 	cmd := &cobra.Command{
@@ -37,6 +44,7 @@ func ServeCmd(ctx context.Context, v *viper.Viper) *cobra.Command {
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			v.BindPFlag("data-dir", cmd.Flags().Lookup("data-dir"))
 			v.BindPFlag("port", cmd.Flags().Lookup("port"))
+			v.BindPFlag("peer-address", cmd.Flags().Lookup("peer-address"))
 
 			if os.Getuid() != 0 {
 				return fmt.Errorf("serve command must be run as root")
@@ -57,6 +65,9 @@ func ServeCmd(ctx context.Context, v *viper.Viper) *cobra.Command {
 			if os.Getenv("LOCAL_ARTIFACT_MIRROR_DATA_DIR") != "" {
 				dataDir = os.Getenv("LOCAL_ARTIFACT_MIRROR_DATA_DIR")
 			}
+			if os.Getenv("LOCAL_ARTIFACT_MIRROR_PEER_ADDRESS") != "" {
+				peerAddress = os.Getenv("LOCAL_ARTIFACT_MIRROR_PEER_ADDRESS")
+			}
 
 			if v.Get("data-dir") != nil {
 				runtimeconfig.SetDataDir(v.GetString("data-dir"))
@@ -70,12 +81,28 @@ func ServeCmd(ctx context.Context, v *viper.Viper) *cobra.Command {
 			if port == 0 {
 				port = ecv1beta1.DefaultLocalArtifactMirrorPort
 			}
+			runtimeconfig.SetLocalArtifactMirrorPort(port)
+
+			peerAddress := v.GetString("peer-address")
+			if peerAddress == "" {
+				address, err := migratePeerAddress(cmd.Context())
+				if err != nil {
+					fmt.Println("Unable to configure the peer address:", err)
+				}
+				peerAddress = address
+			}
 
 			os.Setenv("TMPDIR", runtimeconfig.EmbeddedClusterTmpSubDir())
 
+			loadBinaryRestarts()
+
 			fileServer := http.FileServer(http.Dir(runtimeconfig.EmbeddedClusterHomeDirectory()))
 			loggedFileServer := logAndFilterRequest(fileServer)
-			http.Handle("/", loggedFileServer)
+			mux := http.NewServeMux()
+			mux.Handle("/", loggedFileServer)
+			mux.HandleFunc("/healthz", healthzHandler)
+			mux.HandleFunc("/readyz", readyzHandler)
+			mux.Handle("/metrics", metricsHandler(newMetricsRegistry()))
 
 			stop := make(chan os.Signal, 1)
 			signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
@@ -84,24 +111,37 @@ func ServeCmd(ctx context.Context, v *viper.Viper) *cobra.Command {
 				panic(err)
 			}
 
-			addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
-			server := &http.Server{Addr: addr}
-			go func() {
-				fmt.Printf("Starting server on %s\n", addr)
-				if err := server.ListenAndServe(); err != nil {
-					if err != http.ErrServerClosed {
-						panic(err)
+			// other nodes can only reach the server through the node cluster address, never
+			// through any other interface the host may have.
+			addrs := []string{net.JoinHostPort("127.0.0.1", strconv.Itoa(port))}
+			if peerAddress != "" {
+				addrs = append(addrs, net.JoinHostPort(peerAddress, strconv.Itoa(port)))
+			}
+			handler := instrumentRequest(mux)
+			servers := []*http.Server{}
+			for _, addr := range addrs {
+				server := &http.Server{Addr: addr, Handler: handler}
+				servers = append(servers, server)
+				go func() {
+					fmt.Printf("Starting server on %s\n", addr)
+					if err := server.ListenAndServe(); err != nil {
+						if err != http.ErrServerClosed {
+							panic(err)
+						}
 					}
-				}
-			}()
+				}()
+			}
 
 			<-stop
 			fmt.Println("Shutting down server...")
+			shuttingDown.Store(true)
 
 			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 			defer cancel()
-			if err := server.Shutdown(ctx); err != nil {
-				panic(err)
+			for _, server := range servers {
+				if err := server.Shutdown(ctx); err != nil {
+					panic(err)
+				}
 			}
 			fmt.Println("Server gracefully stopped")
 			return nil
@@ -110,6 +150,7 @@ func ServeCmd(ctx context.Context, v *viper.Viper) *cobra.Command {
 
 	cmd.Flags().StringVar(&dataDir, "data-dir", ecv1beta1.DefaultDataDir, "Path to the data directory")
 	cmd.Flags().IntVar(&port, "port", ecv1beta1.DefaultLocalArtifactMirrorPort, "Port to listen on")
+	cmd.Flags().StringVar(&peerAddress, "peer-address", "", "Node cluster address to also listen on, so other nodes can fetch the airgap artifacts")
 
 	return cmd
 }
@@ -136,6 +177,7 @@ func startBinaryWatcher(stop chan os.Signal) error {
 				continue
 			}
 			fmt.Println("Binary changed, sending signal to stop")
+			recordBinaryRestart()
 			stop <- syscall.SIGTERM
 		}
 	}()
@@ -143,11 +185,16 @@ func startBinaryWatcher(stop chan os.Signal) error {
 }
 
 // logAndFilterRequest is a middleware that logs the HTTP request details. Returns 404
-// if attempting to read the log files as those are not served by this server.
+// if attempting to read the log files as those are not served by this server. Requests
+// not originating from localhost can only read from the peer whitelisted directories.
 func logAndFilterRequest(handler http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		fmt.Printf("%s %s %s\n", r.RemoteAddr, r.Method, r.URL)
-		for _, dir := range whitelistServeDirs {
+		whitelist := whitelistServeDirs
+		if !isLoopbackRequest(r) {
+			whitelist = whitelistPeerServeDirs
+		}
+		for _, dir := range whitelist {
 			if !strings.HasPrefix(dir, "/") {
 				dir = "/" + dir
 			}
@@ -164,3 +211,13 @@ func logAndFilterRequest(handler http.Handler) http.Handler {
 		w.WriteHeader(http.StatusNotFound)
 	})
 }
+
+// isLoopbackRequest returns true if the request has been originated from localhost.
+func isLoopbackRequest(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return false
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_serveEndpoints(t *testing.T) {
+	dataDir := t.TempDir()
+	runtimeconfig.SetDataDir(dataDir)
+
+	// create a file to be served from the bin directory.
+	binDir := runtimeconfig.EmbeddedClusterBinsSubDir()
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "k0s"), []byte("k0s binary"), 0644))
+
+	fileServer := http.FileServer(http.Dir(dataDir))
+	mux := http.NewServeMux()
+	mux.Handle("/", logAndFilterRequest(fileServer))
+	mux.HandleFunc("/healthz", healthzHandler)
+	mux.HandleFunc("/readyz", readyzHandler)
+	mux.Handle("/metrics", metricsHandler(newMetricsRegistry()))
+
+	server := httptest.NewServer(instrumentRequest(mux))
+	defer server.Close()
+
+	get := func(path string) (int, string) {
+		resp, err := http.Get(server.URL + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	code, _ := get("/healthz")
+	assert.Equal(t, http.StatusOK, code)
+
+	code, _ = get("/readyz")
+	assert.Equal(t, http.StatusOK, code)
+
+	code, body := get("/bin/k0s")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "k0s binary", body)
+
+	code, _ = get("/support/secret")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, body = get("/metrics")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `local_artifact_mirror_requests_total{code="200",dir="bin"} 1`)
+	assert.Contains(t, body, `local_artifact_mirror_bytes_served_total{dir="bin"} 10`)
+	assert.Contains(t, body, `local_artifact_mirror_request_errors_total{dir="none"} 1`)
+
+	shuttingDown.Store(true)
+	defer shuttingDown.Store(false)
+	code, body = get("/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.True(t, strings.Contains(body, "shutting down"))
+}
+
+func Test_binaryRestarts(t *testing.T) {
+	runtimeconfig.SetDataDir(t.TempDir())
+
+	assert.Equal(t, 0, readBinaryRestarts())
+	recordBinaryRestart()
+	recordBinaryRestart()
+	assert.Equal(t, 2, readBinaryRestarts())
+}
@@ -141,8 +141,145 @@ type Helm struct {
 	Charts []Chart `json:"charts"`
 }
 
+// AddOn readiness check kinds.
+const (
+	AddOnReadinessCheckKindDeployment  = "Deployment"
+	AddOnReadinessCheckKindDaemonSet   = "DaemonSet"
+	AddOnReadinessCheckKindStatefulSet = "StatefulSet"
+)
+
+// AddOnReadinessCheck is a workload an addon waits for before it is considered ready.
+type AddOnReadinessCheck struct {
+	// +kubebuilder:validation:Enum=Deployment;DaemonSet;StatefulSet
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// Namespace of the workload. Defaults to the addon namespace.
+	// +kubebuilder:validation:Optional
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// AddOn describes a third party addon. Contrary to helm extensions, addons are managed like
+// the built-in addons: their prerequisites are created before the chart is installed, the
+// installation waits for them to be ready, they can be included in disaster recovery and
+// they are installed, upgraded and restored alongside the built-in addons.
+type AddOn struct {
+	// Name is the name of the addon as shown to the user.
+	Name string `json:"name"`
+	// ReleaseName is the name of the helm release.
+	ReleaseName string `json:"releaseName"`
+	// ChartName is the location of the chart, for instance an oci:// reference.
+	ChartName string `json:"chartname"`
+	Version   string `json:"version"`
+	Namespace string `json:"namespace"`
+	// Values is a template rendered into the YAML-formatted helm values of the chart. The
+	// template has access to .Namespace, .ReleaseName, .DataDir, .IsAirgap, .IsHA,
+	// .ServiceCIDR, .HTTPProxy, .HTTPSProxy and .NoProxy.
+	// +kubebuilder:validation:Optional
+	Values string `json:"values,omitempty"`
+	// Prerequisites is a template, rendered like Values, of the YAML-formatted kubernetes
+	// objects created before the chart is installed. Objects that already exist are left
+	// untouched.
+	// +kubebuilder:validation:Optional
+	Prerequisites string `json:"prerequisites,omitempty"`
+	// ReadinessChecks are the workloads waited for after the chart is installed or upgraded.
+	// +kubebuilder:validation:Optional
+	ReadinessChecks []AddOnReadinessCheck `json:"readinessChecks,omitempty"`
+	// DisasterRecovery includes the helm release and the prerequisites in the infrastructure
+	// backups.
+	// +kubebuilder:validation:Optional
+	DisasterRecovery bool `json:"disasterRecovery,omitempty"`
+	// Restore installs the addon alongside the infrastructure addons when restoring, before
+	// the backups are restored.
+	// +kubebuilder:validation:Optional
+	Restore bool `json:"restore,omitempty"`
+	// Order is the position of the addon among the third party addons. Third party addons
+	// are installed, upgraded and restored after the built-in infrastructure addons and
+	// before the admin console.
+	// +kubebuilder:validation:Optional
+	Order int `json:"order,omitempty"`
+	// Timeout specifies the timeout for how long to wait for the chart installation to finish.
+	// +kubebuilder:validation:XIntOrString
+	// +kubebuilder:validation:Optional
+	Timeout BackwardCompatibleDuration `json:"timeout,omitempty"`
+}
+
 type Extensions struct {
 	Helm *Helm `json:"helm,omitempty"`
+	// AddOns are third party addons managed with the same lifecycle as the built-in addons.
+	// +kubebuilder:validation:Optional
+	AddOns []AddOn `json:"addons,omitempty"`
+}
+
+// CNI providers.
+const (
+	CNIProviderCalico = "calico"
+	CNIProviderCilium = "cilium"
+)
+
+// CNISpec selects the container network interface providing the pod network.
+type CNISpec struct {
+	// Provider of the pod network. calico, the default, is deployed by k0s. cilium is
+	// installed as an addon. The provider can not be changed once the cluster is installed.
+	// +kubebuilder:validation:Enum=calico;cilium
+	// +kubebuilder:validation:Optional
+	Provider string `json:"provider,omitempty"`
+}
+
+// IngressSpec configures the built-in ingress controller. The controller runs on every node
+// and binds the host ports 80 and 443.
+type IngressSpec struct {
+	// Enabled installs the built-in ingress controller.
+	// +kubebuilder:validation:Optional
+	Enabled bool `json:"enabled,omitempty"`
+	// AdminConsole exposes the Admin Console through the ingress controller.
+	// +kubebuilder:validation:Optional
+	AdminConsole *AdminConsoleIngressSpec `json:"adminConsole,omitempty"`
+}
+
+// AdminConsoleIngressSpec exposes the Admin Console on a hostname through the built-in
+// ingress controller. The Admin Console remains reachable through its node port.
+type AdminConsoleIngressSpec struct {
+	// Hostname is the host the Admin Console is served on.
+	Hostname string `json:"hostname"`
+	// TLSSecretName is the name of the secret, in the Admin Console namespace, holding the
+	// TLS certificate served for the hostname. Defaults to the certificate of the Admin
+	// Console, the one uploaded when the Admin Console is first accessed.
+	// +kubebuilder:validation:Optional
+	TLSSecretName string `json:"tlsSecretName,omitempty"`
+}
+
+// MonitoringSpec configures the built-in monitoring stack. It collects node and pod metrics,
+// and the metrics of the embedded cluster operator and local artifact mirror.
+type MonitoringSpec struct {
+	// Enabled installs the built-in monitoring stack.
+	// +kubebuilder:validation:Optional
+	Enabled bool `json:"enabled,omitempty"`
+	// Retention is how long metrics are kept, for instance "15d". Defaults to 15 days.
+	// +kubebuilder:validation:Optional
+	Retention string `json:"retention,omitempty"`
+	// RetentionSize is the maximum size of the stored metrics, for instance "10GB". The oldest
+	// metrics are removed first. Unlimited by default.
+	// +kubebuilder:validation:Optional
+	RetentionSize string `json:"retentionSize,omitempty"`
+}
+
+// Storage providers.
+const (
+	StorageProviderOpenEBS  = "openebs"
+	StorageProviderLonghorn = "longhorn"
+)
+
+// StorageSpec selects the provider of the default storage class of the cluster.
+type StorageSpec struct {
+	// Provider of the default storage class. openebs, the default, provisions local volumes
+	// that are lost with their node. longhorn replicates the volumes across nodes. The
+	// provider can not be changed once the cluster is installed.
+	// +kubebuilder:validation:Enum=openebs;longhorn
+	// +kubebuilder:validation:Optional
+	Provider string `json:"provider,omitempty"`
+	// Replicas is the number of replicas of each longhorn volume. Defaults to 3.
+	// +kubebuilder:validation:Optional
+	Replicas int `json:"replicas,omitempty"`
 }
 
 // ConfigSpec defines the desired state of Config
@@ -158,6 +295,18 @@ type ConfigSpec struct {
 	Roles                Roles                `json:"roles,omitempty"`
 	UnsupportedOverrides UnsupportedOverrides `json:"unsupportedOverrides,omitempty"`
 	Extensions           Extensions           `json:"extensions,omitempty"`
+	// CNI selects the container network interface.
+	// +kubebuilder:validation:Optional
+	CNI *CNISpec `json:"cni,omitempty"`
+	// Ingress configures the built-in ingress controller.
+	// +kubebuilder:validation:Optional
+	Ingress *IngressSpec `json:"ingress,omitempty"`
+	// Monitoring configures the built-in monitoring stack.
+	// +kubebuilder:validation:Optional
+	Monitoring *MonitoringSpec `json:"monitoring,omitempty"`
+	// Storage selects the storage provider.
+	// +kubebuilder:validation:Optional
+	Storage *StorageSpec `json:"storage,omitempty"`
 }
 
 // OverrideForBuiltIn returns the override for the built-in extension with the
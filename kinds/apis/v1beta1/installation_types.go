@@ -22,6 +22,7 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/yaml"
 )
@@ -41,6 +42,7 @@ const (
 	InstallationStateFailed                 string = "Failed"
 	InstallationStateUnknown                string = "Unknown"
 	InstallationStatePendingChartCreation   string = "PendingChartCreation"
+	InstallationStatePaused                 string = "Paused"
 )
 
 // Valid installation source types
@@ -48,8 +50,72 @@ const (
 	InstallationSourceTypeCRD string = "CRD"
 )
 
+// UpgradeControlAnnotation is set on an Installation to pause or cancel its upgrade. It is
+// honored by the upgrade job between the upgrade steps.
+const (
+	UpgradeControlAnnotation = "embedded-cluster.replicated.com/upgrade-control"
+	UpgradeControlPause      = "pause"
+	UpgradeControlCancel     = "cancel"
+)
+
+// Annotations set on an Installation when it is superseded by a newer one. They keep track of
+// how the installation ended as its state is then replaced by Obsolete.
+const (
+	InstallationObsoletedStateAnnotation  = "embedded-cluster.replicated.com/obsoleted-state"
+	InstallationObsoletedReasonAnnotation = "embedded-cluster.replicated.com/obsoleted-reason"
+)
+
 const (
 	ConditionTypeV2MigrationInProgress = "V2MigrationInProgress"
+	ConditionTypeUpgradeRolledBack     = "UpgradeRolledBack"
+	ConditionTypePreflightFailed       = "PreflightFailed"
+	// ConditionTypeRegistryGarbageCollected holds the result of the last garbage collection
+	// of the air gap registry.
+	ConditionTypeRegistryGarbageCollected = "RegistryGarbageCollected"
+	// ConditionTypeWaitingForMaintenanceWindow is true while an upgrade waits for a maintenance
+	// window to open before upgrading the next batch of nodes.
+	ConditionTypeWaitingForMaintenanceWindow = "WaitingForMaintenanceWindow"
+)
+
+// HostPreflightsConditionTypePrefix prefixes the type of the conditions holding the result of
+// the last host preflight monitoring run on each node.
+const HostPreflightsConditionTypePrefix = "HostPreflights-"
+
+// What follows is a list of the host preflight checks that can be monitored after the
+// installation.
+const (
+	HostPreflightCheckDiskSpace              string = "DiskSpace"
+	HostPreflightCheckFilesystemWriteLatency string = "FilesystemWriteLatency"
+	HostPreflightCheckClock                  string = "Clock"
+	HostPreflightCheckCgroups                string = "Cgroups"
+	HostPreflightCheckKernelModules          string = "KernelModules"
+)
+
+// What follows is a list of all valid phases for the artifacts distribution in a node.
+const (
+	NodeArtifactsPhasePending   string = "Pending"
+	NodeArtifactsPhaseRunning   string = "Running"
+	NodeArtifactsPhaseSucceeded string = "Succeeded"
+	NodeArtifactsPhaseFailed    string = "Failed"
+)
+
+// What follows is a list of all valid phases for an addon or an extension.
+const (
+	ComponentPhaseInstalling   string = "Installing"
+	ComponentPhaseUpgrading    string = "Upgrading"
+	ComponentPhaseUninstalling string = "Uninstalling"
+	ComponentPhaseInstalled    string = "Installed"
+	ComponentPhaseUpgraded     string = "Upgraded"
+	ComponentPhaseUninstalled  string = "Uninstalled"
+	ComponentPhaseFailed       string = "Failed"
+)
+
+// What follows is a list of all valid phases for the upgrade of a node.
+const (
+	NodeUpgradePhasePending   string = "Pending"
+	NodeUpgradePhaseUpgrading string = "Upgrading"
+	NodeUpgradePhaseUpgraded  string = "Upgraded"
+	NodeUpgradePhaseFailed    string = "Failed"
 )
 
 // ConfigSecretEntryName holds the entry name we are looking for in the secret
@@ -64,6 +130,68 @@ type NodeStatus struct {
 	Hash string `json:"hash"`
 }
 
+// NodeArtifactsStatus is used to keep track of the distribution of the airgap
+// artifacts to a cluster node. Artifacts are either fetched from the registry
+// running inside the cluster or from other nodes (peers) that already have them.
+type NodeArtifactsStatus struct {
+	Name  string `json:"name"`
+	Phase string `json:"phase"`
+	// Peers holds the names of the nodes the artifacts are being fetched from. If
+	// empty the artifacts are fetched from the registry.
+	Peers   []string `json:"peers,omitempty"`
+	Message string   `json:"message,omitempty"`
+}
+
+// ComponentStatus holds the status of an addon or an extension managed by the installation.
+type ComponentStatus struct {
+	// Name is the helm release name of the component.
+	Name string `json:"name"`
+	// Namespace is the namespace the component is deployed to.
+	Namespace string `json:"namespace,omitempty"`
+	// Version is the chart version of the component.
+	Version string `json:"version,omitempty"`
+	// Phase is the current phase of the component.
+	Phase string `json:"phase"`
+	// LastError holds the error that made the component fail, if any.
+	LastError string `json:"lastError,omitempty"`
+	// HelmRevision is the revision of the helm release once the component is deployed.
+	HelmRevision int `json:"helmRevision,omitempty"`
+	// LastTransitionTime is the last time the phase changed.
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
+// ConditionType returns the type of the condition historically used to track the component.
+// Conditions are named after the component namespace and release name.
+func (c ComponentStatus) ConditionType() string {
+	return fmt.Sprintf("%s-%s", c.Namespace, c.Name)
+}
+
+// Condition returns the condition historically used to track the component. It is kept up to
+// date for the clients that still rely on it.
+func (c ComponentStatus) Condition() metav1.Condition {
+	status := metav1.ConditionFalse
+	if c.Phase == ComponentPhaseInstalled || c.Phase == ComponentPhaseUpgraded || c.Phase == ComponentPhaseUninstalled {
+		status = metav1.ConditionTrue
+	}
+	return metav1.Condition{
+		Type:               c.ConditionType(),
+		Status:             status,
+		Reason:             c.Phase,
+		Message:            c.LastError,
+		LastTransitionTime: c.LastTransitionTime,
+	}
+}
+
+// NodeUpgradeStatus holds the status of the upgrade of a node.
+type NodeUpgradeStatus struct {
+	Name string `json:"name"`
+	// Version is the Kubernetes version the node is being upgraded to.
+	Version string `json:"version,omitempty"`
+	// Phase is the current phase of the node upgrade.
+	Phase   string `json:"phase"`
+	Message string `json:"message,omitempty"`
+}
+
 // ArtifactsLocation defines a location from where we can download an
 // airgap bundle. It contains individual URLs for each component of the
 // bundle. These URLs are expected to point to a registry running inside
@@ -85,6 +213,87 @@ type ProxySpec struct {
 	NoProxy         string `json:"noProxy,omitempty"`
 }
 
+// UpgradeStrategy defines how the nodes of the cluster are upgraded. Controllers are always
+// upgraded before workers as Kubernetes does not support kubelets newer than the api server.
+type UpgradeStrategy struct {
+	// MaxParallelNodes is the maximum number of nodes upgraded at the same time. If zero all
+	// the nodes of a role (or of a label value, see OrderByLabel) are upgraded at once.
+	// +optional
+	MaxParallelNodes int `json:"maxParallelNodes,omitempty"`
+	// OrderByLabel groups the nodes of each role by the value of this label. Groups are
+	// upgraded one after the other, ordered by the label value. Nodes without the label are
+	// upgraded last.
+	// +optional
+	OrderByLabel string `json:"orderByLabel,omitempty"`
+	// MaintenanceWindows restricts when a batch of nodes can start being upgraded. If empty,
+	// batches start as soon as the previous one is healthy.
+	// +optional
+	MaintenanceWindows []MaintenanceWindow `json:"maintenanceWindows,omitempty"`
+	// HealthCheckTimeout is how long we wait for the nodes and the application to become
+	// healthy after each batch is upgraded. Defaults to 10 minutes.
+	// +optional
+	HealthCheckTimeout *metav1.Duration `json:"healthCheckTimeout,omitempty"`
+	// MinFreeDiskSpace is the free space required in the data directory of each node for the
+	// upgrade to start. Defaults to 2Gi, or 5Gi in air gap installations.
+	// +optional
+	MinFreeDiskSpace *resource.Quantity `json:"minFreeDiskSpace,omitempty"`
+}
+
+// MaintenanceWindow is a recurring window of time, in UTC, in which node upgrades can start.
+type MaintenanceWindow struct {
+	// Days of the week (Mon, Tue, Wed, Thu, Fri, Sat, Sun) in which the window opens. If
+	// empty the window opens every day.
+	// +optional
+	Days []string `json:"days,omitempty"`
+	// Start is the time of the day the window opens, in HH:MM (24h) format.
+	Start string `json:"start"`
+	// Duration is how long the window stays open.
+	Duration metav1.Duration `json:"duration"`
+}
+
+// HostPreflightMonitoring defines how the host preflights are periodically run on the nodes
+// after the installation.
+type HostPreflightMonitoring struct {
+	// Enabled indicates if the host preflights are periodically run on the nodes.
+	Enabled bool `json:"enabled,omitempty"`
+	// Interval is the time between two runs on a node. Defaults to 6 hours.
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+	// Checks is the list of checks to run (DiskSpace, FilesystemWriteLatency, Clock, Cgroups,
+	// KernelModules). If empty all the checks but FilesystemWriteLatency are run, as it writes
+	// to the disk used by etcd.
+	// +optional
+	Checks []string `json:"checks,omitempty"`
+}
+
+// RegistryGarbageCollection defines how the embedded cluster images no longer referenced are
+// removed from the air gap registry after an upgrade. The application images are never removed.
+type RegistryGarbageCollection struct {
+	// Disabled prevents the images from being removed from the registry.
+	// +optional
+	Disabled bool `json:"disabled,omitempty"`
+	// DryRun only reports the images that would be removed, nothing is deleted.
+	// +optional
+	DryRun bool `json:"dryRun,omitempty"`
+	// KeepReleases is the number of releases, counting the current one, whose images are
+	// kept in the registry. Defaults to 2 (the current and the previous release).
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	KeepReleases int `json:"keepReleases,omitempty"`
+}
+
+// ExternalRegistrySpec holds the configuration of a registry, managed outside of the cluster,
+// used instead of the bundled one in air gap installations. The credentials are not part of
+// the spec, they are kept in the registry pull secret.
+type ExternalRegistrySpec struct {
+	// Address is the host (and optionally port) of the registry, e.g. registry.example.com:5000.
+	Address string `json:"address"`
+	// CA holds the PEM encoded certificate authority used to verify the registry
+	// certificate. If empty the system trust store is used.
+	// +optional
+	CA string `json:"ca,omitempty"`
+}
+
 // NetworkSpec holds the network configuration.
 type NetworkSpec struct {
 	PodCIDR       string `json:"podCIDR,omitempty"`
@@ -141,6 +350,19 @@ type InstallationSpec struct {
 
 	// RuntimeConfig holds the runtime configuration used at installation time.
 	RuntimeConfig *RuntimeConfigSpec `json:"runtimeConfig,omitempty"`
+	// UpgradeStrategy defines how the nodes are upgraded. If not set all the nodes are
+	// upgraded at once.
+	UpgradeStrategy *UpgradeStrategy `json:"upgradeStrategy,omitempty"`
+	// HostPreflightMonitoring defines if and how the host preflights are periodically run on
+	// the nodes after the installation.
+	HostPreflightMonitoring *HostPreflightMonitoring `json:"hostPreflightMonitoring,omitempty"`
+	// RegistryGarbageCollection defines how unreferenced embedded cluster images are removed from
+	// the air gap registry after an upgrade. If not set the images of the current and the previous
+	// release are kept.
+	RegistryGarbageCollection *RegistryGarbageCollection `json:"registryGarbageCollection,omitempty"`
+	// ExternalRegistry holds the registry the air gap images are pushed to when the bundled
+	// registry is not used.
+	ExternalRegistry *ExternalRegistrySpec `json:"externalRegistry,omitempty"`
 
 	// TODO: all fields below should be moved to RuntimeConfig
 
@@ -155,6 +377,10 @@ type InstallationSpec struct {
 	// EndUserK0sConfigOverrides holds the end user k0s config overrides
 	// used at installation time.
 	EndUserK0sConfigOverrides string `json:"endUserK0sConfigOverrides,omitempty"`
+	// EndUserConfig holds the end user configuration, provided through overrides,
+	// used at installation time. Its built-in extensions overrides are applied
+	// every time the addons are installed or upgraded.
+	EndUserConfig *ConfigSpec `json:"endUserConfig,omitempty"`
 
 	Deprecated_AdminConsole        *AdminConsoleSpec        `json:"adminConsole,omitempty"`
 	Deprecated_LocalArtifactMirror *LocalArtifactMirrorSpec `json:"localArtifactMirror,omitempty"`
@@ -222,6 +448,17 @@ type InstallationStatus struct {
 	Reason string `json:"reason,omitempty"`
 	// PendingCharts holds the list of charts that are being created or updated.
 	PendingCharts []string `json:"pendingCharts,omitempty"`
+	// NodesArtifactsStatus holds the progress of the airgap artifacts distribution
+	// for each node in the cluster.
+	NodesArtifactsStatus []NodeArtifactsStatus `json:"nodesArtifactsStatus,omitempty"`
+	// NodesUpgradeStatus holds the progress of the Kubernetes upgrade for each node.
+	NodesUpgradeStatus []NodeUpgradeStatus `json:"nodesUpgradeStatus,omitempty"`
+	// Addons holds the status of the addons. Each addon is also tracked by a condition named
+	// after its namespace and release name.
+	Addons []ComponentStatus `json:"addons,omitempty"`
+	// Extensions holds the status of the helm extensions. Each extension is also tracked by a
+	// condition named after its namespace and release name.
+	Extensions []ComponentStatus `json:"extensions,omitempty"`
 
 	// Conditions is an array of current observed installation conditions.
 	// +listType=map
@@ -244,6 +481,59 @@ func (s *InstallationStatus) SetCondition(condition metav1.Condition) bool {
 	return meta.SetStatusCondition(&s.Conditions, condition)
 }
 
+// SetAddonStatus sets the status of an addon and the condition tracking it. Returns true if
+// the condition changed.
+func (s *InstallationStatus) SetAddonStatus(status ComponentStatus) bool {
+	var condition metav1.Condition
+	s.Addons, condition = setComponentStatus(s.Addons, status)
+	return s.SetCondition(condition)
+}
+
+// SetExtensionStatus sets the status of an extension and the condition tracking it. Returns
+// true if the condition changed.
+func (s *InstallationStatus) SetExtensionStatus(status ComponentStatus) bool {
+	var condition metav1.Condition
+	s.Extensions, condition = setComponentStatus(s.Extensions, status)
+	return s.SetCondition(condition)
+}
+
+// SetNodeUpgradeStatus sets the upgrade status of a node.
+func (s *InstallationStatus) SetNodeUpgradeStatus(status NodeUpgradeStatus) {
+	for i := range s.NodesUpgradeStatus {
+		if s.NodesUpgradeStatus[i].Name == status.Name {
+			s.NodesUpgradeStatus[i] = status
+			return
+		}
+	}
+	s.NodesUpgradeStatus = append(s.NodesUpgradeStatus, status)
+}
+
+// setComponentStatus adds or replaces the status of a component in the list and returns the
+// condition tracking it. The transition time is only updated when the phase changes and the
+// helm revision is kept if the new status does not know it.
+func setComponentStatus(statuses []ComponentStatus, status ComponentStatus) ([]ComponentStatus, metav1.Condition) {
+	for i := range statuses {
+		if statuses[i].Name != status.Name || statuses[i].Namespace != status.Namespace {
+			continue
+		}
+		if status.LastTransitionTime.IsZero() {
+			status.LastTransitionTime = statuses[i].LastTransitionTime
+			if statuses[i].Phase != status.Phase {
+				status.LastTransitionTime = metav1.Now()
+			}
+		}
+		if status.HelmRevision == 0 {
+			status.HelmRevision = statuses[i].HelmRevision
+		}
+		statuses[i] = status
+		return statuses, status.Condition()
+	}
+	if status.LastTransitionTime.IsZero() {
+		status.LastTransitionTime = metav1.Now()
+	}
+	return append(statuses, status), status.Condition()
+}
+
 func (s *InstallationStatus) GetKubernetesInstalled() bool {
 	if s.State == InstallationStateInstalled ||
 		s.State == InstallationStateKubernetesInstalled ||
@@ -258,6 +548,7 @@ func (s *InstallationStatus) GetKubernetesInstalled() bool {
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
 //+kubebuilder:resource:scope=Cluster
+//+kubebuilder:storageversion
 //+kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.state",description="State of the installation"
 //+kubebuilder:printcolumn:name="InstallerVersion",type="string",JSONPath=".spec.config.version",description="Installer version"
 //+kubebuilder:printcolumn:name="CreatedAt",type="string",JSONPath=".metadata.creationTimestamp",description="Creation time of the installation"
@@ -281,6 +572,11 @@ type InstallationList struct {
 	Items           []Installation `json:"items"`
 }
 
+// Hub marks this version as the one the other Installation versions are converted to and
+// from. As the storage version it also holds the typed node upgrade, addon and extension status
+// exposed by v1beta2, which would otherwise be lost in the conversion.
+func (*Installation) Hub() {}
+
 func init() {
 	SchemeBuilder.Register(&Installation{}, &InstallationList{})
 }
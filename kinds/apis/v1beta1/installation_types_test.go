@@ -10,6 +10,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/serializer"
 	k8syaml "sigs.k8s.io/yaml"
@@ -157,3 +158,31 @@ func parseTestsYAML[T any](t *testing.T, prefix string) map[string]T {
 	}
 	return tests
 }
+
+func TestInstallationStatus_SetAddonStatus(t *testing.T) {
+	var status InstallationStatus
+	changed := status.SetAddonStatus(ComponentStatus{Name: "openebs", Namespace: "openebs", Phase: ComponentPhaseUpgrading})
+	assert.True(t, changed)
+	require.Len(t, status.Addons, 1)
+	require.Len(t, status.Conditions, 1)
+	assert.Equal(t, "openebs-openebs", status.Conditions[0].Type)
+	assert.Equal(t, metav1.ConditionFalse, status.Conditions[0].Status)
+	assert.False(t, status.Addons[0].LastTransitionTime.IsZero())
+
+	changed = status.SetAddonStatus(ComponentStatus{Name: "openebs", Namespace: "openebs", Phase: ComponentPhaseUpgraded, HelmRevision: 2})
+	assert.True(t, changed)
+	require.Len(t, status.Addons, 1)
+	assert.Equal(t, 2, status.Addons[0].HelmRevision)
+	assert.Equal(t, metav1.ConditionTrue, status.Conditions[0].Status)
+	assert.Equal(t, ComponentPhaseUpgraded, status.Conditions[0].Reason)
+
+	// the helm revision is kept when not known.
+	changed = status.SetAddonStatus(ComponentStatus{Name: "openebs", Namespace: "openebs", Phase: ComponentPhaseUpgraded})
+	assert.False(t, changed)
+	assert.Equal(t, 2, status.Addons[0].HelmRevision)
+
+	status.SetExtensionStatus(ComponentStatus{Name: "nginx", Namespace: "nginx", Phase: ComponentPhaseFailed, LastError: "boom"})
+	require.Len(t, status.Extensions, 1)
+	require.Len(t, status.Conditions, 2)
+	assert.Equal(t, "boom", status.Conditions[1].Message)
+}
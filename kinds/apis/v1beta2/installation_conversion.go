@@ -0,0 +1,189 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta2
+
+import (
+	"fmt"
+
+	"github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+var _ conversion.Convertible = &Installation{}
+
+// ConvertTo converts this installation to the hub (v1beta1) version. The conditions tracking
+// the addons and the extensions in v1beta1 are rebuilt from their typed status.
+func (src *Installation) ConvertTo(dstRaw conversion.Hub) error {
+	dst, ok := dstRaw.(*v1beta1.Installation)
+	if !ok {
+		return fmt.Errorf("unsupported hub type %T", dstRaw)
+	}
+	in := src.DeepCopy()
+
+	dst.ObjectMeta = in.ObjectMeta
+	dst.Spec = v1beta1.InstallationSpec{
+		ClusterID:                 in.Spec.ClusterID,
+		MetricsBaseURL:            in.Spec.MetricsBaseURL,
+		Artifacts:                 in.Spec.Artifacts,
+		Config:                    in.Spec.Config,
+		BinaryName:                in.Spec.BinaryName,
+		LicenseInfo:               in.Spec.LicenseInfo,
+		ConfigSecret:              in.Spec.ConfigSecret,
+		SourceType:                in.Spec.SourceType,
+		RuntimeConfig:             in.Spec.RuntimeConfig,
+		UpgradeStrategy:           in.Spec.UpgradeStrategy,
+		HostPreflightMonitoring:   in.Spec.HostPreflightMonitoring,
+		RegistryGarbageCollection: in.Spec.RegistryGarbageCollection,
+		ExternalRegistry:          in.Spec.ExternalRegistry,
+		HighAvailability:          in.Spec.HighAvailability,
+		AirGap:                    in.Spec.AirGap,
+		Proxy:                     in.Spec.Proxy,
+		Network:                   in.Spec.Network,
+		EndUserK0sConfigOverrides: in.Spec.EndUserK0sConfigOverrides,
+		EndUserConfig:             in.Spec.EndUserConfig,
+	}
+
+	dst.Status = v1beta1.InstallationStatus{
+		State:                in.Status.State,
+		Reason:               in.Status.Reason,
+		PendingCharts:        in.Status.PendingCharts,
+		NodesArtifactsStatus: in.Status.NodesArtifactsStatus,
+		Conditions:           in.Status.Conditions,
+	}
+	for _, node := range in.Status.Nodes {
+		if node.Hash != "" {
+			dst.Status.NodesStatus = append(dst.Status.NodesStatus, v1beta1.NodeStatus{
+				Name: node.Name,
+				Hash: node.Hash,
+			})
+		}
+		if node.Upgrade != nil {
+			dst.Status.NodesUpgradeStatus = append(dst.Status.NodesUpgradeStatus, v1beta1.NodeUpgradeStatus{
+				Name:    node.Name,
+				Version: node.Upgrade.Version,
+				Phase:   node.Upgrade.Phase,
+				Message: node.Upgrade.Message,
+			})
+		}
+	}
+	for _, addon := range in.Status.Addons {
+		status := v1beta1.ComponentStatus(addon)
+		dst.Status.Addons = append(dst.Status.Addons, status)
+		meta.SetStatusCondition(&dst.Status.Conditions, status.Condition())
+	}
+	for _, ext := range in.Status.Extensions {
+		status := v1beta1.ComponentStatus(ext)
+		dst.Status.Extensions = append(dst.Status.Extensions, status)
+		meta.SetStatusCondition(&dst.Status.Conditions, status.Condition())
+	}
+	return nil
+}
+
+// ConvertFrom converts the hub (v1beta1) version to this version. The deprecated admin console
+// and local artifact mirror settings are moved to the runtime config and the conditions
+// tracking the addons and the extensions are dropped in favour of their typed status.
+func (dst *Installation) ConvertFrom(srcRaw conversion.Hub) error {
+	src, ok := srcRaw.(*v1beta1.Installation)
+	if !ok {
+		return fmt.Errorf("unsupported hub type %T", srcRaw)
+	}
+	in := src.DeepCopy()
+
+	runtimeConfig := in.Spec.RuntimeConfig
+	if in.Spec.Deprecated_AdminConsole != nil && in.Spec.Deprecated_AdminConsole.Port > 0 {
+		if runtimeConfig == nil {
+			runtimeConfig = &v1beta1.RuntimeConfigSpec{}
+		}
+		if runtimeConfig.AdminConsole.Port == 0 {
+			runtimeConfig.AdminConsole.Port = in.Spec.Deprecated_AdminConsole.Port
+		}
+	}
+	if in.Spec.Deprecated_LocalArtifactMirror != nil && in.Spec.Deprecated_LocalArtifactMirror.Port > 0 {
+		if runtimeConfig == nil {
+			runtimeConfig = &v1beta1.RuntimeConfigSpec{}
+		}
+		if runtimeConfig.LocalArtifactMirror.Port == 0 {
+			runtimeConfig.LocalArtifactMirror.Port = in.Spec.Deprecated_LocalArtifactMirror.Port
+		}
+	}
+
+	dst.ObjectMeta = in.ObjectMeta
+	dst.Spec = InstallationSpec{
+		ClusterID:                 in.Spec.ClusterID,
+		MetricsBaseURL:            in.Spec.MetricsBaseURL,
+		Artifacts:                 in.Spec.Artifacts,
+		Config:                    in.Spec.Config,
+		BinaryName:                in.Spec.BinaryName,
+		LicenseInfo:               in.Spec.LicenseInfo,
+		ConfigSecret:              in.Spec.ConfigSecret,
+		SourceType:                in.Spec.SourceType,
+		RuntimeConfig:             runtimeConfig,
+		UpgradeStrategy:           in.Spec.UpgradeStrategy,
+		HostPreflightMonitoring:   in.Spec.HostPreflightMonitoring,
+		RegistryGarbageCollection: in.Spec.RegistryGarbageCollection,
+		ExternalRegistry:          in.Spec.ExternalRegistry,
+		HighAvailability:          in.Spec.HighAvailability,
+		AirGap:                    in.Spec.AirGap,
+		Proxy:                     in.Spec.Proxy,
+		Network:                   in.Spec.Network,
+		EndUserK0sConfigOverrides: in.Spec.EndUserK0sConfigOverrides,
+		EndUserConfig:             in.Spec.EndUserConfig,
+	}
+
+	dst.Status = InstallationStatus{
+		State:                in.Status.State,
+		Reason:               in.Status.Reason,
+		PendingCharts:        in.Status.PendingCharts,
+		NodesArtifactsStatus: in.Status.NodesArtifactsStatus,
+	}
+
+	nodes := map[string]int{}
+	for _, node := range in.Status.NodesStatus {
+		nodes[node.Name] = len(dst.Status.Nodes)
+		dst.Status.Nodes = append(dst.Status.Nodes, NodeStatus{Name: node.Name, Hash: node.Hash})
+	}
+	for _, node := range in.Status.NodesUpgradeStatus {
+		upgrade := &NodeUpgradeStatus{
+			Version: node.Version,
+			Phase:   node.Phase,
+			Message: node.Message,
+		}
+		if idx, ok := nodes[node.Name]; ok {
+			dst.Status.Nodes[idx].Upgrade = upgrade
+			continue
+		}
+		nodes[node.Name] = len(dst.Status.Nodes)
+		dst.Status.Nodes = append(dst.Status.Nodes, NodeStatus{Name: node.Name, Upgrade: upgrade})
+	}
+
+	components := map[string]bool{}
+	for _, addon := range in.Status.Addons {
+		components[addon.ConditionType()] = true
+		dst.Status.Addons = append(dst.Status.Addons, AddonStatus(addon))
+	}
+	for _, ext := range in.Status.Extensions {
+		components[ext.ConditionType()] = true
+		dst.Status.Extensions = append(dst.Status.Extensions, ExtensionStatus(ext))
+	}
+	for _, cond := range in.Status.Conditions {
+		if !components[cond.Type] {
+			dst.Status.Conditions = append(dst.Status.Conditions, cond)
+		}
+	}
+	return nil
+}
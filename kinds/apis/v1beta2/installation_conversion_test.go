@@ -0,0 +1,111 @@
+package v1beta2
+
+import (
+	"testing"
+	"time"
+
+	"github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestInstallationConversion(t *testing.T) {
+	ts := metav1.NewTime(time.Date(2024, 10, 2, 20, 50, 18, 0, time.UTC))
+	openebs := v1beta1.ComponentStatus{
+		Name:               "openebs",
+		Namespace:          "openebs",
+		Version:            "4.1.1",
+		Phase:              v1beta1.ComponentPhaseUpgraded,
+		HelmRevision:       3,
+		LastTransitionTime: ts,
+	}
+	nginx := v1beta1.ComponentStatus{
+		Name:               "nginx",
+		Namespace:          "nginx",
+		Version:            "1.0.0",
+		Phase:              v1beta1.ComponentPhaseFailed,
+		LastError:          "timed out",
+		LastTransitionTime: ts,
+	}
+	hub := &v1beta1.Installation{
+		ObjectMeta: metav1.ObjectMeta{Name: "20241002205018"},
+		Spec: v1beta1.InstallationSpec{
+			ClusterID:     "cluster-id",
+			AirGap:        true,
+			RuntimeConfig: &v1beta1.RuntimeConfigSpec{DataDir: "/var/lib/embedded-cluster"},
+			Network:       &v1beta1.NetworkSpec{PodCIDR: "10.244.0.0/16"},
+		},
+		Status: v1beta1.InstallationStatus{
+			State:  v1beta1.InstallationStateAddonsInstalling,
+			Reason: "Upgrading addons",
+			NodesStatus: []v1beta1.NodeStatus{
+				{Name: "node-1", Hash: "abc"},
+				{Name: "node-2", Hash: "def"},
+			},
+			NodesUpgradeStatus: []v1beta1.NodeUpgradeStatus{
+				{Name: "node-1", Version: "v1.30.5+k0s.0", Phase: v1beta1.NodeUpgradePhaseUpgraded},
+			},
+			Addons:     []v1beta1.ComponentStatus{openebs},
+			Extensions: []v1beta1.ComponentStatus{nginx},
+			Conditions: []metav1.Condition{
+				{Type: v1beta1.ConditionTypeUpgradeRolledBack, Status: metav1.ConditionFalse, Reason: "NotRolledBack", LastTransitionTime: ts},
+				openebs.Condition(),
+				nginx.Condition(),
+			},
+		},
+	}
+
+	var spoke Installation
+	require.NoError(t, spoke.ConvertFrom(hub))
+
+	assert.Equal(t, "cluster-id", spoke.Spec.ClusterID)
+	assert.Equal(t, hub.Spec.RuntimeConfig, spoke.Spec.RuntimeConfig)
+	assert.Equal(t, []NodeStatus{
+		{
+			Name:    "node-1",
+			Hash:    "abc",
+			Upgrade: &NodeUpgradeStatus{Version: "v1.30.5+k0s.0", Phase: v1beta1.NodeUpgradePhaseUpgraded},
+		},
+		{Name: "node-2", Hash: "def"},
+	}, spoke.Status.Nodes)
+	assert.Equal(t, []AddonStatus{AddonStatus(openebs)}, spoke.Status.Addons)
+	assert.Equal(t, []ExtensionStatus{ExtensionStatus(nginx)}, spoke.Status.Extensions)
+	// the conditions tracking addons and extensions are dropped.
+	require.Len(t, spoke.Status.Conditions, 1)
+	assert.Equal(t, v1beta1.ConditionTypeUpgradeRolledBack, spoke.Status.Conditions[0].Type)
+
+	var back v1beta1.Installation
+	require.NoError(t, spoke.ConvertTo(&back))
+	assert.Equal(t, hub, &back)
+}
+
+func TestInstallationConversion_deprecatedFields(t *testing.T) {
+	hub := &v1beta1.Installation{
+		Spec: v1beta1.InstallationSpec{
+			Deprecated_AdminConsole:        &v1beta1.AdminConsoleSpec{Port: 30001},
+			Deprecated_LocalArtifactMirror: &v1beta1.LocalArtifactMirrorSpec{Port: 50001},
+		},
+		Status: v1beta1.InstallationStatus{
+			// conditions without a typed status, written by older versions, are kept.
+			Conditions: []metav1.Condition{
+				{Type: "openebs-openebs", Status: metav1.ConditionTrue, Reason: "Upgraded"},
+			},
+		},
+	}
+
+	var spoke Installation
+	require.NoError(t, spoke.ConvertFrom(hub))
+	require.NotNil(t, spoke.Spec.RuntimeConfig)
+	assert.Equal(t, 30001, spoke.Spec.RuntimeConfig.AdminConsole.Port)
+	assert.Equal(t, 50001, spoke.Spec.RuntimeConfig.LocalArtifactMirror.Port)
+	assert.Equal(t, hub.Status.Conditions, spoke.Status.Conditions)
+	// the source is not modified.
+	assert.Nil(t, hub.Spec.RuntimeConfig)
+
+	var back v1beta1.Installation
+	require.NoError(t, spoke.ConvertTo(&back))
+	assert.Nil(t, back.Spec.Deprecated_AdminConsole)
+	assert.Equal(t, 30001, back.Spec.RuntimeConfig.AdminConsole.Port)
+	assert.Equal(t, hub.Status.Conditions, back.Status.Conditions)
+}
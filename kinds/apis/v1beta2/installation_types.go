@@ -0,0 +1,188 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta2
+
+import (
+	"github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// InstallationSpec defines the desired state of Installation. The types shared with the
+// Config kind and the ones that did not change are reused from v1beta1.
+type InstallationSpec struct {
+	// ClusterID holds the cluster, generated during the installation.
+	ClusterID string `json:"clusterID,omitempty"`
+	// MetricsBaseURL holds the base URL for the metrics server.
+	MetricsBaseURL string `json:"metricsBaseURL,omitempty"`
+	// Artifacts holds the location of the airgap bundle.
+	Artifacts *v1beta1.ArtifactsLocation `json:"artifacts,omitempty"`
+	// Config holds the configuration used at installation time.
+	Config *v1beta1.ConfigSpec `json:"config,omitempty"`
+	// BinaryName holds the name of the binary used to install the cluster.
+	// this will follow the pattern 'appslug-channelslug'
+	BinaryName string `json:"binaryName,omitempty"`
+	// LicenseInfo holds information about the license used to install the cluster.
+	LicenseInfo *v1beta1.LicenseInfo `json:"licenseInfo,omitempty"`
+	// ConfigSecret holds a secret name and namespace. If this is set it means that
+	// the Config for this Installation object must be read from there. This option
+	// supersedes (overrides) the Config field.
+	ConfigSecret *v1beta1.ConfigSecret `json:"configSecret,omitempty"`
+	// SourceType indicates where this Installation object is stored (CRD, ConfigMap, etc...).
+	SourceType string `json:"sourceType,omitempty"`
+	// RuntimeConfig holds the runtime configuration used at installation time.
+	RuntimeConfig *v1beta1.RuntimeConfigSpec `json:"runtimeConfig,omitempty"`
+	// UpgradeStrategy defines how the nodes are upgraded. If not set all the nodes are
+	// upgraded at once.
+	UpgradeStrategy *v1beta1.UpgradeStrategy `json:"upgradeStrategy,omitempty"`
+	// HostPreflightMonitoring defines if and how the host preflights are periodically run on
+	// the nodes after the installation.
+	HostPreflightMonitoring *v1beta1.HostPreflightMonitoring `json:"hostPreflightMonitoring,omitempty"`
+	// RegistryGarbageCollection defines how unreferenced embedded cluster images are removed from
+	// the air gap registry after an upgrade. If not set the images of the current and the previous
+	// release are kept.
+	RegistryGarbageCollection *v1beta1.RegistryGarbageCollection `json:"registryGarbageCollection,omitempty"`
+	// ExternalRegistry holds the registry the air gap images are pushed to when the bundled
+	// registry is not used.
+	ExternalRegistry *v1beta1.ExternalRegistrySpec `json:"externalRegistry,omitempty"`
+	// HighAvailability indicates if the installation is high availability.
+	HighAvailability bool `json:"highAvailability,omitempty"`
+	// AirGap indicates if the installation is airgapped.
+	AirGap bool `json:"airGap,omitempty"`
+	// Proxy holds the proxy configuration.
+	Proxy *v1beta1.ProxySpec `json:"proxy,omitempty"`
+	// Network holds the network configuration.
+	Network *v1beta1.NetworkSpec `json:"network,omitempty"`
+	// EndUserK0sConfigOverrides holds the end user k0s config overrides
+	// used at installation time.
+	EndUserK0sConfigOverrides string `json:"endUserK0sConfigOverrides,omitempty"`
+	// EndUserConfig holds the end user configuration, provided through overrides,
+	// used at installation time. Its built-in extensions overrides are applied
+	// every time the addons are installed or upgraded.
+	EndUserConfig *v1beta1.ConfigSpec `json:"endUserConfig,omitempty"`
+}
+
+// NodeStatus holds the status of a cluster node.
+type NodeStatus struct {
+	Name string `json:"name"`
+	// Hash is a hash of the node status, it changes every time the node status changes.
+	Hash string `json:"hash,omitempty"`
+	// Upgrade holds the progress of the Kubernetes upgrade on the node, if any.
+	Upgrade *NodeUpgradeStatus `json:"upgrade,omitempty"`
+}
+
+// NodeUpgradeStatus holds the progress of the Kubernetes upgrade on a node.
+type NodeUpgradeStatus struct {
+	// Version is the Kubernetes version the node is being upgraded to.
+	Version string `json:"version,omitempty"`
+	// Phase is the current phase of the node upgrade.
+	Phase   string `json:"phase"`
+	Message string `json:"message,omitempty"`
+}
+
+// AddonStatus holds the status of an addon.
+type AddonStatus struct {
+	// Name is the helm release name of the addon.
+	Name string `json:"name"`
+	// Namespace is the namespace the addon is deployed to.
+	Namespace string `json:"namespace,omitempty"`
+	// Version is the chart version of the addon.
+	Version string `json:"version,omitempty"`
+	// Phase is the current phase of the addon.
+	Phase string `json:"phase"`
+	// LastError holds the error that made the addon fail, if any.
+	LastError string `json:"lastError,omitempty"`
+	// HelmRevision is the revision of the helm release once the addon is deployed.
+	HelmRevision int `json:"helmRevision,omitempty"`
+	// LastTransitionTime is the last time the phase changed.
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
+// ExtensionStatus holds the status of a helm extension.
+type ExtensionStatus struct {
+	// Name is the helm release name of the extension.
+	Name string `json:"name"`
+	// Namespace is the namespace the extension is deployed to.
+	Namespace string `json:"namespace,omitempty"`
+	// Version is the chart version of the extension.
+	Version string `json:"version,omitempty"`
+	// Phase is the current phase of the extension.
+	Phase string `json:"phase"`
+	// LastError holds the error that made the extension fail, if any.
+	LastError string `json:"lastError,omitempty"`
+	// HelmRevision is the revision of the helm release once the extension is deployed.
+	HelmRevision int `json:"helmRevision,omitempty"`
+	// LastTransitionTime is the last time the phase changed.
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
+// InstallationStatus defines the observed state of Installation
+type InstallationStatus struct {
+	// State holds the current state of the installation.
+	State string `json:"state,omitempty"`
+	// Reason holds the reason for the current state.
+	Reason string `json:"reason,omitempty"`
+	// Nodes holds the status of each node in the cluster.
+	Nodes []NodeStatus `json:"nodes,omitempty"`
+	// NodesArtifactsStatus holds the progress of the airgap artifacts distribution
+	// for each node in the cluster.
+	NodesArtifactsStatus []v1beta1.NodeArtifactsStatus `json:"nodesArtifactsStatus,omitempty"`
+	// Addons holds the status of each addon.
+	Addons []AddonStatus `json:"addons,omitempty"`
+	// Extensions holds the status of each helm extension.
+	Extensions []ExtensionStatus `json:"extensions,omitempty"`
+	// PendingCharts holds the list of charts that are being created or updated.
+	PendingCharts []string `json:"pendingCharts,omitempty"`
+
+	// Conditions is an array of current observed installation conditions. Addons and
+	// extensions are not tracked here, see Addons and Extensions instead.
+	// +listType=map
+	// +listMapKey=type
+	// +patchStrategy=merge
+	// +patchMergeKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.state",description="State of the installation"
+//+kubebuilder:printcolumn:name="InstallerVersion",type="string",JSONPath=".spec.config.version",description="Installer version"
+//+kubebuilder:printcolumn:name="CreatedAt",type="string",JSONPath=".metadata.creationTimestamp",description="Creation time of the installation"
+//+kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp",description="Age of the resource"
+
+// Installation is the Schema for the installations API
+type Installation struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   InstallationSpec   `json:"spec,omitempty"`
+	Status InstallationStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// InstallationList contains a list of Installation
+type InstallationList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Installation `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Installation{}, &InstallationList{})
+}
@@ -26,6 +26,7 @@ import (
 	apv1b2 "github.com/k0sproject/k0s/pkg/apis/autopilot/v1beta2"
 	k0shelm "github.com/k0sproject/k0s/pkg/apis/helm/v1beta1"
 	"github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/operator/pkg/hostpreflights"
 	"github.com/replicatedhq/embedded-cluster/operator/pkg/metrics"
 	"github.com/replicatedhq/embedded-cluster/operator/pkg/openebs"
 	"github.com/replicatedhq/embedded-cluster/operator/pkg/util"
@@ -50,6 +51,10 @@ import (
 // interval.
 var requeueAfter = time.Hour
 
+// DefaultInstallationHistoryLimit is the default number of obsolete installation objects kept
+// in the cluster. Older ones are deleted and only kept in the installation history.
+const DefaultInstallationHistoryLimit = 10
+
 const copyHostPreflightResultsJobPrefix = "copy-host-preflight-results-"
 const ecNamespace = "embedded-cluster"
 
@@ -128,6 +133,10 @@ type InstallationReconciler struct {
 	Discovery discovery.DiscoveryInterface
 	Scheme    *runtime.Scheme
 	Recorder  record.EventRecorder
+	// InstallationHistoryLimit is the number of obsolete installation objects kept in the
+	// cluster. The most recent obsolete installation is always kept as upgrades compare
+	// against it.
+	InstallationHistoryLimit int
 }
 
 // NodeHasChanged returns true if the node configuration has changed when compared to
@@ -273,6 +282,46 @@ func (r *InstallationReconciler) CoalesceInstallations(
 	return &items[0]
 }
 
+// PruneObsoleteInstallations deletes the obsolete installations beyond the history limit. The
+// installations are recorded in the installation history before being deleted. Installations
+// are expected to be sorted newest first.
+func (r *InstallationReconciler) PruneObsoleteInstallations(ctx context.Context, installs []v1beta1.Installation) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	limit := max(r.InstallationHistoryLimit, 1)
+	var prune []v1beta1.Installation
+	kept := 0
+	for _, in := range installs {
+		if in.Status.State != v1beta1.InstallationStateObsolete {
+			continue
+		}
+		if kept < limit {
+			kept++
+			continue
+		}
+		prune = append(prune, in)
+	}
+	if len(prune) == 0 {
+		return nil
+	}
+
+	entries := []kubeutils.InstallationHistoryEntry{}
+	for _, in := range prune {
+		entries = append(entries, kubeutils.NewInstallationHistoryEntry(in))
+	}
+	if err := kubeutils.RecordInstallationHistory(ctx, r.Client, entries...); err != nil {
+		return fmt.Errorf("failed to record installation history: %w", err)
+	}
+
+	for _, in := range prune {
+		if err := r.Delete(ctx, &in); err != nil && !k8serrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete installation %s: %w", in.Name, err)
+		}
+		log.Info("Pruned obsolete installation", "installation", in.Name)
+	}
+	return nil
+}
+
 // ReadClusterConfigSpecFromSecret reads the cluster config from the secret pointed by spec.ConfigSecret
 // if it is set. This overrides the default configuration from spec.Config.
 func (r *InstallationReconciler) ReadClusterConfigSpecFromSecret(ctx context.Context, in *v1beta1.Installation) error {
@@ -344,7 +393,7 @@ func constructHostPreflightResultsJob(in *v1beta1.Installation, nodeName string)
 }
 
 //+kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
-//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch
 //+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
 //+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=embeddedcluster.replicated.com,resources=installations,verbs=get;list;watch;create;update;patch;delete
@@ -364,6 +413,10 @@ func (r *InstallationReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	if err != nil {
 		return ctrl.Result{}, fmt.Errorf("failed to list installations: %w", err)
 	}
+	// failing to prune old installations does not prevent us from reconciling the current one.
+	if err := r.PruneObsoleteInstallations(ctx, installs); err != nil {
+		log.Error(err, "Failed to prune obsolete installations")
+	}
 	var items []v1beta1.Installation
 	for _, in := range installs {
 		if in.Status.State == v1beta1.InstallationStateObsolete {
@@ -407,6 +460,12 @@ func (r *InstallationReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		return ctrl.Result{}, fmt.Errorf("failed to copy host preflight results: %w", err)
 	}
 
+	// periodically run the host preflights on the nodes. failing to do so does not prevent
+	// us from reconciling the installation.
+	if err := hostpreflights.Reconcile(ctx, r.Client, r.Recorder, in); err != nil {
+		log.Error(err, "Failed to reconcile host preflight monitoring")
+	}
+
 	// cleanup openebs stateful pods
 	if err := r.ReconcileOpenebs(ctx, in); err != nil {
 		return ctrl.Result{}, fmt.Errorf("failed to reconcile openebs: %w", err)
@@ -427,6 +486,9 @@ func (r *InstallationReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	}
 
 	log.Info("Installation reconciliation ended")
+	if interval := hostpreflights.Interval(in); interval > 0 && interval < requeueAfter {
+		return ctrl.Result{RequeueAfter: interval}, nil
+	}
 	return ctrl.Result{RequeueAfter: requeueAfter}, nil
 }
 
@@ -1,13 +1,17 @@
 package controllers
 
 import (
+	"context"
 	"testing"
 
 	"github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
 func TestInstallationReconciler_constructCreateCMCommand(t *testing.T) {
@@ -39,3 +43,55 @@ func TestInstallationReconciler_constructCreateCMCommand(t *testing.T) {
 		Value: "my-node-host-preflight-results",
 	}, job.Spec.Template.Spec.Containers[0].Env[1])
 }
+
+func TestInstallationReconciler_PruneObsoleteInstallations(t *testing.T) {
+	ctx := context.Background()
+	obsolete := func(name, version, state string) *v1beta1.Installation {
+		return &v1beta1.Installation{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        name,
+				Annotations: map[string]string{v1beta1.InstallationObsoletedStateAnnotation: state},
+			},
+			Spec:   v1beta1.InstallationSpec{Config: &v1beta1.ConfigSpec{Version: version}},
+			Status: v1beta1.InstallationStatus{State: v1beta1.InstallationStateObsolete},
+		}
+	}
+	objects := []client.Object{
+		&v1beta1.Installation{
+			ObjectMeta: metav1.ObjectMeta{Name: "20241004000000"},
+			Spec:       v1beta1.InstallationSpec{Config: &v1beta1.ConfigSpec{Version: "1.4.0"}},
+			Status:     v1beta1.InstallationStatus{State: v1beta1.InstallationStateInstalled},
+		},
+		obsolete("20241003000000", "1.3.0", v1beta1.InstallationStateInstalled),
+		obsolete("20241002000000", "1.2.0", v1beta1.InstallationStateFailed),
+		obsolete("20241001000000", "1.1.0", v1beta1.InstallationStateInstalled),
+	}
+	cli := fake.NewClientBuilder().WithScheme(kubeutils.Scheme).WithObjects(objects...).Build()
+	r := &InstallationReconciler{Client: cli, InstallationHistoryLimit: 1}
+
+	installs, err := kubeutils.ListInstallations(ctx, cli)
+	require.NoError(t, err)
+	require.NoError(t, r.PruneObsoleteInstallations(ctx, installs))
+
+	installs, err = kubeutils.ListInstallations(ctx, cli)
+	require.NoError(t, err)
+	names := []string{}
+	for _, in := range installs {
+		names = append(names, in.Name)
+	}
+	assert.Equal(t, []string{"20241004000000", "20241003000000"}, names)
+
+	// the pruned installations are still part of the history.
+	history, err := kubeutils.GetInstallationHistory(ctx, cli)
+	require.NoError(t, err)
+	got := [][]string{}
+	for _, entry := range history {
+		got = append(got, []string{entry.Name, entry.Version, entry.Result})
+	}
+	assert.Equal(t, [][]string{
+		{"20241004000000", "1.4.0", v1beta1.InstallationStateInstalled},
+		{"20241003000000", "1.3.0", v1beta1.InstallationStateInstalled},
+		{"20241002000000", "1.2.0", v1beta1.InstallationStateFailed},
+		{"20241001000000", "1.1.0", v1beta1.InstallationStateInstalled},
+	}, got)
+}
@@ -0,0 +1,130 @@
+package artifacts
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	clusterv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/operator/pkg/util"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const cleanupArtifactsJobPrefix = "cleanup-artifacts-"
+
+// cleanupArtifactsJob is the job we create, in each node, to remove the artifacts that are no
+// longer referenced by the current or the previous installation. This is not yet a complete
+// version of the job as it misses some env variables and a node selector, those are populated
+// by EnsureCleanupJobForNodes.
+var cleanupArtifactsJob = &batchv1.Job{
+	TypeMeta: metav1.TypeMeta{
+		APIVersion: "batch/v1",
+		Kind:       "Job",
+	},
+	ObjectMeta: metav1.ObjectMeta{
+		Namespace: ecNamespace,
+	},
+	Spec: batchv1.JobSpec{
+		BackoffLimit: ptr.To[int32](2),
+		Template: corev1.PodTemplateSpec{
+			Spec: corev1.PodSpec{
+				ServiceAccountName: "embedded-cluster-operator",
+				Volumes: []corev1.Volume{
+					{
+						Name: "host",
+						VolumeSource: corev1.VolumeSource{
+							HostPath: &corev1.HostPathVolumeSource{
+								Path: clusterv1beta1.DefaultDataDir,
+								Type: ptr.To[corev1.HostPathType]("Directory"),
+							},
+						},
+					},
+				},
+				RestartPolicy: corev1.RestartPolicyNever,
+				Containers: []corev1.Container{
+					{
+						Name:            "embedded-cluster-cleanup",
+						ImagePullPolicy: corev1.PullIfNotPresent,
+						VolumeMounts: []corev1.VolumeMount{
+							{
+								Name:      "host",
+								MountPath: "/embedded-cluster",
+								ReadOnly:  false,
+							},
+						},
+						Command: []string{
+							"/bin/sh",
+							"-ex",
+							"-c",
+							"/usr/local/bin/local-artifact-mirror cleanup --data-dir /embedded-cluster --keep-version \"$KEEP_VERSIONS\"\n" +
+								"echo 'done'",
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+// EnsureCleanupJobForNodes creates, in each node, a job that removes the artifacts not
+// referenced by any of the provided installation versions. Jobs created for a different
+// installation are replaced.
+func EnsureCleanupJobForNodes(ctx context.Context, cli client.Client, in *clusterv1beta1.Installation, localArtifactMirrorImage string, keepVersions []string) error {
+	if len(keepVersions) == 0 {
+		return fmt.Errorf("no versions to keep")
+	}
+
+	var nodes corev1.NodeList
+	if err := cli.List(ctx, &nodes); err != nil {
+		return fmt.Errorf("list nodes: %w", err)
+	}
+
+	for _, node := range nodes.Items {
+		job, err := getCleanupJobForNode(cli, in, node, localArtifactMirrorImage, keepVersions)
+		if err != nil {
+			return fmt.Errorf("get cleanup job for node %s: %w", node.Name, err)
+		}
+		err = kubeutils.EnsureObject(ctx, cli, job, func(opts *kubeutils.EnsureObjectOptions) {
+			opts.DeleteOptions = append(opts.DeleteOptions, client.PropagationPolicy(metav1.DeletePropagationForeground))
+			opts.ShouldDelete = func(obj client.Object) bool {
+				return obj.GetAnnotations()[InstallationNameAnnotation] != in.Name
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("ensure cleanup job for node %s: %w", node.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func getCleanupJobForNode(cli client.Client, in *clusterv1beta1.Installation, node corev1.Node, localArtifactMirrorImage string, keepVersions []string) (*batchv1.Job, error) {
+	job := cleanupArtifactsJob.DeepCopy()
+	job.ObjectMeta.Name = util.NameWithLengthLimit(cleanupArtifactsJobPrefix, node.Name)
+	job.ObjectMeta.Labels = applyECOperatorLabels(job.ObjectMeta.Labels, "cleanup")
+	job.ObjectMeta.Annotations = map[string]string{InstallationNameAnnotation: in.Name}
+	job.Spec.Template.Spec.NodeName = node.Name
+	job.Spec.Template.Spec.Volumes[0].VolumeSource.HostPath.Path = runtimeconfig.EmbeddedClusterHomeDirectory()
+	job.Spec.Template.Spec.Containers[0].Env = append(
+		job.Spec.Template.Spec.Containers[0].Env,
+		corev1.EnvVar{Name: "KEEP_VERSIONS", Value: strings.Join(keepVersions, ",")},
+	)
+	job.Spec.Template.Spec.Containers[0].Image = localArtifactMirrorImage
+	job.Spec.Template.Spec.ImagePullSecrets = append(job.Spec.Template.Spec.ImagePullSecrets, GetRegistryImagePullSecret())
+
+	if in.GetUID() != "" {
+		err := ctrl.SetControllerReference(in, job, cli.Scheme())
+		if err != nil {
+			return nil, fmt.Errorf("failed to set controller reference: %w", err)
+		}
+	}
+
+	return job, nil
+}
@@ -0,0 +1,49 @@
+package artifacts
+
+import (
+	"context"
+	"testing"
+
+	clusterv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestEnsureCleanupJobForNodes(t *testing.T) {
+	in := &clusterv1beta1.Installation{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-installation",
+		},
+	}
+
+	cli := fake.NewClientBuilder().
+		WithScheme(kubeutils.Scheme).
+		WithObjects(
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}},
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node2"}},
+		).
+		Build()
+
+	err := EnsureCleanupJobForNodes(context.Background(), cli, in, "local-artifact-mirror", nil)
+	require.Error(t, err, "expected an error when no versions are provided")
+
+	err = EnsureCleanupJobForNodes(context.Background(), cli, in, "local-artifact-mirror", []string{"2.0.0", "1.0.0"})
+	require.NoError(t, err)
+
+	for _, node := range []string{"node1", "node2"} {
+		job := &batchv1.Job{}
+		key := client.ObjectKey{Namespace: ecNamespace, Name: cleanupArtifactsJobPrefix + node}
+		require.NoError(t, cli.Get(context.Background(), key, job))
+
+		assert.Equal(t, "test-installation", job.Annotations[InstallationNameAnnotation])
+		assert.Equal(t, node, job.Spec.Template.Spec.NodeName)
+		assert.Equal(t, "local-artifact-mirror", job.Spec.Template.Spec.Containers[0].Image)
+		assert.Contains(t, job.Spec.Template.Spec.Containers[0].Env, corev1.EnvVar{Name: "KEEP_VERSIONS", Value: "2.0.0,1.0.0"})
+	}
+}
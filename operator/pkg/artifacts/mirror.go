@@ -0,0 +1,102 @@
+package artifacts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	clusterv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// mirrorHTTPClient is the client used to reach the local artifact mirror readiness endpoint
+// on the nodes. The nodes are reached directly, bypassing any configured proxy.
+var mirrorHTTPClient = &http.Client{
+	Timeout:   5 * time.Second,
+	Transport: &http.Transport{Proxy: nil},
+}
+
+// WaitForLocalArtifactMirrors waits until the local artifact mirrors report themselves as
+// ready. Autopilot fetches the airgap artifacts from the local artifact mirrors so we need
+// them to be serving before creating any plan. Only the mirrors of the nodes that completed
+// their artifacts job are waited for, these are the ones serving on the node address. All
+// the mirrors are polled in parallel until the timeout expires.
+func WaitForLocalArtifactMirrors(ctx context.Context, cli client.Client, in *clusterv1beta1.Installation, timeout time.Duration) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	var nodes corev1.NodeList
+	if err := cli.List(ctx, &nodes); err != nil {
+		return fmt.Errorf("list nodes: %w", err)
+	}
+	jobs, err := ListArtifactsJobForNodes(ctx, cli, in)
+	if err != nil {
+		return fmt.Errorf("list artifacts jobs: %w", err)
+	}
+
+	pending := peersForNodes(nodes.Items, jobs)
+	if skipped := len(nodes.Items) - len(pending); skipped > 0 {
+		log.Info("Not waiting for the local artifact mirrors of nodes without artifacts", "nodes", skipped)
+	}
+
+	var lastErrs []error
+	err = wait.PollUntilContextTimeout(ctx, 2*time.Second, timeout, true, func(ctx context.Context) (bool, error) {
+		errs := checkLocalArtifactMirrorsReady(ctx, pending)
+		notReady, notReadyErrs := []artifactsPeer{}, []error{}
+		for i, err := range errs {
+			if err == nil {
+				continue
+			}
+			log.V(5).Info("Local artifact mirror not ready", "node", pending[i].NodeName, "error", err)
+			notReady = append(notReady, pending[i])
+			notReadyErrs = append(notReadyErrs, fmt.Errorf("node %s: %w", pending[i].NodeName, err))
+		}
+		pending, lastErrs = notReady, notReadyErrs
+		return len(pending) == 0, nil
+	})
+	if err != nil {
+		if len(lastErrs) > 0 {
+			err = errors.Join(lastErrs...)
+		}
+		return fmt.Errorf("wait for local artifact mirrors on nodes %s: %w", peerNames(pending), err)
+	}
+
+	return nil
+}
+
+// checkLocalArtifactMirrorsReady checks the readiness endpoint of all the peers at the same
+// time. The returned errors are in the same order as the peers, nil for the ready ones.
+func checkLocalArtifactMirrorsReady(ctx context.Context, peers []artifactsPeer) []error {
+	errs := make([]error, len(peers))
+	var wg sync.WaitGroup
+	for i, peer := range peers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = checkLocalArtifactMirrorReady(ctx, peer.URL+"/readyz")
+		}()
+	}
+	wg.Wait()
+	return errs
+}
+
+func checkLocalArtifactMirrorReady(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	resp, err := mirrorHTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("get %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package artifacts
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	clusterv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestWaitForLocalArtifactMirrors(t *testing.T) {
+	in := &clusterv1beta1.Installation{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-installation",
+		},
+		Spec: clusterv1beta1.InstallationSpec{
+			Artifacts: &clusterv1beta1.ArtifactsLocation{
+				Images:                  "images",
+				HelmCharts:              "helm-charts",
+				EmbeddedClusterBinary:   "embedded-cluster-binary",
+				EmbeddedClusterMetadata: "embedded-cluster-metadata",
+			},
+		},
+	}
+	hash, err := HashForAirgapConfig(in)
+	require.NoError(t, err)
+
+	node := func(name, addr string) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: corev1.NodeStatus{
+				Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: addr}},
+			},
+		}
+	}
+	succeededJob := func(name string) *batchv1.Job {
+		return &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: ecNamespace,
+				Name:      copyArtifactsJobPrefix + name,
+				Annotations: map[string]string{
+					InstallationNameAnnotation:    in.Name,
+					ArtifactsConfigHashAnnotation: hash,
+				},
+			},
+			Status: batchv1.JobStatus{Succeeded: 1},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		ready   bool
+		objs    []client.Object
+		wantErr string
+	}{
+		{
+			name:  "ready mirror",
+			ready: true,
+			objs:  []client.Object{node("node1", "127.0.0.1"), succeededJob("node1")},
+		},
+		{
+			name:  "nodes without artifacts are skipped",
+			ready: true,
+			objs: []client.Object{
+				node("node1", "127.0.0.1"), succeededJob("node1"),
+				node("node2", "192.0.2.1"),
+			},
+		},
+		{
+			name:    "mirror not ready",
+			ready:   false,
+			objs:    []client.Object{node("node1", "127.0.0.1"), succeededJob("node1")},
+			wantErr: "wait for local artifact mirrors on nodes node1: node node1: unexpected status code 503",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !tt.ready {
+					w.WriteHeader(http.StatusServiceUnavailable)
+				}
+			}))
+			defer server.Close()
+
+			_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+			require.NoError(t, err)
+			portNum, err := strconv.Atoi(port)
+			require.NoError(t, err)
+			runtimeconfig.SetLocalArtifactMirrorPort(portNum)
+			defer runtimeconfig.SetLocalArtifactMirrorPort(0)
+
+			cli := fake.NewClientBuilder().
+				WithScheme(kubeutils.Scheme).
+				WithObjects(tt.objs...).
+				Build()
+
+			err = WaitForLocalArtifactMirrors(context.Background(), cli, in, 3*time.Second)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
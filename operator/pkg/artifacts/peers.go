@@ -0,0 +1,132 @@
+package artifacts
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	clusterv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// artifactsPeer is a node that already holds the artifacts for an installation and can
+// serve them to other nodes through its local artifact mirror.
+type artifactsPeer struct {
+	NodeName string
+	URL      string
+}
+
+// peersForNodes returns the nodes that have successfully completed their artifacts jobs
+// and can therefore serve the artifacts to the remaining nodes. Nodes are expected to be
+// sorted.
+func peersForNodes(nodes []corev1.Node, jobs map[string]*batchv1.Job) []artifactsPeer {
+	var peers []artifactsPeer
+	for _, node := range nodes {
+		job := jobs[node.Name]
+		if job == nil || job.Status.Succeeded == 0 {
+			continue
+		}
+		addr := nodeInternalIP(node)
+		if addr == "" {
+			continue
+		}
+		peers = append(peers, artifactsPeer{
+			NodeName: node.Name,
+			URL:      fmt.Sprintf("http://%s:%d", addr, runtimeconfig.LocalArtifactMirrorPort()),
+		})
+	}
+	return peers
+}
+
+// nodeInternalIP returns the internal ip address of the node or an empty string if none
+// has been reported.
+func nodeInternalIP(node corev1.Node) string {
+	for _, addr := range node.Status.Addresses {
+		if addr.Type == corev1.NodeInternalIP {
+			return addr.Address
+		}
+	}
+	return ""
+}
+
+// rotatePeers returns a copy of the peers list rotated by offset positions.
+func rotatePeers(peers []artifactsPeer, offset int) []artifactsPeer {
+	if len(peers) == 0 {
+		return nil
+	}
+	offset = offset % len(peers)
+	return append(append([]artifactsPeer{}, peers[offset:]...), peers[:offset]...)
+}
+
+func peerNames(peers []artifactsPeer) string {
+	names := []string{}
+	for _, peer := range peers {
+		names = append(names, peer.NodeName)
+	}
+	return strings.Join(names, ",")
+}
+
+func peerURLs(peers []artifactsPeer) string {
+	urls := []string{}
+	for _, peer := range peers {
+		urls = append(urls, peer.URL)
+	}
+	return strings.Join(urls, ",")
+}
+
+// IsArtifactsJobFinished returns true if the job has either succeeded or failed.
+func IsArtifactsJobFinished(job *batchv1.Job) bool {
+	if job.Status.Succeeded > 0 {
+		return true
+	}
+	_, failed := artifactsJobFailure(job)
+	return failed
+}
+
+// artifactsJobFailure returns the failure message and true if the job has failed.
+func artifactsJobFailure(job *batchv1.Job) (string, bool) {
+	for _, cond := range job.Status.Conditions {
+		if cond.Type == batchv1.JobFailed && cond.Status == corev1.ConditionTrue {
+			return fmt.Sprintf("%s - %s", cond.Reason, cond.Message), true
+		}
+	}
+	return "", false
+}
+
+// NodesArtifactsStatus returns the artifacts distribution status for each one of the nodes
+// based on their artifacts jobs, as returned by ListArtifactsJobForNodes.
+func NodesArtifactsStatus(jobs map[string]*batchv1.Job) []clusterv1beta1.NodeArtifactsStatus {
+	var names []string
+	for name := range jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	statuses := []clusterv1beta1.NodeArtifactsStatus{}
+	for _, name := range names {
+		status := clusterv1beta1.NodeArtifactsStatus{
+			Name:  name,
+			Phase: clusterv1beta1.NodeArtifactsPhasePending,
+		}
+		job := jobs[name]
+		if job == nil {
+			statuses = append(statuses, status)
+			continue
+		}
+		if peers := job.GetAnnotations()[ArtifactsPeersAnnotation]; peers != "" {
+			status.Peers = strings.Split(peers, ",")
+		}
+		if job.Status.Succeeded > 0 {
+			status.Phase = clusterv1beta1.NodeArtifactsPhaseSucceeded
+		} else if msg, failed := artifactsJobFailure(job); failed {
+			status.Phase = clusterv1beta1.NodeArtifactsPhaseFailed
+			status.Message = msg
+		} else {
+			status.Phase = clusterv1beta1.NodeArtifactsPhaseRunning
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
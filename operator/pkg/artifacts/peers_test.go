@@ -0,0 +1,149 @@
+package artifacts
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	clusterv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestEnsureArtifactsJobForNodes_peers(t *testing.T) {
+	in := &clusterv1beta1.Installation{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test-installation",
+		},
+		Spec: clusterv1beta1.InstallationSpec{
+			Artifacts: &clusterv1beta1.ArtifactsLocation{
+				Images:                  "images",
+				HelmCharts:              "helm-charts",
+				EmbeddedClusterBinary:   "embedded-cluster-binary",
+				EmbeddedClusterMetadata: "embedded-cluster-metadata",
+			},
+		},
+	}
+
+	hash, err := HashForAirgapConfig(in)
+	require.NoError(t, err)
+
+	objs := []client.Object{}
+	for i := 1; i <= 8; i++ {
+		objs = append(objs, &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: fmt.Sprintf("node%d", i),
+			},
+			Status: corev1.NodeStatus{
+				Addresses: []corev1.NodeAddress{
+					{Type: corev1.NodeInternalIP, Address: fmt.Sprintf("10.0.0.%d", i)},
+				},
+			},
+		})
+	}
+	// node1 and node2 already have the artifacts.
+	for _, name := range []string{"node1", "node2"} {
+		objs = append(objs, &batchv1.Job{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: ecNamespace,
+				Name:      copyArtifactsJobPrefix + name,
+				Annotations: map[string]string{
+					InstallationNameAnnotation:    in.Name,
+					ArtifactsConfigHashAnnotation: hash,
+				},
+			},
+			Status: batchv1.JobStatus{
+				Succeeded: 1,
+			},
+		})
+	}
+
+	cli := fake.NewClientBuilder().
+		WithScheme(kubeutils.Scheme).
+		WithObjects(objs...).
+		Build()
+
+	err = EnsureArtifactsJobForNodes(context.Background(), cli, in, "local-artifact-mirror")
+	require.NoError(t, err)
+
+	// only MaxConcurrentArtifactsJobs jobs must have been created, node8 must wait.
+	for i := 3; i <= 7; i++ {
+		job := &batchv1.Job{}
+		key := client.ObjectKey{Namespace: ecNamespace, Name: fmt.Sprintf("%snode%d", copyArtifactsJobPrefix, i)}
+		require.NoError(t, cli.Get(context.Background(), key, job))
+
+		peers := job.Annotations[ArtifactsPeersAnnotation]
+		assert.Contains(t, peers, "node1")
+		assert.Contains(t, peers, "node2")
+
+		var env string
+		for _, e := range job.Spec.Template.Spec.Containers[0].Env {
+			if e.Name == "ARTIFACTS_PEERS" {
+				env = e.Value
+			}
+		}
+		assert.Contains(t, env, "http://10.0.0.1:50000")
+		assert.Contains(t, env, "http://10.0.0.2:50000")
+	}
+
+	job := &batchv1.Job{}
+	key := client.ObjectKey{Namespace: ecNamespace, Name: copyArtifactsJobPrefix + "node8"}
+	err = cli.Get(context.Background(), key, job)
+	assert.True(t, k8serrors.IsNotFound(err), "expected job for node8 not to exist, got %v", err)
+}
+
+func Test_rotatePeers(t *testing.T) {
+	peers := []artifactsPeer{{NodeName: "a"}, {NodeName: "b"}, {NodeName: "c"}}
+
+	assert.Nil(t, rotatePeers(nil, 3))
+	assert.Equal(t, "a,b,c", peerNames(rotatePeers(peers, 0)))
+	assert.Equal(t, "b,c,a", peerNames(rotatePeers(peers, 1)))
+	assert.Equal(t, "c,a,b", peerNames(rotatePeers(peers, 5)))
+	// the original list must not be modified.
+	assert.Equal(t, "a,b,c", peerNames(peers))
+}
+
+func TestNodesArtifactsStatus(t *testing.T) {
+	jobs := map[string]*batchv1.Job{
+		"node1": {
+			Status: batchv1.JobStatus{Succeeded: 1},
+		},
+		"node2": {
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{ArtifactsPeersAnnotation: "node1"},
+			},
+		},
+		"node3": {
+			Status: batchv1.JobStatus{
+				Conditions: []batchv1.JobCondition{
+					{
+						Type:    batchv1.JobFailed,
+						Status:  corev1.ConditionTrue,
+						Reason:  "BackoffLimitExceeded",
+						Message: "Job has reached the specified backoff limit",
+					},
+				},
+			},
+		},
+		"node4": nil,
+	}
+
+	expected := []clusterv1beta1.NodeArtifactsStatus{
+		{Name: "node1", Phase: clusterv1beta1.NodeArtifactsPhaseSucceeded},
+		{Name: "node2", Phase: clusterv1beta1.NodeArtifactsPhaseRunning, Peers: []string{"node1"}},
+		{
+			Name:    "node3",
+			Phase:   clusterv1beta1.NodeArtifactsPhaseFailed,
+			Message: "BackoffLimitExceeded - Job has reached the specified backoff limit",
+		},
+		{Name: "node4", Phase: clusterv1beta1.NodeArtifactsPhasePending},
+	}
+	assert.Equal(t, expected, NodesArtifactsStatus(jobs))
+}
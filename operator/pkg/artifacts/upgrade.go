@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"runtime"
+	"sort"
 
 	autopilotv1beta2 "github.com/k0sproject/k0s/pkg/apis/autopilot/v1beta2"
 	clusterv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
@@ -27,17 +28,30 @@ import (
 const ecNamespace = "embedded-cluster"
 const copyArtifactsJobPrefix = "copy-artifacts-"
 
+// UpgraderComponent is the app.kubernetes.io/component label of the artifacts jobs and of the
+// objects they use.
+const UpgraderComponent = "upgrader"
+
 const (
 	// InstallationNameAnnotation is the annotation we keep in the autopilot plan so we can
 	// map 1 to 1 one installation and one plan.
 	InstallationNameAnnotation    = "embedded-cluster.replicated.com/installation-name"
 	ArtifactsConfigHashAnnotation = "embedded-cluster.replicated.com/artifacts-config-hash"
+	// ArtifactsPeersAnnotation holds the comma separated list of nodes the artifacts job
+	// fetches the artifacts from before falling back to the registry.
+	ArtifactsPeersAnnotation = "embedded-cluster.replicated.com/artifacts-peers"
 )
 
+// MaxConcurrentArtifactsJobs is the maximum number of artifacts jobs running at the same
+// time. Nodes that complete their jobs become peers for the following ones.
+const MaxConcurrentArtifactsJobs = 5
+
 // copyArtifactsJob is a job we create everytime we need to sync files into all nodes. This job
 // mounts the data directory from the node and uses binaries that are present there. This is not
 // yet a complete version of the job as it misses some env variables and a node selector, those are
-// populated during the reconcile cycle.
+// populated during the reconcile cycle. Before replacing the binaries the node address is written
+// to a support file, local artifact mirrors installed before they served the other nodes use it
+// to configure themselves once restarted.
 var copyArtifactsJob = &batchv1.Job{
 	TypeMeta: metav1.TypeMeta{
 		APIVersion: "batch/v1",
@@ -73,13 +87,23 @@ var copyArtifactsJob = &batchv1.Job{
 								ReadOnly:  false,
 							},
 						},
+						Env: []corev1.EnvVar{
+							{
+								Name: "NODE_ADDRESS",
+								ValueFrom: &corev1.EnvVarSource{
+									FieldRef: &corev1.ObjectFieldSelector{FieldPath: "status.hostIP"},
+								},
+							},
+						},
 						Command: []string{
 							"/bin/sh",
 							"-ex",
 							"-c",
-							"/usr/local/bin/local-artifact-mirror pull binaries --data-dir /embedded-cluster $INSTALLATION_DATA\n" +
-								"/usr/local/bin/local-artifact-mirror pull images --data-dir /embedded-cluster $INSTALLATION_DATA\n" +
-								"/usr/local/bin/local-artifact-mirror pull helmcharts --data-dir /embedded-cluster $INSTALLATION_DATA\n" +
+							"mkdir -p /embedded-cluster/support\n" +
+								"echo \"$NODE_ADDRESS\" > /embedded-cluster/support/" + runtimeconfig.LocalArtifactMirrorPeerAddressFile + "\n" +
+								"/usr/local/bin/local-artifact-mirror pull binaries --data-dir /embedded-cluster --peers \"$ARTIFACTS_PEERS\" $INSTALLATION_DATA\n" +
+								"/usr/local/bin/local-artifact-mirror pull images --data-dir /embedded-cluster --peers \"$ARTIFACTS_PEERS\" $INSTALLATION_DATA\n" +
+								"/usr/local/bin/local-artifact-mirror pull helmcharts --data-dir /embedded-cluster --peers \"$ARTIFACTS_PEERS\" $INSTALLATION_DATA\n" +
 								"mv /embedded-cluster/bin/k0s /embedded-cluster/bin/k0s-upgrade\n" +
 								"rm /embedded-cluster/images/images-amd64-* || true\n" +
 								"echo 'done'",
@@ -93,7 +117,9 @@ var copyArtifactsJob = &batchv1.Job{
 
 // EnsureArtifactsJobForNodes copies the installation artifacts to the nodes in the cluster.
 // This is done by creating a job for each node in the cluster, which will pull the
-// artifacts from the internal registry.
+// artifacts from the nodes that already have them (peers) or from the internal registry.
+// At most MaxConcurrentArtifactsJobs jobs run at the same time so the registry is not
+// overloaded, this function must be called repeatedly until all nodes have a job.
 func EnsureArtifactsJobForNodes(ctx context.Context, cli client.Client, in *clusterv1beta1.Installation, localArtifactMirrorImage string) error {
 	if in.Spec.Artifacts == nil {
 		return fmt.Errorf("no artifacts location defined")
@@ -103,6 +129,14 @@ func EnsureArtifactsJobForNodes(ctx context.Context, cli client.Client, in *clus
 	if err := cli.List(ctx, &nodes); err != nil {
 		return fmt.Errorf("list nodes: %w", err)
 	}
+	sort.Slice(nodes.Items, func(i, j int) bool {
+		return nodes.Items[i].Name < nodes.Items[j].Name
+	})
+
+	jobs, err := ListArtifactsJobForNodes(ctx, cli, in)
+	if err != nil {
+		return fmt.Errorf("list artifacts jobs for nodes: %w", err)
+	}
 
 	// generate a hash of the current config so we can detect config changes.
 	cfghash, err := HashForAirgapConfig(in)
@@ -110,11 +144,28 @@ func EnsureArtifactsJobForNodes(ctx context.Context, cli client.Client, in *clus
 		return fmt.Errorf("hash airgap config: %w", err)
 	}
 
-	for _, node := range nodes.Items {
-		_, err := ensureArtifactsJobForNode(ctx, cli, in, node, localArtifactMirrorImage, cfghash)
+	peers := peersForNodes(nodes.Items, jobs)
+	running := 0
+	for _, job := range jobs {
+		if job != nil && !IsArtifactsJobFinished(job) {
+			running++
+		}
+	}
+
+	for i, node := range nodes.Items {
+		if jobs[node.Name] != nil {
+			continue
+		}
+		if running >= MaxConcurrentArtifactsJobs {
+			break
+		}
+		// we rotate the peers list so not all nodes start fetching from the same peer.
+		nodePeers := rotatePeers(peers, i)
+		_, err := ensureArtifactsJobForNode(ctx, cli, in, node, localArtifactMirrorImage, cfghash, nodePeers)
 		if err != nil {
 			return fmt.Errorf("ensure artifacts job for node: %w", err)
 		}
+		running++
 	}
 
 	return nil
@@ -175,8 +226,8 @@ func HashForAirgapConfig(in *clusterv1beta1.Installation) (string, error) {
 	return hash[:10], nil
 }
 
-func ensureArtifactsJobForNode(ctx context.Context, cli client.Client, in *clusterv1beta1.Installation, node corev1.Node, localArtifactMirrorImage, cfghash string) (*batchv1.Job, error) {
-	job, err := getArtifactJobForNode(ctx, cli, in, node, localArtifactMirrorImage)
+func ensureArtifactsJobForNode(ctx context.Context, cli client.Client, in *clusterv1beta1.Installation, node corev1.Node, localArtifactMirrorImage, cfghash string, peers []artifactsPeer) (*batchv1.Job, error) {
+	job, err := getArtifactJobForNode(ctx, cli, in, node, localArtifactMirrorImage, peers)
 	if err != nil {
 		return nil, fmt.Errorf("get job for node: %w", err)
 	}
@@ -200,7 +251,7 @@ func ensureArtifactsJobForNode(ctx context.Context, cli client.Client, in *clust
 	return job, nil
 }
 
-func getArtifactJobForNode(ctx context.Context, cli client.Client, in *clusterv1beta1.Installation, node corev1.Node, localArtifactMirrorImage string) (*batchv1.Job, error) {
+func getArtifactJobForNode(ctx context.Context, cli client.Client, in *clusterv1beta1.Installation, node corev1.Node, localArtifactMirrorImage string, peers []artifactsPeer) (*batchv1.Job, error) {
 	hash, err := HashForAirgapConfig(in)
 	if err != nil {
 		return nil, fmt.Errorf("failed to hash airgap config: %w", err)
@@ -214,14 +265,16 @@ func getArtifactJobForNode(ctx context.Context, cli client.Client, in *clusterv1
 
 	job := copyArtifactsJob.DeepCopy()
 	job.ObjectMeta.Name = util.NameWithLengthLimit(copyArtifactsJobPrefix, node.Name)
-	job.ObjectMeta.Labels = applyECOperatorLabels(job.ObjectMeta.Labels, "upgrader")
+	job.ObjectMeta.Labels = applyECOperatorLabels(job.ObjectMeta.Labels, UpgraderComponent)
 	job.ObjectMeta.Annotations = applyArtifactsJobAnnotations(job.GetAnnotations(), in, hash)
+	job.ObjectMeta.Annotations[ArtifactsPeersAnnotation] = peerNames(peers)
 	job.Spec.Template.Spec.NodeName = node.Name
 	job.Spec.Template.Spec.Volumes[0].VolumeSource.HostPath.Path = runtimeconfig.EmbeddedClusterHomeDirectory()
 	job.Spec.Template.Spec.Containers[0].Env = append(
 		job.Spec.Template.Spec.Containers[0].Env,
 		corev1.EnvVar{Name: "INSTALLATION", Value: in.Name},
 		corev1.EnvVar{Name: "INSTALLATION_DATA", Value: inDataEncoded},
+		corev1.EnvVar{Name: "ARTIFACTS_PEERS", Value: peerURLs(peers)},
 	)
 
 	job.Spec.Template.Spec.Containers[0].Image = localArtifactMirrorImage
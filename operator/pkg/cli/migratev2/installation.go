@@ -7,6 +7,8 @@ import (
 
 	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
 	"github.com/replicatedhq/embedded-cluster/pkg/helpers"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -68,14 +70,15 @@ func setV2MigrationInProgressCondition(
 }
 
 func setInstallationCondition(ctx context.Context, cli client.Client, in *ecv1beta1.Installation, condition metav1.Condition) error {
-	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
-		var copy ecv1beta1.Installation
+	var copy ecv1beta1.Installation
+	var changed bool
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
 		err := cli.Get(ctx, client.ObjectKey{Name: in.Name}, &copy)
 		if err != nil {
 			return fmt.Errorf("get installation: %w", err)
 		}
 
-		copy.Status.SetCondition(condition)
+		changed = copy.Status.SetCondition(condition)
 
 		err = cli.Status().Update(ctx, &copy)
 		if err != nil {
@@ -84,4 +87,39 @@ func setInstallationCondition(ctx context.Context, cli client.Client, in *ecv1be
 
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+
+	if changed {
+		eventType := corev1.EventTypeNormal
+		if condition.Reason == "MigrationFailed" {
+			eventType = corev1.EventTypeWarning
+		}
+		message := fmt.Sprintf("V2 migration %s", migrationEventMessages[condition.Reason])
+		if condition.Message != "" {
+			message = fmt.Sprintf("%s: %s", message, condition.Message)
+		}
+		kubeutils.RecordInstallationEvent(ctx, cli, &copy, eventType, condition.Reason, message)
+	}
+
+	return nil
+}
+
+// migrationEventMessages maps the v2 migration condition reasons to the message used in the
+// events recorded on the installation.
+var migrationEventMessages = map[string]string{
+	"MigrationInProgress": "started",
+	"MigrationComplete":   "completed",
+	"MigrationFailed":     "failed",
+}
+
+// recordMigrationStep records an event on the installation for a step of the v2 migration.
+func recordMigrationStep(ctx context.Context, cli client.Client, in *ecv1beta1.Installation, message string) {
+	var current ecv1beta1.Installation
+	if err := cli.Get(ctx, client.ObjectKey{Name: in.Name}, &current); err != nil {
+		slog.Error("Failed to get installation to record migration step", "error", err)
+		return
+	}
+	kubeutils.RecordInstallationEvent(ctx, cli, &current, corev1.EventTypeNormal, "MigrationStep", message)
 }
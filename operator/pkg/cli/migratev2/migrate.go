@@ -42,7 +42,7 @@ func Run(ctx context.Context, cli client.Client, in *ecv1beta1.Installation) err
 		}
 	}()
 
-	err = runMigration(ctx, cli)
+	err = runMigration(ctx, cli, in)
 	if err != nil {
 		if err := setV2MigrationFailed(ctx, cli, in, err); err != nil {
 			slog.Error("Failed to set v2 migration failed", "error", err)
@@ -60,17 +60,19 @@ func Run(ctx context.Context, cli client.Client, in *ecv1beta1.Installation) err
 	return nil
 }
 
-func runMigration(ctx context.Context, cli client.Client) error {
+func runMigration(ctx context.Context, cli client.Client, in *ecv1beta1.Installation) error {
 	// scale down the operator to ensure that it does not reconcile and revert our changes.
 	err := scaleDownOperator(ctx, cli)
 	if err != nil {
 		return fmt.Errorf("disable operator: %w", err)
 	}
+	recordMigrationStep(ctx, cli, in, "Scaled down the embedded cluster operator")
 
 	err = cleanupK0sCharts(ctx, cli)
 	if err != nil {
 		return fmt.Errorf("cleanup k0s: %w", err)
 	}
+	recordMigrationStep(ctx, cli, in, "Removed the k0s helm chart extensions")
 
 	return nil
 }
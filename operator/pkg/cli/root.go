@@ -3,16 +3,22 @@ package cli
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/replicatedhq/embedded-cluster/operator/controllers"
+	"github.com/replicatedhq/embedded-cluster/operator/pkg/metrics"
+	"github.com/replicatedhq/embedded-cluster/operator/pkg/webhooks"
 	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
 	"github.com/replicatedhq/embedded-cluster/pkg/versions"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"k8s.io/client-go/discovery"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 )
@@ -26,6 +32,9 @@ func RootCmd() *cobra.Command {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var installationHistoryLimit int
+	var webhookServiceName string
+	var webhookServiceNamespace string
 
 	cmd := &cobra.Command{
 		Use:          "manager",
@@ -43,12 +52,13 @@ func RootCmd() *cobra.Command {
 			return nil
 		},
 		Run: func(cmd *cobra.Command, args []string) {
+			webhookCertDir := filepath.Join(os.TempDir(), "k8s-webhook-server", "serving-certs")
 			mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 				Scheme: kubeutils.Scheme,
 				Metrics: metricsserver.Options{
 					BindAddress: metricsAddr,
 				},
-				WebhookServer:                 webhook.NewServer(webhook.Options{Port: 9443}),
+				WebhookServer:                 webhook.NewServer(webhook.Options{Port: 9443, CertDir: webhookCertDir}),
 				HealthProbeBindAddress:        probeAddr,
 				LeaderElection:                enableLeaderElection,
 				LeaderElectionID:              "3f2343ef.replicated.com",
@@ -64,11 +74,37 @@ func RootCmd() *cobra.Command {
 				Scheme:    mgr.GetScheme(),
 				Discovery: discovery.NewDiscoveryClientForConfigOrDie(ctrl.GetConfigOrDie()),
 				Recorder:  mgr.GetEventRecorderFor("installation-controller"),
+
+				InstallationHistoryLimit: installationHistoryLimit,
 			}).SetupWithManager(mgr); err != nil {
 				setupLog.Error(err, "unable to create controller", "controller", "Installation")
 				os.Exit(1)
 			}
 
+			// the collector reads objects the manager does not watch (jobs, plans) and the
+			// webhooks are set up before the manager cache is started so we use an uncached
+			// client instead of the manager's.
+			uncachedClient, err := client.New(mgr.GetConfig(), client.Options{Scheme: mgr.GetScheme()})
+			if err != nil {
+				setupLog.Error(err, "unable to create uncached client")
+				os.Exit(1)
+			}
+			ctrlmetrics.Registry.MustRegister(
+				metrics.NewClusterCollector(uncachedClient),
+				metrics.OpenEBSCleanupActions,
+			)
+
+			if webhookServiceName != "" {
+				if err := webhooks.Setup(cmd.Context(), mgr, uncachedClient, webhooks.Options{
+					ServiceName:      webhookServiceName,
+					ServiceNamespace: webhookServiceNamespace,
+					CertDir:          webhookCertDir,
+				}); err != nil {
+					setupLog.Error(err, "unable to set up webhooks")
+					os.Exit(1)
+				}
+			}
+
 			if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 				setupLog.Error(err, "unable to set up health check")
 				os.Exit(1)
@@ -95,6 +131,12 @@ func RootCmd() *cobra.Command {
 	cmd.Flags().BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	cmd.Flags().IntVar(&installationHistoryLimit, "installation-history-limit", controllers.DefaultInstallationHistoryLimit,
+		"Number of obsolete installation objects kept in the cluster. Older ones are only kept in the installation history.")
+	cmd.Flags().StringVar(&webhookServiceName, "webhook-service-name", "",
+		"Name of the service in front of the webhook server. Admission webhooks are disabled when empty.")
+	cmd.Flags().StringVar(&webhookServiceNamespace, "webhook-service-namespace", runtimeconfig.EmbeddedClusterNamespace,
+		"Namespace of the service in front of the webhook server.")
 
 	return cmd
 }
@@ -2,6 +2,7 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
@@ -27,6 +28,7 @@ import (
 // It is called by KOTS admin console to upgrade the embedded cluster operator and installation.
 func UpgradeJobCmd() *cobra.Command {
 	var inFile, previousInVersion string
+	var rollbackOnFailure bool
 	var in *ecv1beta1.Installation
 
 	cmd := &cobra.Command{
@@ -75,14 +77,38 @@ func UpgradeJobCmd() *cobra.Command {
 			}
 			defer hcli.Close()
 
-			if upgradeErr := performUpgrade(cmd.Context(), kcli, hcli, in); upgradeErr != nil {
-				// if this is the last attempt, mark the installation as failed
-				if err := maybeMarkAsFailed(cmd.Context(), kcli, in, upgradeErr); err != nil {
+			upgradeErr := performUpgrade(cmd.Context(), kcli, hcli, in)
+			if errors.As(upgradeErr, &upgrade.ErrUpgradePaused{}) {
+				// exit cleanly, a new job resumes the upgrade.
+				if err := kubeutils.SetInstallationState(cmd.Context(), kcli, in, ecv1beta1.InstallationStatePaused, helpers.CleanErrorMessage(upgradeErr)); err != nil {
+					return fmt.Errorf("set installation state: %w", err)
+				}
+				slog.Info("Upgrade paused")
+				return nil
+			}
+			if errors.As(upgradeErr, &upgrade.ErrUpgradeCancelled{}) {
+				// the job exits cleanly once the upgrade is rolled back.
+				if err := markAsFailed(cmd.Context(), kcli, hcli, in, upgradeErr, rollbackOnFailure); err != nil {
+					return fmt.Errorf("mark installation as cancelled: %w", err)
+				}
+				slog.Info("Upgrade cancelled")
+				return nil
+			}
+			if upgradeErr != nil {
+				// if this is the last attempt, roll back and mark the installation as failed
+				// nothing was changed if the pre-upgrade checks failed so there is nothing to
+				// roll back.
+				rollback := rollbackOnFailure && !errors.As(upgradeErr, &upgrade.ErrPreflightFailed{})
+				if err := maybeMarkAsFailed(cmd.Context(), kcli, hcli, in, upgradeErr, rollback); err != nil {
 					slog.Error("Failed to mark installation as failed", "error", err)
 				}
 				return upgradeErr
 			}
 
+			if err := upgrade.DeleteSnapshot(cmd.Context(), kcli, in); err != nil {
+				slog.Error("Failed to delete upgrade snapshot", "error", err)
+			}
+
 			slog.Info("Upgrade completed successfully")
 
 			return nil
@@ -99,6 +125,7 @@ func UpgradeJobCmd() *cobra.Command {
 	if err != nil {
 		panic(err)
 	}
+	cmd.Flags().BoolVar(&rollbackOnFailure, "rollback-on-failure", true, "Roll back the addons, extensions and installation spec if the last upgrade attempt fails")
 
 	return cmd
 }
@@ -110,17 +137,36 @@ func performUpgrade(ctx context.Context, kcli client.Client, hcli helm.Client, i
 		}
 	}()
 
+	// the pre-upgrade checks only make sense before we start changing the cluster. once the
+	// snapshot exists the upgrade has started and retries of the job must not be blocked by
+	// the disruption the upgrade itself causes (e.g. nodes restarting).
+	started, err := upgrade.SnapshotExists(ctx, kcli, in)
+	if err != nil {
+		return fmt.Errorf("failed to check if the upgrade has started: %w", err)
+	}
+	if !started {
+		if err := upgrade.RunPreflights(ctx, kcli, in); err != nil {
+			return err
+		}
+	}
+
 	if err := migratev2.Run(ctx, kcli, in); err != nil {
 		return fmt.Errorf("failed to run v2 migration: %w", err)
 	}
 
+	// the snapshot is taken only once, retries of the job keep the one taken by the first
+	// attempt.
+	if err := upgrade.SnapshotReleases(ctx, kcli, hcli, in); err != nil {
+		return fmt.Errorf("failed to snapshot releases: %w", err)
+	}
+
 	if err := upgrade.Upgrade(ctx, kcli, hcli, in); err != nil {
 		return err
 	}
 	return nil
 }
 
-func maybeMarkAsFailed(ctx context.Context, kcli client.Client, in *ecv1beta1.Installation, upgradeErr error) error {
+func maybeMarkAsFailed(ctx context.Context, kcli client.Client, hcli helm.Client, in *ecv1beta1.Installation, upgradeErr error, rollback bool) error {
 	lastAttempt, err := isLastAttempt(ctx, kcli)
 	if err != nil {
 		return fmt.Errorf("check if last attempt: %w", err)
@@ -128,6 +174,19 @@ func maybeMarkAsFailed(ctx context.Context, kcli client.Client, in *ecv1beta1.In
 	if !lastAttempt {
 		return nil
 	}
+	return markAsFailed(ctx, kcli, hcli, in, upgradeErr, rollback)
+}
+
+// markAsFailed optionally rolls back the upgrade and marks the installation as failed.
+func markAsFailed(ctx context.Context, kcli client.Client, hcli helm.Client, in *ecv1beta1.Installation, upgradeErr error, rollback bool) error {
+	if rollback {
+		result, err := upgrade.Rollback(ctx, kcli, hcli, in)
+		if err != nil {
+			slog.Error("Failed to roll back upgrade", "error", err)
+		} else {
+			slog.Info("Upgrade rolled back", "rolledBack", result.RolledBack, "notRolledBack", result.NotRolledBack)
+		}
+	}
 	if err := kubeutils.SetInstallationState(ctx, kcli, in, ecv1beta1.InstallationStateFailed, helpers.CleanErrorMessage(upgradeErr)); err != nil {
 		return fmt.Errorf("set installation state: %w", err)
 	}
@@ -0,0 +1,342 @@
+// Package hostpreflights periodically runs a subset of the host preflights on the cluster
+// nodes and reports their results in the Installation object.
+package hostpreflights
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/operator/pkg/util"
+	"github.com/replicatedhq/embedded-cluster/pkg/preflights"
+	"github.com/replicatedhq/embedded-cluster/pkg/preflights/types"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// DefaultInterval is the default time between two host preflight runs on a node.
+const DefaultInterval = 6 * time.Hour
+
+const (
+	// SpecConfigMapName is the name of the config map holding the host preflight specs run on
+	// the nodes, one for controllers and one for workers.
+	SpecConfigMapName = "embedded-cluster-host-preflight-monitor"
+	// ResultsLabel is set on the config maps holding the results of the last run on each node.
+	// Its value is the node name.
+	ResultsLabel = "embedded-cluster/host-preflight-monitor"
+
+	jobPrefix           = "host-preflight-monitor-"
+	resultsSuffix       = "-host-preflight-monitor"
+	resultsKey          = "results.json"
+	updateTimestampKey  = "update-timestamp"
+	controllerSpecKey   = "controller.yaml"
+	workerSpecKey       = "worker.yaml"
+	ecNamespace         = "embedded-cluster"
+	controlPlaneLabel   = "node-role.kubernetes.io/control-plane"
+	conditionReasonPass = "Passed"
+	conditionReasonWarn = "Warning"
+	conditionReasonFail = "Failed"
+)
+
+// monitorJob is the job we create to run the host preflights on a node. The spec is copied to
+// the data directory and run with the preflight binary shipped with embedded cluster, in the
+// host root, as host collectors expect. The results are then stored in a config map. During
+// a reconcile cycle we will populate the name, node name, volumes and env variables.
+var monitorJob = &batchv1.Job{
+	ObjectMeta: metav1.ObjectMeta{
+		Namespace: ecNamespace,
+	},
+	Spec: batchv1.JobSpec{
+		BackoffLimit: ptr.To[int32](2),
+		Template: corev1.PodTemplateSpec{
+			Spec: corev1.PodSpec{
+				ServiceAccountName: "embedded-cluster-operator",
+				HostPID:            true,
+				RestartPolicy:      corev1.RestartPolicyNever,
+				Tolerations: []corev1.Toleration{
+					{Operator: corev1.TolerationOpExists},
+				},
+				Volumes: []corev1.Volume{
+					{
+						Name: "host",
+						VolumeSource: corev1.VolumeSource{
+							HostPath: &corev1.HostPathVolumeSource{
+								Path: "/",
+								Type: ptr.To[corev1.HostPathType]("Directory"),
+							},
+						},
+					},
+					{
+						Name: "spec",
+						VolumeSource: corev1.VolumeSource{
+							ConfigMap: &corev1.ConfigMapVolumeSource{
+								LocalObjectReference: corev1.LocalObjectReference{Name: SpecConfigMapName},
+							},
+						},
+					},
+				},
+				Containers: []corev1.Container{
+					{
+						Name:  "host-preflight-monitor",
+						Image: "busybox:latest",
+						SecurityContext: &corev1.SecurityContext{
+							Privileged: ptr.To(true),
+						},
+						Command: []string{
+							"/bin/sh",
+							"-e",
+							"-c",
+							"cp /spec/host-preflight.yaml /host${DATA_DIR}/support/host-preflight-monitor.yaml && " +
+								"chroot /host /bin/sh -c 'cd ${DATA_DIR}/support && " +
+								"PATH=$PATH:${DATA_DIR}/bin ${DATA_DIR}/bin/kubectl-preflight --interactive=false --format=json " +
+								"host-preflight-monitor.yaml > host-preflight-monitor-results.json; " +
+								"rc=$?; rm -f preflightbundle-*.tar.gz; [ $rc -ne 1 ]' && " +
+								"/host${DATA_DIR}/bin/kubectl create configmap ${HSPF_CM_NAME} " +
+								"--from-file=results.json=/host${DATA_DIR}/support/host-preflight-monitor-results.json " +
+								"-n embedded-cluster --dry-run=client -oyaml | " +
+								"/host${DATA_DIR}/bin/kubectl label -f - embedded-cluster/host-preflight-monitor=${EC_NODE_NAME} --local -o yaml | " +
+								"/host${DATA_DIR}/bin/kubectl apply -f - && " +
+								"/host${DATA_DIR}/bin/kubectl annotate configmap ${HSPF_CM_NAME} \"update-timestamp=$(date -u +'%Y-%m-%dT%H:%M:%SZ')\" --overwrite",
+						},
+						VolumeMounts: []corev1.VolumeMount{
+							{
+								Name:      "host",
+								MountPath: "/host",
+							},
+							{
+								Name:      "spec",
+								MountPath: "/spec",
+								ReadOnly:  true,
+							},
+						},
+					},
+				},
+			},
+		},
+	},
+}
+
+// Interval returns the time between two host preflight runs on a node. Returns zero if the
+// monitoring is disabled.
+func Interval(in *ecv1beta1.Installation) time.Duration {
+	mon := in.Spec.HostPreflightMonitoring
+	if mon == nil || !mon.Enabled {
+		return 0
+	}
+	if mon.Interval == nil || mon.Interval.Duration <= 0 {
+		return DefaultInterval
+	}
+	return mon.Interval.Duration
+}
+
+// ConditionType returns the type of the Installation condition holding the result of the last
+// host preflight run on the node.
+func ConditionType(nodeName string) string {
+	return ecv1beta1.HostPreflightsConditionTypePrefix + nodeName
+}
+
+// Reconcile runs the host preflights on the nodes whose last results are older than the
+// monitoring interval and sets the results of the last run on each node as conditions in the
+// Installation status. The Installation is not updated remotely but only in its memory
+// representation, the caller must save it.
+func Reconcile(ctx context.Context, cli client.Client, recorder record.EventRecorder, in *ecv1beta1.Installation) error {
+	log := ctrl.LoggerFrom(ctx)
+
+	interval := Interval(in)
+	if interval == 0 {
+		removeConditions(in, nil)
+		return nil
+	}
+
+	var nodes corev1.NodeList
+	if err := cli.List(ctx, &nodes); err != nil {
+		return fmt.Errorf("list nodes: %w", err)
+	}
+
+	if err := ensureSpecConfigMap(ctx, cli, in); err != nil {
+		return fmt.Errorf("ensure host preflight spec: %w", err)
+	}
+
+	seen := map[string]bool{}
+	for _, node := range nodes.Items {
+		seen[node.Name] = true
+
+		var cm corev1.ConfigMap
+		nsn := client.ObjectKey{Namespace: ecNamespace, Name: resultsConfigMapName(node.Name)}
+		if err := cli.Get(ctx, nsn, &cm); err != nil && !k8serrors.IsNotFound(err) {
+			return fmt.Errorf("get host preflight results for node %s: %w", node.Name, err)
+		} else if err == nil {
+			if err := setNodeCondition(recorder, in, node.Name, cm); err != nil {
+				log.Error(err, "Failed to read host preflight results", "node", node.Name)
+			}
+			if !isStale(cm, interval, time.Now()) {
+				continue
+			}
+		}
+
+		job := constructJob(node, interval)
+		if err := cli.Create(ctx, job); err != nil {
+			if !k8serrors.IsAlreadyExists(err) {
+				return fmt.Errorf("create host preflight job for node %s: %w", node.Name, err)
+			}
+			continue
+		}
+		log.Info("Host preflight monitor job created", "node", node.Name)
+	}
+
+	removeConditions(in, seen)
+	return nil
+}
+
+// ensureSpecConfigMap renders the host preflight specs for controllers and workers and stores
+// them in the config map mounted by the jobs.
+func ensureSpecConfigMap(ctx context.Context, cli client.Client, in *ecv1beta1.Installation) error {
+	data := map[string]string{}
+	for key, isController := range map[string]bool{controllerSpecKey: true, workerSpecKey: false} {
+		spec, err := preflights.GetHostPreflightMonitorSpec(ctx, preflights.MonitorOptions{
+			DataDir:      runtimeconfig.EmbeddedClusterHomeDirectory(),
+			K0sDataDir:   runtimeconfig.EmbeddedClusterK0sSubDir(),
+			Checks:       in.Spec.HostPreflightMonitoring.Checks,
+			IsController: isController,
+		})
+		if err != nil {
+			return fmt.Errorf("get host preflight spec: %w", err)
+		}
+		raw, err := preflights.SerializeSpec(spec)
+		if err != nil {
+			return fmt.Errorf("serialize host preflight spec: %w", err)
+		}
+		data[key] = string(raw)
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: SpecConfigMapName, Namespace: ecNamespace},
+	}
+	_, err := controllerutil.CreateOrUpdate(ctx, cli, cm, func() error {
+		cm.Data = data
+		return nil
+	})
+	return err
+}
+
+func constructJob(node corev1.Node, interval time.Duration) *batchv1.Job {
+	labels := map[string]string{
+		"embedded-cluster/node-name": node.Name,
+	}
+
+	job := monitorJob.DeepCopy()
+	job.Name = util.NameWithLengthLimit(jobPrefix, node.Name)
+	job.Labels, job.Spec.Template.Labels = labels, labels
+	// keeping the job around for a while prevents failing runs from being retried on every
+	// reconcile.
+	job.Spec.TTLSecondsAfterFinished = ptr.To(int32(interval.Seconds() / 2))
+
+	specKey := workerSpecKey
+	if _, ok := node.Labels[controlPlaneLabel]; ok {
+		specKey = controllerSpecKey
+	}
+	job.Spec.Template.Spec.Volumes[1].ConfigMap.Items = []corev1.KeyToPath{
+		{Key: specKey, Path: "host-preflight.yaml"},
+	}
+
+	job.Spec.Template.Spec.NodeName = node.Name
+	job.Spec.Template.Spec.Containers[0].Env = append(
+		job.Spec.Template.Spec.Containers[0].Env,
+		corev1.EnvVar{Name: "DATA_DIR", Value: runtimeconfig.EmbeddedClusterHomeDirectory()},
+		corev1.EnvVar{Name: "EC_NODE_NAME", Value: node.Name},
+		corev1.EnvVar{Name: "HSPF_CM_NAME", Value: resultsConfigMapName(node.Name)},
+	)
+
+	// overrides the job image if the environment says so.
+	if img := os.Getenv("EMBEDDEDCLUSTER_UTILS_IMAGE"); img != "" {
+		job.Spec.Template.Spec.Containers[0].Image = img
+	}
+
+	return job
+}
+
+func resultsConfigMapName(nodeName string) string {
+	return util.NameWithLengthLimit(nodeName, resultsSuffix)
+}
+
+// isStale returns true if the results in the config map are older than the interval.
+func isStale(cm corev1.ConfigMap, interval time.Duration, now time.Time) bool {
+	updated, err := time.Parse(time.RFC3339, cm.Annotations[updateTimestampKey])
+	if err != nil {
+		return true
+	}
+	return now.Sub(updated) >= interval
+}
+
+// setNodeCondition sets the condition for the node from the results stored in the config map.
+// A warning event is recorded every time the node starts warning or failing.
+func setNodeCondition(recorder record.EventRecorder, in *ecv1beta1.Installation, nodeName string, cm corev1.ConfigMap) error {
+	output, err := types.OutputFromReader(bytes.NewBufferString(cm.Data[resultsKey]))
+	if err != nil {
+		return fmt.Errorf("parse results: %w", err)
+	}
+
+	cond := metav1.Condition{
+		Type:               ConditionType(nodeName),
+		Status:             metav1.ConditionTrue,
+		Reason:             conditionReasonPass,
+		Message:            "All host preflights passed",
+		ObservedGeneration: in.Generation,
+	}
+	switch {
+	case output.HasFail():
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = conditionReasonFail
+		cond.Message = summarize(output.Fail, "failed")
+	case output.HasWarn():
+		cond.Status = metav1.ConditionFalse
+		cond.Reason = conditionReasonWarn
+		cond.Message = summarize(output.Warn, "warned")
+	}
+
+	previous := meta.FindStatusCondition(in.Status.Conditions, cond.Type)
+	meta.SetStatusCondition(&in.Status.Conditions, cond)
+	if cond.Reason == conditionReasonPass || (previous != nil && previous.Reason == cond.Reason && previous.Message == cond.Message) {
+		return nil
+	}
+	recorder.Eventf(in, corev1.EventTypeWarning, "HostPreflights"+cond.Reason, "Node %s: %s", nodeName, cond.Message)
+	return nil
+}
+
+func summarize(records []types.Record, verb string) string {
+	s := "preflights"
+	if len(records) == 1 {
+		s = "preflight"
+	}
+	msgs := []string{}
+	for _, r := range records {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", r.Title, r.Message))
+	}
+	return fmt.Sprintf("%d host %s %s. %s", len(records), s, verb, strings.Join(msgs, "; "))
+}
+
+// removeConditions removes the host preflight conditions of the nodes not in the provided set.
+func removeConditions(in *ecv1beta1.Installation, nodes map[string]bool) {
+	var conditions []metav1.Condition
+	for _, cond := range in.Status.Conditions {
+		node, ok := strings.CutPrefix(cond.Type, ecv1beta1.HostPreflightsConditionTypePrefix)
+		if ok && !nodes[node] {
+			continue
+		}
+		conditions = append(conditions, cond)
+	}
+	in.Status.Conditions = conditions
+}
@@ -0,0 +1,146 @@
+package hostpreflights
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestReconcile(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	in := &ecv1beta1.Installation{
+		ObjectMeta: metav1.ObjectMeta{Name: "20241002205018"},
+		Spec: ecv1beta1.InstallationSpec{
+			HostPreflightMonitoring: &ecv1beta1.HostPreflightMonitoring{Enabled: true},
+		},
+		Status: ecv1beta1.InstallationStatus{
+			Conditions: []metav1.Condition{
+				{Type: ConditionType("removed-node"), Status: metav1.ConditionTrue, Reason: "Passed"},
+				{Type: "openebs-openebs", Status: metav1.ConditionTrue, Reason: "Upgraded"},
+			},
+		},
+	}
+	controller := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "controller",
+			Labels: map[string]string{"node-role.kubernetes.io/control-plane": "true"},
+		},
+	}
+	worker := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "worker"}}
+	// the controller ran the host preflights recently, the worker a long time ago.
+	controllerResults := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "controller-host-preflight-monitor",
+			Namespace:   "embedded-cluster",
+			Annotations: map[string]string{"update-timestamp": now.Add(-time.Hour).Format(time.RFC3339)},
+		},
+		Data: map[string]string{
+			"results.json": `{"warn":[{"title":"Embedded Cluster Disk Space","message":"The filesystem is more than 80% full"}],"pass":[],"fail":[]}`,
+		},
+	}
+	workerResults := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "worker-host-preflight-monitor",
+			Namespace:   "embedded-cluster",
+			Annotations: map[string]string{"update-timestamp": now.Add(-7 * time.Hour).Format(time.RFC3339)},
+		},
+		Data: map[string]string{
+			"results.json": `{"warn":[],"pass":[{"title":"System Clock","message":"NTP is enabled"}],"fail":[]}`,
+		},
+	}
+	cli := fake.NewClientBuilder().WithScheme(kubeutils.Scheme).
+		WithObjects(controller, worker, controllerResults, workerResults).
+		Build()
+	recorder := record.NewFakeRecorder(10)
+
+	require.NoError(t, Reconcile(ctx, cli, recorder, in))
+
+	cond := meta.FindStatusCondition(in.Status.Conditions, ConditionType("controller"))
+	require.NotNil(t, cond)
+	assert.Equal(t, metav1.ConditionFalse, cond.Status)
+	assert.Equal(t, "Warning", cond.Reason)
+	assert.Contains(t, cond.Message, "Embedded Cluster Disk Space")
+
+	cond = meta.FindStatusCondition(in.Status.Conditions, ConditionType("worker"))
+	require.NotNil(t, cond)
+	assert.Equal(t, metav1.ConditionTrue, cond.Status)
+
+	assert.Nil(t, meta.FindStatusCondition(in.Status.Conditions, ConditionType("removed-node")))
+	assert.NotNil(t, meta.FindStatusCondition(in.Status.Conditions, "openebs-openebs"))
+
+	require.Len(t, recorder.Events, 1)
+	assert.Contains(t, <-recorder.Events, "HostPreflightsWarning")
+
+	var spec corev1.ConfigMap
+	require.NoError(t, cli.Get(ctx, client.ObjectKey{Namespace: "embedded-cluster", Name: SpecConfigMapName}, &spec))
+	assert.Contains(t, spec.Data, "controller.yaml")
+	assert.Contains(t, spec.Data, "worker.yaml")
+
+	// only the worker results are stale.
+	var job batchv1.Job
+	err := cli.Get(ctx, client.ObjectKey{Namespace: "embedded-cluster", Name: "host-preflight-monitor-controller"}, &job)
+	assert.True(t, k8serrors.IsNotFound(err))
+	require.NoError(t, cli.Get(ctx, client.ObjectKey{Namespace: "embedded-cluster", Name: "host-preflight-monitor-worker"}, &job))
+	assert.Equal(t, "worker", job.Spec.Template.Spec.NodeName)
+	assert.Equal(t, "worker.yaml", job.Spec.Template.Spec.Volumes[1].ConfigMap.Items[0].Key)
+	assert.Equal(t, int32(3*60*60), *job.Spec.TTLSecondsAfterFinished)
+
+	// reconciling again does not record the same warning twice.
+	require.NoError(t, Reconcile(ctx, cli, recorder, in))
+	assert.Len(t, recorder.Events, 0)
+
+	// disabling the monitoring removes the conditions.
+	in.Spec.HostPreflightMonitoring.Enabled = false
+	require.NoError(t, Reconcile(ctx, cli, recorder, in))
+	require.Len(t, in.Status.Conditions, 1)
+	assert.Equal(t, "openebs-openebs", in.Status.Conditions[0].Type)
+}
+
+func TestInterval(t *testing.T) {
+	tests := []struct {
+		name string
+		mon  *ecv1beta1.HostPreflightMonitoring
+		want time.Duration
+	}{
+		{
+			name: "not configured",
+			want: 0,
+		},
+		{
+			name: "disabled",
+			mon:  &ecv1beta1.HostPreflightMonitoring{Interval: &metav1.Duration{Duration: time.Hour}},
+			want: 0,
+		},
+		{
+			name: "default interval",
+			mon:  &ecv1beta1.HostPreflightMonitoring{Enabled: true},
+			want: DefaultInterval,
+		},
+		{
+			name: "custom interval",
+			mon:  &ecv1beta1.HostPreflightMonitoring{Enabled: true, Interval: &metav1.Duration{Duration: time.Hour}},
+			want: time.Hour,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := &ecv1beta1.Installation{Spec: ecv1beta1.InstallationSpec{HostPreflightMonitoring: tt.mon}}
+			assert.Equal(t, tt.want, Interval(in))
+		})
+	}
+}
@@ -0,0 +1,365 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	apv1b2 "github.com/k0sproject/k0s/pkg/apis/autopilot/v1beta2"
+	"github.com/prometheus/client_golang/prometheus"
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/operator/pkg/artifacts"
+	"github.com/replicatedhq/embedded-cluster/operator/pkg/autopilot"
+	"github.com/replicatedhq/embedded-cluster/operator/pkg/upgrade"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	metricsNamespace = "embedded_cluster"
+
+	// collectTimeout is the maximum amount of time we spend reading the cluster state on
+	// each scrape.
+	collectTimeout = 10 * time.Second
+)
+
+var (
+	// OpenEBSCleanupActions counts the actions taken when cleaning up stateful pods whose
+	// volumes were placed in nodes no longer part of the cluster.
+	OpenEBSCleanupActions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "openebs_cleanup_actions_total",
+			Help:      "Number of objects deleted while cleaning up stateful pods stuck on removed nodes, by kind.",
+		},
+		[]string{"kind"},
+	)
+)
+
+var (
+	installationStateDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "installation", "state"),
+		"State of the latest installation. Set to 1 for the current state and 0 for all others.",
+		[]string{"installation", "version", "state"}, nil,
+	)
+	installationConditionDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "installation", "condition"),
+		"Status of the latest installation conditions (addons and extensions). Set to 1 for the current status.",
+		[]string{"installation", "condition", "status"}, nil,
+	)
+	upgradeStartDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "upgrade", "start_timestamp_seconds"),
+		"Time the upgrade job for an installation started, in seconds since epoch.",
+		[]string{"installation"}, nil,
+	)
+	upgradeCompletionDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "upgrade", "completion_timestamp_seconds"),
+		"Time the upgrade job for an installation completed, in seconds since epoch.",
+		[]string{"installation"}, nil,
+	)
+	upgradeDurationDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "upgrade", "duration_seconds"),
+		"Duration of the upgrade job for an installation. For running upgrades this is the time elapsed so far.",
+		[]string{"installation", "status"}, nil,
+	)
+	autopilotPlanStateDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "autopilot", "plan_state"),
+		"State of the autopilot plan. Set to 1 for the current state.",
+		[]string{"installation", "state"}, nil,
+	)
+	autopilotPlanSucceededDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "autopilot", "plan_succeeded"),
+		"Whether the autopilot plan has succeeded (1) or not (0).",
+		[]string{"installation"}, nil,
+	)
+	autopilotPlanFailedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "autopilot", "plan_failed"),
+		"Whether the autopilot plan has failed (1) or not (0).",
+		[]string{"installation"}, nil,
+	)
+	nodesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "", "nodes"),
+		"Number of nodes in the cluster by role.",
+		[]string{"role"}, nil,
+	)
+	artifactsJobsRunningDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "artifacts", "jobs_running"),
+		"Number of artifacts jobs running by installation.",
+		[]string{"installation"}, nil,
+	)
+	artifactsJobsFinishedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metricsNamespace, "artifacts", "jobs_finished_total"),
+		"Number of artifacts jobs seen finishing by installation and status, since the operator started.",
+		[]string{"installation", "status"}, nil,
+	)
+)
+
+// installationStates is the list of all the states an installation can be in.
+var installationStates = []string{
+	ecv1beta1.InstallationStateWaiting,
+	ecv1beta1.InstallationStateCopyingArtifacts,
+	ecv1beta1.InstallationStateEnqueued,
+	ecv1beta1.InstallationStateInstalling,
+	ecv1beta1.InstallationStateInstalled,
+	ecv1beta1.InstallationStateKubernetesInstalled,
+	ecv1beta1.InstallationStateAddonsInstalling,
+	ecv1beta1.InstallationStateAddonsInstalled,
+	ecv1beta1.InstallationStateHelmChartUpdateFailure,
+	ecv1beta1.InstallationStateObsolete,
+	ecv1beta1.InstallationStateFailed,
+	ecv1beta1.InstallationStateUnknown,
+	ecv1beta1.InstallationStatePendingChartCreation,
+	ecv1beta1.InstallationStatePaused,
+}
+
+// ClusterCollector is a prometheus collector exporting the state of the cluster as seen by
+// the operator. The state is read from the cluster on every scrape.
+type ClusterCollector struct {
+	cli client.Client
+	now func() time.Time
+
+	mu sync.Mutex
+	// artifactsJobsFinished holds the uid of the finished artifacts jobs still in the cluster
+	// that were already counted, jobs are only counted once.
+	artifactsJobsFinished map[types.UID]bool
+	// artifactsJobsFinishedTotal counts the finished artifacts jobs by installation and status.
+	artifactsJobsFinishedTotal map[artifactsJobKey]float64
+}
+
+type artifactsJobKey struct {
+	installation string
+	status       string
+}
+
+// NewClusterCollector returns a collector reading the cluster state using the provided
+// client. An uncached client is recommended as objects like jobs and plans are not watched
+// by the operator.
+func NewClusterCollector(cli client.Client) *ClusterCollector {
+	return &ClusterCollector{
+		cli:                        cli,
+		now:                        time.Now,
+		artifactsJobsFinished:      map[types.UID]bool{},
+		artifactsJobsFinishedTotal: map[artifactsJobKey]float64{},
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *ClusterCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- installationStateDesc
+	ch <- installationConditionDesc
+	ch <- upgradeStartDesc
+	ch <- upgradeCompletionDesc
+	ch <- upgradeDurationDesc
+	ch <- autopilotPlanStateDesc
+	ch <- autopilotPlanSucceededDesc
+	ch <- autopilotPlanFailedDesc
+	ch <- nodesDesc
+	ch <- artifactsJobsRunningDesc
+	ch <- artifactsJobsFinishedDesc
+}
+
+// Collect implements prometheus.Collector. Errors reading each one of the objects are
+// logged and the corresponding metrics are skipped.
+func (c *ClusterCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+
+	log := ctrl.Log.WithName("metrics")
+
+	if err := c.collectInstallation(ctx, ch); err != nil {
+		log.Error(err, "Failed to collect installation metrics")
+	}
+	if err := c.collectUpgradeJobs(ctx, ch); err != nil {
+		log.Error(err, "Failed to collect upgrade metrics")
+	}
+	if err := c.collectAutopilotPlan(ctx, ch); err != nil {
+		log.Error(err, "Failed to collect autopilot plan metrics")
+	}
+	if err := c.collectNodes(ctx, ch); err != nil {
+		log.Error(err, "Failed to collect node metrics")
+	}
+	if err := c.collectArtifactsJobs(ctx, ch); err != nil {
+		log.Error(err, "Failed to collect artifacts jobs metrics")
+	}
+}
+
+func (c *ClusterCollector) collectInstallation(ctx context.Context, ch chan<- prometheus.Metric) error {
+	in, err := kubeutils.GetLatestInstallation(ctx, c.cli)
+	if err != nil {
+		if errors.Is(err, kubeutils.ErrNoInstallations{}) {
+			return nil
+		}
+		return err
+	}
+
+	version := ""
+	if in.Spec.Config != nil {
+		version = in.Spec.Config.Version
+	}
+
+	for _, state := range installationStates {
+		value := 0.0
+		if in.Status.State == state {
+			value = 1
+		}
+		ch <- prometheus.MustNewConstMetric(installationStateDesc, prometheus.GaugeValue, value, in.Name, version, state)
+	}
+
+	for _, cond := range in.Status.Conditions {
+		ch <- prometheus.MustNewConstMetric(installationConditionDesc, prometheus.GaugeValue, 1, in.Name, cond.Type, string(cond.Status))
+	}
+
+	return nil
+}
+
+func (c *ClusterCollector) collectUpgradeJobs(ctx context.Context, ch chan<- prometheus.Metric) error {
+	var jobs batchv1.JobList
+	err := c.cli.List(
+		ctx, &jobs,
+		client.InNamespace(runtimeconfig.KotsadmNamespace),
+		client.MatchingLabels{"app.kubernetes.io/name": upgrade.JobNameLabel},
+	)
+	if err != nil {
+		return err
+	}
+
+	for _, job := range jobs.Items {
+		if job.Status.StartTime == nil {
+			continue
+		}
+		installation := strings.TrimPrefix(job.Name, upgrade.JobNamePrefix)
+		start := job.Status.StartTime.Time
+		ch <- prometheus.MustNewConstMetric(upgradeStartDesc, prometheus.GaugeValue, float64(start.Unix()), installation)
+
+		status, end := upgradeJobStatus(job)
+		if end != nil {
+			ch <- prometheus.MustNewConstMetric(upgradeCompletionDesc, prometheus.GaugeValue, float64(end.Unix()), installation)
+		} else {
+			end = &metav1.Time{Time: c.now()}
+		}
+		duration := end.Sub(start).Seconds()
+		ch <- prometheus.MustNewConstMetric(upgradeDurationDesc, prometheus.GaugeValue, duration, installation, status)
+	}
+
+	return nil
+}
+
+// upgradeJobStatus returns the status of the upgrade job and the time it has finished, if
+// it has.
+func upgradeJobStatus(job batchv1.Job) (string, *metav1.Time) {
+	for _, cond := range job.Status.Conditions {
+		if cond.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch cond.Type {
+		case batchv1.JobComplete:
+			if job.Status.CompletionTime != nil {
+				return "succeeded", job.Status.CompletionTime
+			}
+			return "succeeded", &cond.LastTransitionTime
+		case batchv1.JobFailed:
+			return "failed", &cond.LastTransitionTime
+		}
+	}
+	return "running", nil
+}
+
+func (c *ClusterCollector) collectAutopilotPlan(ctx context.Context, ch chan<- prometheus.Metric) error {
+	var plan apv1b2.Plan
+	if err := c.cli.Get(ctx, client.ObjectKey{Name: "autopilot"}, &plan); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	installation := plan.Annotations[artifacts.InstallationNameAnnotation]
+	ch <- prometheus.MustNewConstMetric(autopilotPlanStateDesc, prometheus.GaugeValue, 1, installation, string(plan.Status.State))
+	ch <- prometheus.MustNewConstMetric(autopilotPlanSucceededDesc, prometheus.GaugeValue, boolToFloat(autopilot.HasPlanSucceeded(plan)), installation)
+	ch <- prometheus.MustNewConstMetric(autopilotPlanFailedDesc, prometheus.GaugeValue, boolToFloat(autopilot.HasPlanFailed(plan)), installation)
+	return nil
+}
+
+func (c *ClusterCollector) collectNodes(ctx context.Context, ch chan<- prometheus.Metric) error {
+	var nodes corev1.NodeList
+	if err := c.cli.List(ctx, &nodes); err != nil {
+		return err
+	}
+
+	roles := map[string]int{"controller": 0, "worker": 0}
+	for _, node := range nodes.Items {
+		if _, ok := node.Labels["node-role.kubernetes.io/control-plane"]; ok {
+			roles["controller"]++
+			continue
+		}
+		roles["worker"]++
+	}
+	for role, count := range roles {
+		ch <- prometheus.MustNewConstMetric(nodesDesc, prometheus.GaugeValue, float64(count), role)
+	}
+	return nil
+}
+
+// collectArtifactsJobs reports the artifacts jobs running and counts the ones finishing. The
+// jobs are deleted once the upgrade completes, each finished job is counted the first time it
+// is seen.
+func (c *ClusterCollector) collectArtifactsJobs(ctx context.Context, ch chan<- prometheus.Metric) error {
+	var jobs batchv1.JobList
+	err := c.cli.List(
+		ctx, &jobs,
+		client.InNamespace(runtimeconfig.EmbeddedClusterNamespace),
+		client.MatchingLabels{"app.kubernetes.io/component": artifacts.UpgraderComponent},
+	)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	running := map[string]int{}
+	finished := map[types.UID]bool{}
+	for _, job := range jobs.Items {
+		installation := job.Annotations[artifacts.InstallationNameAnnotation]
+		status := ""
+		if job.Status.Succeeded > 0 {
+			status = "succeeded"
+		} else if artifacts.IsArtifactsJobFinished(&job) {
+			status = "failed"
+		}
+		if status == "" {
+			running[installation]++
+			continue
+		}
+		finished[job.UID] = true
+		if !c.artifactsJobsFinished[job.UID] {
+			c.artifactsJobsFinishedTotal[artifactsJobKey{installation, status}]++
+		}
+	}
+	// the uids of the jobs no longer in the cluster are dropped, they are never seen again.
+	c.artifactsJobsFinished = finished
+
+	for installation, count := range running {
+		ch <- prometheus.MustNewConstMetric(artifactsJobsRunningDesc, prometheus.GaugeValue, float64(count), installation)
+	}
+	for k, count := range c.artifactsJobsFinishedTotal {
+		ch <- prometheus.MustNewConstMetric(artifactsJobsFinishedDesc, prometheus.CounterValue, count, k.installation, k.status)
+	}
+	return nil
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
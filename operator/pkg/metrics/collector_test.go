@@ -0,0 +1,170 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	apv1b2 "github.com/k0sproject/k0s/pkg/apis/autopilot/v1beta2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/operator/pkg/artifacts"
+	"github.com/replicatedhq/embedded-cluster/operator/pkg/upgrade"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestClusterCollector(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	start := metav1.NewTime(now.Add(-10 * time.Minute))
+	end := metav1.NewTime(now.Add(-5 * time.Minute))
+
+	cli := fake.NewClientBuilder().
+		WithScheme(kubeutils.Scheme).
+		WithObjects(
+			&ecv1beta1.Installation{
+				ObjectMeta: metav1.ObjectMeta{Name: "20240101120000"},
+				Spec: ecv1beta1.InstallationSpec{
+					Config: &ecv1beta1.ConfigSpec{Version: "1.0.0"},
+				},
+				Status: ecv1beta1.InstallationStatus{
+					State: ecv1beta1.InstallationStateInstalled,
+					Conditions: []metav1.Condition{
+						{Type: "openebs-openebs", Status: metav1.ConditionTrue, Reason: "Installed"},
+					},
+				},
+			},
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{
+				Name:   "node1",
+				Labels: map[string]string{"node-role.kubernetes.io/control-plane": "true"},
+			}},
+			&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node2"}},
+			&batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      upgrade.JobNamePrefix + "20240101120000",
+					Namespace: runtimeconfig.KotsadmNamespace,
+					Labels:    map[string]string{"app.kubernetes.io/name": upgrade.JobNameLabel},
+				},
+				Status: batchv1.JobStatus{
+					StartTime:      &start,
+					CompletionTime: &end,
+					Conditions: []batchv1.JobCondition{
+						{Type: batchv1.JobComplete, Status: corev1.ConditionTrue},
+					},
+				},
+			},
+			&batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "copy-artifacts-node1",
+					Namespace:   runtimeconfig.EmbeddedClusterNamespace,
+					Labels:      map[string]string{"app.kubernetes.io/component": artifacts.UpgraderComponent},
+					Annotations: map[string]string{artifacts.InstallationNameAnnotation: "20240101120000"},
+				},
+				Status: batchv1.JobStatus{Succeeded: 1},
+			},
+			&batchv1.Job{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "copy-artifacts-node2",
+					Namespace:   runtimeconfig.EmbeddedClusterNamespace,
+					Labels:      map[string]string{"app.kubernetes.io/component": artifacts.UpgraderComponent},
+					Annotations: map[string]string{artifacts.InstallationNameAnnotation: "20240101120000"},
+				},
+			},
+			&apv1b2.Plan{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "autopilot",
+					Annotations: map[string]string{artifacts.InstallationNameAnnotation: "20240101120000"},
+				},
+				Status: apv1b2.PlanStatus{State: "Completed"},
+			},
+		).
+		Build()
+
+	collector := NewClusterCollector(cli)
+	collector.now = func() time.Time { return now }
+
+	expected := `
+# HELP embedded_cluster_nodes Number of nodes in the cluster by role.
+# TYPE embedded_cluster_nodes gauge
+embedded_cluster_nodes{role="controller"} 1
+embedded_cluster_nodes{role="worker"} 1
+# HELP embedded_cluster_installation_condition Status of the latest installation conditions (addons and extensions). Set to 1 for the current status.
+# TYPE embedded_cluster_installation_condition gauge
+embedded_cluster_installation_condition{condition="openebs-openebs",installation="20240101120000",status="True"} 1
+# HELP embedded_cluster_upgrade_duration_seconds Duration of the upgrade job for an installation. For running upgrades this is the time elapsed so far.
+# TYPE embedded_cluster_upgrade_duration_seconds gauge
+embedded_cluster_upgrade_duration_seconds{installation="20240101120000",status="succeeded"} 300
+# HELP embedded_cluster_autopilot_plan_succeeded Whether the autopilot plan has succeeded (1) or not (0).
+# TYPE embedded_cluster_autopilot_plan_succeeded gauge
+embedded_cluster_autopilot_plan_succeeded{installation="20240101120000"} 1
+# HELP embedded_cluster_artifacts_jobs_running Number of artifacts jobs running by installation.
+# TYPE embedded_cluster_artifacts_jobs_running gauge
+embedded_cluster_artifacts_jobs_running{installation="20240101120000"} 1
+# HELP embedded_cluster_artifacts_jobs_finished_total Number of artifacts jobs seen finishing by installation and status, since the operator started.
+# TYPE embedded_cluster_artifacts_jobs_finished_total counter
+embedded_cluster_artifacts_jobs_finished_total{installation="20240101120000",status="succeeded"} 1
+`
+	err := testutil.CollectAndCompare(
+		collector, strings.NewReader(expected),
+		"embedded_cluster_nodes",
+		"embedded_cluster_installation_condition",
+		"embedded_cluster_upgrade_duration_seconds",
+		"embedded_cluster_autopilot_plan_succeeded",
+		"embedded_cluster_artifacts_jobs_running",
+		"embedded_cluster_artifacts_jobs_finished_total",
+	)
+	require.NoError(t, err)
+
+	// one series per known installation state.
+	require.Equal(t, len(installationStates), testutil.CollectAndCount(collector, "embedded_cluster_installation_state"))
+}
+
+func TestClusterCollector_artifactsJobsFinished(t *testing.T) {
+	ctx := context.Background()
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "copy-artifacts-node1",
+			Namespace:   runtimeconfig.EmbeddedClusterNamespace,
+			UID:         "uid-1",
+			Labels:      map[string]string{"app.kubernetes.io/component": artifacts.UpgraderComponent},
+			Annotations: map[string]string{artifacts.InstallationNameAnnotation: "20240101120000"},
+		},
+		Status: batchv1.JobStatus{Succeeded: 1},
+	}
+	cli := fake.NewClientBuilder().WithScheme(kubeutils.Scheme).WithObjects(job).Build()
+	collector := NewClusterCollector(cli)
+
+	expected := func(count int) string {
+		return fmt.Sprintf(`
+# HELP embedded_cluster_artifacts_jobs_finished_total Number of artifacts jobs seen finishing by installation and status, since the operator started.
+# TYPE embedded_cluster_artifacts_jobs_finished_total counter
+embedded_cluster_artifacts_jobs_finished_total{installation="20240101120000",status="succeeded"} %d
+`, count)
+	}
+
+	// the job is only counted once, no matter how many times it is scraped.
+	for i := 0; i < 2; i++ {
+		err := testutil.CollectAndCompare(collector, strings.NewReader(expected(1)), "embedded_cluster_artifacts_jobs_finished_total")
+		require.NoError(t, err)
+	}
+
+	// the counter does not decrease when the job is deleted.
+	require.NoError(t, cli.Delete(ctx, job))
+	err := testutil.CollectAndCompare(collector, strings.NewReader(expected(1)), "embedded_cluster_artifacts_jobs_finished_total")
+	require.NoError(t, err)
+
+	// a new job for the next upgrade is counted as well.
+	job = job.DeepCopy()
+	job.ResourceVersion = ""
+	job.UID = "uid-2"
+	require.NoError(t, cli.Create(ctx, job))
+	err = testutil.CollectAndCompare(collector, strings.NewReader(expected(2)), "embedded_cluster_artifacts_jobs_finished_total")
+	require.NoError(t, err)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/replicatedhq/embedded-cluster/operator/pkg/metrics"
 	corev1 "k8s.io/api/core/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -16,7 +17,8 @@ const (
 )
 
 // CleanupStatefulPods checks if any pods with pvcs in a pending state were running on nodes that
-// no longer exist and deletes them.
+// no longer exist and deletes them. Only OpenEBS local volumes, lost with their node, are
+// deleted. Replicated volumes, such as Longhorn ones, are left to be attached to another node.
 func CleanupStatefulPods(ctx context.Context, cli client.Client) error {
 	stuckPVCs, err := findStuckPVCs(ctx, cli)
 	if err != nil {
@@ -101,6 +103,7 @@ func deletePendingPodsWithPVCsInNamespace(ctx context.Context, cli client.Client
 			return fmt.Errorf("delete pod %s: %w", pod.Name, err)
 		} else {
 			log.Info("Deleted stateful pod", "name", pod.Name, "namespace", pod.Namespace, "node", pod.Spec.NodeName)
+			metrics.OpenEBSCleanupActions.WithLabelValues("pod").Inc()
 		}
 	}
 
@@ -123,6 +126,7 @@ func deletePVC(ctx context.Context, cli client.Client, pvc corev1.PersistentVolu
 		}
 
 		log.Info("Deleted pv", "name", pv.Name)
+		metrics.OpenEBSCleanupActions.WithLabelValues("pv").Inc()
 	}
 
 	err = cli.Delete(ctx, &pvc)
@@ -131,6 +135,7 @@ func deletePVC(ctx context.Context, cli client.Client, pvc corev1.PersistentVolu
 	}
 
 	log.Info("Deleted pvc", "name", pvc.Name, "namespace", pvc.Namespace)
+	metrics.OpenEBSCleanupActions.WithLabelValues("pvc").Inc()
 
 	return nil
 }
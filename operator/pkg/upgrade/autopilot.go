@@ -11,48 +11,16 @@ import (
 	"github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
 	ectypes "github.com/replicatedhq/embedded-cluster/kinds/types"
 	"github.com/replicatedhq/embedded-cluster/operator/pkg/artifacts"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
 	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-// determineUpgradeTargets makes sure that we are listing all the nodes in the autopilot plan.
-func determineUpgradeTargets(ctx context.Context, cli client.Client) (apv1b2.PlanCommandTargets, error) {
-	var nodes corev1.NodeList
-	if err := cli.List(ctx, &nodes); err != nil {
-		return apv1b2.PlanCommandTargets{}, fmt.Errorf("failed to list nodes: %w", err)
-	}
-	controllers := []string{}
-	workers := []string{}
-	for _, node := range nodes.Items {
-		if _, ok := node.Labels["node-role.kubernetes.io/control-plane"]; ok {
-			controllers = append(controllers, node.Name)
-			continue
-		}
-		workers = append(workers, node.Name)
-	}
-	return apv1b2.PlanCommandTargets{
-		Controllers: apv1b2.PlanCommandTarget{
-			Discovery: apv1b2.PlanCommandTargetDiscovery{
-				Static: &apv1b2.PlanCommandTargetDiscoveryStatic{Nodes: controllers},
-			},
-		},
-		Workers: apv1b2.PlanCommandTarget{
-			Discovery: apv1b2.PlanCommandTargetDiscovery{
-				Static: &apv1b2.PlanCommandTargetDiscoveryStatic{Nodes: workers},
-			},
-		},
-	}, nil
-}
-
-// startAutopilotUpgrade creates an autopilot plan to upgrade to version specified in spec.config.version.
-func startAutopilotUpgrade(ctx context.Context, cli client.Client, in *v1beta1.Installation, meta *ectypes.ReleaseMetadata) error {
-	targets, err := determineUpgradeTargets(ctx, cli)
-	if err != nil {
-		return fmt.Errorf("failed to determine upgrade targets: %w", err)
-	}
-
+// startAutopilotUpgrade creates an autopilot plan to upgrade the target nodes to version
+// specified in spec.config.version.
+func startAutopilotUpgrade(ctx context.Context, cli client.Client, in *v1beta1.Installation, meta *ectypes.ReleaseMetadata, targets apv1b2.PlanCommandTargets) error {
 	var k0surl string
 	if in.Spec.AirGap {
 		// if we are running in an airgap environment all assets are already present in the
@@ -100,5 +68,9 @@ func startAutopilotUpgrade(ctx context.Context, cli client.Client, in *v1beta1.I
 		return fmt.Errorf("failed to create upgrade plan: %w", err)
 	}
 	in.Status.SetState(v1beta1.InstallationStateEnqueued, "", nil)
+	kubeutils.RecordInstallationEvent(
+		ctx, cli, in, corev1.EventTypeNormal, v1beta1.InstallationStateEnqueued,
+		fmt.Sprintf("Autopilot plan %s enqueued to upgrade Kubernetes to %s", plan.Spec.ID, meta.Versions["Kubernetes"]),
+	)
 	return nil
 }
@@ -0,0 +1,181 @@
+package upgrade
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	batchv1 "k8s.io/api/batch/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ErrUpgradePaused is returned by Upgrade when a pause was requested. The job exits and a new
+// one resumes the upgrade after the last completed step.
+type ErrUpgradePaused struct {
+	Step string
+}
+
+func (e ErrUpgradePaused) Error() string {
+	return fmt.Sprintf("upgrade paused after %s", e.Step)
+}
+
+// ErrUpgradeCancelled is returned by Upgrade when the upgrade was cancelled.
+type ErrUpgradeCancelled struct {
+	Step string
+}
+
+func (e ErrUpgradeCancelled) Error() string {
+	return fmt.Sprintf("upgrade cancelled after %s", e.Step)
+}
+
+// checkpoint is called once an upgrade step is completed. It returns ErrUpgradePaused or
+// ErrUpgradeCancelled if the upgrade was paused or cancelled through the installation upgrade
+// control annotation. The steps completed are skipped when the upgrade resumes as their status
+// is recorded in the installation.
+func checkpoint(ctx context.Context, cli client.Client, in *ecv1beta1.Installation, step string) error {
+	// the installation is read from the cluster as the object held by the job contains
+	// changes that can not yet be written.
+	current, err := kubeutils.GetInstallation(ctx, cli, in.Name)
+	if err != nil {
+		return fmt.Errorf("get installation: %w", err)
+	}
+
+	switch current.Annotations[ecv1beta1.UpgradeControlAnnotation] {
+	case ecv1beta1.UpgradeControlPause:
+		slog.Info("Upgrade paused", "step", step)
+		return ErrUpgradePaused{Step: step}
+	case ecv1beta1.UpgradeControlCancel:
+		slog.Info("Upgrade cancelled", "step", step)
+		return ErrUpgradeCancelled{Step: step}
+	}
+	return nil
+}
+
+// newCheckpointFunc returns the checkpoint called after each addon and extension upgrade.
+func newCheckpointFunc(cli client.Client, in *ecv1beta1.Installation) addons.CheckpointFunc {
+	return func(ctx context.Context, step string) error {
+		return checkpoint(ctx, cli, in, step)
+	}
+}
+
+// PauseUpgrade asks the upgrade job to stop after the step it is currently running.
+func PauseUpgrade(ctx context.Context, cli client.Client, in *ecv1beta1.Installation) error {
+	if !upgradeInProgress(in) {
+		return fmt.Errorf("installation %s is %s, there is no upgrade to pause", in.Name, in.Status.State)
+	}
+	if in.Status.State == ecv1beta1.InstallationStatePaused {
+		return fmt.Errorf("upgrade of installation %s is already paused", in.Name)
+	}
+	return setUpgradeControl(ctx, cli, in, ecv1beta1.UpgradeControlPause)
+}
+
+// ResumeUpgrade resumes a paused upgrade. If the upgrade job has already exited it is started
+// again and resumes after the last completed step.
+func ResumeUpgrade(ctx context.Context, cli client.Client, in *ecv1beta1.Installation) error {
+	if in.Annotations[ecv1beta1.UpgradeControlAnnotation] != ecv1beta1.UpgradeControlPause {
+		return fmt.Errorf("upgrade of installation %s is not paused", in.Name)
+	}
+	if err := setUpgradeControl(ctx, cli, in, ""); err != nil {
+		return err
+	}
+	if in.Status.State != ecv1beta1.InstallationStatePaused {
+		// the job has not reached a checkpoint yet, it just carries on.
+		return nil
+	}
+	return restartUpgradeJob(ctx, cli, in)
+}
+
+// CancelUpgrade asks the upgrade job to stop after the step it is currently running and to
+// roll back the upgrade. Paused upgrades are restarted so the job can do so.
+func CancelUpgrade(ctx context.Context, cli client.Client, in *ecv1beta1.Installation) error {
+	if !upgradeInProgress(in) {
+		return fmt.Errorf("installation %s is %s, there is no upgrade to cancel", in.Name, in.Status.State)
+	}
+	if err := setUpgradeControl(ctx, cli, in, ecv1beta1.UpgradeControlCancel); err != nil {
+		return err
+	}
+	if in.Status.State != ecv1beta1.InstallationStatePaused {
+		return nil
+	}
+	return restartUpgradeJob(ctx, cli, in)
+}
+
+func upgradeInProgress(in *ecv1beta1.Installation) bool {
+	switch in.Status.State {
+	case ecv1beta1.InstallationStateInstalled, ecv1beta1.InstallationStateFailed, ecv1beta1.InstallationStateObsolete:
+		return false
+	}
+	return true
+}
+
+func setUpgradeControl(ctx context.Context, cli client.Client, in *ecv1beta1.Installation, value string) error {
+	err := kubeutils.UpdateInstallation(ctx, cli, in, func(in *ecv1beta1.Installation) {
+		if value == "" {
+			delete(in.Annotations, ecv1beta1.UpgradeControlAnnotation)
+			return
+		}
+		if in.Annotations == nil {
+			in.Annotations = map[string]string{}
+		}
+		in.Annotations[ecv1beta1.UpgradeControlAnnotation] = value
+	})
+	if err != nil {
+		return fmt.Errorf("update installation: %w", err)
+	}
+	return nil
+}
+
+// restartUpgradeJob replaces the upgrade job, which exited when the upgrade was paused, by a
+// new one with the same spec.
+func restartUpgradeJob(ctx context.Context, cli client.Client, in *ecv1beta1.Installation) error {
+	nsn := client.ObjectKey{Namespace: upgradeJobNamespace, Name: fmt.Sprintf(upgradeJobName, in.Name)}
+
+	var job batchv1.Job
+	if err := cli.Get(ctx, nsn, &job); err != nil {
+		return fmt.Errorf("get upgrade job: %w", err)
+	}
+	if job.Status.CompletionTime == nil && job.Status.Failed == 0 && job.Status.Active > 0 {
+		return nil
+	}
+
+	if err := cli.Delete(ctx, &job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil {
+		return fmt.Errorf("delete upgrade job: %w", err)
+	}
+	err := wait.PollUntilContextTimeout(ctx, time.Second, time.Minute, true, func(ctx context.Context) (bool, error) {
+		err := cli.Get(ctx, nsn, &batchv1.Job{})
+		if k8serrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	})
+	if err != nil {
+		return fmt.Errorf("wait for upgrade job deletion: %w", err)
+	}
+
+	newJob := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   job.Namespace,
+			Name:        job.Name,
+			Labels:      job.Labels,
+			Annotations: job.Annotations,
+		},
+		Spec: *job.Spec.DeepCopy(),
+	}
+	// the selector and its labels are generated by the api server for each job.
+	newJob.Spec.Selector = nil
+	newJob.Spec.ManualSelector = nil
+	for _, label := range []string{"controller-uid", "batch.kubernetes.io/controller-uid", "job-name", "batch.kubernetes.io/job-name"} {
+		delete(newJob.Spec.Template.Labels, label)
+	}
+	if err := cli.Create(ctx, newJob); err != nil {
+		return fmt.Errorf("create upgrade job: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package upgrade
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_checkpoint(t *testing.T) {
+	tests := []struct {
+		name    string
+		control string
+		wantErr error
+	}{
+		{
+			name: "no control annotation",
+		},
+		{
+			name:    "paused",
+			control: ecv1beta1.UpgradeControlPause,
+			wantErr: ErrUpgradePaused{Step: "addon OpenEBS"},
+		},
+		{
+			name:    "cancelled",
+			control: ecv1beta1.UpgradeControlCancel,
+			wantErr: ErrUpgradeCancelled{Step: "addon OpenEBS"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			in := &ecv1beta1.Installation{ObjectMeta: metav1.ObjectMeta{Name: "20241002205018"}}
+			if tt.control != "" {
+				in.Annotations = map[string]string{ecv1beta1.UpgradeControlAnnotation: tt.control}
+			}
+			cli := fake.NewClientBuilder().WithScheme(kubeutils.Scheme).WithObjects(in).Build()
+
+			err := checkpoint(ctx, cli, in, "addon OpenEBS")
+			if tt.wantErr != nil {
+				assert.Equal(t, tt.wantErr, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestResumeUpgrade(t *testing.T) {
+	ctx := context.Background()
+	in := &ecv1beta1.Installation{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "20241002205018",
+			Annotations: map[string]string{ecv1beta1.UpgradeControlAnnotation: ecv1beta1.UpgradeControlPause},
+		},
+		Status: ecv1beta1.InstallationStatus{State: ecv1beta1.InstallationStatePaused},
+	}
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "embedded-cluster-upgrade-20241002205018",
+			Namespace: upgradeJobNamespace,
+		},
+		Spec: batchv1.JobSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"batch.kubernetes.io/controller-uid": "abc"}},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"app.kubernetes.io/name":             "embedded-cluster-upgrade",
+						"batch.kubernetes.io/controller-uid": "abc",
+					},
+				},
+			},
+		},
+		Status: batchv1.JobStatus{Succeeded: 1, CompletionTime: &metav1.Time{}},
+	}
+	cli := fake.NewClientBuilder().WithScheme(kubeutils.Scheme).WithObjects(in, job).Build()
+
+	require.NoError(t, ResumeUpgrade(ctx, cli, in))
+
+	var got ecv1beta1.Installation
+	require.NoError(t, cli.Get(ctx, client.ObjectKeyFromObject(in), &got))
+	assert.NotContains(t, got.Annotations, ecv1beta1.UpgradeControlAnnotation)
+
+	// the job is replaced by a new one with the same spec.
+	var newJob batchv1.Job
+	require.NoError(t, cli.Get(ctx, client.ObjectKeyFromObject(job), &newJob))
+	assert.Nil(t, newJob.Status.CompletionTime)
+	assert.Nil(t, newJob.Spec.Selector)
+	assert.Equal(t, map[string]string{"app.kubernetes.io/name": "embedded-cluster-upgrade"}, newJob.Spec.Template.Labels)
+
+	// there is nothing left to resume.
+	err := ResumeUpgrade(ctx, cli, &got)
+	assert.Error(t, err)
+}
+
+func TestCancelUpgrade(t *testing.T) {
+	ctx := context.Background()
+	in := &ecv1beta1.Installation{
+		ObjectMeta: metav1.ObjectMeta{Name: "20241002205018"},
+		Status:     ecv1beta1.InstallationStatus{State: ecv1beta1.InstallationStateInstalled},
+	}
+	cli := fake.NewClientBuilder().WithScheme(kubeutils.Scheme).WithObjects(in).Build()
+
+	// upgrades that are not in progress can not be cancelled.
+	require.Error(t, CancelUpgrade(ctx, cli, in))
+
+	in.Status.State = ecv1beta1.InstallationStateAddonsInstalling
+	require.NoError(t, cli.Update(ctx, in))
+	require.NoError(t, CancelUpgrade(ctx, cli, in))
+
+	err := checkpoint(ctx, cli, in, "Kubernetes")
+	assert.True(t, errors.As(err, &ErrUpgradeCancelled{}))
+}
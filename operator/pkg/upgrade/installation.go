@@ -7,6 +7,7 @@ import (
 
 	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
 	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -60,6 +61,42 @@ func reApplyInstallation(ctx context.Context, cli client.Client, in *ecv1beta1.I
 	return nil
 }
 
+// inheritEndUserConfig returns the installation with the end user config of the previous
+// installation if it has none of its own. Installations created for upgrades do not carry the
+// overrides the end user provided at install time.
+func inheritEndUserConfig(in *ecv1beta1.Installation, previous *ecv1beta1.Installation) *ecv1beta1.Installation {
+	if in.Spec.EndUserConfig != nil || previous == nil || previous.Spec.EndUserConfig == nil {
+		return in
+	}
+	next := in.DeepCopy()
+	next.Spec.EndUserConfig = previous.Spec.EndUserConfig.DeepCopy()
+	return next
+}
+
+// inheritExternalRegistry returns the installation with the external registry of the previous
+// installation if it has none of its own. The registry is chosen at install time and the
+// installations created for upgrades do not carry it.
+func inheritExternalRegistry(in *ecv1beta1.Installation, previous *ecv1beta1.Installation) *ecv1beta1.Installation {
+	if in.Spec.ExternalRegistry != nil || previous == nil || previous.Spec.ExternalRegistry == nil {
+		return in
+	}
+	next := in.DeepCopy()
+	next.Spec.ExternalRegistry = previous.Spec.ExternalRegistry.DeepCopy()
+	return next
+}
+
+// inheritRegistryGarbageCollection returns the installation with the registry garbage
+// collection policy of the previous installation if it has none of its own, so a policy set
+// by the vendor is not lost on the next upgrade.
+func inheritRegistryGarbageCollection(in *ecv1beta1.Installation, previous *ecv1beta1.Installation) *ecv1beta1.Installation {
+	if in.Spec.RegistryGarbageCollection != nil || previous == nil || previous.Spec.RegistryGarbageCollection == nil {
+		return in
+	}
+	next := in.DeepCopy()
+	next.Spec.RegistryGarbageCollection = previous.Spec.RegistryGarbageCollection.DeepCopy()
+	return next
+}
+
 // disableOldInstallations resets old installation statuses keeping only the newest one with
 // proper status set. It sets the state for all old installations as "obsolete" as they
 // are not necessary anymore and are kept only for historic reasons.
@@ -74,13 +111,28 @@ func disableOldInstallations(ctx context.Context, cli client.Client) error {
 			continue
 		}
 
-		err := kubeutils.UpdateInstallationStatus(ctx, cli, &in, func(status *ecv1beta1.InstallationStatus) {
+		// keep track of the state the installation was in so we can later tell if the upgrade
+		// started from a healthy installation and record how it ended in the history.
+		state, reason := in.Status.State, in.Status.Reason
+		err := kubeutils.UpdateInstallation(ctx, cli, &in, func(in *ecv1beta1.Installation) {
+			if in.Annotations == nil {
+				in.Annotations = map[string]string{}
+			}
+			in.Annotations[ecv1beta1.InstallationObsoletedStateAnnotation] = state
+			in.Annotations[ecv1beta1.InstallationObsoletedReasonAnnotation] = reason
+		})
+		if err != nil {
+			return fmt.Errorf("annotate installation: %w", err)
+		}
+
+		err = kubeutils.UpdateInstallationStatus(ctx, cli, &in, func(status *ecv1beta1.InstallationStatus) {
 			status.NodesStatus = nil
 			status.SetState(ecv1beta1.InstallationStateObsolete, "This is not the most recent installation object", nil)
 		})
 		if err != nil {
 			return fmt.Errorf("update installation: %w", err)
 		}
+		kubeutils.RecordInstallationEvent(ctx, cli, &in, corev1.EventTypeNormal, ecv1beta1.InstallationStateObsolete, "Installation superseded by a newer installation")
 	}
 
 	return nil
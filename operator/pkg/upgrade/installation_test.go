@@ -0,0 +1,127 @@
+package upgrade
+
+import (
+	"testing"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_inheritEndUserConfig(t *testing.T) {
+	euCfg := &ecv1beta1.ConfigSpec{
+		UnsupportedOverrides: ecv1beta1.UnsupportedOverrides{
+			BuiltInExtensions: []ecv1beta1.BuiltInExtension{
+				{Name: "admin-console", Values: "labels:\n  foo: bar\n"},
+			},
+		},
+	}
+	newCfg := &ecv1beta1.ConfigSpec{
+		UnsupportedOverrides: ecv1beta1.UnsupportedOverrides{
+			BuiltInExtensions: []ecv1beta1.BuiltInExtension{
+				{Name: "openebs", Values: "foo: bar\n"},
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		in       *ecv1beta1.Installation
+		previous *ecv1beta1.Installation
+		want     *ecv1beta1.ConfigSpec
+	}{
+		{
+			name: "no previous installation",
+			in:   &ecv1beta1.Installation{},
+			want: nil,
+		},
+		{
+			name:     "inherited from the previous installation",
+			in:       &ecv1beta1.Installation{},
+			previous: &ecv1beta1.Installation{Spec: ecv1beta1.InstallationSpec{EndUserConfig: euCfg}},
+			want:     euCfg,
+		},
+		{
+			name:     "own end user config is kept",
+			in:       &ecv1beta1.Installation{Spec: ecv1beta1.InstallationSpec{EndUserConfig: newCfg}},
+			previous: &ecv1beta1.Installation{Spec: ecv1beta1.InstallationSpec{EndUserConfig: euCfg}},
+			want:     newCfg,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := inheritEndUserConfig(tt.in, tt.previous)
+			assert.Equal(t, tt.want, got.Spec.EndUserConfig)
+		})
+	}
+}
+
+func Test_inheritExternalRegistry(t *testing.T) {
+	registry := &ecv1beta1.ExternalRegistrySpec{Address: "harbor.example.com"}
+
+	tests := []struct {
+		name     string
+		in       *ecv1beta1.Installation
+		previous *ecv1beta1.Installation
+		want     *ecv1beta1.ExternalRegistrySpec
+	}{
+		{
+			name: "no previous installation",
+			in:   &ecv1beta1.Installation{},
+			want: nil,
+		},
+		{
+			name:     "previous installation uses the bundled registry",
+			in:       &ecv1beta1.Installation{},
+			previous: &ecv1beta1.Installation{},
+			want:     nil,
+		},
+		{
+			name:     "inherited from the previous installation",
+			in:       &ecv1beta1.Installation{},
+			previous: &ecv1beta1.Installation{Spec: ecv1beta1.InstallationSpec{ExternalRegistry: registry}},
+			want:     registry,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := inheritExternalRegistry(tt.in, tt.previous)
+			assert.Equal(t, tt.want, got.Spec.ExternalRegistry)
+		})
+	}
+}
+
+func Test_inheritRegistryGarbageCollection(t *testing.T) {
+	policy := &ecv1beta1.RegistryGarbageCollection{DryRun: true, KeepReleases: 3}
+	own := &ecv1beta1.RegistryGarbageCollection{Disabled: true}
+
+	tests := []struct {
+		name     string
+		in       *ecv1beta1.Installation
+		previous *ecv1beta1.Installation
+		want     *ecv1beta1.RegistryGarbageCollection
+	}{
+		{
+			name: "no previous installation",
+			in:   &ecv1beta1.Installation{},
+			want: nil,
+		},
+		{
+			name:     "inherited from the previous installation",
+			in:       &ecv1beta1.Installation{},
+			previous: &ecv1beta1.Installation{Spec: ecv1beta1.InstallationSpec{RegistryGarbageCollection: policy}},
+			want:     policy,
+		},
+		{
+			name:     "installation defines its own policy",
+			in:       &ecv1beta1.Installation{Spec: ecv1beta1.InstallationSpec{RegistryGarbageCollection: own}},
+			previous: &ecv1beta1.Installation{Spec: ecv1beta1.InstallationSpec{RegistryGarbageCollection: policy}},
+			want:     own,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := inheritRegistryGarbageCollection(tt.in, tt.previous)
+			assert.Equal(t, tt.want, got.Spec.RegistryGarbageCollection)
+		})
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
@@ -29,7 +30,12 @@ import (
 )
 
 const (
-	upgradeJobName      = "embedded-cluster-upgrade-%s"
+	// JobNamePrefix is the prefix of the upgrade job names, followed by the installation name.
+	JobNamePrefix = "embedded-cluster-upgrade-"
+	// JobNameLabel is the app.kubernetes.io/name label set on the upgrade jobs.
+	JobNameLabel = "embedded-cluster-upgrade"
+
+	upgradeJobName      = JobNamePrefix + "%s"
 	upgradeJobNamespace = runtimeconfig.KotsadmNamespace
 	upgradeJobConfigMap = "upgrade-job-configmap-%s"
 )
@@ -145,8 +151,8 @@ func CreateUpgradeJob(
 			Namespace: upgradeJobNamespace,
 			Name:      fmt.Sprintf(upgradeJobName, in.Name),
 			Labels: map[string]string{
-				"app.kubernetes.io/instance": "embedded-cluster-upgrade",
-				"app.kubernetes.io/name":     "embedded-cluster-upgrade",
+				"app.kubernetes.io/instance": JobNameLabel,
+				"app.kubernetes.io/name":     JobNameLabel,
 			},
 		},
 		Spec: batchv1.JobSpec{
@@ -154,8 +160,8 @@ func CreateUpgradeJob(
 			Template: corev1.PodTemplateSpec{
 				ObjectMeta: metav1.ObjectMeta{
 					Labels: map[string]string{
-						"app.kubernetes.io/instance": "embedded-cluster-upgrade",
-						"app.kubernetes.io/name":     "embedded-cluster-upgrade",
+						"app.kubernetes.io/instance": JobNameLabel,
+						"app.kubernetes.io/name":     JobNameLabel,
 					},
 				},
 				Spec: corev1.PodSpec{
@@ -251,6 +257,19 @@ func operatorImageName(ctx context.Context, cli client.Client, in *ecv1beta1.Ins
 	return "", fmt.Errorf("no embedded-cluster-operator image found in release metadata")
 }
 
+func localArtifactMirrorImageName(ctx context.Context, cli client.Client, in *ecv1beta1.Installation) (string, error) {
+	meta, err := release.MetadataFor(ctx, in, cli)
+	if err != nil {
+		return "", fmt.Errorf("failed to get release metadata: %w", err)
+	}
+	for _, image := range meta.Images {
+		if strings.Contains(image, "embedded-cluster-local-artifact-mirror") {
+			return image, nil
+		}
+	}
+	return "", fmt.Errorf("no embedded-cluster-local-artifact-mirror image found in release metadata")
+}
+
 func airgapDistributeArtifacts(ctx context.Context, cli client.Client, in *ecv1beta1.Installation, localArtifactMirrorImage string) error {
 	// in airgap installations let's make sure all assets have been copied to nodes.
 	// this may take some time so we only move forward when 'ready'.
@@ -259,6 +278,15 @@ func airgapDistributeArtifacts(ctx context.Context, cli client.Client, in *ecv1b
 		return fmt.Errorf("ensure airgap artifacts: %w", err)
 	}
 
+	// the local artifact mirrors are restarted when their binary is updated, we want them to
+	// be serving the artifacts before autopilot attempts to fetch them. this is best-effort,
+	// a mirror may not be reachable from the operator and autopilot retries the downloads
+	// from the node itself anyway.
+	err = artifacts.WaitForLocalArtifactMirrors(ctx, cli, in, 5*time.Minute)
+	if err != nil {
+		controllerruntime.LoggerFrom(ctx).Error(err, "Unable to confirm the local artifact mirrors are ready, continuing")
+	}
+
 	// once all assets are in place we can create the autopilot plan to push the images to
 	// containerd.
 	err = ensureAirgapArtifactsInCluster(ctx, cli, in)
@@ -281,23 +309,31 @@ func ensureAirgapArtifactsOnNodes(ctx context.Context, cli client.Client, in *ec
 		log.Info("Registry credentials secret changed", "operation", op)
 	}
 
-	err = artifacts.EnsureArtifactsJobForNodes(ctx, cli, in, localArtifactMirrorImage)
-	if err != nil {
-		return fmt.Errorf("ensure artifacts job for nodes: %w", err)
-	}
-
 	log.Info("Waiting for artifacts to be placed on nodes...")
 
 	err = wait.PollUntilContextCancel(ctx, 5*time.Second, true, func(ctx context.Context) (bool, error) {
+		// jobs are created in batches, nodes that have completed their jobs become peers
+		// for the nodes whose jobs are created afterwards.
+		err := artifacts.EnsureArtifactsJobForNodes(ctx, cli, in, localArtifactMirrorImage)
+		if err != nil {
+			return false, fmt.Errorf("ensure artifacts job for nodes: %w", err)
+		}
+
 		jobs, err := artifacts.ListArtifactsJobForNodes(ctx, cli, in)
 		if err != nil {
 			return false, fmt.Errorf("list artifacts jobs for nodes: %w", err)
 		}
 
+		if err := updateNodesArtifactsStatus(ctx, cli, in, artifacts.NodesArtifactsStatus(jobs)); err != nil {
+			return false, fmt.Errorf("update nodes artifacts status: %w", err)
+		}
+
 		ready := true
 		for nodeName, job := range jobs {
 			if job == nil {
-				return false, fmt.Errorf("job for node %s not found", nodeName)
+				// job has not yet been scheduled
+				ready = false
+				continue
 			}
 			if job.Status.Succeeded > 0 {
 				continue
@@ -325,6 +361,23 @@ func ensureAirgapArtifactsOnNodes(ctx context.Context, cli client.Client, in *ec
 	return nil
 }
 
+// updateNodesArtifactsStatus updates the artifacts distribution status in the installation
+// object if it has changed.
+func updateNodesArtifactsStatus(ctx context.Context, cli client.Client, in *ecv1beta1.Installation, statuses []ecv1beta1.NodeArtifactsStatus) error {
+	if equality.Semantic.DeepEqual(in.Status.NodesArtifactsStatus, statuses) {
+		return nil
+	}
+	// we use a copy as the installation object is used to build the upgrade job.
+	err := kubeutils.UpdateInstallationStatus(ctx, cli, in.DeepCopy(), func(status *ecv1beta1.InstallationStatus) {
+		status.NodesArtifactsStatus = statuses
+	})
+	if err != nil {
+		return err
+	}
+	in.Status.NodesArtifactsStatus = statuses
+	return nil
+}
+
 func ensureAirgapArtifactsInCluster(ctx context.Context, cli client.Client, in *ecv1beta1.Installation) error {
 	log := controllerruntime.LoggerFrom(ctx)
 
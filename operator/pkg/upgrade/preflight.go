@@ -0,0 +1,344 @@
+package upgrade
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	etcdv1beta1 "github.com/k0sproject/k0s/pkg/apis/etcd/v1beta1"
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+)
+
+const (
+	// minFreeDataDirBytes is the free space we require by default in the filesystem holding
+	// the data directory of each node. This is room for the new k0s binary and images.
+	minFreeDataDirBytes = 2 << 30
+	// minFreeDataDirBytesAirgap is the free space we require by default in air gap
+	// installations, where the images are also pushed to the registry.
+	minFreeDataDirBytesAirgap = 5 << 30
+	// pendingPVCTimeout is the time after which a PVC bound to a node but not yet provisioned
+	// is considered stuck.
+	pendingPVCTimeout = 10 * time.Minute
+)
+
+// newNodeFreeDiskBytes returns a function returning the free space in the filesystem holding
+// the kubelet root directory of a node. As the k0s data directory lives inside the embedded
+// cluster data directory this is also the free space available for the artifacts.
+var newNodeFreeDiskBytes = newKubeletNodeFreeDiskBytes
+
+// ErrPreflightFailed is returned when the cluster is not healthy enough to be upgraded.
+type ErrPreflightFailed struct {
+	Failures []string
+}
+
+func (e ErrPreflightFailed) Error() string {
+	return fmt.Sprintf("pre-upgrade checks failed: %s", strings.Join(e.Failures, "; "))
+}
+
+// RunPreflights verifies the cluster is healthy before starting an upgrade. All the checks are
+// run and their failures reported together through the installation PreflightFailed condition
+// and an ErrPreflightFailed error.
+func RunPreflights(ctx context.Context, cli client.Client, in *ecv1beta1.Installation) error {
+	slog.Info("Running pre-upgrade checks")
+
+	checks := []func(context.Context, client.Client, *ecv1beta1.Installation) ([]string, error){
+		checkNodesReady,
+		checkEtcdMembers,
+		checkNodesDiskSpace,
+		checkPendingPVCs,
+		checkAirgapStorage,
+		checkPreviousInstallation,
+	}
+
+	var failures []string
+	for _, check := range checks {
+		result, err := check(ctx, cli, in)
+		if err != nil {
+			return fmt.Errorf("run pre-upgrade check: %w", err)
+		}
+		failures = append(failures, result...)
+	}
+
+	if len(failures) > 0 {
+		err := ErrPreflightFailed{Failures: failures}
+		if cerr := setPreflightCondition(ctx, cli, in, metav1.ConditionTrue, "PreflightChecksFailed", err.Error()); cerr != nil {
+			slog.Error("Failed to set preflight failed condition", "error", cerr)
+		}
+		return err
+	}
+
+	if err := setPreflightCondition(ctx, cli, in, metav1.ConditionFalse, "PreflightChecksPassed", ""); err != nil {
+		return fmt.Errorf("set preflight condition: %w", err)
+	}
+
+	slog.Info("Pre-upgrade checks passed")
+	return nil
+}
+
+func setPreflightCondition(ctx context.Context, cli client.Client, in *ecv1beta1.Installation, status metav1.ConditionStatus, reason, message string) error {
+	return kubeutils.SetInstallationConditionStatus(ctx, cli, in, metav1.Condition{
+		Type:    ecv1beta1.ConditionTypePreflightFailed,
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	})
+}
+
+func checkNodesReady(ctx context.Context, cli client.Client, _ *ecv1beta1.Installation) ([]string, error) {
+	var nodes corev1.NodeList
+	if err := cli.List(ctx, &nodes); err != nil {
+		return nil, fmt.Errorf("list nodes: %w", err)
+	}
+
+	var failures []string
+	for _, node := range nodes.Items {
+		ready := false
+		for _, cond := range node.Status.Conditions {
+			if cond.Type == corev1.NodeReady && cond.Status == corev1.ConditionTrue {
+				ready = true
+				break
+			}
+		}
+		if !ready {
+			failures = append(failures, fmt.Sprintf("node %s is not ready", node.Name))
+		}
+	}
+	return failures, nil
+}
+
+func checkEtcdMembers(ctx context.Context, cli client.Client, _ *ecv1beta1.Installation) ([]string, error) {
+	var members etcdv1beta1.EtcdMemberList
+	if err := cli.List(ctx, &members); err != nil {
+		if meta.IsNoMatchError(err) {
+			// older k0s versions do not expose the etcd members.
+			return nil, nil
+		}
+		return nil, fmt.Errorf("list etcd members: %w", err)
+	}
+
+	var failures []string
+	for _, member := range members.Items {
+		if member.Spec.Leave {
+			continue
+		}
+		cond := member.Status.GetCondition(etcdv1beta1.ConditionTypeJoined)
+		if cond == nil || cond.Status != etcdv1beta1.ConditionTrue {
+			msg := fmt.Sprintf("etcd member %s is not healthy", member.Name)
+			if cond != nil && cond.Message != "" {
+				msg = fmt.Sprintf("%s: %s", msg, cond.Message)
+			}
+			failures = append(failures, msg)
+		}
+	}
+	return failures, nil
+}
+
+func checkNodesDiskSpace(ctx context.Context, cli client.Client, in *ecv1beta1.Installation) ([]string, error) {
+	var nodes corev1.NodeList
+	if err := cli.List(ctx, &nodes); err != nil {
+		return nil, fmt.Errorf("list nodes: %w", err)
+	}
+
+	required := minFreeDiskBytes(in)
+	nodeFreeDiskBytes, err := newNodeFreeDiskBytes()
+	if err != nil {
+		return nil, fmt.Errorf("create kubelet stats client: %w", err)
+	}
+
+	var failures []string
+	for _, node := range nodes.Items {
+		free, err := nodeFreeDiskBytes(ctx, node.Name)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("unable to determine free disk space on node %s: %v", node.Name, err))
+			continue
+		}
+		if free < required {
+			failures = append(failures, fmt.Sprintf(
+				"node %s has %s free in the data directory, at least %s are required",
+				node.Name, formatBytes(free), formatBytes(required),
+			))
+		}
+	}
+	return failures, nil
+}
+
+// minFreeDiskBytes returns the free space required in the data directory of each node, as set
+// in the upgrade strategy or the default for the installation.
+func minFreeDiskBytes(in *ecv1beta1.Installation) uint64 {
+	if in.Spec.UpgradeStrategy != nil && in.Spec.UpgradeStrategy.MinFreeDiskSpace != nil {
+		if value := in.Spec.UpgradeStrategy.MinFreeDiskSpace.Value(); value > 0 {
+			return uint64(value)
+		}
+	}
+	if in.Spec.AirGap {
+		return minFreeDataDirBytesAirgap
+	}
+	return minFreeDataDirBytes
+}
+
+func checkPendingPVCs(ctx context.Context, cli client.Client, _ *ecv1beta1.Installation) ([]string, error) {
+	var pvcs corev1.PersistentVolumeClaimList
+	if err := cli.List(ctx, &pvcs); err != nil {
+		return nil, fmt.Errorf("list pvcs: %w", err)
+	}
+
+	var failures []string
+	for _, pvc := range pvcs.Items {
+		if pvc.Status.Phase != corev1.ClaimPending {
+			continue
+		}
+		// with WaitForFirstConsumer storage classes claims stay pending until a pod using
+		// them is scheduled, those are not stuck.
+		if _, ok := pvc.Annotations["volume.kubernetes.io/selected-node"]; !ok {
+			continue
+		}
+		if time.Since(pvc.CreationTimestamp.Time) < pendingPVCTimeout {
+			continue
+		}
+		failures = append(failures, fmt.Sprintf("pvc %s/%s is stuck pending", pvc.Namespace, pvc.Name))
+	}
+	return failures, nil
+}
+
+func checkAirgapStorage(ctx context.Context, cli client.Client, in *ecv1beta1.Installation) ([]string, error) {
+	if !in.Spec.AirGap {
+		return nil, nil
+	}
+
+	failures, err := checkWorkloadsReady(ctx, cli, runtimeconfig.RegistryNamespace)
+	if err != nil {
+		return nil, fmt.Errorf("check registry: %w", err)
+	}
+
+	if in.Spec.HighAvailability {
+		result, err := checkWorkloadsReady(ctx, cli, runtimeconfig.SeaweedFSNamespace)
+		if err != nil {
+			return nil, fmt.Errorf("check seaweedfs: %w", err)
+		}
+		failures = append(failures, result...)
+	}
+	return failures, nil
+}
+
+// checkWorkloadsReady returns a failure for each deployment or statefulset in the namespace
+// that does not have all its replicas ready.
+func checkWorkloadsReady(ctx context.Context, cli client.Client, namespace string) ([]string, error) {
+	var failures []string
+
+	var deployments appsv1.DeploymentList
+	if err := cli.List(ctx, &deployments, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("list deployments: %w", err)
+	}
+	for _, deploy := range deployments.Items {
+		desired := int32(1)
+		if deploy.Spec.Replicas != nil {
+			desired = *deploy.Spec.Replicas
+		}
+		if deploy.Status.ReadyReplicas < desired {
+			failures = append(failures, fmt.Sprintf(
+				"deployment %s/%s is degraded (%d/%d replicas ready)",
+				namespace, deploy.Name, deploy.Status.ReadyReplicas, desired,
+			))
+		}
+	}
+
+	var statefulsets appsv1.StatefulSetList
+	if err := cli.List(ctx, &statefulsets, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("list statefulsets: %w", err)
+	}
+	for _, sts := range statefulsets.Items {
+		desired := int32(1)
+		if sts.Spec.Replicas != nil {
+			desired = *sts.Spec.Replicas
+		}
+		if sts.Status.ReadyReplicas < desired {
+			failures = append(failures, fmt.Sprintf(
+				"statefulset %s/%s is degraded (%d/%d replicas ready)",
+				namespace, sts.Name, sts.Status.ReadyReplicas, desired,
+			))
+		}
+	}
+
+	return failures, nil
+}
+
+func checkPreviousInstallation(ctx context.Context, cli client.Client, in *ecv1beta1.Installation) ([]string, error) {
+	previous, err := kubeutils.GetPreviousInstallation(ctx, cli, in)
+	if errors.Is(err, kubeutils.ErrInstallationNotFound{}) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("get previous installation: %w", err)
+	}
+
+	// the previous installation is marked as obsolete as soon as the new one is created so
+	// we look at the state it had before.
+	state := previous.Status.State
+	if state == ecv1beta1.InstallationStateObsolete {
+		if prevState, ok := previous.Annotations[ecv1beta1.InstallationObsoletedStateAnnotation]; ok {
+			state = prevState
+		}
+	}
+
+	if state != ecv1beta1.InstallationStateInstalled {
+		return []string{fmt.Sprintf("previous installation %s is %s, not %s", previous.Name, state, ecv1beta1.InstallationStateInstalled)}, nil
+	}
+	return nil, nil
+}
+
+// newKubeletNodeFreeDiskBytes returns a function reading the free space in the node filesystem
+// from the kubelet stats summary, proxied through the api server. The clientset is shared by
+// all the nodes.
+func newKubeletNodeFreeDiskBytes() (func(context.Context, string) (uint64, error), error) {
+	cfg, err := config.GetConfig()
+	if err != nil {
+		return nil, fmt.Errorf("get kubernetes config: %w", err)
+	}
+	clientset, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("create kubernetes clientset: %w", err)
+	}
+	return func(ctx context.Context, node string) (uint64, error) {
+		return kubeletNodeFreeDiskBytes(ctx, clientset, node)
+	}, nil
+}
+
+func kubeletNodeFreeDiskBytes(ctx context.Context, clientset kubernetes.Interface, node string) (uint64, error) {
+	data, err := clientset.CoreV1().RESTClient().Get().
+		Resource("nodes").Name(node).SubResource("proxy").Suffix("stats/summary").
+		DoRaw(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("get kubelet stats summary: %w", err)
+	}
+
+	var summary struct {
+		Node struct {
+			Fs *struct {
+				AvailableBytes *uint64 `json:"availableBytes"`
+			} `json:"fs"`
+		} `json:"node"`
+	}
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return 0, fmt.Errorf("unmarshal kubelet stats summary: %w", err)
+	}
+	if summary.Node.Fs == nil || summary.Node.Fs.AvailableBytes == nil {
+		return 0, fmt.Errorf("kubelet stats summary has no filesystem information")
+	}
+	return *summary.Node.Fs.AvailableBytes, nil
+}
+
+func formatBytes(b uint64) string {
+	return fmt.Sprintf("%.1fGi", float64(b)/float64(1<<30))
+}
@@ -0,0 +1,207 @@
+package upgrade
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	etcdv1beta1 "github.com/k0sproject/k0s/pkg/apis/etcd/v1beta1"
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRunPreflights(t *testing.T) {
+	readyNode := func(name string) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{
+				{Type: corev1.NodeReady, Status: corev1.ConditionTrue},
+			}},
+		}
+	}
+	previous := &ecv1beta1.Installation{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "20241001000000",
+			Annotations: map[string]string{ecv1beta1.InstallationObsoletedStateAnnotation: ecv1beta1.InstallationStateInstalled},
+		},
+		Spec:   ecv1beta1.InstallationSpec{Config: &ecv1beta1.ConfigSpec{Version: "1.15.0"}},
+		Status: ecv1beta1.InstallationStatus{State: ecv1beta1.InstallationStateObsolete},
+	}
+	newInstallation := func(airgap bool) *ecv1beta1.Installation {
+		return &ecv1beta1.Installation{
+			ObjectMeta: metav1.ObjectMeta{Name: "20241002000000"},
+			Spec: ecv1beta1.InstallationSpec{
+				Config:           &ecv1beta1.ConfigSpec{Version: "1.16.0"},
+				AirGap:           airgap,
+				HighAvailability: airgap,
+			},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		airgap   bool
+		objects  []client.Object
+		freeDisk uint64
+		failures []string
+	}{
+		{
+			name:     "healthy cluster",
+			objects:  []client.Object{readyNode("node1"), previous},
+			freeDisk: 10 << 30,
+		},
+		{
+			name: "sick cluster",
+			objects: []client.Object{
+				readyNode("node1"),
+				&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node2"}},
+				&ecv1beta1.Installation{
+					ObjectMeta: metav1.ObjectMeta{Name: "20241001000000"},
+					Spec:       ecv1beta1.InstallationSpec{Config: &ecv1beta1.ConfigSpec{Version: "1.15.0"}},
+					Status:     ecv1beta1.InstallationStatus{State: ecv1beta1.InstallationStateFailed},
+				},
+				&etcdv1beta1.EtcdMember{
+					ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+					Status: etcdv1beta1.Status{Conditions: []etcdv1beta1.JoinCondition{
+						{Type: etcdv1beta1.ConditionTypeJoined, Status: etcdv1beta1.ConditionFalse},
+					}},
+				},
+				&corev1.PersistentVolumeClaim{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:              "data",
+						Namespace:         "kotsadm",
+						CreationTimestamp: metav1.NewTime(time.Now().Add(-time.Hour)),
+						Annotations:       map[string]string{"volume.kubernetes.io/selected-node": "node1"},
+					},
+					Status: corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimPending},
+				},
+				&corev1.PersistentVolumeClaim{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:              "unused",
+						Namespace:         "kotsadm",
+						CreationTimestamp: metav1.NewTime(time.Now().Add(-time.Hour)),
+					},
+					Status: corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimPending},
+				},
+			},
+			freeDisk: 1 << 30,
+			failures: []string{
+				"node node2 is not ready",
+				"etcd member node1 is not healthy",
+				"node node1 has 1.0Gi free in the data directory, at least 2.0Gi are required",
+				"node node2 has 1.0Gi free in the data directory, at least 2.0Gi are required",
+				"pvc kotsadm/data is stuck pending",
+				"previous installation 20241001000000 is Failed, not Installed",
+			},
+		},
+		{
+			name:   "degraded air gap storage",
+			airgap: true,
+			objects: []client.Object{
+				readyNode("node1"),
+				previous,
+				&appsv1.Deployment{
+					ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: runtimeconfig.RegistryNamespace},
+					Spec:       appsv1.DeploymentSpec{Replicas: ptr.To[int32](2)},
+					Status:     appsv1.DeploymentStatus{ReadyReplicas: 1},
+				},
+				&appsv1.StatefulSet{
+					ObjectMeta: metav1.ObjectMeta{Name: "seaweedfs-master", Namespace: runtimeconfig.SeaweedFSNamespace},
+					Spec:       appsv1.StatefulSetSpec{Replicas: ptr.To[int32](3)},
+					Status:     appsv1.StatefulSetStatus{ReadyReplicas: 3},
+				},
+				&appsv1.StatefulSet{
+					ObjectMeta: metav1.ObjectMeta{Name: "seaweedfs-volume", Namespace: runtimeconfig.SeaweedFSNamespace},
+					Spec:       appsv1.StatefulSetSpec{Replicas: ptr.To[int32](3)},
+					Status:     appsv1.StatefulSetStatus{ReadyReplicas: 0},
+				},
+			},
+			freeDisk: 10 << 30,
+			failures: []string{
+				"deployment registry/registry is degraded (1/2 replicas ready)",
+				"statefulset seaweedfs/seaweedfs-volume is degraded (0/3 replicas ready)",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			in := newInstallation(tt.airgap)
+			cli := fake.NewClientBuilder().
+				WithScheme(kubeutils.Scheme).
+				WithObjects(append(tt.objects, in)...).
+				WithStatusSubresource(in).
+				Build()
+
+			newNodeFreeDiskBytes = func() (func(context.Context, string) (uint64, error), error) {
+				return func(ctx context.Context, node string) (uint64, error) {
+					return tt.freeDisk, nil
+				}, nil
+			}
+			t.Cleanup(func() { newNodeFreeDiskBytes = newKubeletNodeFreeDiskBytes })
+
+			err := RunPreflights(ctx, cli, in)
+
+			var got ecv1beta1.Installation
+			require.NoError(t, cli.Get(ctx, client.ObjectKeyFromObject(in), &got))
+			cond := meta.FindStatusCondition(got.Status.Conditions, ecv1beta1.ConditionTypePreflightFailed)
+			require.NotNil(t, cond)
+
+			if len(tt.failures) == 0 {
+				require.NoError(t, err)
+				assert.Equal(t, metav1.ConditionFalse, cond.Status)
+				return
+			}
+
+			var perr ErrPreflightFailed
+			require.True(t, errors.As(err, &perr))
+			assert.Equal(t, tt.failures, perr.Failures)
+			assert.Equal(t, metav1.ConditionTrue, cond.Status)
+			assert.Equal(t, err.Error(), cond.Message)
+		})
+	}
+}
+
+func Test_minFreeDiskBytes(t *testing.T) {
+	minFreeDiskSpace := resource.MustParse("10Gi")
+	tests := []struct {
+		name string
+		spec ecv1beta1.InstallationSpec
+		want uint64
+	}{
+		{
+			name: "online default",
+			want: 2 << 30,
+		},
+		{
+			name: "air gap default",
+			spec: ecv1beta1.InstallationSpec{AirGap: true},
+			want: 5 << 30,
+		},
+		{
+			name: "set in the upgrade strategy",
+			spec: ecv1beta1.InstallationSpec{
+				AirGap:          true,
+				UpgradeStrategy: &ecv1beta1.UpgradeStrategy{MinFreeDiskSpace: &minFreeDiskSpace},
+			},
+			want: 10 << 30,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, minFreeDiskBytes(&ecv1beta1.Installation{Spec: tt.spec}))
+		})
+	}
+}
@@ -0,0 +1,192 @@
+package upgrade
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/operator/pkg/release"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/registry"
+	"github.com/replicatedhq/embedded-cluster/pkg/artifacts"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// defaultKeepReleases is the number of releases whose images are kept in the registry when
+// the installation does not define a retention policy, the current and the previous one.
+const defaultKeepReleases = 2
+
+// garbageCollectRegistry removes from the registry the embedded cluster images not referenced
+// by the releases retained by the installation garbage collection policy nor used by any pod
+// and then runs the registry garbage collector to free the space used by their blobs. The
+// application repositories are never pruned. The outcome is reported as a condition. External
+// registries are not garbage collected.
+func garbageCollectRegistry(ctx context.Context, cli client.Client, in *ecv1beta1.Installation) error {
+	if in.Spec.ExternalRegistry != nil {
+		slog.Info("Skipping registry garbage collection, the registry is not managed by the cluster")
+		return nil
+	}
+	policy := ecv1beta1.RegistryGarbageCollection{}
+	if in.Spec.RegistryGarbageCollection != nil {
+		policy = *in.Spec.RegistryGarbageCollection
+	}
+	if policy.Disabled {
+		slog.Info("Registry garbage collection is disabled")
+		return nil
+	}
+	keepReleases := policy.KeepReleases
+	if keepReleases <= 0 {
+		keepReleases = defaultKeepReleases
+	}
+
+	retained, err := retainedInstallations(ctx, cli, in, keepReleases)
+	if err != nil {
+		return fmt.Errorf("get retained installations: %w", err)
+	}
+	images, err := releaseImages(ctx, cli, retained)
+	if err != nil {
+		return fmt.Errorf("get release images: %w", err)
+	}
+	inUse, err := podImages(ctx, cli)
+	if err != nil {
+		return fmt.Errorf("get images in use: %w", err)
+	}
+	keep := append(slices.Clone(images), inUse...)
+
+	serviceCIDR := ""
+	if in.Spec.Network != nil {
+		serviceCIDR = in.Spec.Network.ServiceCIDR
+	}
+	registryIP, err := registry.GetRegistryClusterIP(serviceCIDR)
+	if err != nil {
+		return fmt.Errorf("get registry cluster ip: %w", err)
+	}
+	addr := fmt.Sprintf("%s:5000", registryIP)
+
+	slog.Info("Pruning registry images", "releases", len(retained), "keep", len(keep), "dryRun", policy.DryRun)
+	// the application images are pushed through the admin console as well, we can not tell
+	// which ones are still needed so only the embedded cluster repositories are pruned.
+	opts := artifacts.PruneRegistryOptions{DryRun: policy.DryRun, Repositories: images}
+	pruned, err := artifacts.PruneRegistry(ctx, cli, addr, keep, opts)
+	if err != nil {
+		// some versions of the registry were deployed without tls.
+		opts.PlainHTTP = true
+		if pruned, err = artifacts.PruneRegistry(ctx, cli, addr, keep, opts); err != nil {
+			return fmt.Errorf("prune registry: %w", err)
+		}
+	}
+	for _, image := range pruned {
+		slog.Info("Pruned registry image", "image", image, "dryRun", policy.DryRun)
+	}
+
+	if !policy.DryRun && len(pruned) > 0 {
+		var out bytes.Buffer
+		if err := registry.GarbageCollect(ctx, cli, &out); err != nil {
+			return fmt.Errorf("registry garbage collect: %w", err)
+		}
+		slog.Debug("Registry garbage collected", "output", out.String())
+	}
+
+	return setRegistryGarbageCollectedCondition(ctx, cli, in, pruned, policy.DryRun)
+}
+
+// retainedInstallations returns the installations whose embedded cluster images are kept in
+// the registry, the most recent installation of each one of the last keepReleases embedded
+// cluster versions, starting with the provided one.
+func retainedInstallations(ctx context.Context, cli client.Client, in *ecv1beta1.Installation, keepReleases int) ([]ecv1beta1.Installation, error) {
+	installations, err := kubeutils.ListInstallations(ctx, cli)
+	if err != nil {
+		return nil, fmt.Errorf("list installations: %w", err)
+	}
+
+	retained := []ecv1beta1.Installation{*in}
+	versions := []string{installationVersion(in)}
+	for _, installation := range installations {
+		if len(retained) == keepReleases {
+			break
+		}
+		version := installationVersion(&installation)
+		if version == "" || slices.Contains(versions, version) || installation.Name > in.Name {
+			continue
+		}
+		retained = append(retained, installation)
+		versions = append(versions, version)
+	}
+	return retained, nil
+}
+
+// releaseImages returns the images referenced by the retained installations, the embedded
+// cluster images in their release metadata and the airgap artifacts.
+func releaseImages(ctx context.Context, cli client.Client, retained []ecv1beta1.Installation) ([]string, error) {
+	images := []string{}
+	for _, installation := range retained {
+		meta, err := release.MetadataFor(ctx, &installation, cli)
+		if err != nil {
+			return nil, fmt.Errorf("get release metadata for installation %s: %w", installation.Name, err)
+		} else if meta == nil {
+			return nil, fmt.Errorf("no release metadata found for installation %s", installation.Name)
+		}
+		images = append(images, meta.Images...)
+
+		if a := installation.Spec.Artifacts; a != nil {
+			images = append(images, a.Images, a.HelmCharts, a.EmbeddedClusterBinary, a.EmbeddedClusterMetadata)
+			for _, artifact := range a.AdditionalArtifacts {
+				images = append(images, artifact)
+			}
+		}
+	}
+	return compactImages(images), nil
+}
+
+// podImages returns the images used by the containers of any pod in the cluster.
+func podImages(ctx context.Context, cli client.Client) ([]string, error) {
+	var pods corev1.PodList
+	if err := cli.List(ctx, &pods); err != nil {
+		return nil, fmt.Errorf("list pods: %w", err)
+	}
+	images := []string{}
+	for _, pod := range pods.Items {
+		for _, container := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
+			images = append(images, container.Image)
+		}
+	}
+	return compactImages(images), nil
+}
+
+func compactImages(images []string) []string {
+	images = slices.DeleteFunc(images, func(image string) bool { return image == "" })
+	slices.Sort(images)
+	return slices.Compact(images)
+}
+
+func setRegistryGarbageCollectedCondition(ctx context.Context, cli client.Client, in *ecv1beta1.Installation, pruned []string, dryRun bool) error {
+	reason := "ImagesPruned"
+	message := fmt.Sprintf("%d images removed from the registry.", len(pruned))
+	if dryRun {
+		reason = "DryRun"
+		message = fmt.Sprintf("%d images would be removed from the registry.", len(pruned))
+		if len(pruned) > 0 {
+			message = fmt.Sprintf("%s Images: %s.", message, strings.Join(pruned, ", "))
+		}
+	}
+
+	return kubeutils.SetInstallationConditionStatus(ctx, cli, in, metav1.Condition{
+		Type:    ecv1beta1.ConditionTypeRegistryGarbageCollected,
+		Status:  metav1.ConditionTrue,
+		Reason:  reason,
+		Message: message,
+	})
+}
+
+func installationVersion(in *ecv1beta1.Installation) string {
+	if in.Spec.Config == nil {
+		return ""
+	}
+	return in.Spec.Config.Version
+}
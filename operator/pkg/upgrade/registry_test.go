@@ -0,0 +1,147 @@
+package upgrade
+
+import (
+	"context"
+	"testing"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	ectypes "github.com/replicatedhq/embedded-cluster/kinds/types"
+	"github.com/replicatedhq/embedded-cluster/operator/pkg/release"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newRegistryTestInstallation(name, version string) *ecv1beta1.Installation {
+	return &ecv1beta1.Installation{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: ecv1beta1.InstallationSpec{
+			Config: &ecv1beta1.ConfigSpec{Version: version},
+		},
+	}
+}
+
+func Test_retainedInstallations(t *testing.T) {
+	installations := []client.Object{
+		newRegistryTestInstallation("20241001000000", "1.0.0"),
+		newRegistryTestInstallation("20241002000000", "2.0.0"),
+		newRegistryTestInstallation("20241003000000", "2.0.0"),
+		newRegistryTestInstallation("20241004000000", "3.0.0"),
+		newRegistryTestInstallation("20241005000000", "4.0.0"),
+	}
+	in := installations[3].(*ecv1beta1.Installation)
+
+	tests := []struct {
+		name         string
+		keepReleases int
+		want         []string
+	}{
+		{
+			name:         "current release only",
+			keepReleases: 1,
+			want:         []string{"20241004000000"},
+		},
+		{
+			name:         "current and previous release",
+			keepReleases: 2,
+			want:         []string{"20241004000000", "20241003000000"},
+		},
+		{
+			name:         "more releases than installed",
+			keepReleases: 5,
+			want:         []string{"20241004000000", "20241003000000", "20241001000000"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cli := fake.NewClientBuilder().WithScheme(kubeutils.Scheme).WithObjects(installations...).Build()
+
+			retained, err := retainedInstallations(context.Background(), cli, in, tt.keepReleases)
+			require.NoError(t, err)
+
+			names := []string{}
+			for _, installation := range retained {
+				names = append(names, installation.Name)
+			}
+			assert.Equal(t, tt.want, names)
+		})
+	}
+}
+
+func Test_releaseImages(t *testing.T) {
+	release.CacheMeta("1.0.0", ectypes.ReleaseMetadata{Images: []string{"ec/operator:1.0.0", "ec/registry:2.8"}})
+	release.CacheMeta("2.0.0", ectypes.ReleaseMetadata{Images: []string{"ec/operator:2.0.0", "ec/registry:2.8"}})
+
+	previous := newRegistryTestInstallation("20241001000000", "1.0.0")
+	current := newRegistryTestInstallation("20241002000000", "2.0.0")
+	current.Spec.Artifacts = &ecv1beta1.ArtifactsLocation{
+		Images:                  "10.96.0.11:5000/embedded-cluster/images:2.0.0",
+		HelmCharts:              "10.96.0.11:5000/embedded-cluster/charts:2.0.0",
+		EmbeddedClusterBinary:   "10.96.0.11:5000/embedded-cluster/binary:2.0.0",
+		EmbeddedClusterMetadata: "10.96.0.11:5000/embedded-cluster/metadata:2.0.0",
+	}
+	cli := fake.NewClientBuilder().WithScheme(kubeutils.Scheme).Build()
+
+	images, err := releaseImages(context.Background(), cli, []ecv1beta1.Installation{*current, *previous})
+	require.NoError(t, err)
+	assert.Equal(t, []string{
+		"10.96.0.11:5000/embedded-cluster/binary:2.0.0",
+		"10.96.0.11:5000/embedded-cluster/charts:2.0.0",
+		"10.96.0.11:5000/embedded-cluster/images:2.0.0",
+		"10.96.0.11:5000/embedded-cluster/metadata:2.0.0",
+		"ec/operator:1.0.0",
+		"ec/operator:2.0.0",
+		"ec/registry:2.8",
+	}, images)
+}
+
+func Test_podImages(t *testing.T) {
+	pods := []client.Object{
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "legacy", Namespace: "default"},
+			Spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{{Name: "init", Image: "app/migrations:0.9.0"}},
+				Containers:     []corev1.Container{{Name: "main", Image: "app/api:1.0.0"}},
+			},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "default"},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "main", Image: "app/api:1.0.0"}},
+			},
+		},
+	}
+	cli := fake.NewClientBuilder().WithScheme(kubeutils.Scheme).WithObjects(pods...).Build()
+
+	images, err := podImages(context.Background(), cli)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app/api:1.0.0", "app/migrations:0.9.0"}, images)
+}
+
+func Test_garbageCollectRegistry_disabled(t *testing.T) {
+	in := newRegistryTestInstallation("20241002000000", "2.0.0")
+	in.Spec.RegistryGarbageCollection = &ecv1beta1.RegistryGarbageCollection{Disabled: true}
+	cli := fake.NewClientBuilder().WithScheme(kubeutils.Scheme).WithObjects(in).WithStatusSubresource(in).Build()
+
+	require.NoError(t, garbageCollectRegistry(context.Background(), cli, in))
+
+	var got ecv1beta1.Installation
+	require.NoError(t, cli.Get(context.Background(), client.ObjectKeyFromObject(in), &got))
+	assert.Empty(t, got.Status.Conditions)
+}
+
+func Test_garbageCollectRegistry_externalRegistry(t *testing.T) {
+	in := newRegistryTestInstallation("20241002000000", "2.0.0")
+	in.Spec.ExternalRegistry = &ecv1beta1.ExternalRegistrySpec{Address: "harbor.example.com"}
+	cli := fake.NewClientBuilder().WithScheme(kubeutils.Scheme).WithObjects(in).WithStatusSubresource(in).Build()
+
+	require.NoError(t, garbageCollectRegistry(context.Background(), cli, in))
+
+	var got ecv1beta1.Installation
+	require.NoError(t, cli.Get(context.Background(), client.ObjectKeyFromObject(in), &got))
+	assert.Empty(t, got.Status.Conditions)
+}
@@ -0,0 +1,432 @@
+package upgrade
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/operator/pkg/artifacts"
+	"github.com/replicatedhq/embedded-cluster/operator/pkg/release"
+	"github.com/replicatedhq/embedded-cluster/operator/pkg/util"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/types"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"github.com/replicatedhq/embedded-cluster/pkg/helpers"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const upgradeSnapshotPrefix = "upgrade-snapshot-"
+
+// ReleaseSnapshot holds the revision a helm release had before the upgrade started. A zero
+// revision means the release did not exist.
+type ReleaseSnapshot struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Revision  int    `json:"revision"`
+}
+
+// String returns the release as namespace/name.
+func (r ReleaseSnapshot) String() string {
+	return fmt.Sprintf("%s/%s", r.Namespace, r.Name)
+}
+
+// Snapshot is the state of the cluster captured before an upgrade starts. It is used to roll
+// the upgrade back if it fails.
+type Snapshot struct {
+	// Releases holds the revisions of the addons and extensions releases, in upgrade order.
+	Releases []ReleaseSnapshot `json:"releases"`
+	// KubeletVersion is the version reported by the nodes before the upgrade.
+	KubeletVersion string `json:"kubeletVersion,omitempty"`
+	// PreviousSpec is the spec of the installation we are upgrading from.
+	PreviousSpec *ecv1beta1.InstallationSpec `json:"previousSpec,omitempty"`
+}
+
+// RollbackResult holds what could and what could not be rolled back.
+type RollbackResult struct {
+	RolledBack    []string
+	NotRolledBack []string
+}
+
+// SnapshotReleases records the state needed to roll back the upgrade to the provided
+// installation: the revision of the helm release of every addon and extension, the kubelet
+// version and the spec of the previous installation. If a snapshot already exists for the
+// installation, e.g. because the upgrade job is being retried, it is kept as is.
+func SnapshotReleases(ctx context.Context, cli client.Client, hcli helm.Client, in *ecv1beta1.Installation) error {
+	existing, err := getSnapshot(ctx, cli, in)
+	if err != nil {
+		return fmt.Errorf("get existing snapshot: %w", err)
+	} else if existing != nil {
+		slog.Info("Upgrade snapshot already exists", "installation", in.Name)
+		return nil
+	}
+
+	previous, err := previousInstallation(ctx, cli, in)
+	if err != nil {
+		return fmt.Errorf("get previous installation: %w", err)
+	}
+	var previousSpec *ecv1beta1.InstallationSpec
+	if previous != nil {
+		previousSpec = previous.Spec.DeepCopy()
+	}
+	// the addons are computed as the upgrade computes them.
+	in = inheritExternalRegistry(in, previous)
+
+	meta, err := release.MetadataFor(ctx, in, cli)
+	if err != nil {
+		return fmt.Errorf("get release metadata: %w", err)
+	}
+	addOns, err := addons.GetAddOnsForUpgrade(in, meta)
+	if err != nil {
+		return fmt.Errorf("get addons for upgrade: %w", err)
+	}
+	// addons removed by the upgrade are recorded too so the rollback reports them.
+	removed, err := addons.GetAddOnsForRemoval(previous, in, meta)
+	if err != nil {
+		return fmt.Errorf("get addons for removal: %w", err)
+	}
+	addOns = append(removed, addOns...)
+
+	releases, err := snapshotReleases(ctx, hcli, addOns, upgradeExtensionCharts(previous, in))
+	if err != nil {
+		return err
+	}
+
+	var nodes corev1.NodeList
+	if err := cli.List(ctx, &nodes); err != nil {
+		return fmt.Errorf("list nodes: %w", err)
+	}
+	kubeletVersion := ""
+	if len(nodes.Items) > 0 {
+		kubeletVersion = nodes.Items[0].Status.NodeInfo.KubeletVersion
+	}
+
+	snapshot := Snapshot{
+		Releases:       releases,
+		KubeletVersion: kubeletVersion,
+		PreviousSpec:   previousSpec,
+	}
+	if err := createSnapshot(ctx, cli, in, snapshot); err != nil {
+		return fmt.Errorf("create snapshot: %w", err)
+	}
+
+	slog.Info("Upgrade snapshot created", "installation", in.Name, "releases", len(releases))
+	return nil
+}
+
+// Rollback rolls back a failed upgrade to the state recorded by SnapshotReleases. Helm
+// releases are rolled back to their previous revisions (or uninstalled if they were created
+// by the upgrade), the addons of the previous installation whose releases were removed by the
+// upgrade are re-applied and the installation spec is restored to the previous one. Kubernetes
+// (k0s) itself can not be rolled back. What could and could not be rolled back is recorded in
+// the installation UpgradeRolledBack condition.
+func Rollback(ctx context.Context, cli client.Client, hcli helm.Client, in *ecv1beta1.Installation) (*RollbackResult, error) {
+	snapshot, err := getSnapshot(ctx, cli, in)
+	if err != nil {
+		return nil, fmt.Errorf("get snapshot: %w", err)
+	} else if snapshot == nil {
+		return nil, fmt.Errorf("no upgrade snapshot found for installation %s", in.Name)
+	}
+
+	slog.Info("Rolling back upgrade", "installation", in.Name)
+
+	result, removed := rollbackReleases(ctx, hcli, snapshot.Releases)
+	reapplyRemovedReleases(ctx, cli, hcli, in, snapshot.PreviousSpec, removed, result)
+
+	if snapshot.KubeletVersion != "" {
+		match, err := clusterNodesMatchVersion(ctx, cli, snapshot.KubeletVersion)
+		if err != nil {
+			result.NotRolledBack = append(result.NotRolledBack, fmt.Sprintf("kubernetes: %s", helpers.CleanErrorMessage(err)))
+		} else if !match {
+			result.NotRolledBack = append(result.NotRolledBack, fmt.Sprintf("kubernetes: upgraded from %s and can not be rolled back", snapshot.KubeletVersion))
+		}
+	}
+
+	if snapshot.PreviousSpec != nil {
+		err := kubeutils.UpdateInstallation(ctx, cli, in, func(in *ecv1beta1.Installation) {
+			in.Spec = *snapshot.PreviousSpec.DeepCopy()
+		})
+		if err != nil {
+			result.NotRolledBack = append(result.NotRolledBack, fmt.Sprintf("installation spec: %s", helpers.CleanErrorMessage(err)))
+		} else {
+			result.RolledBack = append(result.RolledBack, "installation spec")
+		}
+	}
+
+	for _, item := range result.RolledBack {
+		kubeutils.RecordInstallationEvent(ctx, cli, in, corev1.EventTypeNormal, "RolledBack", fmt.Sprintf("Rolled back %s", item))
+	}
+	for _, item := range result.NotRolledBack {
+		kubeutils.RecordInstallationEvent(ctx, cli, in, corev1.EventTypeWarning, "RollbackFailed", fmt.Sprintf("Could not roll back %s", item))
+	}
+
+	if err := setRollbackCondition(ctx, cli, in, result); err != nil {
+		return result, fmt.Errorf("set rollback condition: %w", err)
+	}
+
+	if err := DeleteSnapshot(ctx, cli, in); err != nil {
+		slog.Error("Failed to delete upgrade snapshot", "error", err)
+	}
+
+	return result, nil
+}
+
+// SnapshotExists returns true if a snapshot was already taken for the upgrade to the provided
+// installation, meaning the upgrade has already started.
+func SnapshotExists(ctx context.Context, cli client.Client, in *ecv1beta1.Installation) (bool, error) {
+	snapshot, err := getSnapshot(ctx, cli, in)
+	if err != nil {
+		return false, err
+	}
+	return snapshot != nil, nil
+}
+
+// DeleteSnapshot removes the snapshot taken for the upgrade to the provided installation.
+func DeleteSnapshot(ctx context.Context, cli client.Client, in *ecv1beta1.Installation) error {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      snapshotConfigMapName(in),
+			Namespace: runtimeconfig.KotsadmNamespace,
+		},
+	}
+	if err := cli.Delete(ctx, cm); err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("delete snapshot config map: %w", err)
+	}
+	return nil
+}
+
+// previousInstallation returns the installation we are upgrading from or nil if there is none.
+func previousInstallation(ctx context.Context, cli client.Client, in *ecv1beta1.Installation) (*ecv1beta1.Installation, error) {
+	previous, err := kubeutils.GetPreviousInstallation(ctx, cli, in)
+	if errors.Is(err, kubeutils.ErrInstallationNotFound{}) {
+		return nil, nil
+	}
+	return previous, err
+}
+
+// snapshotReleases returns the current revision of the releases of the provided addons and
+// extension charts.
+func snapshotReleases(ctx context.Context, hcli helm.Client, addOns []types.AddOn, charts []ecv1beta1.Chart) ([]ReleaseSnapshot, error) {
+	releases := []ReleaseSnapshot{}
+	for _, addon := range addOns {
+		releases = append(releases, ReleaseSnapshot{Name: addon.ReleaseName(), Namespace: addon.Namespace()})
+	}
+	for _, chart := range charts {
+		releases = append(releases, ReleaseSnapshot{Name: chart.Name, Namespace: chart.TargetNS})
+	}
+
+	for i := range releases {
+		revision, err := hcli.ReleaseRevision(ctx, releases[i].Namespace, releases[i].Name)
+		if err != nil {
+			return nil, fmt.Errorf("get revision for release %s: %w", releases[i], err)
+		}
+		releases[i].Revision = revision
+	}
+	return releases, nil
+}
+
+// rollbackReleases rolls the releases back, in reverse upgrade order, to the revisions in the
+// snapshot. Releases created during the upgrade are uninstalled. Releases removed during the
+// upgrade can not be rolled back as their history is gone, they are returned apart.
+func rollbackReleases(ctx context.Context, hcli helm.Client, releases []ReleaseSnapshot) (*RollbackResult, []ReleaseSnapshot) {
+	result := &RollbackResult{}
+	var removed []ReleaseSnapshot
+	for i := len(releases) - 1; i >= 0; i-- {
+		rel := releases[i]
+
+		current, err := hcli.ReleaseRevision(ctx, rel.Namespace, rel.Name)
+		if err != nil {
+			result.NotRolledBack = append(result.NotRolledBack, fmt.Sprintf("%s: %s", rel, helpers.CleanErrorMessage(err)))
+			continue
+		}
+
+		switch {
+		case current == rel.Revision:
+			continue
+
+		case rel.Revision == 0:
+			slog.Info("Uninstalling release created during the upgrade", "release", rel.String())
+			err := hcli.Uninstall(ctx, helm.UninstallOptions{
+				ReleaseName:    rel.Name,
+				Namespace:      rel.Namespace,
+				Wait:           true,
+				IgnoreNotFound: true,
+			})
+			if err != nil {
+				result.NotRolledBack = append(result.NotRolledBack, fmt.Sprintf("%s: %s", rel, helpers.CleanErrorMessage(err)))
+				continue
+			}
+			result.RolledBack = append(result.RolledBack, fmt.Sprintf("%s: uninstalled", rel))
+
+		case current == 0:
+			removed = append(removed, rel)
+
+		default:
+			slog.Info("Rolling back release", "release", rel.String(), "from", current, "to", rel.Revision)
+			err := hcli.Rollback(ctx, helm.RollbackOptions{
+				ReleaseName: rel.Name,
+				Namespace:   rel.Namespace,
+				Revision:    rel.Revision,
+			})
+			if err != nil {
+				result.NotRolledBack = append(result.NotRolledBack, fmt.Sprintf("%s: %s", rel, helpers.CleanErrorMessage(err)))
+				continue
+			}
+			result.RolledBack = append(result.RolledBack, fmt.Sprintf("%s: revision %d", rel, rel.Revision))
+		}
+	}
+	return result, removed
+}
+
+// reapplyRemovedReleases re-applies the addons of the previous installation whose releases
+// were removed during the upgrade. Releases that do not belong to an addon of the previous
+// installation, e.g. extensions, can not be restored.
+func reapplyRemovedReleases(ctx context.Context, cli client.Client, hcli helm.Client, in *ecv1beta1.Installation, previousSpec *ecv1beta1.InstallationSpec, removed []ReleaseSnapshot, result *RollbackResult) {
+	if len(removed) == 0 {
+		return
+	}
+
+	var previous *ecv1beta1.Installation
+	var prevAddOns []types.AddOn
+	var err error
+	if previousSpec != nil {
+		previous = in.DeepCopy()
+		previous.Spec = *previousSpec.DeepCopy()
+		prevAddOns, err = previousAddOns(ctx, cli, previous)
+	}
+
+	for _, rel := range removed {
+		idx := slices.IndexFunc(prevAddOns, func(addon types.AddOn) bool {
+			return addon.Namespace() == rel.Namespace && addon.ReleaseName() == rel.Name
+		})
+		switch {
+		case err != nil:
+			result.NotRolledBack = append(result.NotRolledBack, fmt.Sprintf("%s: %s", rel, helpers.CleanErrorMessage(err)))
+
+		case idx < 0:
+			result.NotRolledBack = append(result.NotRolledBack, fmt.Sprintf("%s: release was removed during the upgrade", rel))
+
+		default:
+			slog.Info("Re-applying release removed during the upgrade", "release", rel.String())
+			if err := addons.Reapply(ctx, hcli, cli, previous, prevAddOns[idx]); err != nil {
+				result.NotRolledBack = append(result.NotRolledBack, fmt.Sprintf("%s: %s", rel, helpers.CleanErrorMessage(err)))
+				continue
+			}
+			result.RolledBack = append(result.RolledBack, fmt.Sprintf("%s: re-applied", rel))
+		}
+	}
+}
+
+// previousAddOns returns the addons managed for the previous installation, rebuilt from the
+// spec recorded in the snapshot.
+func previousAddOns(ctx context.Context, cli client.Client, previous *ecv1beta1.Installation) ([]types.AddOn, error) {
+	meta, err := release.MetadataFor(ctx, previous, cli)
+	if err != nil {
+		return nil, fmt.Errorf("get previous release metadata: %w", err)
+	} else if meta == nil {
+		return nil, fmt.Errorf("no release metadata found for the previous installation")
+	}
+	return addons.GetAddOnsForUpgrade(previous, meta)
+}
+
+// upgradeExtensionCharts returns the extension charts present in either the previous or the
+// new installation, in the order they are processed during the upgrade.
+func upgradeExtensionCharts(previous, in *ecv1beta1.Installation) []ecv1beta1.Chart {
+	var charts []ecv1beta1.Chart
+	seen := map[string]bool{}
+	for _, i := range []*ecv1beta1.Installation{previous, in} {
+		if i == nil || i.Spec.Config == nil || i.Spec.Config.Extensions.Helm == nil {
+			continue
+		}
+		for _, chart := range i.Spec.Config.Extensions.Helm.Charts {
+			key := chart.TargetNS + "/" + chart.Name
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			charts = append(charts, chart)
+		}
+	}
+	return charts
+}
+
+func setRollbackCondition(ctx context.Context, cli client.Client, in *ecv1beta1.Installation, result *RollbackResult) error {
+	status, reason := metav1.ConditionTrue, "RolledBack"
+	if len(result.NotRolledBack) > 0 {
+		status, reason = metav1.ConditionFalse, "PartiallyRolledBack"
+	}
+
+	var message []string
+	if len(result.RolledBack) > 0 {
+		message = append(message, fmt.Sprintf("Rolled back: %s.", strings.Join(result.RolledBack, ", ")))
+	}
+	if len(result.NotRolledBack) > 0 {
+		message = append(message, fmt.Sprintf("Not rolled back: %s.", strings.Join(result.NotRolledBack, ", ")))
+	}
+	if len(message) == 0 {
+		message = append(message, "Nothing to roll back.")
+	}
+
+	return kubeutils.SetInstallationConditionStatus(ctx, cli, in, metav1.Condition{
+		Type:    ecv1beta1.ConditionTypeUpgradeRolledBack,
+		Status:  status,
+		Reason:  reason,
+		Message: strings.Join(message, " "),
+	})
+}
+
+func snapshotConfigMapName(in *ecv1beta1.Installation) string {
+	return util.NameWithLengthLimit(upgradeSnapshotPrefix, in.Name)
+}
+
+func getSnapshot(ctx context.Context, cli client.Client, in *ecv1beta1.Installation) (*Snapshot, error) {
+	var cm corev1.ConfigMap
+	nsn := client.ObjectKey{Name: snapshotConfigMapName(in), Namespace: runtimeconfig.KotsadmNamespace}
+	if err := cli.Get(ctx, nsn, &cm); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get snapshot config map: %w", err)
+	}
+
+	var snapshot Snapshot
+	if err := json.Unmarshal([]byte(cm.Data["snapshot.json"]), &snapshot); err != nil {
+		return nil, fmt.Errorf("unmarshal snapshot: %w", err)
+	}
+	return &snapshot, nil
+}
+
+func createSnapshot(ctx context.Context, cli client.Client, in *ecv1beta1.Installation, snapshot Snapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("marshal snapshot: %w", err)
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      snapshotConfigMapName(in),
+			Namespace: runtimeconfig.KotsadmNamespace,
+			Labels: map[string]string{
+				"app.kubernetes.io/name":      JobNameLabel,
+				"app.kubernetes.io/component": "snapshot",
+			},
+			Annotations: map[string]string{
+				artifacts.InstallationNameAnnotation: in.Name,
+			},
+		},
+		Data: map[string]string{"snapshot.json": string(data)},
+	}
+	if err := cli.Create(ctx, cm); err != nil {
+		return fmt.Errorf("create snapshot config map: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package upgrade
+
+import (
+	"context"
+	"testing"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	ectypes "github.com/replicatedhq/embedded-cluster/kinds/types"
+	"github.com/replicatedhq/embedded-cluster/operator/pkg/release"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_rollbackReleases(t *testing.T) {
+	ctx := context.Background()
+	releases := []ReleaseSnapshot{
+		{Name: "openebs", Namespace: "openebs", Revision: 2},
+		{Name: "velero", Namespace: "velero", Revision: 0},
+		{Name: "admin-console", Namespace: "kotsadm", Revision: 5},
+		{Name: "removed", Namespace: "ext", Revision: 1},
+	}
+
+	hcli := &helm.MockClient{}
+	hcli.On("ReleaseRevision", mock.Anything, "ext", "removed").Return(0, nil)
+	hcli.On("ReleaseRevision", mock.Anything, "kotsadm", "admin-console").Return(5, nil)
+	hcli.On("ReleaseRevision", mock.Anything, "velero", "velero").Return(1, nil)
+	hcli.On("ReleaseRevision", mock.Anything, "openebs", "openebs").Return(3, nil)
+	hcli.On("Uninstall", mock.Anything, helm.UninstallOptions{
+		ReleaseName: "velero", Namespace: "velero", Wait: true, IgnoreNotFound: true,
+	}).Return(nil)
+	hcli.On("Rollback", mock.Anything, helm.RollbackOptions{
+		ReleaseName: "openebs", Namespace: "openebs", Revision: 2,
+	}).Return(nil)
+
+	result, removed := rollbackReleases(ctx, hcli, releases)
+	hcli.AssertExpectations(t)
+
+	assert.Equal(t, []string{"velero/velero: uninstalled", "openebs/openebs: revision 2"}, result.RolledBack)
+	assert.Empty(t, result.NotRolledBack)
+	assert.Equal(t, []ReleaseSnapshot{{Name: "removed", Namespace: "ext", Revision: 1}}, removed)
+}
+
+func TestRollback(t *testing.T) {
+	ctx := context.Background()
+	release.CacheMeta("0.9.0", ectypes.ReleaseMetadata{
+		Configs: ecv1beta1.Helm{
+			Charts: []ecv1beta1.Chart{
+				{Name: "embedded-cluster-operator", ChartName: "replicated/embedded-cluster-operator", Version: "0.9.0"},
+			},
+		},
+		Images: []string{
+			"proxy.replicated.com/anonymous/replicated/embedded-cluster-operator-image:0.9.0",
+			"proxy.replicated.com/anonymous/replicated/ec-utils:0.9.0",
+		},
+	})
+	in := &ecv1beta1.Installation{
+		ObjectMeta: metav1.ObjectMeta{Name: "20241002205018"},
+		Spec:       ecv1beta1.InstallationSpec{Config: &ecv1beta1.ConfigSpec{Version: "2.0.0"}},
+	}
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+		Status:     corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{KubeletVersion: "v1.30.5+k0s"}},
+	}
+	cli := fake.NewClientBuilder().
+		WithScheme(kubeutils.Scheme).
+		WithObjects(in, node).
+		WithStatusSubresource(in).
+		Build()
+
+	snapshot := Snapshot{
+		Releases: []ReleaseSnapshot{
+			{Name: "openebs", Namespace: "openebs", Revision: 2},
+			{Name: "velero", Namespace: "velero", Revision: 1},
+			{Name: "removed", Namespace: "ext", Revision: 1},
+		},
+		KubeletVersion: "v1.29.9+k0s",
+		PreviousSpec: &ecv1beta1.InstallationSpec{
+			Config:      &ecv1beta1.ConfigSpec{Version: "0.9.0"},
+			LicenseInfo: &ecv1beta1.LicenseInfo{IsDisasterRecoverySupported: true},
+		},
+	}
+	require.NoError(t, createSnapshot(ctx, cli, in, snapshot))
+
+	hcli := &helm.MockClient{}
+	hcli.On("ReleaseRevision", mock.Anything, "openebs", "openebs").Return(3, nil)
+	hcli.On("Rollback", mock.Anything, helm.RollbackOptions{
+		ReleaseName: "openebs", Namespace: "openebs", Revision: 2,
+	}).Return(nil)
+	// velero was removed by the upgrade, it is re-applied from the previous installation.
+	hcli.On("ReleaseRevision", mock.Anything, "velero", "velero").Return(0, nil)
+	hcli.On("ReleaseExists", mock.Anything, "velero", "velero").Return(false, nil)
+	hcli.On("Install", mock.Anything, mock.MatchedBy(func(opts helm.InstallOptions) bool {
+		return opts.ReleaseName == "velero" && opts.Namespace == "velero"
+	})).Return(nil, nil)
+	hcli.On("ReleaseRevision", mock.Anything, "ext", "removed").Return(0, nil)
+
+	result, err := Rollback(ctx, cli, hcli, in)
+	require.NoError(t, err)
+	hcli.AssertExpectations(t)
+
+	assert.Equal(t, []string{"openebs/openebs: revision 2", "velero/velero: re-applied", "installation spec"}, result.RolledBack)
+	assert.Equal(t, []string{
+		"ext/removed: release was removed during the upgrade",
+		"kubernetes: upgraded from v1.29.9+k0s and can not be rolled back",
+	}, result.NotRolledBack)
+
+	// the installation spec is restored to the previous one.
+	var got ecv1beta1.Installation
+	require.NoError(t, cli.Get(ctx, client.ObjectKeyFromObject(in), &got))
+	assert.Equal(t, "0.9.0", got.Spec.Config.Version)
+	assert.True(t, got.Spec.LicenseInfo.IsDisasterRecoverySupported)
+
+	cond := meta.FindStatusCondition(got.Status.Conditions, ecv1beta1.ConditionTypeUpgradeRolledBack)
+	require.NotNil(t, cond)
+	assert.Equal(t, metav1.ConditionFalse, cond.Status)
+	assert.Equal(t, "PartiallyRolledBack", cond.Reason)
+
+	// the snapshot is removed once the rollback is done.
+	snap, err := getSnapshot(ctx, cli, in)
+	require.NoError(t, err)
+	assert.Nil(t, snap)
+}
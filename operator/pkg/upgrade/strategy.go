@@ -0,0 +1,315 @@
+package upgrade
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	apv1b2 "github.com/k0sproject/k0s/pkg/apis/autopilot/v1beta2"
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// defaultBatchHealthTimeout is how long we wait for a batch of nodes and the application to
+// become healthy if the upgrade strategy does not say otherwise.
+const defaultBatchHealthTimeout = 10 * time.Minute
+
+// upgradeBatch is a group of nodes upgraded by a single autopilot plan.
+type upgradeBatch struct {
+	Controllers []string
+	Workers     []string
+}
+
+// nodes returns the names of all the nodes in the batch.
+func (b upgradeBatch) nodes() []string {
+	return append(append([]string{}, b.Controllers...), b.Workers...)
+}
+
+// targets returns the autopilot plan targets for the nodes in the batch.
+func (b upgradeBatch) targets() apv1b2.PlanCommandTargets {
+	return apv1b2.PlanCommandTargets{
+		Controllers: apv1b2.PlanCommandTarget{
+			Discovery: apv1b2.PlanCommandTargetDiscovery{
+				Static: &apv1b2.PlanCommandTargetDiscoveryStatic{Nodes: b.Controllers},
+			},
+		},
+		Workers: apv1b2.PlanCommandTarget{
+			Discovery: apv1b2.PlanCommandTargetDiscovery{
+				Static: &apv1b2.PlanCommandTargetDiscoveryStatic{Nodes: b.Workers},
+			},
+		},
+	}
+}
+
+// determineUpgradeBatches splits the nodes not yet running the desired version in batches
+// according to the upgrade strategy. Without a strategy all the nodes are upgraded in a single
+// batch. Controllers are always upgraded before workers.
+func determineUpgradeBatches(ctx context.Context, cli client.Client, desiredVersion string, strategy *ecv1beta1.UpgradeStrategy) ([]upgradeBatch, error) {
+	var nodes corev1.NodeList
+	if err := cli.List(ctx, &nodes); err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	controllers := []corev1.Node{}
+	workers := []corev1.Node{}
+	for _, node := range nodes.Items {
+		if node.Status.NodeInfo.KubeletVersion == desiredVersion {
+			continue
+		}
+		if _, ok := node.Labels["node-role.kubernetes.io/control-plane"]; ok {
+			controllers = append(controllers, node)
+			continue
+		}
+		workers = append(workers, node)
+	}
+
+	if strategy == nil {
+		if len(controllers) == 0 && len(workers) == 0 {
+			return nil, nil
+		}
+		return []upgradeBatch{{Controllers: nodeNames(controllers), Workers: nodeNames(workers)}}, nil
+	}
+
+	var batches []upgradeBatch
+	for _, group := range groupNodesByLabel(controllers, strategy.OrderByLabel) {
+		for _, chunk := range chunkNodeNames(group, strategy.MaxParallelNodes) {
+			batches = append(batches, upgradeBatch{Controllers: chunk})
+		}
+	}
+	for _, group := range groupNodesByLabel(workers, strategy.OrderByLabel) {
+		for _, chunk := range chunkNodeNames(group, strategy.MaxParallelNodes) {
+			batches = append(batches, upgradeBatch{Workers: chunk})
+		}
+	}
+	return batches, nil
+}
+
+// groupNodesByLabel groups the node names by the value of the provided label, ordered by
+// the label value. Nodes without the label are returned in the last group. If no label is
+// provided all the nodes are returned in a single group.
+func groupNodesByLabel(nodes []corev1.Node, label string) [][]string {
+	if len(nodes) == 0 {
+		return nil
+	}
+	if label == "" {
+		return [][]string{nodeNames(nodes)}
+	}
+
+	groups := map[string][]corev1.Node{}
+	var unlabeled []corev1.Node
+	for _, node := range nodes {
+		value, ok := node.Labels[label]
+		if !ok {
+			unlabeled = append(unlabeled, node)
+			continue
+		}
+		groups[value] = append(groups[value], node)
+	}
+
+	values := make([]string, 0, len(groups))
+	for value := range groups {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+
+	var result [][]string
+	for _, value := range values {
+		result = append(result, nodeNames(groups[value]))
+	}
+	if len(unlabeled) > 0 {
+		result = append(result, nodeNames(unlabeled))
+	}
+	return result
+}
+
+// chunkNodeNames splits the names in chunks of at most size elements. A size of zero or less
+// returns all the names in a single chunk.
+func chunkNodeNames(names []string, size int) [][]string {
+	if size <= 0 || len(names) <= size {
+		return [][]string{names}
+	}
+	var chunks [][]string
+	for start := 0; start < len(names); start += size {
+		end := min(start+size, len(names))
+		chunks = append(chunks, names[start:end])
+	}
+	return chunks
+}
+
+func nodeNames(nodes []corev1.Node) []string {
+	names := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		names = append(names, node.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// nextMaintenanceWindow returns the time at which the next maintenance window opens. If a
+// window is currently open now is returned.
+func nextMaintenanceWindow(windows []ecv1beta1.MaintenanceWindow, now time.Time) (time.Time, error) {
+	now = now.UTC()
+	var next time.Time
+	for _, window := range windows {
+		start, err := time.Parse("15:04", window.Start)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("parse maintenance window start %q: %w", window.Start, err)
+		}
+		days, err := parseWeekdays(window.Days)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		// windows may span midnight so we also look at the one opened yesterday.
+		for offset := -1; offset <= 7; offset++ {
+			day := now.AddDate(0, 0, offset)
+			if len(days) > 0 && !days[day.Weekday()] {
+				continue
+			}
+			open := time.Date(day.Year(), day.Month(), day.Day(), start.Hour(), start.Minute(), 0, 0, time.UTC)
+			if !now.Before(open) && now.Before(open.Add(window.Duration.Duration)) {
+				return now, nil
+			}
+			if open.After(now) && (next.IsZero() || open.Before(next)) {
+				next = open
+			}
+		}
+	}
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("no maintenance window opens in the next week")
+	}
+	return next, nil
+}
+
+func parseWeekdays(days []string) (map[time.Weekday]bool, error) {
+	result := map[time.Weekday]bool{}
+	for _, day := range days {
+		found := false
+		for wd := time.Sunday; wd <= time.Saturday; wd++ {
+			if strings.EqualFold(day, wd.String()[:3]) || strings.EqualFold(day, wd.String()) {
+				result[wd] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("invalid maintenance window day %q", day)
+		}
+	}
+	return result, nil
+}
+
+// waitForMaintenanceWindow blocks until one of the maintenance windows in the installation
+// upgrade strategy is open. Returns immediately if no windows are defined. The next window may
+// be days away so the wait is surfaced in the installation state and in the
+// WaitingForMaintenanceWindow condition until the window opens.
+func waitForMaintenanceWindow(ctx context.Context, cli client.Client, in *ecv1beta1.Installation) error {
+	if in.Spec.UpgradeStrategy == nil || len(in.Spec.UpgradeStrategy.MaintenanceWindows) == 0 {
+		return nil
+	}
+
+	next, err := nextMaintenanceWindow(in.Spec.UpgradeStrategy.MaintenanceWindows, time.Now())
+	if err != nil {
+		return fmt.Errorf("determine next maintenance window: %w", err)
+	}
+	wait := time.Until(next)
+	if wait <= 0 {
+		return nil
+	}
+
+	slog.Info("Waiting for maintenance window", "opens", next)
+	reason := fmt.Sprintf("Waiting for maintenance window opening at %s", next.Format(time.RFC3339))
+	if err := kubeutils.SetInstallationState(ctx, cli, in, ecv1beta1.InstallationStateInstalling, reason, ""); err != nil {
+		return fmt.Errorf("update installation status: %w", err)
+	}
+	err = kubeutils.SetInstallationConditionStatus(ctx, cli, in, metav1.Condition{
+		Type:    ecv1beta1.ConditionTypeWaitingForMaintenanceWindow,
+		Status:  metav1.ConditionTrue,
+		Reason:  "MaintenanceWindowClosed",
+		Message: fmt.Sprintf("The upgrade resumes when the maintenance window opens at %s.", next.Format(time.RFC3339)),
+	})
+	if err != nil {
+		return fmt.Errorf("set maintenance window condition: %w", err)
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(wait):
+	}
+
+	err = kubeutils.SetInstallationConditionStatus(ctx, cli, in, metav1.Condition{
+		Type:    ecv1beta1.ConditionTypeWaitingForMaintenanceWindow,
+		Status:  metav1.ConditionFalse,
+		Reason:  "MaintenanceWindowOpen",
+		Message: "The maintenance window is open.",
+	})
+	if err != nil {
+		return fmt.Errorf("set maintenance window condition: %w", err)
+	}
+	return nil
+}
+
+// waitForBatchHealthy waits for the nodes in the batch to be ready and running the desired
+// version, and for the application workloads to be ready.
+func waitForBatchHealthy(ctx context.Context, cli client.Client, in *ecv1beta1.Installation, desiredVersion string, batch upgradeBatch) error {
+	timeout := defaultBatchHealthTimeout
+	if in.Spec.UpgradeStrategy != nil && in.Spec.UpgradeStrategy.HealthCheckTimeout != nil {
+		timeout = in.Spec.UpgradeStrategy.HealthCheckTimeout.Duration
+	}
+
+	var failures []string
+	err := wait.PollUntilContextTimeout(ctx, 5*time.Second, timeout, true, func(ctx context.Context) (bool, error) {
+		var err error
+		failures, err = batchHealthFailures(ctx, cli, desiredVersion, batch)
+		if err != nil {
+			return false, err
+		}
+		return len(failures) == 0, nil
+	})
+	if err != nil {
+		if len(failures) > 0 {
+			return fmt.Errorf("batch did not become healthy: %s", strings.Join(failures, "; "))
+		}
+		return err
+	}
+	return nil
+}
+
+// batchHealthFailures returns the reasons why the batch is not yet healthy.
+func batchHealthFailures(ctx context.Context, cli client.Client, desiredVersion string, batch upgradeBatch) ([]string, error) {
+	var failures []string
+	for _, name := range batch.nodes() {
+		var node corev1.Node
+		if err := cli.Get(ctx, client.ObjectKey{Name: name}, &node); err != nil {
+			return nil, fmt.Errorf("get node %s: %w", name, err)
+		}
+		if node.Status.NodeInfo.KubeletVersion != desiredVersion {
+			failures = append(failures, fmt.Sprintf("node %s is running %s", name, node.Status.NodeInfo.KubeletVersion))
+			continue
+		}
+		ready := false
+		for _, cond := range node.Status.Conditions {
+			if cond.Type == corev1.NodeReady && cond.Status == corev1.ConditionTrue {
+				ready = true
+			}
+		}
+		if !ready {
+			failures = append(failures, fmt.Sprintf("node %s is not ready", name))
+		}
+	}
+
+	result, err := checkWorkloadsReady(ctx, cli, runtimeconfig.KotsadmNamespace)
+	if err != nil {
+		return nil, fmt.Errorf("check application workloads: %w", err)
+	}
+	return append(failures, result...), nil
+}
@@ -0,0 +1,155 @@
+package upgrade
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_determineUpgradeBatches(t *testing.T) {
+	node := func(name, version string, controller bool, zone string) client.Object {
+		labels := map[string]string{}
+		if controller {
+			labels["node-role.kubernetes.io/control-plane"] = "true"
+		}
+		if zone != "" {
+			labels["topology.kubernetes.io/zone"] = zone
+		}
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+			Status:     corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{KubeletVersion: version}},
+		}
+	}
+	nodes := []client.Object{
+		node("controller1", "v1.29.9+k0s", true, "b"),
+		node("controller2", "v1.29.9+k0s", true, "a"),
+		node("controller3", "v1.30.5+k0s", true, "a"),
+		node("worker1", "v1.29.9+k0s", false, "b"),
+		node("worker2", "v1.29.9+k0s", false, ""),
+		node("worker3", "v1.29.9+k0s", false, "a"),
+		node("worker4", "v1.29.9+k0s", false, "a"),
+		node("worker5", "v1.29.9+k0s", false, "a"),
+	}
+
+	tests := []struct {
+		name     string
+		strategy *ecv1beta1.UpgradeStrategy
+		want     []upgradeBatch
+	}{
+		{
+			name: "no strategy upgrades all nodes at once",
+			want: []upgradeBatch{
+				{
+					Controllers: []string{"controller1", "controller2"},
+					Workers:     []string{"worker1", "worker2", "worker3", "worker4", "worker5"},
+				},
+			},
+		},
+		{
+			name:     "max parallel nodes",
+			strategy: &ecv1beta1.UpgradeStrategy{MaxParallelNodes: 2},
+			want: []upgradeBatch{
+				{Controllers: []string{"controller1", "controller2"}},
+				{Workers: []string{"worker1", "worker2"}},
+				{Workers: []string{"worker3", "worker4"}},
+				{Workers: []string{"worker5"}},
+			},
+		},
+		{
+			name:     "ordered by label",
+			strategy: &ecv1beta1.UpgradeStrategy{MaxParallelNodes: 2, OrderByLabel: "topology.kubernetes.io/zone"},
+			want: []upgradeBatch{
+				{Controllers: []string{"controller2"}},
+				{Controllers: []string{"controller1"}},
+				{Workers: []string{"worker3", "worker4"}},
+				{Workers: []string{"worker5"}},
+				{Workers: []string{"worker1"}},
+				{Workers: []string{"worker2"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cli := fake.NewClientBuilder().WithScheme(kubeutils.Scheme).WithObjects(nodes...).Build()
+			got, err := determineUpgradeBatches(context.Background(), cli, "v1.30.5+k0s", tt.strategy)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_nextMaintenanceWindow(t *testing.T) {
+	// 2024-10-02 is a wednesday.
+	now := time.Date(2024, 10, 2, 23, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		windows []ecv1beta1.MaintenanceWindow
+		want    time.Time
+		wantErr bool
+	}{
+		{
+			name: "window open now",
+			windows: []ecv1beta1.MaintenanceWindow{
+				{Start: "23:00", Duration: metav1.Duration{Duration: time.Hour}},
+			},
+			want: now,
+		},
+		{
+			name: "window opened yesterday spanning midnight",
+			windows: []ecv1beta1.MaintenanceWindow{
+				{Days: []string{"Tue"}, Start: "22:00", Duration: metav1.Duration{Duration: 26 * time.Hour}},
+			},
+			want: now,
+		},
+		{
+			name: "next window later in the week",
+			windows: []ecv1beta1.MaintenanceWindow{
+				{Days: []string{"Sat", "sunday"}, Start: "02:00", Duration: metav1.Duration{Duration: 4 * time.Hour}},
+				{Days: []string{"Fri"}, Start: "22:00", Duration: metav1.Duration{Duration: time.Hour}},
+			},
+			want: time.Date(2024, 10, 4, 22, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "same day next week",
+			windows: []ecv1beta1.MaintenanceWindow{
+				{Days: []string{"Wed"}, Start: "01:00", Duration: metav1.Duration{Duration: time.Hour}},
+			},
+			want: time.Date(2024, 10, 9, 1, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "invalid day",
+			windows: []ecv1beta1.MaintenanceWindow{
+				{Days: []string{"Funday"}, Start: "01:00", Duration: metav1.Duration{Duration: time.Hour}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid start",
+			windows: []ecv1beta1.MaintenanceWindow{
+				{Start: "1am", Duration: metav1.Duration{Duration: time.Hour}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := nextMaintenanceWindow(tt.windows, now)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -2,15 +2,19 @@ package upgrade
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"reflect"
+	"slices"
+	"strings"
 	"time"
 
 	apv1b2 "github.com/k0sproject/k0s/pkg/apis/autopilot/v1beta2"
 	k0sv1beta1 "github.com/k0sproject/k0s/pkg/apis/k0s/v1beta1"
 	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
 	ectypes "github.com/replicatedhq/embedded-cluster/kinds/types"
+	"github.com/replicatedhq/embedded-cluster/operator/pkg/artifacts"
 	"github.com/replicatedhq/embedded-cluster/operator/pkg/autopilot"
 	"github.com/replicatedhq/embedded-cluster/operator/pkg/release"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons"
@@ -21,7 +25,7 @@ import (
 	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
 	"github.com/replicatedhq/embedded-cluster/pkg/support"
 	"github.com/sirupsen/logrus"
-	"k8s.io/apimachinery/pkg/api/errors"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -38,15 +42,35 @@ func Upgrade(ctx context.Context, cli client.Client, hcli helm.Client, in *ecv1b
 		return fmt.Errorf("override installation data dirs: %w", err)
 	}
 
+	// Keep the overrides and the registry the end user provided at install time, and the
+	// registry garbage collection policy, they are persisted with the rest of the spec once the
+	// installation is re-applied.
+	previous, err := previousInstallation(ctx, cli, in)
+	if err != nil {
+		return fmt.Errorf("get previous installation: %w", err)
+	}
+	in = inheritEndUserConfig(in, previous)
+	in = inheritExternalRegistry(in, previous)
+	in = inheritRegistryGarbageCollection(in, previous)
+
+	// a new job resuming a paused upgrade starts here, the steps already completed are
+	// skipped through the installation conditions.
+	if err := checkpoint(ctx, cli, in, "pre-upgrade checks"); err != nil {
+		return err
+	}
+
 	err = upgradeK0s(ctx, cli, in)
 	if err != nil {
 		return fmt.Errorf("k0s upgrade: %w", err)
 	}
+	if err := checkpoint(ctx, cli, in, "Kubernetes"); err != nil {
+		return err
+	}
 
 	// We must update the cluster config after we upgrade k0s as it is possible that the schema
 	// between versions has changed. One drawback of this is that the sandbox (pause) image does
 	// not get updated, and possibly others but I cannot confirm this.
-	err = updateClusterConfig(ctx, cli)
+	err = updateClusterConfig(ctx, cli, in)
 	if err != nil {
 		return fmt.Errorf("cluster config update: %w", err)
 	}
@@ -80,6 +104,49 @@ func Upgrade(ctx context.Context, cli client.Client, hcli helm.Client, in *ecv1b
 		return fmt.Errorf("set installation state: %w", err)
 	}
 
+	if in.Spec.AirGap {
+		// failing to prune the artifacts does not affect the upgrade, they will be pruned
+		// after the next one.
+		if err := pruneAirgapArtifacts(ctx, cli, in); err != nil {
+			slog.Error("Failed to prune airgap artifacts", "error", err)
+		}
+		// the same goes for the images in the registry.
+		if err := garbageCollectRegistry(ctx, cli, in); err != nil {
+			slog.Error("Failed to garbage collect the registry", "error", err)
+		}
+	}
+
+	return nil
+}
+
+// pruneAirgapArtifacts creates a job in each node to remove the artifacts that are not
+// referenced by the current or the previous installation. There is nothing to prune if there
+// is no previous installation.
+func pruneAirgapArtifacts(ctx context.Context, cli client.Client, in *ecv1beta1.Installation) error {
+	previous, err := kubeutils.GetPreviousInstallation(ctx, cli, in)
+	if errors.Is(err, kubeutils.ErrInstallationNotFound{}) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("get previous installation: %w", err)
+	}
+
+	keep := []string{}
+	if in.Spec.Config != nil && in.Spec.Config.Version != "" {
+		keep = append(keep, in.Spec.Config.Version)
+	}
+	if previous.Spec.Config != nil && previous.Spec.Config.Version != "" {
+		keep = append(keep, previous.Spec.Config.Version)
+	}
+
+	image, err := localArtifactMirrorImageName(ctx, cli, in)
+	if err != nil {
+		return fmt.Errorf("get local artifact mirror image: %w", err)
+	}
+
+	slog.Info("Pruning airgap artifacts", "keep", keep)
+	if err := artifacts.EnsureCleanupJobForNodes(ctx, cli, in, image, keep); err != nil {
+		return fmt.Errorf("ensure cleanup job for nodes: %w", err)
+	}
 	return nil
 }
 
@@ -119,8 +186,96 @@ func upgradeK0s(ctx context.Context, cli client.Client, in *ecv1beta1.Installati
 		return fmt.Errorf("update installation status: %w", err)
 	}
 
+	// nodes are upgraded in batches, one autopilot plan per batch. batches are computed again
+	// after each plan so nodes upgraded by a previous attempt of the job are skipped.
+	for {
+		batches, err := determineUpgradeBatches(ctx, cli, desiredVersion, in.Spec.UpgradeStrategy)
+		if err != nil {
+			return fmt.Errorf("determine upgrade batches: %w", err)
+		}
+		if len(batches) == 0 {
+			break
+		}
+		batch := batches[0]
+
+		for _, pending := range batches[1:] {
+			if err := setNodesUpgradeStatus(ctx, cli, in, desiredVersion, ecv1beta1.NodeUpgradePhasePending, "", pending.nodes()); err != nil {
+				return fmt.Errorf("update nodes upgrade status: %w", err)
+			}
+		}
+
+		if err := waitForMaintenanceWindow(ctx, cli, in); err != nil {
+			return fmt.Errorf("wait for maintenance window: %w", err)
+		}
+
+		if in.Spec.UpgradeStrategy != nil {
+			reason := fmt.Sprintf("Upgrading Kubernetes on nodes %s (%d batches left)", strings.Join(batch.nodes(), ", "), len(batches)-1)
+			if err := kubeutils.SetInstallationState(ctx, cli, in, ecv1beta1.InstallationStateInstalling, reason, ""); err != nil {
+				return fmt.Errorf("update installation status: %w", err)
+			}
+		}
+
+		if err := setNodesUpgradeStatus(ctx, cli, in, desiredVersion, ecv1beta1.NodeUpgradePhaseUpgrading, "", batch.nodes()); err != nil {
+			return fmt.Errorf("update nodes upgrade status: %w", err)
+		}
+
+		if err := upgradeK0sBatch(ctx, cli, desiredVersion, in, meta, batch); err != nil {
+			if err := setNodesUpgradeStatus(ctx, cli, in, desiredVersion, ecv1beta1.NodeUpgradePhaseFailed, helpers.CleanErrorMessage(err), batch.nodes()); err != nil {
+				slog.Error("Failed to update nodes upgrade status", "error", err)
+			}
+			return err
+		}
+
+		// the plan has ended, if the nodes were not upgraded we would loop forever.
+		upgraded, err := determineUpgradeBatches(ctx, cli, desiredVersion, in.Spec.UpgradeStrategy)
+		if err != nil {
+			return fmt.Errorf("determine upgrade batches: %w", err)
+		}
+		if len(upgraded) > 0 && slices.Equal(upgraded[0].nodes(), batch.nodes()) {
+			message := "node did not match version after upgrade"
+			if err := setNodesUpgradeStatus(ctx, cli, in, desiredVersion, ecv1beta1.NodeUpgradePhaseFailed, message, batch.nodes()); err != nil {
+				slog.Error("Failed to update nodes upgrade status", "error", err)
+			}
+			return fmt.Errorf("cluster nodes did not match version after upgrade")
+		}
+
+		if err := setNodesUpgradeStatus(ctx, cli, in, desiredVersion, ecv1beta1.NodeUpgradePhaseUpgraded, "", batch.nodes()); err != nil {
+			return fmt.Errorf("update nodes upgrade status: %w", err)
+		}
+
+		// when upgrading in batches we make sure the nodes and the application are healthy
+		// before moving on to the next batch.
+		if in.Spec.UpgradeStrategy != nil {
+			if err := waitForBatchHealthy(ctx, cli, in, desiredVersion, batch); err != nil {
+				return fmt.Errorf("wait for upgraded nodes: %w", err)
+			}
+		}
+	}
+
+	match, err = clusterNodesMatchVersion(ctx, cli, desiredVersion)
+	if err != nil {
+		return fmt.Errorf("check cluster nodes match version after plan completion: %w", err)
+	}
+	if !match {
+		return fmt.Errorf("cluster nodes did not match version after upgrade")
+	}
+
+	// all the plans have been completed, so we can move on - kubernetes is now upgraded
+	slog.Info("Upgrade completed successfully", "version", desiredVersion)
+
+	err = kubeutils.SetInstallationState(ctx, cli, in, ecv1beta1.InstallationStateKubernetesInstalled, "Kubernetes upgraded")
+	if err != nil {
+		return fmt.Errorf("set installation state: %w", err)
+	}
+
+	return nil
+}
+
+// upgradeK0sBatch runs an autopilot plan to upgrade the nodes in the batch and removes it once
+// it has completed.
+func upgradeK0sBatch(ctx context.Context, cli client.Client, desiredVersion string, in *ecv1beta1.Installation, meta *ectypes.ReleaseMetadata, batch upgradeBatch) error {
 	// create an autopilot upgrade plan if one does not yet exist
-	if err := createAutopilotPlan(ctx, cli, desiredVersion, in, meta); err != nil {
+	if err := createAutopilotPlan(ctx, cli, desiredVersion, in, meta, batch.targets()); err != nil {
 		return fmt.Errorf("create autpilot upgrade plan: %w", err)
 	}
 
@@ -144,46 +299,44 @@ func upgradeK0s(ctx context.Context, cli client.Client, in *ecv1beta1.Installati
 			}
 		}
 	}
-	// if this was not a k0s upgrade plan, we can just delete the plan and restart the function to get a k0s upgrade
+	// if this was not a k0s upgrade plan, we can just delete the plan and start again to get a k0s upgrade
 	if !isOurK0sUpgrade {
 		err = cli.Delete(ctx, &plan)
 		if err != nil {
 			return fmt.Errorf("delete autopilot plan: %w", err)
 		}
-		return upgradeK0s(ctx, cli, in)
-	}
-
-	match, err = clusterNodesMatchVersion(ctx, cli, desiredVersion)
-	if err != nil {
-		return fmt.Errorf("check cluster nodes match version after plan completion: %w", err)
-	}
-	if !match {
-		return fmt.Errorf("cluster nodes did not match version after upgrade")
+		return upgradeK0sBatch(ctx, cli, desiredVersion, in, meta, batch)
 	}
 
-	// the plan has been completed, so we can move on - kubernetes is now upgraded
-	slog.Info("Upgrade completed successfully", "version", desiredVersion)
 	if err := cli.Delete(ctx, &plan); err != nil {
 		return fmt.Errorf("delete successful upgrade plan: %w", err)
 	}
-
-	err = kubeutils.SetInstallationState(ctx, cli, in, ecv1beta1.InstallationStateKubernetesInstalled, "Kubernetes upgraded")
-	if err != nil {
-		return fmt.Errorf("set installation state: %w", err)
-	}
-
 	return nil
 }
 
+// setNodesUpgradeStatus records the Kubernetes upgrade phase of the nodes in the installation.
+func setNodesUpgradeStatus(ctx context.Context, cli client.Client, in *ecv1beta1.Installation, version, phase, message string, nodes []string) error {
+	return kubeutils.UpdateInstallationStatus(ctx, cli, in, func(status *ecv1beta1.InstallationStatus) {
+		for _, node := range nodes {
+			status.SetNodeUpgradeStatus(ecv1beta1.NodeUpgradeStatus{
+				Name:    node,
+				Version: version,
+				Phase:   phase,
+				Message: message,
+			})
+		}
+	})
+}
+
 // updateClusterConfig updates the cluster config with the latest images.
-func updateClusterConfig(ctx context.Context, cli client.Client) error {
+func updateClusterConfig(ctx context.Context, cli client.Client, in *ecv1beta1.Installation) error {
 	var currentCfg k0sv1beta1.ClusterConfig
 	err := cli.Get(ctx, client.ObjectKey{Name: "k0s", Namespace: "kube-system"}, &currentCfg)
 	if err != nil {
 		return fmt.Errorf("get cluster config: %w", err)
 	}
 
-	cfg := config.RenderK0sConfig()
+	cfg := config.RenderK0sConfig(config.CNIProvider(in.Spec.Config))
 	if currentCfg.Spec.Images != nil {
 		if reflect.DeepEqual(*currentCfg.Spec.Images, *cfg.Spec.Images) {
 			return nil
@@ -220,7 +373,12 @@ func upgradeAddons(ctx context.Context, cli client.Client, hcli helm.Client, in
 		return fmt.Errorf("no images available")
 	}
 
-	if err := addons.Upgrade(ctx, hcli, in, meta); err != nil {
+	previous, err := previousInstallation(ctx, cli, in)
+	if err != nil {
+		return fmt.Errorf("get previous installation: %w", err)
+	}
+
+	if err := addons.Upgrade(ctx, hcli, previous, in, meta, newCheckpointFunc(cli, in)); err != nil {
 		return fmt.Errorf("upgrade addons: %w", err)
 	}
 
@@ -243,7 +401,7 @@ func upgradeExtensions(ctx context.Context, cli client.Client, hcli helm.Client,
 		return fmt.Errorf("get previous installation: %w", err)
 	}
 
-	if err := extensions.Upgrade(ctx, cli, hcli, previous, in); err != nil {
+	if err := extensions.Upgrade(ctx, cli, hcli, previous, in, newCheckpointFunc(cli, in)); err != nil {
 		return fmt.Errorf("upgrade extensions: %w", err)
 	}
 
@@ -255,19 +413,19 @@ func upgradeExtensions(ctx context.Context, cli client.Client, hcli helm.Client,
 	return nil
 }
 
-func createAutopilotPlan(ctx context.Context, cli client.Client, desiredVersion string, in *ecv1beta1.Installation, meta *ectypes.ReleaseMetadata) error {
+func createAutopilotPlan(ctx context.Context, cli client.Client, desiredVersion string, in *ecv1beta1.Installation, meta *ectypes.ReleaseMetadata, targets apv1b2.PlanCommandTargets) error {
 	var plan apv1b2.Plan
 	okey := client.ObjectKey{Name: "autopilot"}
-	if err := cli.Get(ctx, okey, &plan); err != nil && !errors.IsNotFound(err) {
+	if err := cli.Get(ctx, okey, &plan); err != nil && !k8serrors.IsNotFound(err) {
 		return fmt.Errorf("get upgrade plan: %w", err)
-	} else if errors.IsNotFound(err) {
+	} else if k8serrors.IsNotFound(err) {
 		// if the kubernetes version has changed we create an upgrade command
 		slog.Info("Starting k0s autopilot upgrade plan", "version", desiredVersion)
 
 		// there is no autopilot plan in the cluster so we are free to
 		// start our own plan. here we link the plan to the installation
 		// by its name.
-		if err := startAutopilotUpgrade(ctx, cli, in, meta); err != nil {
+		if err := startAutopilotUpgrade(ctx, cli, in, meta, targets); err != nil {
 			return fmt.Errorf("start upgrade: %w", err)
 		}
 	}
@@ -0,0 +1,74 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/config"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// DefaultHealthCheckTimeout is the time the upgrade waits for a batch of nodes to become
+// healthy when the upgrade strategy does not say otherwise.
+const DefaultHealthCheckTimeout = 10 * time.Minute
+
+// DefaultHelmConcurrencyLevel is the number of helm charts installed concurrently when the
+// config does not say otherwise.
+const DefaultHelmConcurrencyLevel = 1
+
+// InstallationDefaulter fills in the defaults of Installation objects.
+type InstallationDefaulter struct{}
+
+var _ admission.CustomDefaulter = &InstallationDefaulter{}
+
+// Default sets the defaults of the installation.
+func (d *InstallationDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	in, ok := obj.(*ecv1beta1.Installation)
+	if !ok {
+		return fmt.Errorf("expected an installation but got a %T", obj)
+	}
+	defaultInstallationSpec(&in.Spec)
+	return nil
+}
+
+// ConfigDefaulter fills in the defaults of Config objects.
+type ConfigDefaulter struct{}
+
+var _ admission.CustomDefaulter = &ConfigDefaulter{}
+
+// Default sets the defaults of the config.
+func (d *ConfigDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	cfg, ok := obj.(*ecv1beta1.Config)
+	if !ok {
+		return fmt.Errorf("expected a config but got a %T", obj)
+	}
+	defaultConfigSpec(&cfg.Spec)
+	return nil
+}
+
+func defaultInstallationSpec(spec *ecv1beta1.InstallationSpec) {
+	if spec.SourceType == "" {
+		spec.SourceType = ecv1beta1.InstallationSourceTypeCRD
+	}
+	if spec.Config != nil {
+		defaultConfigSpec(spec.Config)
+	}
+	if spec.Network != nil && spec.Network.NodePortRange == "" {
+		spec.Network.NodePortRange = config.DefaultServiceNodePortRange
+	}
+	if spec.UpgradeStrategy != nil && spec.UpgradeStrategy.HealthCheckTimeout == nil {
+		spec.UpgradeStrategy.HealthCheckTimeout = &metav1.Duration{Duration: DefaultHealthCheckTimeout}
+	}
+}
+
+func defaultConfigSpec(spec *ecv1beta1.ConfigSpec) {
+	// charts are left untouched on purpose, any change to them is seen as a chart upgrade when
+	// comparing the installation with the previous one.
+	if spec.Extensions.Helm != nil && spec.Extensions.Helm.ConcurrencyLevel == 0 {
+		spec.Extensions.Helm.ConcurrencyLevel = DefaultHelmConcurrencyLevel
+	}
+}
@@ -0,0 +1,47 @@
+package webhooks
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestInstallationDefaulter(t *testing.T) {
+	in := &ecv1beta1.Installation{
+		Spec: ecv1beta1.InstallationSpec{
+			Config: &ecv1beta1.ConfigSpec{
+				Extensions: ecv1beta1.Extensions{
+					Helm: &ecv1beta1.Helm{Charts: []ecv1beta1.Chart{{Name: "nginx"}}},
+				},
+			},
+			Network:         &ecv1beta1.NetworkSpec{PodCIDR: "10.244.0.0/16"},
+			UpgradeStrategy: &ecv1beta1.UpgradeStrategy{MaxParallelNodes: 1},
+		},
+	}
+	require.NoError(t, (&InstallationDefaulter{}).Default(context.Background(), in))
+
+	assert.Equal(t, ecv1beta1.InstallationSourceTypeCRD, in.Spec.SourceType)
+	assert.Equal(t, "80-32767", in.Spec.Network.NodePortRange)
+	assert.Equal(t, &metav1.Duration{Duration: 10 * time.Minute}, in.Spec.UpgradeStrategy.HealthCheckTimeout)
+	assert.Equal(t, 1, in.Spec.Config.Extensions.Helm.ConcurrencyLevel)
+	// charts are not defaulted as that would be seen as a chart change.
+	assert.Equal(t, ecv1beta1.Chart{Name: "nginx"}, in.Spec.Config.Extensions.Helm.Charts[0])
+
+	// values already set are kept.
+	in = &ecv1beta1.Installation{
+		Spec: ecv1beta1.InstallationSpec{
+			SourceType:      "ConfigMap",
+			Network:         &ecv1beta1.NetworkSpec{NodePortRange: "30000-32767"},
+			UpgradeStrategy: &ecv1beta1.UpgradeStrategy{HealthCheckTimeout: &metav1.Duration{Duration: time.Minute}},
+		},
+	}
+	require.NoError(t, (&InstallationDefaulter{}).Default(context.Background(), in))
+	assert.Equal(t, "ConfigMap", in.Spec.SourceType)
+	assert.Equal(t, "30000-32767", in.Spec.Network.NodePortRange)
+	assert.Equal(t, time.Minute, in.Spec.UpgradeStrategy.HealthCheckTimeout.Duration)
+}
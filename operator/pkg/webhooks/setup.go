@@ -0,0 +1,294 @@
+// Package webhooks implements the validating and defaulting admission webhooks for the
+// Installation and Config kinds and the conversion webhook for the Installation versions.
+package webhooks
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/certs"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// CertificateSecretName is the name of the secret holding the webhook server certificate.
+	CertificateSecretName = "embedded-cluster-operator-webhook-tls"
+	// ValidatingWebhookConfigurationName is the name of the validating webhook configuration.
+	ValidatingWebhookConfigurationName = "embedded-cluster-operator-validating-webhook"
+	// MutatingWebhookConfigurationName is the name of the mutating webhook configuration.
+	MutatingWebhookConfigurationName = "embedded-cluster-operator-mutating-webhook"
+
+	certificateDuration = 365 * 24 * time.Hour
+	// certificates are renewed when the operator starts and they are about to expire.
+	certificateRenewBefore = 30 * 24 * time.Hour
+
+	installationCRDName = "installations.embeddedcluster.replicated.com"
+	conversionPath      = "/convert"
+
+	installationValidatePath = "/validate-embeddedcluster-replicated-com-v1beta1-installation"
+	installationMutatePath   = "/mutate-embeddedcluster-replicated-com-v1beta1-installation"
+	configValidatePath       = "/validate-embeddedcluster-replicated-com-v1beta1-config"
+	configMutatePath         = "/mutate-embeddedcluster-replicated-com-v1beta1-config"
+)
+
+// Options holds the information needed to expose the webhooks to the api server.
+type Options struct {
+	// ServiceName and ServiceNamespace identify the service in front of the webhook server.
+	ServiceName      string
+	ServiceNamespace string
+	// CertDir is the directory the webhook server reads its certificate from.
+	CertDir string
+}
+
+//+kubebuilder:rbac:groups=admissionregistration.k8s.io,resources=validatingwebhookconfigurations;mutatingwebhookconfigurations,verbs=get;list;watch;create;update;patch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;create;update
+//+kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=get;update;patch
+
+// Setup registers the Installation and Config webhooks with the manager. The webhook server
+// certificate is self signed and kept in a secret so all operator replicas share it. Once the
+// certificate is in place the webhook configurations are created or updated to point to the
+// operator service. Webhooks are configured to ignore failures so a broken operator does not
+// prevent installations from being created. The Installation CRD is also configured to use the
+// operator to convert between its versions, v1beta1 remains the storage version so clients
+// using it keep working when the operator is not running.
+func Setup(ctx context.Context, mgr ctrl.Manager, cli client.Client, opts Options) error {
+	caBundle, err := ensureCertificate(ctx, cli, opts)
+	if err != nil {
+		return fmt.Errorf("ensure webhook certificate: %w", err)
+	}
+
+	if err := ctrl.NewWebhookManagedBy(mgr).
+		For(&ecv1beta1.Installation{}).
+		WithValidator(&InstallationValidator{}).
+		WithDefaulter(&InstallationDefaulter{}).
+		Complete(); err != nil {
+		return fmt.Errorf("create installation webhook: %w", err)
+	}
+
+	if err := ctrl.NewWebhookManagedBy(mgr).
+		For(&ecv1beta1.Config{}).
+		WithValidator(&ConfigValidator{}).
+		WithDefaulter(&ConfigDefaulter{}).
+		Complete(); err != nil {
+		return fmt.Errorf("create config webhook: %w", err)
+	}
+
+	if err := ensureWebhookConfigurations(ctx, cli, opts, caBundle); err != nil {
+		return fmt.Errorf("ensure webhook configurations: %w", err)
+	}
+
+	if err := ensureConversionWebhook(ctx, cli, opts, caBundle); err != nil {
+		return fmt.Errorf("ensure conversion webhook: %w", err)
+	}
+	return nil
+}
+
+// ensureCertificate makes sure a valid certificate for the webhook service exists in the
+// certificate secret and writes it to the webhook server certificate directory. Returns the
+// certificate, as it is self signed it is also the CA bundle for the webhook configurations.
+func ensureCertificate(ctx context.Context, cli client.Client, opts Options) ([]byte, error) {
+	secret := &corev1.Secret{}
+	nsn := client.ObjectKey{Namespace: opts.ServiceNamespace, Name: CertificateSecretName}
+	if err := cli.Get(ctx, nsn, secret); k8serrors.IsNotFound(err) {
+		secret = nil
+	} else if err != nil {
+		return nil, fmt.Errorf("get secret: %w", err)
+	}
+
+	if secret == nil || !certificateIsValid(secret.Data[corev1.TLSCertKey], time.Now()) {
+		crt, key, err := generateCertificate(opts)
+		if err != nil {
+			return nil, fmt.Errorf("generate certificate: %w", err)
+		}
+		data := map[string][]byte{
+			corev1.TLSCertKey:       []byte(crt),
+			corev1.TLSPrivateKeyKey: []byte(key),
+		}
+
+		if secret == nil {
+			secret = &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      CertificateSecretName,
+					Namespace: opts.ServiceNamespace,
+				},
+				Type: corev1.SecretTypeTLS,
+				Data: data,
+			}
+			if err := cli.Create(ctx, secret); err != nil {
+				return nil, fmt.Errorf("create secret: %w", err)
+			}
+		} else {
+			secret.Data = data
+			if err := cli.Update(ctx, secret); err != nil {
+				return nil, fmt.Errorf("update secret: %w", err)
+			}
+		}
+	}
+
+	if err := os.MkdirAll(opts.CertDir, 0755); err != nil {
+		return nil, fmt.Errorf("create certificate directory: %w", err)
+	}
+	for _, name := range []string{corev1.TLSCertKey, corev1.TLSPrivateKeyKey} {
+		if err := os.WriteFile(filepath.Join(opts.CertDir, name), secret.Data[name], 0600); err != nil {
+			return nil, fmt.Errorf("write %s: %w", name, err)
+		}
+	}
+	return secret.Data[corev1.TLSCertKey], nil
+}
+
+func generateCertificate(opts Options) (string, string, error) {
+	svc := fmt.Sprintf("%s.%s.svc", opts.ServiceName, opts.ServiceNamespace)
+	builder, err := certs.NewBuilder(
+		certs.WithCommonName(svc),
+		certs.WithDuration(certificateDuration),
+		certs.WithDNSName(opts.ServiceName),
+		certs.WithDNSName(fmt.Sprintf("%s.%s", opts.ServiceName, opts.ServiceNamespace)),
+		certs.WithDNSName(svc),
+		certs.WithDNSName(fmt.Sprintf("%s.cluster.local", svc)),
+	)
+	if err != nil {
+		return "", "", fmt.Errorf("create cert builder: %w", err)
+	}
+	return builder.Generate()
+}
+
+// certificateIsValid returns true if the pem encoded certificate can be parsed and is not
+// about to expire.
+func certificateIsValid(data []byte, now time.Time) bool {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return false
+	}
+	crt, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return false
+	}
+	return now.Add(certificateRenewBefore).Before(crt.NotAfter)
+}
+
+func ensureWebhookConfigurations(ctx context.Context, cli client.Client, opts Options, caBundle []byte) error {
+	validating := &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: ValidatingWebhookConfigurationName},
+	}
+	if _, err := controllerutil.CreateOrUpdate(ctx, cli, validating, func() error {
+		validating.Webhooks = []admissionregistrationv1.ValidatingWebhook{
+			validatingWebhook("vinstallation.embeddedcluster.replicated.com", "installations", installationValidatePath, opts, caBundle),
+			validatingWebhook("vconfig.embeddedcluster.replicated.com", "configs", configValidatePath, opts, caBundle),
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("create or update validating webhook configuration: %w", err)
+	}
+
+	mutating := &admissionregistrationv1.MutatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: MutatingWebhookConfigurationName},
+	}
+	if _, err := controllerutil.CreateOrUpdate(ctx, cli, mutating, func() error {
+		mutating.Webhooks = []admissionregistrationv1.MutatingWebhook{
+			mutatingWebhook("minstallation.embeddedcluster.replicated.com", "installations", installationMutatePath, opts, caBundle),
+			mutatingWebhook("mconfig.embeddedcluster.replicated.com", "configs", configMutatePath, opts, caBundle),
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("create or update mutating webhook configuration: %w", err)
+	}
+	return nil
+}
+
+// ensureConversionWebhook points the conversion of the Installation CRD versions to the
+// conversion endpoint served by the operator.
+func ensureConversionWebhook(ctx context.Context, cli client.Client, opts Options, caBundle []byte) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var crd apiextensionsv1.CustomResourceDefinition
+		if err := cli.Get(ctx, client.ObjectKey{Name: installationCRDName}, &crd); err != nil {
+			return fmt.Errorf("get installation crd: %w", err)
+		}
+		crd.Spec.Conversion = &apiextensionsv1.CustomResourceConversion{
+			Strategy: apiextensionsv1.WebhookConverter,
+			Webhook: &apiextensionsv1.WebhookConversion{
+				ClientConfig: &apiextensionsv1.WebhookClientConfig{
+					Service: &apiextensionsv1.ServiceReference{
+						Name:      opts.ServiceName,
+						Namespace: opts.ServiceNamespace,
+						Path:      ptr.To(conversionPath),
+						Port:      ptr.To(int32(443)),
+					},
+					CABundle: caBundle,
+				},
+				ConversionReviewVersions: []string{"v1"},
+			},
+		}
+		if err := cli.Update(ctx, &crd); err != nil {
+			return fmt.Errorf("update installation crd: %w", err)
+		}
+		return nil
+	})
+}
+
+func validatingWebhook(name, resource, path string, opts Options, caBundle []byte) admissionregistrationv1.ValidatingWebhook {
+	return admissionregistrationv1.ValidatingWebhook{
+		Name:                    name,
+		ClientConfig:            webhookClientConfig(path, opts, caBundle),
+		Rules:                   webhookRules(resource),
+		FailurePolicy:           ptr.To(admissionregistrationv1.Ignore),
+		SideEffects:             ptr.To(admissionregistrationv1.SideEffectClassNone),
+		AdmissionReviewVersions: []string{"v1"},
+		TimeoutSeconds:          ptr.To(int32(10)),
+	}
+}
+
+func mutatingWebhook(name, resource, path string, opts Options, caBundle []byte) admissionregistrationv1.MutatingWebhook {
+	return admissionregistrationv1.MutatingWebhook{
+		Name:                    name,
+		ClientConfig:            webhookClientConfig(path, opts, caBundle),
+		Rules:                   webhookRules(resource),
+		FailurePolicy:           ptr.To(admissionregistrationv1.Ignore),
+		SideEffects:             ptr.To(admissionregistrationv1.SideEffectClassNone),
+		AdmissionReviewVersions: []string{"v1"},
+		TimeoutSeconds:          ptr.To(int32(10)),
+	}
+}
+
+func webhookClientConfig(path string, opts Options, caBundle []byte) admissionregistrationv1.WebhookClientConfig {
+	return admissionregistrationv1.WebhookClientConfig{
+		Service: &admissionregistrationv1.ServiceReference{
+			Name:      opts.ServiceName,
+			Namespace: opts.ServiceNamespace,
+			Path:      ptr.To(path),
+			Port:      ptr.To(int32(443)),
+		},
+		CABundle: caBundle,
+	}
+}
+
+func webhookRules(resource string) []admissionregistrationv1.RuleWithOperations {
+	return []admissionregistrationv1.RuleWithOperations{
+		{
+			Operations: []admissionregistrationv1.OperationType{
+				admissionregistrationv1.Create,
+				admissionregistrationv1.Update,
+			},
+			Rule: admissionregistrationv1.Rule{
+				APIGroups:   []string{ecv1beta1.GroupVersion.Group},
+				APIVersions: []string{ecv1beta1.GroupVersion.Version},
+				Resources:   []string{resource},
+				Scope:       ptr.To(admissionregistrationv1.ClusterScope),
+			},
+		},
+	}
+}
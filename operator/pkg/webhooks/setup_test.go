@@ -0,0 +1,104 @@
+package webhooks
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/conversion"
+)
+
+func Test_ensureCertificate(t *testing.T) {
+	ctx := context.Background()
+	opts := Options{
+		ServiceName:      "embedded-cluster-operator-webhook",
+		ServiceNamespace: "embedded-cluster",
+		CertDir:          t.TempDir(),
+	}
+	cli := fake.NewClientBuilder().WithScheme(kubeutils.Scheme).Build()
+
+	caBundle, err := ensureCertificate(ctx, cli, opts)
+	require.NoError(t, err)
+	assert.True(t, certificateIsValid(caBundle, time.Now()))
+
+	var secret corev1.Secret
+	require.NoError(t, cli.Get(ctx, client.ObjectKey{Namespace: opts.ServiceNamespace, Name: CertificateSecretName}, &secret))
+	for _, name := range []string{corev1.TLSCertKey, corev1.TLSPrivateKeyKey} {
+		data, err := os.ReadFile(filepath.Join(opts.CertDir, name))
+		require.NoError(t, err)
+		assert.Equal(t, secret.Data[name], data)
+	}
+
+	// the certificate is reused while it is valid.
+	again, err := ensureCertificate(ctx, cli, opts)
+	require.NoError(t, err)
+	assert.Equal(t, caBundle, again)
+
+	// and renewed when it is about to expire.
+	assert.False(t, certificateIsValid(caBundle, time.Now().Add(certificateDuration-certificateRenewBefore)))
+	secret.Data[corev1.TLSCertKey] = []byte("invalid")
+	require.NoError(t, cli.Update(ctx, &secret))
+	renewed, err := ensureCertificate(ctx, cli, opts)
+	require.NoError(t, err)
+	assert.NotEqual(t, caBundle, renewed)
+	assert.True(t, certificateIsValid(renewed, time.Now()))
+}
+
+func Test_ensureWebhookConfigurations(t *testing.T) {
+	ctx := context.Background()
+	opts := Options{ServiceName: "embedded-cluster-operator-webhook", ServiceNamespace: "embedded-cluster"}
+	cli := fake.NewClientBuilder().WithScheme(kubeutils.Scheme).Build()
+
+	require.NoError(t, ensureWebhookConfigurations(ctx, cli, opts, []byte("ca")))
+	// running it again updates the existing configurations.
+	require.NoError(t, ensureWebhookConfigurations(ctx, cli, opts, []byte("new-ca")))
+
+	var validating admissionregistrationv1.ValidatingWebhookConfiguration
+	require.NoError(t, cli.Get(ctx, client.ObjectKey{Name: ValidatingWebhookConfigurationName}, &validating))
+	require.Len(t, validating.Webhooks, 2)
+	assert.Equal(t, []byte("new-ca"), validating.Webhooks[0].ClientConfig.CABundle)
+	assert.Equal(t, installationValidatePath, *validating.Webhooks[0].ClientConfig.Service.Path)
+	assert.Equal(t, admissionregistrationv1.Ignore, *validating.Webhooks[0].FailurePolicy)
+
+	var mutating admissionregistrationv1.MutatingWebhookConfiguration
+	require.NoError(t, cli.Get(ctx, client.ObjectKey{Name: MutatingWebhookConfigurationName}, &mutating))
+	require.Len(t, mutating.Webhooks, 2)
+	assert.Equal(t, configMutatePath, *mutating.Webhooks[1].ClientConfig.Service.Path)
+	assert.Equal(t, []string{"configs"}, mutating.Webhooks[1].Rules[0].Resources)
+}
+
+func Test_ensureConversionWebhook(t *testing.T) {
+	ctx := context.Background()
+	opts := Options{ServiceName: "embedded-cluster-operator-webhook", ServiceNamespace: "embedded-cluster"}
+	crd := &apiextensionsv1.CustomResourceDefinition{ObjectMeta: metav1.ObjectMeta{Name: installationCRDName}}
+	cli := fake.NewClientBuilder().WithScheme(kubeutils.Scheme).WithObjects(crd).Build()
+
+	require.NoError(t, ensureConversionWebhook(ctx, cli, opts, []byte("ca")))
+
+	var got apiextensionsv1.CustomResourceDefinition
+	require.NoError(t, cli.Get(ctx, client.ObjectKey{Name: installationCRDName}, &got))
+	require.NotNil(t, got.Spec.Conversion)
+	assert.Equal(t, apiextensionsv1.WebhookConverter, got.Spec.Conversion.Strategy)
+	assert.Equal(t, []byte("ca"), got.Spec.Conversion.Webhook.ClientConfig.CABundle)
+	assert.Equal(t, conversionPath, *got.Spec.Conversion.Webhook.ClientConfig.Service.Path)
+	assert.Equal(t, opts.ServiceName, got.Spec.Conversion.Webhook.ClientConfig.Service.Name)
+}
+
+func TestInstallationIsConvertible(t *testing.T) {
+	// the webhook builder only serves the conversion endpoint for convertible types.
+	ok, err := conversion.IsConvertible(kubeutils.Scheme, &ecv1beta1.Installation{})
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
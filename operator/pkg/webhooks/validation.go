@@ -0,0 +1,385 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/operator/schemas"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons"
+	"github.com/replicatedhq/embedded-cluster/pkg/config"
+	"github.com/replicatedhq/embedded-cluster/pkg/netutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/preflights"
+	"github.com/xeipuuv/gojsonschema"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"sigs.k8s.io/yaml"
+)
+
+var nodePortRangeRegex = regexp.MustCompile(`^(\d+)-(\d+)$`)
+
+// configSchema loads the JSON schema of the Config kind.
+var configSchema = gojsonschema.NewBytesLoader(schemas.ConfigV1Beta1)
+
+// InstallationValidator validates Installation objects before they are persisted.
+type InstallationValidator struct{}
+
+var _ admission.CustomValidator = &InstallationValidator{}
+
+// ValidateCreate validates a new installation.
+func (v *InstallationValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	in, ok := obj.(*ecv1beta1.Installation)
+	if !ok {
+		return nil, fmt.Errorf("expected an installation but got a %T", obj)
+	}
+	return nil, validateInstallation(in)
+}
+
+// ValidateUpdate validates an updated installation. Updates that do not touch the spec are
+// always allowed so old installations can still be annotated and marked as obsolete.
+func (v *InstallationValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldIn, ok := oldObj.(*ecv1beta1.Installation)
+	if !ok {
+		return nil, fmt.Errorf("expected an installation but got a %T", oldObj)
+	}
+	newIn, ok := newObj.(*ecv1beta1.Installation)
+	if !ok {
+		return nil, fmt.Errorf("expected an installation but got a %T", newObj)
+	}
+	if equality.Semantic.DeepEqual(oldIn.Spec, newIn.Spec) {
+		return nil, nil
+	}
+	return nil, validateInstallation(newIn)
+}
+
+// ValidateDelete allows all installations to be deleted.
+func (v *InstallationValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// ConfigValidator validates Config objects before they are persisted.
+type ConfigValidator struct{}
+
+var _ admission.CustomValidator = &ConfigValidator{}
+
+// ValidateCreate validates a new config.
+func (v *ConfigValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	cfg, ok := obj.(*ecv1beta1.Config)
+	if !ok {
+		return nil, fmt.Errorf("expected a config but got a %T", obj)
+	}
+	return nil, validateConfig(cfg)
+}
+
+// ValidateUpdate validates an updated config.
+func (v *ConfigValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	cfg, ok := newObj.(*ecv1beta1.Config)
+	if !ok {
+		return nil, fmt.Errorf("expected a config but got a %T", newObj)
+	}
+	return nil, validateConfig(cfg)
+}
+
+// ValidateDelete allows all configs to be deleted.
+func (v *ConfigValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func validateInstallation(in *ecv1beta1.Installation) error {
+	errs := validateInstallationSpec(&in.Spec, field.NewPath("spec"))
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(ecv1beta1.GroupVersion.WithKind("Installation").GroupKind(), in.Name, errs)
+}
+
+func validateConfig(cfg *ecv1beta1.Config) error {
+	errs := validateConfigSpec(&cfg.Spec, field.NewPath("spec"))
+	if len(errs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(ecv1beta1.GroupVersion.WithKind("Config").GroupKind(), cfg.Name, errs)
+}
+
+func validateInstallationSpec(spec *ecv1beta1.InstallationSpec, path *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	if spec.Config != nil {
+		errs = append(errs, validateConfigSpec(spec.Config, path.Child("config"))...)
+	}
+	if spec.Network != nil {
+		errs = append(errs, validateNetworkSpec(spec.Network, path.Child("network"))...)
+	}
+	if spec.EndUserK0sConfigOverrides != "" {
+		if _, err := config.PatchK0sConfig(config.RenderK0sConfig(config.CNIProvider(spec.Config)), spec.EndUserK0sConfigOverrides); err != nil {
+			errs = append(errs, field.Invalid(path.Child("endUserK0sConfigOverrides"), field.OmitValueType{}, err.Error()))
+		}
+	}
+	if spec.UpgradeStrategy != nil {
+		errs = append(errs, validateUpgradeStrategy(spec.UpgradeStrategy, path.Child("upgradeStrategy"))...)
+	}
+	if spec.HostPreflightMonitoring != nil {
+		errs = append(errs, validateHostPreflightMonitoring(spec.HostPreflightMonitoring, path.Child("hostPreflightMonitoring"))...)
+	}
+	if gc := spec.RegistryGarbageCollection; gc != nil && gc.KeepReleases < 0 {
+		errs = append(errs, field.Invalid(path.Child("registryGarbageCollection", "keepReleases"), gc.KeepReleases, "must not be negative"))
+	}
+	return errs
+}
+
+func validateConfigSpec(spec *ecv1beta1.ConfigSpec, path *field.Path) field.ErrorList {
+	errs := validateConfigSchema(spec, path)
+
+	if spec.UnsupportedOverrides.K0s != "" {
+		if _, err := config.PatchK0sConfig(config.RenderK0sConfig(config.CNIProvider(spec)), spec.UnsupportedOverrides.K0s); err != nil {
+			errs = append(errs, field.Invalid(path.Child("unsupportedOverrides", "k0s"), field.OmitValueType{}, err.Error()))
+		}
+	}
+
+	releases := addons.ReleaseNames()
+	errs = append(errs, validateAddOns(spec.Extensions.AddOns, releases, path.Child("extensions", "addons"))...)
+	for _, addon := range spec.Extensions.AddOns {
+		releases = append(releases, addon.ReleaseName)
+	}
+	for i, ext := range spec.UnsupportedOverrides.BuiltInExtensions {
+		extPath := path.Child("unsupportedOverrides", "builtInExtensions").Index(i)
+		found := false
+		for _, name := range releases {
+			if ext.Name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			errs = append(errs, field.NotSupported(extPath.Child("name"), ext.Name, releases))
+		}
+		errs = append(errs, validateYAML(ext.Values, extPath.Child("values"))...)
+	}
+
+	if spec.Extensions.Helm != nil {
+		names := map[string]bool{}
+		for i, chart := range spec.Extensions.Helm.Charts {
+			chartPath := path.Child("extensions", "helm", "charts").Index(i)
+			if chart.Name == "" {
+				errs = append(errs, field.Required(chartPath.Child("name"), ""))
+			} else if names[chart.Name] {
+				errs = append(errs, field.Duplicate(chartPath.Child("name"), chart.Name))
+			}
+			names[chart.Name] = true
+			errs = append(errs, validateYAML(chart.Values, chartPath.Child("values"))...)
+		}
+	}
+	return errs
+}
+
+// validateConfigSchema validates the config spec against the JSON schema generated from the
+// Config CRD. Errors reported by the schema are translated into field errors under path.
+func validateConfigSchema(spec *ecv1beta1.ConfigSpec, path *field.Path) field.ErrorList {
+	data, err := json.Marshal(map[string]interface{}{"spec": spec})
+	if err != nil {
+		return field.ErrorList{field.InternalError(path, fmt.Errorf("marshal spec: %w", err))}
+	}
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return field.ErrorList{field.InternalError(path, fmt.Errorf("unmarshal spec: %w", err))}
+	}
+
+	result, err := gojsonschema.Validate(configSchema, gojsonschema.NewGoLoader(dropNulls(doc)))
+	if err != nil {
+		return field.ErrorList{field.InternalError(path, fmt.Errorf("validate schema: %w", err))}
+	}
+
+	errs := field.ErrorList{}
+	for _, rerr := range result.Errors() {
+		errs = append(errs, field.Invalid(schemaFieldPath(path, rerr.Field()), rerr.Value(), rerr.Description()))
+	}
+	return errs
+}
+
+// dropNulls removes the null values from the decoded JSON document. Nil slices and maps are
+// encoded as null and, like the api server does for fields that are not nullable, we ignore
+// them instead of reporting them as having the wrong type.
+func dropNulls(doc interface{}) interface{} {
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			if value == nil {
+				delete(v, key)
+				continue
+			}
+			v[key] = dropNulls(value)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = dropNulls(v[i])
+		}
+	}
+	return doc
+}
+
+// schemaFieldPath converts a field as reported by the schema validation (e.g.
+// "spec.extensions.helm.charts.0.name") into a path relative to the provided one.
+func schemaFieldPath(path *field.Path, schemaField string) *field.Path {
+	parts := strings.Split(schemaField, ".")
+	if len(parts) > 0 && parts[0] == "spec" {
+		parts = parts[1:]
+	}
+	for _, part := range parts {
+		if idx, err := strconv.Atoi(part); err == nil {
+			path = path.Index(idx)
+			continue
+		}
+		path = path.Child(part)
+	}
+	return path
+}
+
+func validateNetworkSpec(network *ecv1beta1.NetworkSpec, path *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	// pod and service cidrs can be of any size and in any range, here we only make sure they
+	// are well formed.
+	if network.PodCIDR != "" {
+		if err := netutils.ValidateCIDR(network.PodCIDR, 32, false); err != nil {
+			errs = append(errs, field.Invalid(path.Child("podCIDR"), network.PodCIDR, err.Error()))
+		}
+	}
+	if network.ServiceCIDR != "" {
+		if err := netutils.ValidateCIDR(network.ServiceCIDR, 32, false); err != nil {
+			errs = append(errs, field.Invalid(path.Child("serviceCIDR"), network.ServiceCIDR, err.Error()))
+		}
+	}
+	if network.NodePortRange != "" {
+		if err := validateNodePortRange(network.NodePortRange); err != nil {
+			errs = append(errs, field.Invalid(path.Child("nodePortRange"), network.NodePortRange, err.Error()))
+		}
+	}
+	return errs
+}
+
+func validateNodePortRange(portRange string) error {
+	matches := nodePortRangeRegex.FindStringSubmatch(portRange)
+	if matches == nil {
+		return fmt.Errorf("must be in the format <min>-<max>")
+	}
+	min, _ := strconv.Atoi(matches[1])
+	max, _ := strconv.Atoi(matches[2])
+	if min < 1 || max > 65535 {
+		return fmt.Errorf("ports must be between 1 and 65535")
+	}
+	if min > max {
+		return fmt.Errorf("the first port must not be greater than the last")
+	}
+	return nil
+}
+
+func validateUpgradeStrategy(strategy *ecv1beta1.UpgradeStrategy, path *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	if strategy.MaxParallelNodes < 0 {
+		errs = append(errs, field.Invalid(path.Child("maxParallelNodes"), strategy.MaxParallelNodes, "must not be negative"))
+	}
+	if strategy.HealthCheckTimeout != nil && strategy.HealthCheckTimeout.Duration <= 0 {
+		errs = append(errs, field.Invalid(path.Child("healthCheckTimeout"), strategy.HealthCheckTimeout.Duration.String(), "must be greater than zero"))
+	}
+	if strategy.MinFreeDiskSpace != nil && strategy.MinFreeDiskSpace.Sign() <= 0 {
+		errs = append(errs, field.Invalid(path.Child("minFreeDiskSpace"), strategy.MinFreeDiskSpace.String(), "must be greater than zero"))
+	}
+	for i, window := range strategy.MaintenanceWindows {
+		windowPath := path.Child("maintenanceWindows").Index(i)
+		if _, err := time.Parse("15:04", window.Start); err != nil {
+			errs = append(errs, field.Invalid(windowPath.Child("start"), window.Start, "must be in the format HH:MM"))
+		}
+		if window.Duration.Duration <= 0 {
+			errs = append(errs, field.Invalid(windowPath.Child("duration"), window.Duration.Duration.String(), "must be greater than zero"))
+		}
+		for j, day := range window.Days {
+			if !isWeekday(day) {
+				errs = append(errs, field.Invalid(windowPath.Child("days").Index(j), day, "must be a day of the week"))
+			}
+		}
+	}
+	return errs
+}
+
+// minHostPreflightInterval is the minimum time between two host preflight runs on a node. Some
+// of the checks take a few minutes to run.
+const minHostPreflightInterval = 10 * time.Minute
+
+func validateHostPreflightMonitoring(mon *ecv1beta1.HostPreflightMonitoring, path *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	if mon.Interval != nil && mon.Interval.Duration < minHostPreflightInterval {
+		errs = append(errs, field.Invalid(path.Child("interval"), mon.Interval.Duration.String(), fmt.Sprintf("must be at least %s", minHostPreflightInterval)))
+	}
+	for i, check := range mon.Checks {
+		if !slices.Contains(preflights.MonitorChecks, check) {
+			errs = append(errs, field.NotSupported(path.Child("checks").Index(i), check, preflights.MonitorChecks))
+		}
+	}
+	return errs
+}
+
+// isWeekday returns true if day is the full or the three letter name of a day of the week.
+func isWeekday(day string) bool {
+	for wd := time.Sunday; wd <= time.Saturday; wd++ {
+		if strings.EqualFold(day, wd.String()[:3]) || strings.EqualFold(day, wd.String()) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateAddOns validates the third party addons. Release names must be unique and must not
+// clash with the built-in addons listed in builtIn.
+func validateAddOns(addOns []ecv1beta1.AddOn, builtIn []string, path *field.Path) field.ErrorList {
+	errs := field.ErrorList{}
+	names := map[string]bool{}
+	for _, name := range builtIn {
+		names[name] = true
+	}
+	for i, addon := range addOns {
+		addonPath := path.Index(i)
+		for _, required := range []struct{ name, value string }{
+			{"name", addon.Name},
+			{"releaseName", addon.ReleaseName},
+			{"chartname", addon.ChartName},
+			{"version", addon.Version},
+			{"namespace", addon.Namespace},
+		} {
+			if required.value == "" {
+				errs = append(errs, field.Required(addonPath.Child(required.name), ""))
+			}
+		}
+		if addon.ReleaseName != "" && names[addon.ReleaseName] {
+			errs = append(errs, field.Duplicate(addonPath.Child("releaseName"), addon.ReleaseName))
+		}
+		names[addon.ReleaseName] = true
+		for _, tpl := range []struct{ name, value string }{
+			{"values", addon.Values},
+			{"prerequisites", addon.Prerequisites},
+		} {
+			if _, err := template.New(tpl.name).Parse(tpl.value); err != nil {
+				errs = append(errs, field.Invalid(addonPath.Child(tpl.name), field.OmitValueType{}, fmt.Sprintf("must be a valid template: %v", err)))
+			}
+		}
+	}
+	return errs
+}
+
+func validateYAML(values string, path *field.Path) field.ErrorList {
+	if values == "" {
+		return nil
+	}
+	var parsed map[string]interface{}
+	if err := yaml.Unmarshal([]byte(values), &parsed); err != nil {
+		return field.ErrorList{field.Invalid(path, field.OmitValueType{}, fmt.Sprintf("must be a valid YAML object: %v", err))}
+	}
+	return nil
+}
@@ -0,0 +1,253 @@
+package webhooks
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestInstallationValidator(t *testing.T) {
+	tests := []struct {
+		name       string
+		spec       ecv1beta1.InstallationSpec
+		wantFields []string
+	}{
+		{
+			name: "valid",
+			spec: ecv1beta1.InstallationSpec{
+				Config: &ecv1beta1.ConfigSpec{
+					Version: "1.0.0",
+					UnsupportedOverrides: ecv1beta1.UnsupportedOverrides{
+						K0s: "config:\n  spec:\n    telemetry:\n      enabled: false\n",
+						BuiltInExtensions: []ecv1beta1.BuiltInExtension{
+							{Name: "admin-console", Values: "foo: bar\n"},
+						},
+					},
+					Extensions: ecv1beta1.Extensions{
+						Helm: &ecv1beta1.Helm{
+							Charts: []ecv1beta1.Chart{{Name: "nginx", ChartName: "oci://nginx", Version: "1.0.0"}},
+						},
+					},
+				},
+				Network: &ecv1beta1.NetworkSpec{
+					PodCIDR:       "10.244.0.0/16",
+					ServiceCIDR:   "10.96.0.0/12",
+					NodePortRange: "80-32767",
+				},
+				EndUserK0sConfigOverrides: "config:\n  spec:\n    api:\n      extraArgs:\n        foo: bar\n",
+				UpgradeStrategy: &ecv1beta1.UpgradeStrategy{
+					MaxParallelNodes: 2,
+					MaintenanceWindows: []ecv1beta1.MaintenanceWindow{
+						{Days: []string{"Sat", "sunday"}, Start: "22:00", Duration: metav1.Duration{Duration: 4 * time.Hour}},
+					},
+				},
+				HostPreflightMonitoring: &ecv1beta1.HostPreflightMonitoring{
+					Enabled:  true,
+					Interval: &metav1.Duration{Duration: time.Hour},
+					Checks:   []string{ecv1beta1.HostPreflightCheckDiskSpace, ecv1beta1.HostPreflightCheckClock},
+				},
+				RegistryGarbageCollection: &ecv1beta1.RegistryGarbageCollection{
+					DryRun:       true,
+					KeepReleases: 3,
+				},
+			},
+		},
+		{
+			name: "invalid network",
+			spec: ecv1beta1.InstallationSpec{
+				Network: &ecv1beta1.NetworkSpec{
+					PodCIDR:       "10.244.0.1/16",
+					ServiceCIDR:   "not-a-cidr",
+					NodePortRange: "32767-80",
+				},
+			},
+			wantFields: []string{"spec.network.podCIDR", "spec.network.serviceCIDR", "spec.network.nodePortRange"},
+		},
+		{
+			name: "invalid k0s overrides",
+			spec: ecv1beta1.InstallationSpec{
+				Config: &ecv1beta1.ConfigSpec{
+					UnsupportedOverrides: ecv1beta1.UnsupportedOverrides{K0s: "config: [not, a, map"},
+				},
+				EndUserK0sConfigOverrides: "config:\n  spec: 1\n",
+			},
+			wantFields: []string{"spec.config.unsupportedOverrides.k0s", "spec.endUserK0sConfigOverrides"},
+		},
+		{
+			name: "unknown built-in extension and invalid chart values",
+			spec: ecv1beta1.InstallationSpec{
+				Config: &ecv1beta1.ConfigSpec{
+					UnsupportedOverrides: ecv1beta1.UnsupportedOverrides{
+						BuiltInExtensions: []ecv1beta1.BuiltInExtension{{Name: "unknown"}},
+					},
+					Extensions: ecv1beta1.Extensions{
+						Helm: &ecv1beta1.Helm{
+							Charts: []ecv1beta1.Chart{
+								{Name: "nginx", Values: "- a list"},
+								{Name: "nginx"},
+								{},
+							},
+						},
+					},
+				},
+			},
+			wantFields: []string{
+				"spec.config.unsupportedOverrides.builtInExtensions[0].name",
+				"spec.config.extensions.helm.charts[0].values",
+				"spec.config.extensions.helm.charts[1].name",
+				"spec.config.extensions.helm.charts[2].name",
+			},
+		},
+		{
+			name: "invalid third party addons",
+			spec: ecv1beta1.InstallationSpec{
+				Config: &ecv1beta1.ConfigSpec{
+					UnsupportedOverrides: ecv1beta1.UnsupportedOverrides{
+						BuiltInExtensions: []ecv1beta1.BuiltInExtension{{Name: "cert-manager"}},
+					},
+					Extensions: ecv1beta1.Extensions{
+						AddOns: []ecv1beta1.AddOn{
+							{Name: "Cert Manager", ReleaseName: "cert-manager", ChartName: "oci://registry.example.com/cert-manager", Version: "1.16.1", Namespace: "cert-manager"},
+							{Name: "Registry", ReleaseName: "docker-registry", ChartName: "oci://registry.example.com/registry", Version: "1.0.0", Namespace: "registry"},
+							{Name: "Broken", ReleaseName: "broken", Values: "{{ .Namespace", Namespace: "broken"},
+						},
+					},
+				},
+			},
+			wantFields: []string{
+				"spec.config.extensions.addons[1].releaseName",
+				"spec.config.extensions.addons[2].chartname",
+				"spec.config.extensions.addons[2].version",
+				"spec.config.extensions.addons[2].values",
+			},
+		},
+		{
+			name: "invalid upgrade strategy",
+			spec: ecv1beta1.InstallationSpec{
+				UpgradeStrategy: &ecv1beta1.UpgradeStrategy{
+					MaxParallelNodes: -1,
+					MaintenanceWindows: []ecv1beta1.MaintenanceWindow{
+						{Days: []string{"Someday"}, Start: "25:00"},
+					},
+					MinFreeDiskSpace: ptr.To(resource.MustParse("0")),
+				},
+			},
+			wantFields: []string{
+				"spec.upgradeStrategy.maxParallelNodes",
+				"spec.upgradeStrategy.minFreeDiskSpace",
+				"spec.upgradeStrategy.maintenanceWindows[0].start",
+				"spec.upgradeStrategy.maintenanceWindows[0].duration",
+				"spec.upgradeStrategy.maintenanceWindows[0].days[0]",
+			},
+		},
+		{
+			name: "invalid host preflight monitoring",
+			spec: ecv1beta1.InstallationSpec{
+				HostPreflightMonitoring: &ecv1beta1.HostPreflightMonitoring{
+					Enabled:  true,
+					Interval: &metav1.Duration{Duration: time.Minute},
+					Checks:   []string{ecv1beta1.HostPreflightCheckClock, "Memory"},
+				},
+			},
+			wantFields: []string{
+				"spec.hostPreflightMonitoring.interval",
+				"spec.hostPreflightMonitoring.checks[1]",
+			},
+		},
+		{
+			name: "invalid registry garbage collection",
+			spec: ecv1beta1.InstallationSpec{
+				RegistryGarbageCollection: &ecv1beta1.RegistryGarbageCollection{
+					KeepReleases: -1,
+				},
+			},
+			wantFields: []string{
+				"spec.registryGarbageCollection.keepReleases",
+			},
+		},
+		{
+			name: "unknown cni and storage providers",
+			spec: ecv1beta1.InstallationSpec{
+				Config: &ecv1beta1.ConfigSpec{
+					CNI:     &ecv1beta1.CNISpec{Provider: "Cilium"},
+					Storage: &ecv1beta1.StorageSpec{Provider: "ceph"},
+				},
+			},
+			wantFields: []string{
+				"spec.config.cni.provider",
+				"spec.config.storage.provider",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := &ecv1beta1.Installation{
+				ObjectMeta: metav1.ObjectMeta{Name: "20241002205018"},
+				Spec:       tt.spec,
+			}
+			_, err := (&InstallationValidator{}).ValidateCreate(context.Background(), in)
+			if len(tt.wantFields) == 0 {
+				require.NoError(t, err)
+				return
+			}
+			assert.ElementsMatch(t, tt.wantFields, invalidFields(t, err))
+		})
+	}
+}
+
+func TestInstallationValidator_ValidateUpdate(t *testing.T) {
+	invalid := &ecv1beta1.Installation{
+		ObjectMeta: metav1.ObjectMeta{Name: "20241002205018"},
+		Spec: ecv1beta1.InstallationSpec{
+			Network: &ecv1beta1.NetworkSpec{PodCIDR: "not-a-cidr"},
+		},
+	}
+
+	// updates that do not change the spec are accepted.
+	annotated := invalid.DeepCopy()
+	annotated.Annotations = map[string]string{"foo": "bar"}
+	_, err := (&InstallationValidator{}).ValidateUpdate(context.Background(), invalid, annotated)
+	require.NoError(t, err)
+
+	changed := invalid.DeepCopy()
+	changed.Spec.Network.ServiceCIDR = "10.96.0.0/12"
+	_, err = (&InstallationValidator{}).ValidateUpdate(context.Background(), invalid, changed)
+	assert.ElementsMatch(t, []string{"spec.network.podCIDR"}, invalidFields(t, err))
+}
+
+func TestConfigValidator(t *testing.T) {
+	cfg := &ecv1beta1.Config{
+		ObjectMeta: metav1.ObjectMeta{Name: "embedded-cluster"},
+		Spec: ecv1beta1.ConfigSpec{
+			UnsupportedOverrides: ecv1beta1.UnsupportedOverrides{
+				BuiltInExtensions: []ecv1beta1.BuiltInExtension{{Name: "openebs", Values: "a: [b"}},
+			},
+		},
+	}
+	_, err := (&ConfigValidator{}).ValidateCreate(context.Background(), cfg)
+	assert.ElementsMatch(t, []string{"spec.unsupportedOverrides.builtInExtensions[0].values"}, invalidFields(t, err))
+
+	cfg.Spec.UnsupportedOverrides.BuiltInExtensions[0].Values = "a: b"
+	_, err = (&ConfigValidator{}).ValidateCreate(context.Background(), cfg)
+	require.NoError(t, err)
+}
+
+func invalidFields(t *testing.T, err error) []string {
+	t.Helper()
+	require.True(t, apierrors.IsInvalid(err), "expected an invalid error, got %v", err)
+	status, ok := err.(apierrors.APIStatus)
+	require.True(t, ok)
+	fields := []string{}
+	for _, cause := range status.Status().Details.Causes {
+		fields = append(fields, cause.Field)
+	}
+	return fields
+}
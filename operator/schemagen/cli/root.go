@@ -108,6 +108,7 @@ func generateSchemas(v *viper.Viper) error {
 }
 
 func writeSchema(schema *extensionsv1.JSONSchemaProps, outfile string) error {
+	allowIntOrString(schema)
 
 	b, err := json.MarshalIndent(schema, "", "  ")
 	if err != nil {
@@ -145,3 +146,26 @@ func writeSchema(schema *extensionsv1.JSONSchemaProps, outfile string) error {
 
 	return nil
 }
+
+// allowIntOrString makes the fields marked as x-kubernetes-int-or-string accept both types. The
+// api server honors the extension but json schema validators do not, they would reject the
+// integers, e.g. the helm chart timeouts that are marshaled as nanoseconds.
+func allowIntOrString(schema *extensionsv1.JSONSchemaProps) {
+	if schema == nil {
+		return
+	}
+	if schema.XIntOrString && schema.Type != "" {
+		schema.Type = ""
+		schema.AnyOf = []extensionsv1.JSONSchemaProps{{Type: "integer"}, {Type: "string"}}
+	}
+	for name, prop := range schema.Properties {
+		allowIntOrString(&prop)
+		schema.Properties[name] = prop
+	}
+	if schema.Items != nil {
+		allowIntOrString(schema.Items.Schema)
+	}
+	if schema.AdditionalProperties != nil {
+		allowIntOrString(schema.AdditionalProperties.Schema)
+	}
+}
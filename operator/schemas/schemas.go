@@ -0,0 +1,11 @@
+// Package schemas holds the JSON schemas generated from the CRDs in this project.
+package schemas
+
+import (
+	_ "embed"
+)
+
+// ConfigV1Beta1 is the JSON schema of the v1beta1 Config kind.
+//
+//go:embed config-embeddedcluster-v1beta1.json
+var ConfigV1Beta1 []byte
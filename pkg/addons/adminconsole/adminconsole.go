@@ -5,6 +5,12 @@ import (
 
 	"github.com/pkg/errors"
 	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/embeddedclusteroperator"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/ingress"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/registry"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/seaweedfs"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/storage"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/velero"
 	"github.com/replicatedhq/embedded-cluster/pkg/release"
 	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
 	"github.com/replicatedhq/embedded-cluster/pkg/spinner"
@@ -20,6 +26,18 @@ type AdminConsole struct {
 	Password      string
 	PrivateCAs    []string
 	KotsInstaller KotsInstaller
+	// Ingress exposes the admin console through the built-in ingress controller.
+	Ingress *ecv1beta1.AdminConsoleIngressSpec
+	// StorageProvider provisions the admin console volumes. Defaults to OpenEBS.
+	StorageProvider string
+	// ExternalRegistry is used instead of the bundled registry in air gap installations, the
+	// registry secret is created with its credentials.
+	ExternalRegistry         *ecv1beta1.ExternalRegistrySpec
+	ExternalRegistryUsername string
+	ExternalRegistryPassword string
+	// ExtraDependencies are the release names of other addons, not known in advance, the
+	// admin console depends on.
+	ExtraDependencies []string
 }
 
 type KotsInstaller func(msg *spinner.MessageWriter) error
@@ -86,6 +104,20 @@ func (a *AdminConsole) Namespace() string {
 	return namespace
 }
 
+// Dependencies returns the addons the admin console depends on. It is installed last as the
+// application it deploys may rely on any of the other addons.
+func (a *AdminConsole) Dependencies() []string {
+	deps := []string{
+		storage.ReleaseName(a.StorageProvider),
+		(&embeddedclusteroperator.EmbeddedClusterOperator{}).ReleaseName(),
+		(&registry.Registry{}).ReleaseName(),
+		(&seaweedfs.SeaweedFS{}).ReleaseName(),
+		(&velero.Velero{}).ReleaseName(),
+		(&ingress.Ingress{}).ReleaseName(),
+	}
+	return append(deps, a.ExtraDependencies...)
+}
+
 func getBackupLabels() map[string]string {
 	return map[string]string{
 		"replicated.com/disaster-recovery":       "infra",
@@ -0,0 +1,103 @@
+package adminconsole
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/ingress"
+	networkingv1 "k8s.io/api/networking/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// ingressName is the name of the ingress exposing the admin console through the built-in
+	// ingress controller.
+	ingressName = "kotsadm"
+	// defaultTLSSecretName is the secret holding the certificate served by the admin console.
+	// It is created, and updated when a certificate is uploaded, by the admin console itself.
+	defaultTLSSecretName = "kotsadm-tls"
+	// kurlProxyServiceName and kurlProxyServicePort are the service terminating tls in front of
+	// the admin console.
+	kurlProxyServiceName = "kurl-proxy-kotsadm"
+	kurlProxyServicePort = 8800
+)
+
+// ensureIngress exposes the admin console on the hostname of the spec through the built-in
+// ingress controller. The ingress is removed if the spec is nil.
+func ensureIngress(ctx context.Context, kcli client.Client, spec *ecv1beta1.AdminConsoleIngressSpec) error {
+	if spec == nil {
+		obj := &networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{Name: ingressName, Namespace: namespace}}
+		if err := kcli.Delete(ctx, obj); err != nil && !k8serrors.IsNotFound(err) {
+			return errors.Wrap(err, "delete ingress")
+		}
+		return nil
+	}
+
+	obj := newIngress(spec)
+	var existing networkingv1.Ingress
+	err := kcli.Get(ctx, client.ObjectKeyFromObject(obj), &existing)
+	if k8serrors.IsNotFound(err) {
+		if err := kcli.Create(ctx, obj); err != nil {
+			return errors.Wrap(err, "create ingress")
+		}
+		return nil
+	} else if err != nil {
+		return errors.Wrap(err, "get ingress")
+	}
+
+	existing.Labels = obj.Labels
+	existing.Annotations = obj.Annotations
+	existing.Spec = obj.Spec
+	if err := kcli.Update(ctx, &existing); err != nil {
+		return errors.Wrap(err, "update ingress")
+	}
+	return nil
+}
+
+func newIngress(spec *ecv1beta1.AdminConsoleIngressSpec) *networkingv1.Ingress {
+	tlsSecretName := spec.TLSSecretName
+	if tlsSecretName == "" {
+		tlsSecretName = defaultTLSSecretName
+	}
+
+	return &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ingressName,
+			Namespace: namespace,
+			Labels:    getBackupLabels(),
+			Annotations: map[string]string{
+				// the kurl proxy only serves https
+				"nginx.ingress.kubernetes.io/backend-protocol": "HTTPS",
+				"nginx.ingress.kubernetes.io/proxy-body-size":  "0",
+			},
+		},
+		Spec: networkingv1.IngressSpec{
+			IngressClassName: ptr.To(ingress.ClassName),
+			TLS: []networkingv1.IngressTLS{{
+				Hosts:      []string{spec.Hostname},
+				SecretName: tlsSecretName,
+			}},
+			Rules: []networkingv1.IngressRule{{
+				Host: spec.Hostname,
+				IngressRuleValue: networkingv1.IngressRuleValue{
+					HTTP: &networkingv1.HTTPIngressRuleValue{
+						Paths: []networkingv1.HTTPIngressPath{{
+							Path:     "/",
+							PathType: ptr.To(networkingv1.PathTypePrefix),
+							Backend: networkingv1.IngressBackend{
+								Service: &networkingv1.IngressServiceBackend{
+									Name: kurlProxyServiceName,
+									Port: networkingv1.ServiceBackendPort{Number: kurlProxyServicePort},
+								},
+							},
+						}},
+					},
+				},
+			}},
+		},
+	}
+}
@@ -3,10 +3,12 @@ package adminconsole
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"os"
 
 	"github.com/pkg/errors"
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/registry"
 	"github.com/replicatedhq/embedded-cluster/pkg/helm"
 	"github.com/replicatedhq/embedded-cluster/pkg/spinner"
@@ -42,6 +44,10 @@ func (a *AdminConsole) Install(ctx context.Context, kcli client.Client, hcli hel
 		return errors.Wrap(err, "helm install")
 	}
 
+	if err := ensureIngress(ctx, kcli, a.Ingress); err != nil {
+		return errors.Wrap(err, "ensure ingress")
+	}
+
 	// install the application
 
 	if a.KotsInstaller != nil {
@@ -63,16 +69,22 @@ func (a *AdminConsole) createPreRequisites(ctx context.Context, kcli client.Clie
 		return errors.Wrap(err, "create kots password secret")
 	}
 
-	if err := createCAConfigmap(ctx, kcli, namespace, a.PrivateCAs); err != nil {
+	if err := createCAConfigmap(ctx, kcli, namespace, a.PrivateCAs, a.ExternalRegistry); err != nil {
 		return errors.Wrap(err, "create kots CA configmap")
 	}
 
-	if a.IsAirgap {
+	// kots pushes the application images to the registry in the registry secret.
+	if a.ExternalRegistry != nil {
+		if err := createRegistrySecret(ctx, kcli, namespace, a.ExternalRegistry.Address, a.ExternalRegistryUsername, a.ExternalRegistryPassword); err != nil {
+			return errors.Wrap(err, "create registry secret")
+		}
+	} else if a.IsAirgap {
 		registryIP, err := registry.GetRegistryClusterIP(a.ServiceCIDR)
 		if err != nil {
 			return errors.Wrap(err, "get registry cluster IP")
 		}
-		if err := createRegistrySecret(ctx, kcli, namespace, registryIP); err != nil {
+		address := fmt.Sprintf("%s:5000", registryIP)
+		if err := createRegistrySecret(ctx, kcli, namespace, address, "embedded-cluster", registry.GetRegistryPassword()); err != nil {
 			return errors.Wrap(err, "create registry secret")
 		}
 	}
@@ -125,11 +137,15 @@ func createPasswordSecret(ctx context.Context, kcli client.Client, namespace str
 	return nil
 }
 
-func createCAConfigmap(ctx context.Context, cli client.Client, namespace string, privateCAs []string) error {
+func createCAConfigmap(ctx context.Context, cli client.Client, namespace string, privateCAs []string, externalRegistry *ecv1beta1.ExternalRegistrySpec) error {
 	cas, err := privateCAsToMap(privateCAs)
 	if err != nil {
 		return errors.Wrap(err, "create private cas map")
 	}
+	// kots must trust the external registry to push the application images to it.
+	if externalRegistry != nil && externalRegistry.CA != "" {
+		cas["external_registry_ca.crt"] = externalRegistry.CA
+	}
 
 	kotsCAConfigmap := corev1.ConfigMap{
 		TypeMeta: metav1.TypeMeta{
@@ -155,9 +171,11 @@ func createCAConfigmap(ctx context.Context, cli client.Client, namespace string,
 	return nil
 }
 
-func createRegistrySecret(ctx context.Context, kcli client.Client, namespace string, registryIP string) error {
-	authString := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("embedded-cluster:%s", registry.GetRegistryPassword())))
-	authConfig := fmt.Sprintf(`{"auths":{"%s:5000":{"username": "embedded-cluster", "password": "%s", "auth": "%s"}}}`, registryIP, registry.GetRegistryPassword(), authString)
+func createRegistrySecret(ctx context.Context, kcli client.Client, namespace string, address string, username string, password string) error {
+	authConfig, err := registryAuthConfig(address, username, password)
+	if err != nil {
+		return errors.Wrap(err, "create registry auth config")
+	}
 
 	registryCreds := corev1.Secret{
 		TypeMeta: metav1.TypeMeta{
@@ -179,7 +197,7 @@ func createRegistrySecret(ctx context.Context, kcli client.Client, namespace str
 		Type: "kubernetes.io/dockerconfigjson",
 	}
 
-	err := kcli.Create(ctx, &registryCreds)
+	err = kcli.Create(ctx, &registryCreds)
 	if err != nil {
 		return errors.Wrap(err, "create registry-auth secret")
 	}
@@ -187,6 +205,30 @@ func createRegistrySecret(ctx context.Context, kcli client.Client, namespace str
 	return nil
 }
 
+// registryAuthConfig returns the docker config json with the credentials of the registry in
+// address.
+func registryAuthConfig(address string, username string, password string) (string, error) {
+	type authEntry struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Auth     string `json:"auth"`
+	}
+	config := map[string]map[string]authEntry{
+		"auths": {
+			address: {
+				Username: username,
+				Password: password,
+				Auth:     base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", username, password))),
+			},
+		},
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
 func privateCAsToMap(privateCAs []string) (map[string]string, error) {
 	cas := map[string]string{}
 	for i, path := range privateCAs {
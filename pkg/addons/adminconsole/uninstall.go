@@ -0,0 +1,14 @@
+package adminconsole
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Uninstall always fails as the addon is required by every installation.
+func (a *AdminConsole) Uninstall(ctx context.Context, kcli client.Client, hcli helm.Client) error {
+	return errors.Errorf("%s can not be uninstalled", a.Name())
+}
@@ -36,5 +36,9 @@ func (a *AdminConsole) Upgrade(ctx context.Context, kcli client.Client, hcli hel
 		return errors.Wrap(err, "helm upgrade")
 	}
 
+	if err := ensureIngress(ctx, kcli, a.Ingress); err != nil {
+		return errors.Wrap(err, "ensure ingress")
+	}
+
 	return nil
 }
@@ -0,0 +1,70 @@
+package cilium
+
+import (
+	_ "embed"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/embedded-cluster/pkg/release"
+	"gopkg.in/yaml.v3"
+)
+
+// Cilium provides the pod network when selected as the cni provider. k0s is configured with
+// a custom network provider, so the nodes only become ready once Cilium is installed.
+type Cilium struct{}
+
+const (
+	releaseName = "cilium"
+	namespace   = "kube-system"
+
+	// HealthPort is the port the agents use to probe the connectivity between the nodes.
+	HealthPort = 4240
+	// VXLANPort is the udp port the pod traffic between the nodes is tunneled through.
+	VXLANPort = 8472
+)
+
+// Interfaces are the network interfaces created by Cilium on the nodes.
+var Interfaces = []string{"cilium_host", "cilium_net", "cilium_vxlan", "lxc+"}
+
+var (
+	//go:embed static/values.tpl.yaml
+	rawvalues []byte
+	// helmValues is the unmarshal version of rawvalues.
+	helmValues map[string]interface{}
+	//go:embed static/metadata.yaml
+	rawmetadata []byte
+	// Metadata is the unmarshal version of rawmetadata.
+	Metadata release.AddonMetadata
+)
+
+func init() {
+	if err := yaml.Unmarshal(rawmetadata, &Metadata); err != nil {
+		panic(errors.Wrap(err, "unable to unmarshal metadata"))
+	}
+	hv, err := release.RenderHelmValues(rawvalues, Metadata)
+	if err != nil {
+		panic(errors.Wrap(err, "unable to unmarshal values"))
+	}
+	helmValues = hv
+}
+
+func (c *Cilium) Name() string {
+	return "Network"
+}
+
+func (c *Cilium) Version() string {
+	return Metadata.Version
+}
+
+func (c *Cilium) ReleaseName() string {
+	return releaseName
+}
+
+func (c *Cilium) Namespace() string {
+	return namespace
+}
+
+// Dependencies returns the addons Cilium depends on. It does not depend on any, all the other
+// addons need the pod network it provides.
+func (c *Cilium) Dependencies() []string {
+	return nil
+}
@@ -0,0 +1,30 @@
+package cilium
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"github.com/replicatedhq/embedded-cluster/pkg/spinner"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func (c *Cilium) Install(ctx context.Context, kcli client.Client, hcli helm.Client, overrides []string, writer *spinner.MessageWriter) error {
+	values, err := c.GenerateHelmValues(ctx, kcli, overrides)
+	if err != nil {
+		return errors.Wrap(err, "generate helm values")
+	}
+
+	_, err = hcli.Install(ctx, helm.InstallOptions{
+		ReleaseName:  releaseName,
+		ChartPath:    Metadata.Location,
+		ChartVersion: Metadata.Version,
+		Values:       values,
+		Namespace:    namespace,
+	})
+	if err != nil {
+		return errors.Wrap(err, "helm install")
+	}
+
+	return nil
+}
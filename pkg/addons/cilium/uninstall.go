@@ -0,0 +1,15 @@
+package cilium
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Uninstall always fails as the cni provider can not be changed once the cluster is installed,
+// the nodes would lose their pod network.
+func (c *Cilium) Uninstall(ctx context.Context, kcli client.Client, hcli helm.Client) error {
+	return errors.Errorf("%s can not be uninstalled", c.Name())
+}
@@ -0,0 +1,155 @@
+package addons
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/types"
+	"github.com/replicatedhq/embedded-cluster/pkg/dryrun"
+	"go.uber.org/multierr"
+)
+
+// parallelism is the maximum number of addons installed, upgraded or restored at the same time.
+var parallelism = 3
+
+// addOnFunc installs, upgrades or restores a single addon.
+type addOnFunc func(ctx context.Context, addon types.AddOn) error
+
+type addOnState int
+
+const (
+	addOnPending addOnState = iota
+	addOnRunning
+	addOnSucceeded
+	addOnFailed
+	addOnSkipped
+)
+
+// stopError is returned by an addOnFunc to stop starting new addons. The addons already
+// running are waited for.
+type stopError struct {
+	err error
+}
+
+func (e stopError) Error() string {
+	return e.err.Error()
+}
+
+func (e stopError) Unwrap() error {
+	return e.err
+}
+
+// stopAddOns wraps err so no new addon is started once it is returned by an addOnFunc.
+func stopAddOns(err error) error {
+	return stopError{err: err}
+}
+
+type addOnResult struct {
+	name string
+	err  error
+}
+
+// runAddOns runs fn for all the addons as a dependency graph. An addon starts once all of its
+// dependencies present in addons succeeded, at most parallelism addons run at the same time and
+// addons ready at the same time start in the order they are provided. Addons depending on a
+// failed addon are skipped and no new addon is started once an addon returns an error wrapped
+// with stopAddOns. The errors of all the failed addons are returned together.
+func runAddOns(ctx context.Context, addons []types.AddOn, fn addOnFunc) error {
+	limit := parallelism
+	if dryrun.Enabled() {
+		// dry runs record the operations, keep them in a deterministic order.
+		limit = 1
+	}
+
+	states := map[string]addOnState{}
+	for _, addon := range addons {
+		if _, ok := states[addon.ReleaseName()]; ok {
+			return errors.Errorf("addon %s is listed more than once", addon.ReleaseName())
+		}
+		states[addon.ReleaseName()] = addOnPending
+	}
+
+	results := make(chan addOnResult)
+	running := 0
+	remaining := len(addons)
+	var errs error
+
+	stopped := false
+	for remaining > 0 {
+		for progress := !stopped; progress; {
+			progress = false
+			for _, addon := range addons {
+				name := addon.ReleaseName()
+				if states[name] != addOnPending {
+					continue
+				}
+
+				ready, failedDep := dependenciesState(addon, states)
+				if failedDep != "" {
+					slog.Warn("Skipping addon as a dependency failed", "name", addon.Name(), "dependency", failedDep)
+					states[name] = addOnSkipped
+					remaining--
+					progress = true
+					continue
+				}
+				if !ready || running >= limit {
+					continue
+				}
+
+				states[name] = addOnRunning
+				running++
+				progress = true
+				go func(addon types.AddOn) {
+					results <- addOnResult{name: addon.ReleaseName(), err: fn(ctx, addon)}
+				}(addon)
+			}
+		}
+
+		if remaining == 0 {
+			break
+		}
+		if running == 0 {
+			if stopped {
+				break
+			}
+			return multierr.Append(errs, fmt.Errorf("dependency cycle between the remaining addons"))
+		}
+
+		result := <-results
+		running--
+		remaining--
+		if result.err != nil {
+			states[result.name] = addOnFailed
+			errs = multierr.Append(errs, result.err)
+			if errors.As(result.err, &stopError{}) {
+				stopped = true
+			}
+			continue
+		}
+		states[result.name] = addOnSucceeded
+	}
+
+	return errs
+}
+
+// dependenciesState returns whether all the dependencies of the addon succeeded or, if any of
+// them failed or was skipped, its name. Dependencies not present in states are ignored.
+func dependenciesState(addon types.AddOn, states map[string]addOnState) (bool, string) {
+	ready := true
+	for _, dep := range addon.Dependencies() {
+		state, ok := states[dep]
+		if !ok || dep == addon.ReleaseName() {
+			continue
+		}
+		switch state {
+		case addOnFailed, addOnSkipped:
+			return false, dep
+		case addOnSucceeded:
+		default:
+			ready = false
+		}
+	}
+	return ready, ""
+}
@@ -0,0 +1,174 @@
+package addons
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/adminconsole"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/openebs"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/thirdparty"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testAddOn(name string, deps ...string) types.AddOn {
+	return &thirdparty.ThirdParty{
+		Manifest:  ecv1beta1.AddOn{Name: name, ReleaseName: name},
+		DependsOn: deps,
+	}
+}
+
+func Test_runAddOns(t *testing.T) {
+	errBoom := errors.New("boom")
+	errPaused := errors.New("paused")
+
+	tests := []struct {
+		name      string
+		addons    []types.AddOn
+		fail      map[string]error
+		wantRun   []string
+		wantErrIs []error
+		wantErr   string
+	}{
+		{
+			name: "dependencies run first",
+			addons: []types.AddOn{
+				testAddOn("c", "a", "b"),
+				testAddOn("b", "a"),
+				testAddOn("a"),
+			},
+			wantRun: []string{"a", "b", "c"},
+		},
+		{
+			name: "missing dependencies are ignored",
+			addons: []types.AddOn{
+				testAddOn("a", "not-installed"),
+			},
+			wantRun: []string{"a"},
+		},
+		{
+			name: "dependents of a failed addon are skipped",
+			addons: []types.AddOn{
+				testAddOn("a"),
+				testAddOn("b", "a"),
+				testAddOn("c", "b"),
+				testAddOn("d"),
+			},
+			fail:      map[string]error{"a": errBoom},
+			wantRun:   []string{"a", "d"},
+			wantErrIs: []error{errBoom},
+		},
+		{
+			name: "errors are aggregated",
+			addons: []types.AddOn{
+				testAddOn("a"),
+				testAddOn("b"),
+			},
+			fail:      map[string]error{"a": errBoom, "b": errPaused},
+			wantRun:   []string{"a", "b"},
+			wantErrIs: []error{errBoom, errPaused},
+		},
+		{
+			name: "stop prevents starting new addons",
+			addons: []types.AddOn{
+				testAddOn("a"),
+				testAddOn("b", "a"),
+			},
+			fail:      map[string]error{"a": stopAddOns(errPaused)},
+			wantRun:   []string{"a"},
+			wantErrIs: []error{errPaused},
+		},
+		{
+			name: "dependency cycle",
+			addons: []types.AddOn{
+				testAddOn("a", "b"),
+				testAddOn("b", "a"),
+			},
+			wantErr: "dependency cycle",
+		},
+		{
+			name: "duplicate addon",
+			addons: []types.AddOn{
+				testAddOn("a"),
+				testAddOn("a"),
+			},
+			wantErr: "listed more than once",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// run one addon at a time so the order is deterministic.
+			orig := parallelism
+			parallelism = 1
+			t.Cleanup(func() { parallelism = orig })
+
+			var run []string
+			err := runAddOns(context.Background(), tt.addons, func(ctx context.Context, addon types.AddOn) error {
+				run = append(run, addon.Name())
+				return tt.fail[addon.Name()]
+			})
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.Equal(t, tt.wantRun, run)
+			if len(tt.wantErrIs) == 0 {
+				require.NoError(t, err)
+				return
+			}
+			for _, want := range tt.wantErrIs {
+				assert.ErrorIs(t, err, want)
+			}
+		})
+	}
+}
+
+func Test_runAddOns_parallelism(t *testing.T) {
+	addons := []types.AddOn{
+		testAddOn("a"), testAddOn("b"), testAddOn("c"), testAddOn("d"), testAddOn("e"),
+	}
+
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	release := make(chan struct{})
+	started := make(chan struct{}, len(addons))
+	done := make(chan error)
+	go func() {
+		done <- runAddOns(context.Background(), addons, func(ctx context.Context, addon types.AddOn) error {
+			mu.Lock()
+			running++
+			maxRunning = max(maxRunning, running)
+			mu.Unlock()
+			started <- struct{}{}
+			<-release
+			mu.Lock()
+			running--
+			mu.Unlock()
+			return nil
+		})
+	}()
+
+	for i := 0; i < parallelism; i++ {
+		<-started
+	}
+	close(release)
+	require.NoError(t, <-done)
+	assert.Equal(t, parallelism, maxRunning)
+}
+
+func Test_setThirdPartyDependencies(t *testing.T) {
+	ac := &adminconsole.AdminConsole{}
+	first := &thirdparty.ThirdParty{Manifest: ecv1beta1.AddOn{ReleaseName: "first", Order: 1}}
+	second := &thirdparty.ThirdParty{Manifest: ecv1beta1.AddOn{ReleaseName: "second", Order: 2}}
+	addOns := []types.AddOn{&openebs.OpenEBS{}, first, second, ac}
+
+	setThirdPartyDependencies(addOns)
+
+	assert.Equal(t, []string{"openebs"}, first.DependsOn)
+	assert.Equal(t, []string{"openebs", "first"}, second.DependsOn)
+	assert.Equal(t, []string{"first", "second"}, ac.ExtraDependencies)
+}
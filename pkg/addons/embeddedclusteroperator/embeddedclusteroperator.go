@@ -19,8 +19,14 @@ type EmbeddedClusterOperator struct {
 	ImageRepoOverride     string
 	ImageTagOverride      string
 	UtilsImageOverride    string
+	// ExposeMetrics makes the operator serve its metrics on all interfaces so they can be
+	// scraped by the monitoring addon.
+	ExposeMetrics bool
 }
 
+// MetricsPort is the port the operator serves its metrics on.
+const MetricsPort = 8080
+
 const (
 	releaseName = "embedded-cluster-operator"
 	namespace   = "embedded-cluster"
@@ -68,6 +74,10 @@ func (e *EmbeddedClusterOperator) Namespace() string {
 	return namespace
 }
 
+func (e *EmbeddedClusterOperator) Dependencies() []string {
+	return nil
+}
+
 func (e *EmbeddedClusterOperator) ChartLocation() string {
 	if e.ChartLocationOverride != "" {
 		return e.ChartLocationOverride
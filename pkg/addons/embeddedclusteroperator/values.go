@@ -2,6 +2,7 @@ package embeddedclusteroperator
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/pkg/errors"
 	"github.com/replicatedhq/embedded-cluster/pkg/helm"
@@ -43,6 +44,13 @@ func (e *EmbeddedClusterOperator) GenerateHelmValues(ctx context.Context, kcli c
 		copiedValues["isAirgap"] = "true"
 	}
 
+	if e.ExposeMetrics {
+		err = helm.SetValue(copiedValues, "metrics.bindAddress", fmt.Sprintf(":%d", MetricsPort))
+		if err != nil {
+			return nil, errors.Wrap(err, "set metrics.bindAddress")
+		}
+	}
+
 	if e.Proxy != nil {
 		copiedValues["extraEnv"] = []map[string]interface{}{
 			{
@@ -8,6 +8,7 @@ import (
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/adminconsole"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/registry"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/seaweedfs"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/storage"
 	"github.com/replicatedhq/embedded-cluster/pkg/constants"
 	"github.com/replicatedhq/embedded-cluster/pkg/helm"
 	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
@@ -42,19 +43,28 @@ func CanEnableHA(ctx context.Context, kcli client.Client) (bool, error) {
 	return ncps >= 3, nil
 }
 
-// EnableHA enables high availability.
-func EnableHA(ctx context.Context, kcli client.Client, hcli helm.Client, isAirgap bool, serviceCIDR string, proxy *ecv1beta1.ProxySpec, cfgspec *ecv1beta1.ConfigSpec) error {
+// EnableHA enables high availability. The overrides in both the embedded and the end user
+// config specs are applied to the addons reconfigured.
+func EnableHA(ctx context.Context, kcli client.Client, hcli helm.Client, isAirgap bool, serviceCIDR string, proxy *ecv1beta1.ProxySpec, cfgspec *ecv1beta1.ConfigSpec, euCfgSpec *ecv1beta1.ConfigSpec) error {
 	loading := spinner.Start()
 	defer loading.Close()
 
 	logrus.Debugf("Enabling high availability")
 
-	if isAirgap {
+	storageProvider := storage.Provider(GetStorageSpec(cfgspec, euCfgSpec))
+
+	in, err := kubeutils.GetLatestInstallation(ctx, kcli)
+	if err != nil {
+		return errors.Wrap(err, "get latest installation")
+	}
+
+	// the registry data only needs to be moved to seaweedfs when the bundled registry is used.
+	if isAirgap && in.Spec.ExternalRegistry == nil {
 		loading.Infof("Enabling high availability")
 
-		// TODO (@salah): add support for end user overrides
 		sw := &seaweedfs.SeaweedFS{
-			ServiceCIDR: serviceCIDR,
+			ServiceCIDR:     serviceCIDR,
+			StorageProvider: storageProvider,
 		}
 		exists, err := hcli.ReleaseExists(ctx, sw.Namespace(), sw.ReleaseName())
 		if err != nil {
@@ -62,7 +72,7 @@ func EnableHA(ctx context.Context, kcli client.Client, hcli helm.Client, isAirga
 		}
 		if !exists {
 			logrus.Debugf("Installing seaweedfs")
-			if err := sw.Install(ctx, kcli, hcli, addOnOverrides(sw, cfgspec, nil), nil); err != nil {
+			if err := sw.Install(ctx, kcli, hcli, addOnOverrides(sw, cfgspec, euCfgSpec), nil); err != nil {
 				return errors.Wrap(err, "install seaweedfs")
 			}
 			logrus.Debugf("Seaweedfs installed!")
@@ -70,10 +80,10 @@ func EnableHA(ctx context.Context, kcli client.Client, hcli helm.Client, isAirga
 			logrus.Debugf("Seaweedfs already installed")
 		}
 
-		// TODO (@salah): add support for end user overrides
 		reg := &registry.Registry{
-			ServiceCIDR: serviceCIDR,
-			IsHA:        true,
+			ServiceCIDR:     serviceCIDR,
+			IsHA:            true,
+			StorageProvider: storageProvider,
 		}
 		logrus.Debugf("Migrating registry data")
 		if err := reg.Migrate(ctx, kcli, loading); err != nil {
@@ -81,7 +91,7 @@ func EnableHA(ctx context.Context, kcli client.Client, hcli helm.Client, isAirga
 		}
 		logrus.Debugf("Registry migration complete!")
 		logrus.Debugf("Upgrading registry")
-		if err := reg.Upgrade(ctx, kcli, hcli, addOnOverrides(reg, cfgspec, nil)); err != nil {
+		if err := reg.Upgrade(ctx, kcli, hcli, addOnOverrides(reg, cfgspec, euCfgSpec)); err != nil {
 			return errors.Wrap(err, "upgrade registry")
 		}
 		logrus.Debugf("Registry upgraded!")
@@ -90,17 +100,12 @@ func EnableHA(ctx context.Context, kcli client.Client, hcli helm.Client, isAirga
 	loading.Infof("Updating the Admin Console for high availability")
 
 	logrus.Debugf("Enabling admin console high availability")
-	err := EnableAdminConsoleHA(ctx, kcli, hcli, isAirgap, serviceCIDR, proxy, cfgspec)
+	err = EnableAdminConsoleHA(ctx, kcli, hcli, isAirgap, serviceCIDR, proxy, cfgspec, euCfgSpec)
 	if err != nil {
 		return errors.Wrap(err, "enable admin console high availability")
 	}
 	logrus.Debugf("Admin console high availability enabled!")
 
-	in, err := kubeutils.GetLatestInstallation(ctx, kcli)
-	if err != nil {
-		return errors.Wrap(err, "get latest installation")
-	}
-
 	if err := kubeutils.UpdateInstallation(ctx, kcli, in, func(in *ecv1beta1.Installation) {
 		in.Spec.HighAvailability = true
 	}); err != nil {
@@ -114,15 +119,16 @@ func EnableHA(ctx context.Context, kcli client.Client, hcli helm.Client, isAirga
 }
 
 // EnableAdminConsoleHA enables high availability for the admin console.
-func EnableAdminConsoleHA(ctx context.Context, kcli client.Client, hcli helm.Client, isAirgap bool, serviceCIDR string, proxy *ecv1beta1.ProxySpec, cfgspec *ecv1beta1.ConfigSpec) error {
-	// TODO (@salah): add support for end user overrides
+func EnableAdminConsoleHA(ctx context.Context, kcli client.Client, hcli helm.Client, isAirgap bool, serviceCIDR string, proxy *ecv1beta1.ProxySpec, cfgspec *ecv1beta1.ConfigSpec, euCfgSpec *ecv1beta1.ConfigSpec) error {
 	ac := &adminconsole.AdminConsole{
-		IsAirgap:    isAirgap,
-		IsHA:        true,
-		Proxy:       proxy,
-		ServiceCIDR: serviceCIDR,
+		IsAirgap:        isAirgap,
+		IsHA:            true,
+		Proxy:           proxy,
+		ServiceCIDR:     serviceCIDR,
+		Ingress:         adminConsoleIngress(GetIngressSpec(cfgspec, euCfgSpec)),
+		StorageProvider: storage.Provider(GetStorageSpec(cfgspec, euCfgSpec)),
 	}
-	if err := ac.Upgrade(ctx, kcli, hcli, addOnOverrides(ac, cfgspec, nil)); err != nil {
+	if err := ac.Upgrade(ctx, kcli, hcli, addOnOverrides(ac, cfgspec, euCfgSpec)); err != nil {
 		return errors.Wrap(err, "upgrade admin console")
 	}
 
@@ -0,0 +1,70 @@
+package ingress
+
+import (
+	_ "embed"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/embedded-cluster/pkg/release"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"gopkg.in/yaml.v3"
+)
+
+// Ingress is the built-in ingress controller. It runs on every node and binds the host ports
+// 80 and 443.
+type Ingress struct{}
+
+const (
+	releaseName = "ingress-nginx"
+	namespace   = runtimeconfig.IngressNamespace
+
+	// ClassName is the name of the ingress class served by the controller. It is the default
+	// ingress class of the cluster.
+	ClassName = "nginx"
+	// HTTPPort and HTTPSPort are the host ports the controller binds.
+	HTTPPort  = 80
+	HTTPSPort = 443
+)
+
+var (
+	//go:embed static/values.tpl.yaml
+	rawvalues []byte
+	// helmValues is the unmarshal version of rawvalues.
+	helmValues map[string]interface{}
+	//go:embed static/metadata.yaml
+	rawmetadata []byte
+	// Metadata is the unmarshal version of rawmetadata.
+	Metadata release.AddonMetadata
+)
+
+func init() {
+	if err := yaml.Unmarshal(rawmetadata, &Metadata); err != nil {
+		panic(errors.Wrap(err, "unable to unmarshal metadata"))
+	}
+	hv, err := release.RenderHelmValues(rawvalues, Metadata)
+	if err != nil {
+		panic(errors.Wrap(err, "unable to unmarshal values"))
+	}
+	helmValues = hv
+}
+
+func (i *Ingress) Name() string {
+	return "Ingress Controller"
+}
+
+func (i *Ingress) Version() string {
+	return Metadata.Version
+}
+
+func (i *Ingress) ReleaseName() string {
+	return releaseName
+}
+
+func (i *Ingress) Namespace() string {
+	return namespace
+}
+
+// Dependencies returns the addons the ingress controller depends on. It does not depend on
+// any.
+func (i *Ingress) Dependencies() []string {
+	return nil
+}
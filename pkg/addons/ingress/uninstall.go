@@ -0,0 +1,19 @@
+package ingress
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Uninstall removes the ingress controller once it is disabled, freeing the host ports 80 and
+// 443. Ingress objects created by the application are left untouched.
+func (i *Ingress) Uninstall(ctx context.Context, kcli client.Client, hcli helm.Client) error {
+	if err := kubeutils.UninstallReleaseAndNamespace(ctx, kcli, hcli, releaseName, namespace); err != nil {
+		return errors.Wrap(err, "uninstall release")
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package ingress
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func (i *Ingress) GenerateHelmValues(ctx context.Context, kcli client.Client, overrides []string) (map[string]interface{}, error) {
+	// create a copy of the helm values so we don't modify the original
+	marshalled, err := helm.MarshalValues(helmValues)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal helm values")
+	}
+	copiedValues, err := helm.UnmarshalValues(marshalled)
+	if err != nil {
+		return nil, errors.Wrap(err, "unmarshal helm values")
+	}
+
+	for _, override := range overrides {
+		copiedValues, err = helm.PatchValues(copiedValues, override)
+		if err != nil {
+			return nil, errors.Wrap(err, "patch helm values")
+		}
+	}
+
+	return copiedValues, nil
+}
@@ -7,8 +7,11 @@ import (
 	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/adminconsole"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/embeddedclusteroperator"
-	"github.com/replicatedhq/embedded-cluster/pkg/addons/openebs"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/ingress"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/monitoring"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/registry"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/storage"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/thirdparty"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/types"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/velero"
 	"github.com/replicatedhq/embedded-cluster/pkg/helm"
@@ -29,6 +32,10 @@ type InstallOptions struct {
 	EndUserConfigSpec       *ecv1beta1.ConfigSpec
 	KotsInstaller           adminconsole.KotsInstaller
 	IsRestore               bool
+	// ExternalRegistry is used instead of the bundled registry in air gap installations.
+	ExternalRegistry         *ecv1beta1.ExternalRegistrySpec
+	ExternalRegistryUsername string
+	ExternalRegistryPassword string
 }
 
 func Install(ctx context.Context, hcli helm.Client, opts InstallOptions) error {
@@ -42,8 +49,11 @@ func Install(ctx context.Context, hcli helm.Client, opts InstallOptions) error {
 		addons = getAddOnsForRestore(opts)
 	}
 
-	for _, addon := range addons {
-		loading := spinner.Start()
+	progress := spinner.StartMulti()
+	defer progress.Close()
+
+	install := func(ctx context.Context, addon types.AddOn) error {
+		loading := progress.Start()
 		loading.Infof("Installing %s", addon.Name())
 
 		overrides := addOnOverrides(addon, opts.EmbeddedConfigSpec, opts.EndUserConfigSpec)
@@ -54,23 +64,38 @@ func Install(ctx context.Context, hcli helm.Client, opts InstallOptions) error {
 		}
 
 		loading.Closef("%s is ready!", addon.Name())
+		return nil
+	}
+
+	// the pods of the other addons can not start before the pod network is up, the cni addon
+	// is installed on its own first.
+	if cni := cniAddOn(opts.EmbeddedConfigSpec); cni != nil {
+		if err := install(ctx, cni); err != nil {
+			return err
+		}
 	}
 
-	return nil
+	return runAddOns(ctx, addons, install)
 }
 
 func getAddOnsForInstall(opts InstallOptions) []types.AddOn {
+	storageSpec := GetStorageSpec(opts.EmbeddedConfigSpec, opts.EndUserConfigSpec)
+	storageProvider := storage.Provider(storageSpec)
+	monitoringSpec := GetMonitoringSpec(opts.EmbeddedConfigSpec, opts.EndUserConfigSpec)
+
 	addOns := []types.AddOn{
-		&openebs.OpenEBS{},
+		storageAddOn(storageSpec),
 		&embeddedclusteroperator.EmbeddedClusterOperator{
-			IsAirgap: opts.IsAirgap,
-			Proxy:    opts.Proxy,
+			IsAirgap:      opts.IsAirgap,
+			Proxy:         opts.Proxy,
+			ExposeMetrics: monitoringSpec.Enabled,
 		},
 	}
 
-	if opts.IsAirgap {
+	if opts.IsAirgap && opts.ExternalRegistry == nil {
 		addOns = append(addOns, &registry.Registry{
-			ServiceCIDR: opts.ServiceCIDR,
+			ServiceCIDR:     opts.ServiceCIDR,
+			StorageProvider: storageProvider,
 		})
 	}
 
@@ -80,24 +105,83 @@ func getAddOnsForInstall(opts InstallOptions) []types.AddOn {
 		})
 	}
 
+	ingressSpec := GetIngressSpec(opts.EmbeddedConfigSpec, opts.EndUserConfigSpec)
+	if ingressSpec.Enabled {
+		addOns = append(addOns, &ingress.Ingress{})
+	}
+
+	if monitoringSpec.Enabled {
+		addOns = append(addOns, &monitoring.Monitoring{
+			Retention:       monitoringSpec.Retention,
+			RetentionSize:   monitoringSpec.RetentionSize,
+			StorageProvider: storageProvider,
+		})
+	}
+
+	for _, manifest := range thirdPartyManifests(opts.EmbeddedConfigSpec) {
+		addOns = append(addOns, &thirdparty.ThirdParty{
+			Manifest:    manifest,
+			IsAirgap:    opts.IsAirgap,
+			Proxy:       opts.Proxy,
+			ServiceCIDR: opts.ServiceCIDR,
+		})
+	}
+
 	addOns = append(addOns, &adminconsole.AdminConsole{
-		IsAirgap:      opts.IsAirgap,
-		Proxy:         opts.Proxy,
-		ServiceCIDR:   opts.ServiceCIDR,
-		Password:      opts.AdminConsolePwd,
-		PrivateCAs:    opts.PrivateCAs,
-		KotsInstaller: opts.KotsInstaller,
+		IsAirgap:        opts.IsAirgap,
+		Proxy:           opts.Proxy,
+		ServiceCIDR:     opts.ServiceCIDR,
+		Password:        opts.AdminConsolePwd,
+		PrivateCAs:      opts.PrivateCAs,
+		KotsInstaller:   opts.KotsInstaller,
+		Ingress:         adminConsoleIngress(ingressSpec),
+		StorageProvider: storageProvider,
+
+		ExternalRegistry:         opts.ExternalRegistry,
+		ExternalRegistryUsername: opts.ExternalRegistryUsername,
+		ExternalRegistryPassword: opts.ExternalRegistryPassword,
 	})
 
+	setThirdPartyDependencies(addOns)
 	return addOns
 }
 
 func getAddOnsForRestore(opts InstallOptions) []types.AddOn {
+	storageSpec := GetStorageSpec(opts.EmbeddedConfigSpec, opts.EndUserConfigSpec)
+
+	// the volumes are restored by velero into the storage classes of the storage provider.
 	addOns := []types.AddOn{
-		&openebs.OpenEBS{},
+		storageAddOn(storageSpec),
 		&velero.Velero{
 			Proxy: opts.Proxy,
 		},
 	}
+
+	if GetIngressSpec(opts.EmbeddedConfigSpec, opts.EndUserConfigSpec).Enabled {
+		addOns = append(addOns, &ingress.Ingress{})
+	}
+
+	// metrics are not backed up, the monitoring stack starts collecting them from scratch.
+	if monitoringSpec := GetMonitoringSpec(opts.EmbeddedConfigSpec, opts.EndUserConfigSpec); monitoringSpec.Enabled {
+		addOns = append(addOns, &monitoring.Monitoring{
+			Retention:       monitoringSpec.Retention,
+			RetentionSize:   monitoringSpec.RetentionSize,
+			StorageProvider: storage.Provider(storageSpec),
+		})
+	}
+
+	for _, manifest := range thirdPartyManifests(opts.EmbeddedConfigSpec) {
+		if !manifest.Restore {
+			continue
+		}
+		addOns = append(addOns, &thirdparty.ThirdParty{
+			Manifest:    manifest,
+			IsAirgap:    opts.IsAirgap,
+			Proxy:       opts.Proxy,
+			ServiceCIDR: opts.ServiceCIDR,
+		})
+	}
+
+	setThirdPartyDependencies(addOns)
 	return addOns
 }
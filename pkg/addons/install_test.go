@@ -6,8 +6,11 @@ import (
 	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/adminconsole"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/embeddedclusteroperator"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/ingress"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/longhorn"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/openebs"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/registry"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/thirdparty"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/types"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/velero"
 	"github.com/stretchr/testify/assert"
@@ -183,6 +186,131 @@ func Test_getAddOnsForInstall(t *testing.T) {
 				assert.Equal(t, "password123", adminConsole.Password)
 			},
 		},
+		{
+			name: "third party addons",
+			opts: InstallOptions{
+				AdminConsolePwd: "password123",
+				EmbeddedConfigSpec: &ecv1beta1.ConfigSpec{
+					Extensions: ecv1beta1.Extensions{
+						AddOns: []ecv1beta1.AddOn{
+							{Name: "Trust Manager", ReleaseName: "trust-manager", Order: 2},
+							{Name: "Cert Manager", ReleaseName: "cert-manager", Order: 1},
+						},
+					},
+				},
+			},
+			verify: func(t *testing.T, addons []types.AddOn) {
+				assert.Len(t, addons, 5)
+
+				certManager, ok := addons[2].(*thirdparty.ThirdParty)
+				require.True(t, ok, "third addon should be a third party addon")
+				assert.Equal(t, "cert-manager", certManager.ReleaseName())
+
+				trustManager, ok := addons[3].(*thirdparty.ThirdParty)
+				require.True(t, ok, "fourth addon should be a third party addon")
+				assert.Equal(t, "trust-manager", trustManager.ReleaseName())
+
+				_, ok = addons[4].(*adminconsole.AdminConsole)
+				require.True(t, ok, "fifth addon should be AdminConsole")
+			},
+		},
+		{
+			name: "ingress controller with admin console hostname",
+			opts: InstallOptions{
+				AdminConsolePwd: "password123",
+				EmbeddedConfigSpec: &ecv1beta1.ConfigSpec{
+					Ingress: &ecv1beta1.IngressSpec{Enabled: true},
+				},
+				EndUserConfigSpec: &ecv1beta1.ConfigSpec{
+					Ingress: &ecv1beta1.IngressSpec{
+						Enabled:      true,
+						AdminConsole: &ecv1beta1.AdminConsoleIngressSpec{Hostname: "console.example.com"},
+					},
+				},
+			},
+			verify: func(t *testing.T, addons []types.AddOn) {
+				assert.Len(t, addons, 4)
+
+				_, ok := addons[2].(*ingress.Ingress)
+				require.True(t, ok, "third addon should be Ingress")
+
+				adminConsole, ok := addons[3].(*adminconsole.AdminConsole)
+				require.True(t, ok, "fourth addon should be AdminConsole")
+				require.NotNil(t, adminConsole.Ingress)
+				assert.Equal(t, "console.example.com", adminConsole.Ingress.Hostname)
+				assert.Contains(t, adminConsole.Dependencies(), "ingress-nginx")
+			},
+		},
+		{
+			name: "admin console hostname without ingress controller",
+			opts: InstallOptions{
+				AdminConsolePwd: "password123",
+				EmbeddedConfigSpec: &ecv1beta1.ConfigSpec{
+					Ingress: &ecv1beta1.IngressSpec{
+						AdminConsole: &ecv1beta1.AdminConsoleIngressSpec{Hostname: "console.example.com"},
+					},
+				},
+			},
+			verify: func(t *testing.T, addons []types.AddOn) {
+				assert.Len(t, addons, 3)
+
+				adminConsole, ok := addons[2].(*adminconsole.AdminConsole)
+				require.True(t, ok, "third addon should be AdminConsole")
+				assert.Nil(t, adminConsole.Ingress, "AdminConsole should not be exposed through the ingress controller")
+			},
+		},
+		{
+			name: "longhorn storage provider",
+			opts: InstallOptions{
+				IsAirgap:        true,
+				AdminConsolePwd: "password123",
+				EmbeddedConfigSpec: &ecv1beta1.ConfigSpec{
+					Storage: &ecv1beta1.StorageSpec{Provider: ecv1beta1.StorageProviderLonghorn, Replicas: 2},
+				},
+			},
+			verify: func(t *testing.T, addons []types.AddOn) {
+				assert.Len(t, addons, 4)
+
+				lh, ok := addons[0].(*longhorn.Longhorn)
+				require.True(t, ok, "first addon should be Longhorn")
+				assert.Equal(t, 2, lh.Replicas)
+
+				reg, ok := addons[2].(*registry.Registry)
+				require.True(t, ok, "third addon should be Registry")
+				assert.Equal(t, ecv1beta1.StorageProviderLonghorn, reg.StorageProvider)
+				assert.Contains(t, reg.Dependencies(), "longhorn")
+
+				adminConsole, ok := addons[3].(*adminconsole.AdminConsole)
+				require.True(t, ok, "fourth addon should be AdminConsole")
+				assert.Contains(t, adminConsole.Dependencies(), "longhorn")
+				assert.NotContains(t, adminConsole.Dependencies(), "openebs")
+			},
+		},
+		{
+			name: "airgap installation with an external registry",
+			opts: InstallOptions{
+				IsAirgap:                 true,
+				ServiceCIDR:              "10.96.0.0/12",
+				AdminConsolePwd:          "password123",
+				ExternalRegistry:         &ecv1beta1.ExternalRegistrySpec{Address: "harbor.example.com"},
+				ExternalRegistryUsername: "robot",
+				ExternalRegistryPassword: "secret",
+			},
+			verify: func(t *testing.T, addons []types.AddOn) {
+				assert.Len(t, addons, 3)
+
+				eco, ok := addons[1].(*embeddedclusteroperator.EmbeddedClusterOperator)
+				require.True(t, ok, "second addon should be EmbeddedClusterOperator")
+				assert.True(t, eco.IsAirgap, "ECO should be in airgap mode")
+
+				adminConsole, ok := addons[2].(*adminconsole.AdminConsole)
+				require.True(t, ok, "third addon should be AdminConsole")
+				assert.True(t, adminConsole.IsAirgap, "AdminConsole should be in airgap mode")
+				assert.Equal(t, "harbor.example.com", adminConsole.ExternalRegistry.Address)
+				assert.Equal(t, "robot", adminConsole.ExternalRegistryUsername)
+				assert.Equal(t, "secret", adminConsole.ExternalRegistryPassword)
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -192,3 +320,31 @@ func Test_getAddOnsForInstall(t *testing.T) {
 		})
 	}
 }
+
+func Test_getAddOnsForRestore(t *testing.T) {
+	addons := getAddOnsForRestore(InstallOptions{
+		EmbeddedConfigSpec: &ecv1beta1.ConfigSpec{
+			Extensions: ecv1beta1.Extensions{
+				AddOns: []ecv1beta1.AddOn{
+					{Name: "Cert Manager", ReleaseName: "cert-manager", Restore: true},
+					{Name: "Dashboard", ReleaseName: "dashboard"},
+				},
+			},
+		},
+	})
+	require.Len(t, addons, 3)
+	_, ok := addons[0].(*openebs.OpenEBS)
+	require.True(t, ok, "first addon should be OpenEBS")
+	_, ok = addons[1].(*velero.Velero)
+	require.True(t, ok, "second addon should be Velero")
+	assert.Equal(t, "cert-manager", addons[2].ReleaseName())
+
+	addons = getAddOnsForRestore(InstallOptions{
+		EndUserConfigSpec: &ecv1beta1.ConfigSpec{
+			Storage: &ecv1beta1.StorageSpec{Provider: ecv1beta1.StorageProviderLonghorn},
+		},
+	})
+	require.Len(t, addons, 2)
+	_, ok = addons[0].(*longhorn.Longhorn)
+	require.True(t, ok, "first addon should be Longhorn")
+}
@@ -0,0 +1,71 @@
+package longhorn
+
+import (
+	_ "embed"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/embedded-cluster/pkg/release"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"gopkg.in/yaml.v3"
+)
+
+// Longhorn provides replicated block storage, volumes survive the loss of a node as long as
+// one of their replicas is healthy. It is installed in place of OpenEBS when selected as the
+// storage provider.
+type Longhorn struct {
+	// Replicas is the number of replicas of each volume. The chart default is used if zero.
+	Replicas int
+}
+
+const (
+	releaseName = "longhorn"
+	namespace   = runtimeconfig.LonghornNamespace
+	// StorageClassName is the name of the default storage class created by Longhorn.
+	StorageClassName = "longhorn"
+)
+
+var (
+	//go:embed static/values.tpl.yaml
+	rawvalues []byte
+	// helmValues is the unmarshal version of rawvalues.
+	helmValues map[string]interface{}
+	//go:embed static/metadata.yaml
+	rawmetadata []byte
+	// Metadata is the unmarshal version of rawmetadata.
+	Metadata release.AddonMetadata
+)
+
+func init() {
+	if err := yaml.Unmarshal(rawmetadata, &Metadata); err != nil {
+		panic(errors.Wrap(err, "unable to unmarshal metadata"))
+	}
+	hv, err := release.RenderHelmValues(rawvalues, Metadata)
+	if err != nil {
+		panic(errors.Wrap(err, "unable to unmarshal values"))
+	}
+	helmValues = hv
+}
+
+func (l *Longhorn) Name() string {
+	return "Storage"
+}
+
+func (l *Longhorn) Version() string {
+	return Metadata.Version
+}
+
+func (l *Longhorn) ReleaseName() string {
+	return releaseName
+}
+
+func (l *Longhorn) Namespace() string {
+	return namespace
+}
+
+func (l *Longhorn) Dependencies() []string {
+	return nil
+}
+
+func (l *Longhorn) StorageClassName() string {
+	return StorageClassName
+}
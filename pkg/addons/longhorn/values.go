@@ -0,0 +1,50 @@
+package longhorn
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func (l *Longhorn) GenerateHelmValues(ctx context.Context, kcli client.Client, overrides []string) (map[string]interface{}, error) {
+	// create a copy of the helm values so we don't modify the original
+	marshalled, err := helm.MarshalValues(helmValues)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal helm values")
+	}
+	copiedValues, err := helm.UnmarshalValues(marshalled)
+	if err != nil {
+		return nil, errors.Wrap(err, "unmarshal helm values")
+	}
+
+	err = helm.SetValue(copiedValues, "defaultSettings.defaultDataPath", runtimeconfig.EmbeddedClusterLonghornSubDir())
+	if err != nil {
+		return nil, errors.Wrap(err, "set defaultSettings.defaultDataPath")
+	}
+
+	kubeletRootDir := filepath.Join(runtimeconfig.EmbeddedClusterK0sSubDir(), "kubelet")
+	err = helm.SetValue(copiedValues, "csi.kubeletRootDir", kubeletRootDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "set csi.kubeletRootDir")
+	}
+
+	if l.Replicas > 0 {
+		err = helm.SetValue(copiedValues, "persistence.defaultClassReplicaCount", l.Replicas)
+		if err != nil {
+			return nil, errors.Wrap(err, "set persistence.defaultClassReplicaCount")
+		}
+	}
+
+	for _, override := range overrides {
+		copiedValues, err = helm.PatchValues(copiedValues, override)
+		if err != nil {
+			return nil, errors.Wrap(err, "patch helm values")
+		}
+	}
+
+	return copiedValues, nil
+}
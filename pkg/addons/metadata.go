@@ -5,7 +5,11 @@ import (
 	"github.com/pkg/errors"
 	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/adminconsole"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/cilium"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/embeddedclusteroperator"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/ingress"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/longhorn"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/monitoring"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/openebs"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/registry"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/seaweedfs"
@@ -15,9 +19,15 @@ import (
 func Versions() map[string]string {
 	versions := map[string]string{}
 
+	for k, v := range cilium.Version() {
+		versions[k] = v
+	}
 	for k, v := range openebs.Version() {
 		versions[k] = v
 	}
+	for k, v := range longhorn.Version() {
+		versions[k] = v
+	}
 	for k, v := range embeddedclusteroperator.Version() {
 		versions[k] = v
 	}
@@ -30,6 +40,12 @@ func Versions() map[string]string {
 	for k, v := range velero.Version() {
 		versions[k] = v
 	}
+	for k, v := range ingress.Version() {
+		versions[k] = v
+	}
+	for k, v := range monitoring.Version() {
+		versions[k] = v
+	}
 	for k, v := range adminconsole.Version() {
 		versions[k] = v
 	}
@@ -37,18 +53,51 @@ func Versions() map[string]string {
 	return versions
 }
 
+// ReleaseNames returns the helm release names of all the addons. These are the names used to
+// override the addons values through the built-in extensions.
+func ReleaseNames() []string {
+	return []string{
+		(&cilium.Cilium{}).ReleaseName(),
+		(&openebs.OpenEBS{}).ReleaseName(),
+		(&longhorn.Longhorn{}).ReleaseName(),
+		(&embeddedclusteroperator.EmbeddedClusterOperator{}).ReleaseName(),
+		(&registry.Registry{}).ReleaseName(),
+		(&seaweedfs.SeaweedFS{}).ReleaseName(),
+		(&velero.Velero{}).ReleaseName(),
+		(&ingress.Ingress{}).ReleaseName(),
+		(&monitoring.Monitoring{}).ReleaseName(),
+		(&adminconsole.AdminConsole{}).ReleaseName(),
+	}
+}
+
 func GenerateChartConfigs() ([]ecv1beta1.Chart, []k0sv1beta1.Repository, error) {
 	charts := []ecv1beta1.Chart{}
 	repositories := []k0sv1beta1.Repository{}
 
+	// cilium
+	chart, repos, err := cilium.GenerateChartConfig()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "generate chart config for cilium")
+	}
+	charts = append(charts, chart...)
+	repositories = append(repositories, repos...)
+
 	// openebs
-	chart, repos, err := openebs.GenerateChartConfig()
+	chart, repos, err = openebs.GenerateChartConfig()
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "generate chart config for openebs")
 	}
 	charts = append(charts, chart...)
 	repositories = append(repositories, repos...)
 
+	// longhorn
+	chart, repos, err = longhorn.GenerateChartConfig()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "generate chart config for longhorn")
+	}
+	charts = append(charts, chart...)
+	repositories = append(repositories, repos...)
+
 	// embedded cluster operator
 	chart, repos, err = embeddedclusteroperator.GenerateChartConfig()
 	if err != nil {
@@ -81,6 +130,22 @@ func GenerateChartConfigs() ([]ecv1beta1.Chart, []k0sv1beta1.Repository, error)
 	charts = append(charts, chart...)
 	repositories = append(repositories, repos...)
 
+	// ingress
+	chart, repos, err = ingress.GenerateChartConfig()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "generate chart config for ingress")
+	}
+	charts = append(charts, chart...)
+	repositories = append(repositories, repos...)
+
+	// monitoring
+	chart, repos, err = monitoring.GenerateChartConfig()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "generate chart config for monitoring")
+	}
+	charts = append(charts, chart...)
+	repositories = append(repositories, repos...)
+
 	// admin console
 	chart, repos, err = adminconsole.GenerateChartConfig()
 	if err != nil {
@@ -95,11 +160,15 @@ func GenerateChartConfigs() ([]ecv1beta1.Chart, []k0sv1beta1.Repository, error)
 func GetImages() []string {
 	images := []string{}
 
+	images = append(images, cilium.GetImages()...)
 	images = append(images, openebs.GetImages()...)
+	images = append(images, longhorn.GetImages()...)
 	images = append(images, embeddedclusteroperator.GetImages()...)
 	images = append(images, registry.GetImages()...)
 	images = append(images, seaweedfs.GetImages()...)
 	images = append(images, velero.GetImages()...)
+	images = append(images, ingress.GetImages()...)
+	images = append(images, monitoring.GetImages()...)
 	images = append(images, adminconsole.GetImages()...)
 
 	return images
@@ -108,11 +177,15 @@ func GetImages() []string {
 func GetAdditionalImages() []string {
 	images := []string{}
 
+	images = append(images, cilium.GetAdditionalImages()...)
 	images = append(images, openebs.GetAdditionalImages()...)
+	images = append(images, longhorn.GetAdditionalImages()...)
 	images = append(images, embeddedclusteroperator.GetAdditionalImages()...)
 	images = append(images, registry.GetAdditionalImages()...)
 	images = append(images, seaweedfs.GetAdditionalImages()...)
 	images = append(images, velero.GetAdditionalImages()...)
+	images = append(images, ingress.GetAdditionalImages()...)
+	images = append(images, monitoring.GetAdditionalImages()...)
 	images = append(images, adminconsole.GetAdditionalImages()...)
 
 	return images
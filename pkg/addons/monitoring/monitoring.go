@@ -0,0 +1,74 @@
+package monitoring
+
+import (
+	_ "embed"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/storage"
+	"github.com/replicatedhq/embedded-cluster/pkg/release"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"gopkg.in/yaml.v3"
+)
+
+// Monitoring is the built-in monitoring stack: prometheus, node exporter and kube state
+// metrics. Besides the nodes and pods it scrapes the embedded cluster operator and the local
+// artifact mirror.
+type Monitoring struct {
+	// Retention is how long metrics are kept. The chart default is used if empty.
+	Retention string
+	// RetentionSize is the maximum size of the stored metrics. Unlimited if empty.
+	RetentionSize string
+	// StorageProvider provisions the volume holding the metrics. Defaults to OpenEBS.
+	StorageProvider string
+}
+
+const (
+	releaseName = "prometheus"
+	namespace   = runtimeconfig.MonitoringNamespace
+)
+
+var (
+	//go:embed static/values.tpl.yaml
+	rawvalues []byte
+	// helmValues is the unmarshal version of rawvalues.
+	helmValues map[string]interface{}
+	//go:embed static/metadata.yaml
+	rawmetadata []byte
+	// Metadata is the unmarshal version of rawmetadata.
+	Metadata release.AddonMetadata
+)
+
+func init() {
+	if err := yaml.Unmarshal(rawmetadata, &Metadata); err != nil {
+		panic(errors.Wrap(err, "unable to unmarshal metadata"))
+	}
+	hv, err := release.RenderHelmValues(rawvalues, Metadata)
+	if err != nil {
+		panic(errors.Wrap(err, "unable to unmarshal values"))
+	}
+	helmValues = hv
+}
+
+func (m *Monitoring) Name() string {
+	return "Monitoring"
+}
+
+func (m *Monitoring) Version() string {
+	return Metadata.Version
+}
+
+func (m *Monitoring) ReleaseName() string {
+	return releaseName
+}
+
+func (m *Monitoring) Namespace() string {
+	return namespace
+}
+
+// Dependencies returns the addons the monitoring stack depends on. The metrics are stored in
+// a volume provisioned by the storage provider.
+func (m *Monitoring) Dependencies() []string {
+	return []string{
+		storage.ReleaseName(m.StorageProvider),
+	}
+}
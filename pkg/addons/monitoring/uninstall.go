@@ -0,0 +1,19 @@
+package monitoring
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Uninstall removes the monitoring stack once it is disabled. The monitoring namespace, and
+// the volume holding the metrics, are deleted with it.
+func (m *Monitoring) Uninstall(ctx context.Context, kcli client.Client, hcli helm.Client) error {
+	if err := kubeutils.UninstallReleaseAndNamespace(ctx, kcli, hcli, releaseName, namespace); err != nil {
+		return errors.Wrap(err, "uninstall release")
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package monitoring
+
+import (
+	"bytes"
+	"context"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/embeddedclusteroperator"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/storage"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// extraScrapeConfigs scrapes the embedded cluster operator pods and the local artifact
+// mirror running on every node. Both are not discovered by the default chart jobs as they
+// carry no prometheus.io annotations. The mirror is scraped on the node internal address,
+// which it only listens on when it was given a peer address. Nodes installed before the
+// peer address existed get one when an air gap upgrade distributes the artifacts to them,
+// until then their mirror target is reported as down.
+var extraScrapeConfigs = template.Must(template.New("scrape").Parse(`- job_name: embedded-cluster-operator
+  kubernetes_sd_configs:
+  - role: pod
+    namespaces:
+      names:
+      - {{ .OperatorNamespace }}
+  relabel_configs:
+  - source_labels: [__meta_kubernetes_pod_label_app_kubernetes_io_name]
+    action: keep
+    regex: embedded-cluster-operator
+  - source_labels: [__meta_kubernetes_pod_ip]
+    action: replace
+    target_label: __address__
+    regex: (.+)
+    replacement: $1:{{ .OperatorPort }}
+  - source_labels: [__meta_kubernetes_pod_node_name]
+    action: replace
+    target_label: node
+- job_name: local-artifact-mirror
+  kubernetes_sd_configs:
+  - role: node
+  relabel_configs:
+  - source_labels: [__meta_kubernetes_node_address_InternalIP]
+    action: replace
+    target_label: __address__
+    regex: (.+)
+    replacement: $1:{{ .LocalArtifactMirrorPort }}
+  - source_labels: [__meta_kubernetes_node_name]
+    action: replace
+    target_label: node
+`))
+
+func (m *Monitoring) GenerateHelmValues(ctx context.Context, kcli client.Client, overrides []string) (map[string]interface{}, error) {
+	// create a copy of the helm values so we don't modify the original
+	marshalled, err := helm.MarshalValues(helmValues)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal helm values")
+	}
+	copiedValues, err := helm.UnmarshalValues(marshalled)
+	if err != nil {
+		return nil, errors.Wrap(err, "unmarshal helm values")
+	}
+
+	err = helm.SetValue(copiedValues, "server.persistentVolume.storageClass", storage.ClassName(m.StorageProvider))
+	if err != nil {
+		return nil, errors.Wrap(err, "set server.persistentVolume.storageClass")
+	}
+
+	if m.Retention != "" {
+		err = helm.SetValue(copiedValues, "server.retention", m.Retention)
+		if err != nil {
+			return nil, errors.Wrap(err, "set server.retention")
+		}
+	}
+	if m.RetentionSize != "" {
+		err = helm.SetValue(copiedValues, "server.retentionSize", m.RetentionSize)
+		if err != nil {
+			return nil, errors.Wrap(err, "set server.retentionSize")
+		}
+	}
+
+	scrapeConfigs, err := renderExtraScrapeConfigs()
+	if err != nil {
+		return nil, errors.Wrap(err, "render extra scrape configs")
+	}
+	copiedValues["extraScrapeConfigs"] = scrapeConfigs
+
+	for _, override := range overrides {
+		copiedValues, err = helm.PatchValues(copiedValues, override)
+		if err != nil {
+			return nil, errors.Wrap(err, "patch helm values")
+		}
+	}
+
+	return copiedValues, nil
+}
+
+func renderExtraScrapeConfigs() (string, error) {
+	buf := bytes.NewBuffer(nil)
+	err := extraScrapeConfigs.Execute(buf, map[string]interface{}{
+		"OperatorNamespace":       runtimeconfig.EmbeddedClusterNamespace,
+		"OperatorPort":            embeddedclusteroperator.MetricsPort,
+		"LocalArtifactMirrorPort": runtimeconfig.LocalArtifactMirrorPort(),
+	})
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,47 @@
+package monitoring
+
+import (
+	"context"
+	"testing"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateHelmValues(t *testing.T) {
+	m := &Monitoring{Retention: "30d", RetentionSize: "5GB", StorageProvider: "longhorn"}
+	values, err := m.GenerateHelmValues(context.Background(), nil, nil)
+	require.NoError(t, err)
+
+	retention, err := helm.GetValue(values, "$.server.retention")
+	require.NoError(t, err)
+	assert.Equal(t, "30d", retention)
+
+	retentionSize, err := helm.GetValue(values, "$.server.retentionSize")
+	require.NoError(t, err)
+	assert.Equal(t, "5GB", retentionSize)
+
+	storageClass, err := helm.GetValue(values, "$.server.persistentVolume.storageClass")
+	require.NoError(t, err)
+	assert.Equal(t, "longhorn", storageClass)
+
+	scrapeConfigs, ok := values["extraScrapeConfigs"].(string)
+	require.True(t, ok, "extraScrapeConfigs should be a string")
+	assert.Contains(t, scrapeConfigs, "job_name: embedded-cluster-operator")
+	assert.Contains(t, scrapeConfigs, "replacement: $1:8080")
+	assert.Contains(t, scrapeConfigs, "job_name: local-artifact-mirror")
+	assert.Contains(t, scrapeConfigs, "replacement: $1:50000")
+
+	// the chart default is kept when no retention is configured.
+	values, err = (&Monitoring{}).GenerateHelmValues(context.Background(), nil, nil)
+	require.NoError(t, err)
+	retention, err = helm.GetValue(values, "$.server.retention")
+	require.NoError(t, err)
+	assert.Equal(t, "15d", retention)
+	_, err = helm.GetValue(values, "$.server.retentionSize")
+	assert.Error(t, err)
+	storageClass, err = helm.GetValue(values, "$.server.persistentVolume.storageClass")
+	require.NoError(t, err)
+	assert.Equal(t, "openebs-hostpath", storageClass)
+}
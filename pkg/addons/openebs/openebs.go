@@ -13,6 +13,8 @@ type OpenEBS struct{}
 const (
 	releaseName = "openebs"
 	namespace   = "openebs"
+	// StorageClassName is the name of the default storage class created by OpenEBS.
+	StorageClassName = "openebs-hostpath"
 )
 
 var (
@@ -52,3 +54,11 @@ func (o *OpenEBS) ReleaseName() string {
 func (o *OpenEBS) Namespace() string {
 	return namespace
 }
+
+func (o *OpenEBS) Dependencies() []string {
+	return nil
+}
+
+func (o *OpenEBS) StorageClassName() string {
+	return StorageClassName
+}
@@ -0,0 +1,15 @@
+package openebs
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Uninstall always fails as the storage provider can not be changed once the cluster is
+// installed, the volumes it provisioned would be lost.
+func (o *OpenEBS) Uninstall(ctx context.Context, kcli client.Client, hcli helm.Client) error {
+	return errors.Errorf("%s can not be uninstalled", o.Name())
+}
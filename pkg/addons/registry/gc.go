@@ -0,0 +1,95 @@
+package registry
+
+import (
+	"context"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// registryConfigPath is where the chart mounts the registry configuration.
+	registryConfigPath = "/etc/docker/registry/config.yml"
+	// deploymentName and containerName are the registry deployment and container created by
+	// the chart.
+	deploymentName = "registry"
+	containerName  = "docker-registry"
+)
+
+// readOnlyEnv puts the registry in maintenance mode, pushes and deletes are rejected while
+// pulls keep working.
+var readOnlyEnv = corev1.EnvVar{
+	Name:  "REGISTRY_STORAGE_MAINTENANCE_READONLY",
+	Value: `{"enabled":true}`,
+}
+
+// GarbageCollect runs the registry garbage collector, removing the blobs no longer referenced
+// by any manifest. Blobs being pushed while it runs would be removed too so all the registry
+// replicas are put in read-only mode until it finishes.
+func GarbageCollect(ctx context.Context, kcli client.Client, stdout io.Writer) (finalErr error) {
+	if err := setReadOnly(ctx, kcli, true); err != nil {
+		return errors.Wrap(err, "enable read-only mode")
+	}
+	defer func() {
+		if err := setReadOnly(ctx, kcli, false); err != nil && finalErr == nil {
+			finalErr = errors.Wrap(err, "disable read-only mode")
+		}
+	}()
+
+	if err := execInPod(ctx, []string{"registry", "garbage-collect", registryConfigPath}, stdout); err != nil {
+		return errors.Wrap(err, "exec in pod")
+	}
+	return nil
+}
+
+// setReadOnly sets or removes the read-only mode environment variable in the registry
+// deployment and waits for all the replicas to be rolled out.
+func setReadOnly(ctx context.Context, kcli client.Client, enabled bool) error {
+	var deploy appsv1.Deployment
+	if err := kcli.Get(ctx, client.ObjectKey{Namespace: namespace, Name: deploymentName}, &deploy); err != nil {
+		return errors.Wrap(err, "get registry deployment")
+	}
+
+	for i, container := range deploy.Spec.Template.Spec.Containers {
+		if container.Name != containerName {
+			continue
+		}
+		env := slices.DeleteFunc(slices.Clone(container.Env), func(env corev1.EnvVar) bool {
+			return env.Name == readOnlyEnv.Name
+		})
+		if enabled {
+			env = append(env, readOnlyEnv)
+		}
+		deploy.Spec.Template.Spec.Containers[i].Env = env
+	}
+	if err := kcli.Update(ctx, &deploy); err != nil {
+		return errors.Wrap(err, "update registry deployment")
+	}
+
+	return waitForRollout(ctx, kcli, deploy.Generation)
+}
+
+// waitForRollout waits until all the registry replicas run the given generation of the
+// deployment so no replica serves requests with the previous configuration.
+func waitForRollout(ctx context.Context, kcli client.Client, generation int64) error {
+	return wait.PollUntilContextTimeout(ctx, 2*time.Second, 5*time.Minute, true, func(ctx context.Context) (bool, error) {
+		var deploy appsv1.Deployment
+		if err := kcli.Get(ctx, client.ObjectKey{Namespace: namespace, Name: deploymentName}, &deploy); err != nil {
+			return false, errors.Wrap(err, "get registry deployment")
+		}
+		replicas := int32(1)
+		if deploy.Spec.Replicas != nil {
+			replicas = *deploy.Spec.Replicas
+		}
+		return deploy.Status.ObservedGeneration >= generation &&
+			deploy.Status.UpdatedReplicas == replicas &&
+			deploy.Status.ReadyReplicas == replicas &&
+			deploy.Status.Replicas == replicas, nil
+	})
+}
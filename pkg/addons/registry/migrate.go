@@ -186,10 +186,17 @@ func execInPod(ctx context.Context, command []string, stdout io.Writer) error {
 	if err != nil {
 		return errors.Wrap(err, "list registry pods")
 	}
-	if len(pods.Items) == 0 {
-		return errors.New("no registry pods found")
+	// pods being replaced by a rollout may still be listed.
+	podName := ""
+	for _, pod := range pods.Items {
+		if pod.DeletionTimestamp == nil && pod.Status.Phase == corev1.PodRunning {
+			podName = pod.Name
+			break
+		}
+	}
+	if podName == "" {
+		return errors.New("no running registry pods found")
 	}
-	podName := pods.Items[0].Name
 
 	req := clientSet.CoreV1().RESTClient().Post().
 		Resource("pods").
@@ -4,6 +4,8 @@ import (
 	_ "embed"
 
 	"github.com/pkg/errors"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/seaweedfs"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/storage"
 	"github.com/replicatedhq/embedded-cluster/pkg/helpers"
 	"github.com/replicatedhq/embedded-cluster/pkg/release"
 	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
@@ -13,6 +15,9 @@ import (
 type Registry struct {
 	ServiceCIDR string
 	IsHA        bool
+	// StorageProvider provisions the registry volume when not highly available. Defaults to
+	// OpenEBS.
+	StorageProvider string
 }
 
 const (
@@ -73,6 +78,15 @@ func (r *Registry) Namespace() string {
 	return namespace
 }
 
+// Dependencies returns the addons the registry depends on. Its storage is provisioned by
+// the storage provider or, when highly available, by SeaweedFS.
+func (r *Registry) Dependencies() []string {
+	return []string{
+		storage.ReleaseName(r.StorageProvider),
+		(&seaweedfs.SeaweedFS{}).ReleaseName(),
+	}
+}
+
 func GetRegistryPassword() string {
 	return registryPassword
 }
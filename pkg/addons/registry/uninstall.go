@@ -0,0 +1,62 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Uninstall removes the registry once the installation is no longer air gapped. The registry
+// namespace, and the volume holding the images, are deleted with it. Callers must make sure
+// no pod pulls its images from the registry anymore, see InUse.
+func (r *Registry) Uninstall(ctx context.Context, kcli client.Client, hcli helm.Client) error {
+	if err := kubeutils.UninstallReleaseAndNamespace(ctx, kcli, hcli, releaseName, namespace); err != nil {
+		return errors.Wrap(err, "uninstall release")
+	}
+	return nil
+}
+
+// InUse returns true if any pod in the cluster references an image stored in the registry.
+func (r *Registry) InUse(ctx context.Context, kcli client.Client) (bool, error) {
+	registryIP, err := GetRegistryClusterIP(r.ServiceCIDR)
+	if err != nil {
+		return false, errors.Wrap(err, "get registry cluster IP")
+	}
+	prefix := fmt.Sprintf("%s:5000/", registryIP)
+
+	var pods corev1.PodList
+	if err := kcli.List(ctx, &pods); err != nil {
+		return false, errors.Wrap(err, "list pods")
+	}
+	for _, pod := range pods.Items {
+		if pod.Namespace == namespace {
+			continue
+		}
+		for _, image := range podImages(pod) {
+			if strings.HasPrefix(image, prefix) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func podImages(pod corev1.Pod) []string {
+	var images []string
+	for _, c := range pod.Spec.InitContainers {
+		images = append(images, c.Image)
+	}
+	for _, c := range pod.Spec.Containers {
+		images = append(images, c.Image)
+	}
+	for _, c := range pod.Spec.EphemeralContainers {
+		images = append(images, c.Image)
+	}
+	return images
+}
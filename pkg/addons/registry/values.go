@@ -5,6 +5,7 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/seaweedfs"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/storage"
 	"github.com/replicatedhq/embedded-cluster/pkg/helm"
 	corev1 "k8s.io/api/core/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
@@ -41,6 +42,13 @@ func (r *Registry) GenerateHelmValues(ctx context.Context, kcli client.Client, o
 		copiedValues["tlsSecretName"] = tlsSecretName
 	}
 
+	if !r.IsHA {
+		err = helm.SetValue(copiedValues, "persistence.storageClass", storage.ClassName(r.StorageProvider))
+		if err != nil {
+			return nil, errors.Wrap(err, "set persistence.storageClass")
+		}
+	}
+
 	registryIP, err := GetRegistryClusterIP(r.ServiceCIDR)
 	if err != nil {
 		return nil, errors.Wrap(err, "get registry cluster IP")
@@ -4,6 +4,7 @@ import (
 	_ "embed"
 
 	"github.com/pkg/errors"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/storage"
 	"github.com/replicatedhq/embedded-cluster/pkg/release"
 	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
 	"gopkg.in/yaml.v3"
@@ -11,6 +12,8 @@ import (
 
 type SeaweedFS struct {
 	ServiceCIDR string
+	// StorageProvider provisions the SeaweedFS volumes. Defaults to OpenEBS.
+	StorageProvider string
 }
 
 const (
@@ -68,6 +71,14 @@ func (s *SeaweedFS) Namespace() string {
 	return namespace
 }
 
+// Dependencies returns the addons SeaweedFS depends on. Its volumes are provisioned by the
+// storage provider.
+func (s *SeaweedFS) Dependencies() []string {
+	return []string{
+		storage.ReleaseName(s.StorageProvider),
+	}
+}
+
 func getBackupLabels() map[string]string {
 	return map[string]string{
 		"app.kubernetes.io/name": "seaweedfs",
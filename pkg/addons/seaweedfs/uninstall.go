@@ -0,0 +1,19 @@
+package seaweedfs
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Uninstall removes seaweedfs once the registry no longer uses it. The seaweedfs namespace,
+// and the volumes holding its data, are deleted with it.
+func (s *SeaweedFS) Uninstall(ctx context.Context, kcli client.Client, hcli helm.Client) error {
+	if err := kubeutils.UninstallReleaseAndNamespace(ctx, kcli, hcli, releaseName, namespace); err != nil {
+		return errors.Wrap(err, "uninstall release")
+	}
+	return nil
+}
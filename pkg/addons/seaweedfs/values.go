@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 
 	"github.com/pkg/errors"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/storage"
 	"github.com/replicatedhq/embedded-cluster/pkg/helm"
 	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -33,6 +34,13 @@ func (s *SeaweedFS) GenerateHelmValues(ctx context.Context, kcli client.Client,
 		return nil, errors.Wrap(err, "set helm values global.logs.hostPathPrefix")
 	}
 
+	storageClass := storage.ClassName(s.StorageProvider)
+	for _, path := range []string{"volume.dataDirs[0].storageClass", "filer.data.storageClass", "filer.logs.storageClass"} {
+		if err := helm.SetValue(copiedValues, path, storageClass); err != nil {
+			return nil, errors.Wrapf(err, "set %s", path)
+		}
+	}
+
 	for _, override := range overrides {
 		copiedValues, err = helm.PatchValues(copiedValues, override)
 		if err != nil {
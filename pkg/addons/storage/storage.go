@@ -0,0 +1,47 @@
+// Package storage maps the storage provider selected in the config to the addon implementing
+// it. The addons with volumes use it to depend on, and to provision their volumes from, the
+// selected provider.
+package storage
+
+import (
+	"fmt"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/longhorn"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/openebs"
+)
+
+// Provider returns the storage provider selected by the spec, OpenEBS if none is.
+func Provider(spec ecv1beta1.StorageSpec) string {
+	if spec.Provider == "" {
+		return ecv1beta1.StorageProviderOpenEBS
+	}
+	return spec.Provider
+}
+
+// ValidateProvider returns an error if the storage provider selected by the spec is not
+// supported.
+func ValidateProvider(spec ecv1beta1.StorageSpec) error {
+	switch provider := Provider(spec); provider {
+	case ecv1beta1.StorageProviderOpenEBS, ecv1beta1.StorageProviderLonghorn:
+		return nil
+	default:
+		return fmt.Errorf("unsupported storage provider %q, must be %q or %q", provider, ecv1beta1.StorageProviderOpenEBS, ecv1beta1.StorageProviderLonghorn)
+	}
+}
+
+// ReleaseName returns the release name of the addon implementing the storage provider.
+func ReleaseName(provider string) string {
+	if provider == ecv1beta1.StorageProviderLonghorn {
+		return (&longhorn.Longhorn{}).ReleaseName()
+	}
+	return (&openebs.OpenEBS{}).ReleaseName()
+}
+
+// ClassName returns the name of the default storage class created by the storage provider.
+func ClassName(provider string) string {
+	if provider == ecv1beta1.StorageProviderLonghorn {
+		return longhorn.StorageClassName
+	}
+	return openebs.StorageClassName
+}
@@ -0,0 +1,183 @@
+package thirdparty
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/pkg/errors"
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/spinner"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func (t *ThirdParty) Install(ctx context.Context, kcli client.Client, hcli helm.Client, overrides []string, writer *spinner.MessageWriter) error {
+	if err := t.createPreRequisites(ctx, kcli); err != nil {
+		return errors.Wrap(err, "create prerequisites")
+	}
+
+	values, err := t.GenerateHelmValues(ctx, kcli, overrides)
+	if err != nil {
+		return errors.Wrap(err, "generate helm values")
+	}
+
+	_, err = hcli.Install(ctx, helm.InstallOptions{
+		ReleaseName:  t.Manifest.ReleaseName,
+		ChartPath:    t.Manifest.ChartName,
+		ChartVersion: t.Manifest.Version,
+		Values:       values,
+		Namespace:    t.Manifest.Namespace,
+		Labels:       t.getBackupLabels(),
+		Timeout:      t.Manifest.Timeout.Duration,
+	})
+	if err != nil {
+		return errors.Wrap(err, "helm install")
+	}
+
+	if err := t.waitForReady(ctx, kcli, writer); err != nil {
+		return errors.Wrap(err, "wait for ready")
+	}
+
+	return nil
+}
+
+// createPreRequisites creates the addon namespace and the objects listed in the manifest
+// prerequisites. Objects that already exist are left untouched.
+func (t *ThirdParty) createPreRequisites(ctx context.Context, kcli client.Client) error {
+	if err := createNamespace(ctx, kcli, t.Manifest.Namespace); err != nil {
+		return errors.Wrap(err, "create namespace")
+	}
+
+	objects, err := t.prerequisites()
+	if err != nil {
+		return errors.Wrap(err, "get prerequisites")
+	}
+	for _, obj := range objects {
+		if err := kcli.Create(ctx, obj); err != nil && !k8serrors.IsAlreadyExists(err) {
+			return errors.Wrapf(err, "create %s %s", obj.GetKind(), obj.GetName())
+		}
+	}
+
+	return nil
+}
+
+// prerequisites renders and decodes the objects listed in the manifest prerequisites. Objects
+// without a namespace are created in the addon namespace unless they are cluster scoped.
+func (t *ThirdParty) prerequisites() ([]*unstructured.Unstructured, error) {
+	if t.Manifest.Prerequisites == "" {
+		return nil, nil
+	}
+
+	rendered, err := t.render("prerequisites", t.Manifest.Prerequisites)
+	if err != nil {
+		return nil, errors.Wrap(err, "render prerequisites")
+	}
+
+	objects := []*unstructured.Unstructured{}
+	decoder := k8syaml.NewYAMLOrJSONDecoder(bytes.NewBufferString(rendered), 4096)
+	for {
+		obj := &unstructured.Unstructured{}
+		if err := decoder.Decode(&obj.Object); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, errors.Wrap(err, "decode prerequisites")
+		}
+		if len(obj.Object) == 0 {
+			continue
+		}
+		if obj.GetNamespace() == "" && !isClusterScoped(obj.GetKind()) {
+			obj.SetNamespace(t.Manifest.Namespace)
+		}
+		if labels := t.getBackupLabels(); labels != nil {
+			objLabels := obj.GetLabels()
+			if objLabels == nil {
+				objLabels = map[string]string{}
+			}
+			for k, v := range labels {
+				objLabels[k] = v
+			}
+			obj.SetLabels(objLabels)
+		}
+		objects = append(objects, obj)
+	}
+	return objects, nil
+}
+
+// isClusterScoped returns true for the cluster scoped kinds commonly created as prerequisites.
+func isClusterScoped(kind string) bool {
+	switch kind {
+	case "Namespace", "ClusterRole", "ClusterRoleBinding", "CustomResourceDefinition", "StorageClass", "PriorityClass":
+		return true
+	}
+	return false
+}
+
+func createNamespace(ctx context.Context, kcli client.Client, namespace string) error {
+	ns := corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: namespace,
+		},
+	}
+	if err := kcli.Create(ctx, &ns); err != nil && !k8serrors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+// waitForReady waits for the workloads listed in the manifest readiness checks.
+func (t *ThirdParty) waitForReady(ctx context.Context, kcli client.Client, writer *spinner.MessageWriter) error {
+	for _, check := range t.Manifest.ReadinessChecks {
+		namespace := check.Namespace
+		if namespace == "" {
+			namespace = t.Manifest.Namespace
+		}
+		if writer != nil {
+			writer.Infof("Waiting for %s %s to be ready", check.Kind, check.Name)
+		}
+
+		var err error
+		switch check.Kind {
+		case ecv1beta1.AddOnReadinessCheckKindDeployment:
+			err = kubeutils.WaitForDeployment(ctx, kcli, namespace, check.Name, nil)
+		case ecv1beta1.AddOnReadinessCheckKindDaemonSet:
+			err = kubeutils.WaitForDaemonset(ctx, kcli, namespace, check.Name, nil)
+		case ecv1beta1.AddOnReadinessCheckKindStatefulSet:
+			err = waitForStatefulSet(ctx, kcli, namespace, check.Name)
+		default:
+			err = errors.Errorf("unknown readiness check kind %q", check.Kind)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "%s %s", check.Kind, check.Name)
+		}
+	}
+	return nil
+}
+
+func waitForStatefulSet(ctx context.Context, kcli client.Client, namespace, name string) error {
+	var lasterr error
+	if err := wait.ExponentialBackoffWithContext(
+		ctx, kubeutils.DefaultBackoff, func(ctx context.Context) (bool, error) {
+			ready, err := kubeutils.IsStatefulSetReady(ctx, kcli, namespace, name)
+			if err != nil {
+				lasterr = errors.Wrap(err, "get statefulset status")
+				return false, nil
+			}
+			return ready, nil
+		},
+	); err != nil {
+		if lasterr != nil {
+			return errors.Wrap(lasterr, "timed out waiting for statefulset")
+		}
+		return errors.New("timed out waiting for statefulset")
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package thirdparty
+
+import (
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+)
+
+// ThirdParty is an addon described by a vendor supplied manifest. It gets the same lifecycle
+// as the built-in addons.
+type ThirdParty struct {
+	Manifest    ecv1beta1.AddOn
+	IsAirgap    bool
+	IsHA        bool
+	Proxy       *ecv1beta1.ProxySpec
+	ServiceCIDR string
+	// DependsOn are the release names of the addons installed before this one.
+	DependsOn []string
+}
+
+func (t *ThirdParty) Name() string {
+	return t.Manifest.Name
+}
+
+func (t *ThirdParty) Version() string {
+	return t.Manifest.Version
+}
+
+func (t *ThirdParty) ReleaseName() string {
+	return t.Manifest.ReleaseName
+}
+
+func (t *ThirdParty) Namespace() string {
+	return t.Manifest.Namespace
+}
+
+func (t *ThirdParty) Dependencies() []string {
+	return t.DependsOn
+}
+
+func (t *ThirdParty) getBackupLabels() map[string]string {
+	if !t.Manifest.DisasterRecovery {
+		return nil
+	}
+	return map[string]string{
+		"replicated.com/disaster-recovery":       "infra",
+		"replicated.com/disaster-recovery-chart": t.Manifest.ReleaseName,
+	}
+}
@@ -0,0 +1,102 @@
+package thirdparty
+
+import (
+	"context"
+	"testing"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestThirdParty_Install(t *testing.T) {
+	ctx := context.Background()
+
+	addon := &ThirdParty{
+		Manifest: ecv1beta1.AddOn{
+			Name:        "Cert Manager",
+			ReleaseName: "cert-manager",
+			ChartName:   "oci://registry.example.com/charts/cert-manager",
+			Version:     "1.16.1",
+			Namespace:   "cert-manager",
+			Values: `namespace: {{ .Namespace }}
+proxy: "{{ .HTTPProxy }}"
+replicaCount: 1
+`,
+			Prerequisites: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cert-manager-config
+data:
+  airgap: "{{ .IsAirgap }}"
+---
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: cert-manager-webhooks
+`,
+			ReadinessChecks: []ecv1beta1.AddOnReadinessCheck{
+				{Kind: ecv1beta1.AddOnReadinessCheckKindDeployment, Name: "cert-manager"},
+			},
+			DisasterRecovery: true,
+		},
+		IsAirgap: true,
+		Proxy:    &ecv1beta1.ProxySpec{HTTPProxy: "http://proxy.example.com"},
+	}
+
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "cert-manager", Namespace: "cert-manager"},
+		Status:     appsv1.DeploymentStatus{ReadyReplicas: 1},
+	}
+	kcli := fake.NewClientBuilder().WithScheme(kubeutils.Scheme).WithObjects(deploy).Build()
+
+	hcli := &helm.MockClient{}
+	hcli.On("Install", mock.Anything, helm.InstallOptions{
+		ReleaseName:  "cert-manager",
+		ChartPath:    "oci://registry.example.com/charts/cert-manager",
+		ChartVersion: "1.16.1",
+		Values: map[string]interface{}{
+			"namespace":    "cert-manager",
+			"proxy":        "http://proxy.example.com",
+			"replicaCount": float64(2),
+		},
+		Namespace: "cert-manager",
+		Labels: map[string]string{
+			"replicated.com/disaster-recovery":       "infra",
+			"replicated.com/disaster-recovery-chart": "cert-manager",
+		},
+	}).Return(nil, nil)
+
+	err := addon.Install(ctx, kcli, hcli, []string{"replicaCount: 2"}, nil)
+	require.NoError(t, err)
+	hcli.AssertExpectations(t)
+
+	var cm corev1.ConfigMap
+	require.NoError(t, kcli.Get(ctx, types.NamespacedName{Namespace: "cert-manager", Name: "cert-manager-config"}, &cm))
+	assert.Equal(t, "true", cm.Data["airgap"])
+	assert.Equal(t, "infra", cm.Labels["replicated.com/disaster-recovery"])
+
+	var ns corev1.Namespace
+	require.NoError(t, kcli.Get(ctx, types.NamespacedName{Name: "cert-manager-webhooks"}, &ns))
+}
+
+func TestThirdParty_GenerateHelmValues(t *testing.T) {
+	addon := &ThirdParty{
+		Manifest: ecv1beta1.AddOn{Values: "proxy: {{ .Proxy }}"},
+	}
+	_, err := addon.GenerateHelmValues(context.Background(), nil, nil)
+	assert.Error(t, err, "unknown template fields should fail")
+
+	addon = &ThirdParty{Manifest: ecv1beta1.AddOn{}}
+	values, err := addon.GenerateHelmValues(context.Background(), nil, []string{"a: b"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"a": "b"}, values)
+}
@@ -0,0 +1,36 @@
+package thirdparty
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Uninstall removes the addon once it is no longer declared in the config. The volumes created
+// by the release are deleted and so is the namespace, unless other workloads still run in it.
+// Prerequisites are left untouched as they may be shared.
+func (t *ThirdParty) Uninstall(ctx context.Context, kcli client.Client, hcli helm.Client) error {
+	err := hcli.Uninstall(ctx, helm.UninstallOptions{
+		ReleaseName:    t.Manifest.ReleaseName,
+		Namespace:      t.Manifest.Namespace,
+		Wait:           true,
+		IgnoreNotFound: true,
+	})
+	if err != nil {
+		return errors.Wrap(err, "helm uninstall")
+	}
+
+	pvcLabels := map[string]string{"app.kubernetes.io/instance": t.Manifest.ReleaseName}
+	if err := kubeutils.DeletePVCs(ctx, kcli, t.Manifest.Namespace, pvcLabels); err != nil {
+		return errors.Wrap(err, "delete persistent volume claims")
+	}
+
+	if err := kubeutils.DeleteNamespaceIfUnused(ctx, kcli, t.Manifest.Namespace, nil); err != nil {
+		return errors.Wrap(err, "delete namespace")
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package thirdparty
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func (t *ThirdParty) Upgrade(ctx context.Context, kcli client.Client, hcli helm.Client, overrides []string) error {
+	exists, err := hcli.ReleaseExists(ctx, t.Manifest.Namespace, t.Manifest.ReleaseName)
+	if err != nil {
+		return errors.Wrap(err, "check if release exists")
+	}
+	if !exists {
+		slog.Info("Release not found, installing", "release", t.Manifest.ReleaseName, "namespace", t.Manifest.Namespace)
+		if err := t.Install(ctx, kcli, hcli, overrides, nil); err != nil {
+			return errors.Wrap(err, "install")
+		}
+		return nil
+	}
+
+	// prerequisites may have been added by the new version of the manifest.
+	if err := t.createPreRequisites(ctx, kcli); err != nil {
+		return errors.Wrap(err, "create prerequisites")
+	}
+
+	values, err := t.GenerateHelmValues(ctx, kcli, overrides)
+	if err != nil {
+		return errors.Wrap(err, "generate helm values")
+	}
+
+	_, err = hcli.Upgrade(ctx, helm.UpgradeOptions{
+		ReleaseName:  t.Manifest.ReleaseName,
+		ChartPath:    t.Manifest.ChartName,
+		ChartVersion: t.Manifest.Version,
+		Values:       values,
+		Namespace:    t.Manifest.Namespace,
+		Labels:       t.getBackupLabels(),
+		Timeout:      t.Manifest.Timeout.Duration,
+		Force:        false,
+	})
+	if err != nil {
+		return errors.Wrap(err, "helm upgrade")
+	}
+
+	if err := t.waitForReady(ctx, kcli, nil); err != nil {
+		return errors.Wrap(err, "wait for ready")
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package thirdparty
+
+import (
+	"bytes"
+	"context"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// templateData is the data available to the values and prerequisites templates.
+type templateData struct {
+	Namespace   string
+	ReleaseName string
+	DataDir     string
+	IsAirgap    bool
+	IsHA        bool
+	ServiceCIDR string
+	HTTPProxy   string
+	HTTPSProxy  string
+	NoProxy     string
+}
+
+func (t *ThirdParty) templateData() templateData {
+	data := templateData{
+		Namespace:   t.Manifest.Namespace,
+		ReleaseName: t.Manifest.ReleaseName,
+		DataDir:     runtimeconfig.EmbeddedClusterHomeDirectory(),
+		IsAirgap:    t.IsAirgap,
+		IsHA:        t.IsHA,
+		ServiceCIDR: t.ServiceCIDR,
+	}
+	if t.Proxy != nil {
+		data.HTTPProxy = t.Proxy.HTTPProxy
+		data.HTTPSProxy = t.Proxy.HTTPSProxy
+		data.NoProxy = t.Proxy.NoProxy
+	}
+	return data
+}
+
+// render renders the manifest template with the addon template data.
+func (t *ThirdParty) render(name, tpl string) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(tpl)
+	if err != nil {
+		return "", errors.Wrap(err, "parse template")
+	}
+	buf := bytes.NewBuffer(nil)
+	if err := tmpl.Execute(buf, t.templateData()); err != nil {
+		return "", errors.Wrap(err, "execute template")
+	}
+	return buf.String(), nil
+}
+
+func (t *ThirdParty) GenerateHelmValues(ctx context.Context, kcli client.Client, overrides []string) (map[string]interface{}, error) {
+	rendered, err := t.render("values", t.Manifest.Values)
+	if err != nil {
+		return nil, errors.Wrap(err, "render values")
+	}
+	values, err := helm.UnmarshalValues(rendered)
+	if err != nil {
+		return nil, errors.Wrap(err, "unmarshal helm values")
+	}
+	if values == nil {
+		values = map[string]interface{}{}
+	}
+
+	for _, override := range overrides {
+		values, err = helm.PatchValues(values, override)
+		if err != nil {
+			return nil, errors.Wrap(err, "patch helm values")
+		}
+	}
+
+	return values, nil
+}
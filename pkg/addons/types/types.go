@@ -4,10 +4,15 @@ import (
 	"context"
 
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/adminconsole"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/cilium"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/embeddedclusteroperator"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/ingress"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/longhorn"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/monitoring"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/openebs"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/registry"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/seaweedfs"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/thirdparty"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/velero"
 	"github.com/replicatedhq/embedded-cluster/pkg/helm"
 	"github.com/replicatedhq/embedded-cluster/pkg/spinner"
@@ -19,14 +24,33 @@ type AddOn interface {
 	Version() string
 	ReleaseName() string
 	Namespace() string
+	// Dependencies returns the release names of the addons that must be ready before this one
+	// is installed or upgraded. Dependencies not managed in the cluster are ignored.
+	Dependencies() []string
 	GenerateHelmValues(ctx context.Context, kcli client.Client, overrides []string) (map[string]interface{}, error)
 	Install(ctx context.Context, kcli client.Client, hcli helm.Client, overrides []string, writer *spinner.MessageWriter) error
 	Upgrade(ctx context.Context, kcli client.Client, hcli helm.Client, overrides []string) error
+	// Uninstall removes the addon once it is no longer enabled, along with the namespace,
+	// custom resource definitions and volumes it owns.
+	Uninstall(ctx context.Context, kcli client.Client, hcli helm.Client) error
 }
 
+// StorageAddOn is an addon providing the default storage class of the cluster. Exactly one
+// storage addon, selected in the config, is installed.
+type StorageAddOn interface {
+	AddOn
+	StorageClassName() string
+}
+
+var _ StorageAddOn = (*openebs.OpenEBS)(nil)
+var _ StorageAddOn = (*longhorn.Longhorn)(nil)
+
+var _ AddOn = (*cilium.Cilium)(nil)
 var _ AddOn = (*adminconsole.AdminConsole)(nil)
-var _ AddOn = (*openebs.OpenEBS)(nil)
 var _ AddOn = (*registry.Registry)(nil)
 var _ AddOn = (*seaweedfs.SeaweedFS)(nil)
 var _ AddOn = (*velero.Velero)(nil)
+var _ AddOn = (*ingress.Ingress)(nil)
+var _ AddOn = (*monitoring.Monitoring)(nil)
 var _ AddOn = (*embeddedclusteroperator.EmbeddedClusterOperator)(nil)
+var _ AddOn = (*thirdparty.ThirdParty)(nil)
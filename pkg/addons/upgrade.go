@@ -4,17 +4,22 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"sync"
 
 	"github.com/pkg/errors"
 	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
 	ectypes "github.com/replicatedhq/embedded-cluster/kinds/types"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/adminconsole"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/embeddedclusteroperator"
-	"github.com/replicatedhq/embedded-cluster/pkg/addons/openebs"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/ingress"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/monitoring"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/registry"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/seaweedfs"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/storage"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/thirdparty"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/types"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/velero"
+	"github.com/replicatedhq/embedded-cluster/pkg/config"
 	"github.com/replicatedhq/embedded-cluster/pkg/helm"
 	"github.com/replicatedhq/embedded-cluster/pkg/helpers"
 	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
@@ -22,29 +27,216 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-func Upgrade(ctx context.Context, hcli helm.Client, in *ecv1beta1.Installation, meta *ectypes.ReleaseMetadata) error {
+// statusMu serializes the changes made to the installation by the addons upgraded in parallel.
+var statusMu sync.Mutex
+
+// CheckpointFunc is called after each addon or extension is upgraded. Returning an error stops
+// the upgrade.
+type CheckpointFunc func(ctx context.Context, step string) error
+
+// Upgrade upgrades the addons enabled for the provided installation. Addons managed for the
+// previous installation that are no longer enabled are uninstalled first. The previous
+// installation may be nil.
+func Upgrade(ctx context.Context, hcli helm.Client, prev *ecv1beta1.Installation, in *ecv1beta1.Installation, meta *ectypes.ReleaseMetadata, checkpoint CheckpointFunc) error {
 	kcli, err := kubeutils.KubeClient()
 	if err != nil {
 		return errors.Wrap(err, "create kube client")
 	}
 
-	addons, err := getAddOnsForUpgrade(in, meta)
+	addons, err := GetAddOnsForUpgrade(in, meta)
 	if err != nil {
 		return errors.Wrap(err, "get addons for upgrade")
 	}
-	for _, addon := range addons {
-		if err := upgradeAddOn(ctx, hcli, kcli, in, addon); err != nil {
+	removed, err := GetAddOnsForRemoval(prev, in, meta)
+	if err != nil {
+		return errors.Wrap(err, "get addons for removal")
+	}
+	removed, err = registryRemoval(ctx, hcli, kcli, in, meta, removed)
+	if err != nil {
+		return errors.Wrap(err, "check registry removal")
+	}
+
+	return upgradeAddOns(ctx, hcli, kcli, in, removed, addons, checkpoint)
+}
+
+// registryRemoval defers the removal of the registry, and of the seaweedfs storing its images,
+// while pods still pull their images from it. This is the case right after an air gap
+// installation is converted to online, the pods are only moved to the online images by the
+// upgrade itself. The registry left behind is removed by a later upgrade, once it is unused.
+// Only releases deployed by a previous air gap installation are considered left behind, a
+// vendor may use the same names in clusters that were never air gapped.
+func registryRemoval(ctx context.Context, hcli helm.Client, kcli client.Client, in *ecv1beta1.Installation, meta *ectypes.ReleaseMetadata, removed []types.AddOn) ([]types.AddOn, error) {
+	if in.Spec.AirGap {
+		return removed, nil
+	}
+
+	result := []types.AddOn{}
+	for _, addon := range removed {
+		switch addon.(type) {
+		case *registry.Registry, *seaweedfs.SeaweedFS:
+		default:
+			result = append(result, addon)
+		}
+	}
+
+	wasAirgap, err := previouslyAirgap(ctx, kcli, in)
+	if err != nil {
+		return nil, errors.Wrap(err, "check previous air gap installations")
+	} else if !wasAirgap {
+		return result, nil
+	}
+
+	// the registry addons the installation would have if it was still air gapped, the ones
+	// whose release still exists are left from the previous air gap installation.
+	airgap := in.DeepCopy()
+	airgap.Spec.AirGap = true
+	airgap.Spec.ExternalRegistry = nil
+	addOns, err := GetAddOnsForUpgrade(airgap, meta)
+	if err != nil {
+		return nil, errors.Wrap(err, "get air gap addons")
+	}
+
+	leftover := []types.AddOn{}
+	var reg *registry.Registry
+	for _, addon := range addOns {
+		switch a := addon.(type) {
+		case *registry.Registry:
+			reg = a
+		case *seaweedfs.SeaweedFS:
+		default:
+			continue
+		}
+		exists, err := hcli.ReleaseExists(ctx, addon.Namespace(), addon.ReleaseName())
+		if err != nil {
+			return nil, errors.Wrapf(err, "check %s release", addon.Name())
+		}
+		if exists {
+			leftover = append(leftover, addon)
+		}
+	}
+	if len(leftover) == 0 {
+		return result, nil
+	}
+
+	inUse, err := reg.InUse(ctx, kcli)
+	if err != nil {
+		return nil, errors.Wrap(err, "check registry in use")
+	}
+	if inUse {
+		slog.Info("Registry is still in use, its removal is deferred to the next upgrade")
+		return result, nil
+	}
+	return append(result, leftover...), nil
+}
+
+// previouslyAirgap returns true if any installation before the provided one was air gapped
+// with the registry deployed in the cluster.
+func previouslyAirgap(ctx context.Context, kcli client.Client, in *ecv1beta1.Installation) (bool, error) {
+	installations, err := kubeutils.ListInstallations(ctx, kcli)
+	if err != nil {
+		return false, errors.Wrap(err, "list installations")
+	}
+	for _, installation := range installations {
+		if installation.Name == in.Name {
+			continue
+		}
+		if installation.Spec.AirGap && installation.Spec.ExternalRegistry == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// upgradeAddOns uninstalls the removed addons and then upgrades the provided ones. The addons
+// run in parallel, each one works on its own copy of the installation as updating the status
+// reads the installation from the cluster into it. The provided installation is not modified.
+func upgradeAddOns(ctx context.Context, hcli helm.Client, kcli client.Client, in *ecv1beta1.Installation, removed []types.AddOn, addons []types.AddOn, checkpoint CheckpointFunc) error {
+	// first uninstall the addons no longer enabled, dependents before their dependencies
+	err := runAddOns(ctx, reverseDependencies(removed), func(ctx context.Context, addon types.AddOn) error {
+		if err := uninstallAddOn(ctx, hcli, kcli, in.DeepCopy(), addon); err != nil {
 			return errors.Wrapf(err, "addon %s", addon.Name())
 		}
+		return runCheckpoint(ctx, checkpoint, "uninstall addon "+addon.Name())
+	})
+	if err != nil {
+		return err
 	}
 
+	return runAddOns(ctx, addons, func(ctx context.Context, addon types.AddOn) error {
+		if err := upgradeAddOn(ctx, hcli, kcli, in.DeepCopy(), addon); err != nil {
+			return errors.Wrapf(err, "addon %s", addon.Name())
+		}
+		return runCheckpoint(ctx, checkpoint, "addon "+addon.Name())
+	})
+}
+
+// runCheckpoint calls the checkpoint, if any, once the step is done. Its error stops the addons
+// not yet started.
+func runCheckpoint(ctx context.Context, checkpoint CheckpointFunc, step string) error {
+	if checkpoint == nil {
+		return nil
+	}
+	statusMu.Lock()
+	defer statusMu.Unlock()
+	if err := checkpoint(ctx, step); err != nil {
+		return stopAddOns(err)
+	}
 	return nil
 }
 
-func getAddOnsForUpgrade(in *ecv1beta1.Installation, meta *ectypes.ReleaseMetadata) ([]types.AddOn, error) {
-	addOns := []types.AddOn{
-		&openebs.OpenEBS{},
+// Reapply upgrades the addon with the overrides of the provided installation, installing it
+// if its release no longer exists. It restores the addons of the previous installation when an
+// upgrade is rolled back, so the installation status is not modified.
+func Reapply(ctx context.Context, hcli helm.Client, kcli client.Client, in *ecv1beta1.Installation, addon types.AddOn) error {
+	overrides := addOnOverrides(addon, in.Spec.Config, in.Spec.EndUserConfig)
+	return addon.Upgrade(ctx, kcli, hcli, overrides)
+}
+
+// GetAddOnsForRemoval returns the addons managed for the previous installation that are no
+// longer enabled for the provided one. Nothing is removed if there is no previous installation.
+// An error is returned if the cni or storage provider changed, the nodes would lose their pod
+// network or the volumes would be lost.
+func GetAddOnsForRemoval(prev *ecv1beta1.Installation, in *ecv1beta1.Installation, meta *ectypes.ReleaseMetadata) ([]types.AddOn, error) {
+	if prev == nil {
+		return nil, nil
+	}
+	prevCNI := config.CNIProvider(prev.Spec.Config)
+	cniProvider := config.CNIProvider(in.Spec.Config)
+	if prevCNI != cniProvider {
+		return nil, errors.Errorf("cni provider can not be changed from %s to %s", prevCNI, cniProvider)
+	}
+	prevStorage := storage.Provider(GetStorageSpec(prev.Spec.Config, prev.Spec.EndUserConfig))
+	storageProvider := storage.Provider(GetStorageSpec(in.Spec.Config, in.Spec.EndUserConfig))
+	if prevStorage != storageProvider {
+		return nil, errors.Errorf("storage provider can not be changed from %s to %s", prevStorage, storageProvider)
+	}
+	// the images are only available in the registry they were pushed to at install time.
+	if prev.Spec.AirGap && in.Spec.AirGap && (prev.Spec.ExternalRegistry == nil) != (in.Spec.ExternalRegistry == nil) {
+		return nil, errors.New("external registry can not be added or removed")
+	}
+	prevAddOns, err := GetAddOnsForUpgrade(prev, meta)
+	if err != nil {
+		return nil, errors.Wrap(err, "get addons for previous installation")
+	}
+	addOns, err := GetAddOnsForUpgrade(in, meta)
+	if err != nil {
+		return nil, errors.Wrap(err, "get addons for installation")
+	}
+	return diffAddOns(prevAddOns, addOns), nil
+}
+
+// GetAddOnsForUpgrade returns the addons, in upgrade order, managed for the provided
+// installation.
+func GetAddOnsForUpgrade(in *ecv1beta1.Installation, meta *ectypes.ReleaseMetadata) ([]types.AddOn, error) {
+	storageSpec := GetStorageSpec(in.Spec.Config, in.Spec.EndUserConfig)
+	storageProvider := storage.Provider(storageSpec)
+	monitoringSpec := GetMonitoringSpec(in.Spec.Config, in.Spec.EndUserConfig)
+
+	addOns := []types.AddOn{}
+	if cni := cniAddOn(in.Spec.Config); cni != nil {
+		addOns = append(addOns, cni)
 	}
+	addOns = append(addOns, storageAddOn(storageSpec))
 
 	serviceCIDR := ""
 	if in.Spec.Network != nil {
@@ -72,17 +264,21 @@ func getAddOnsForUpgrade(in *ecv1beta1.Installation, meta *ectypes.ReleaseMetada
 		ImageRepoOverride:     ecoImageRepo,
 		ImageTagOverride:      ecoImageTag,
 		UtilsImageOverride:    ecoUtilsImage,
+		ExposeMetrics:         monitoringSpec.Enabled,
 	})
 
-	if in.Spec.AirGap {
+	// there is nothing to deploy when an external registry is used.
+	if in.Spec.AirGap && in.Spec.ExternalRegistry == nil {
 		addOns = append(addOns, &registry.Registry{
-			ServiceCIDR: serviceCIDR,
-			IsHA:        in.Spec.HighAvailability,
+			ServiceCIDR:     serviceCIDR,
+			IsHA:            in.Spec.HighAvailability,
+			StorageProvider: storageProvider,
 		})
 
 		if in.Spec.HighAvailability {
 			addOns = append(addOns, &seaweedfs.SeaweedFS{
-				ServiceCIDR: serviceCIDR,
+				ServiceCIDR:     serviceCIDR,
+				StorageProvider: storageProvider,
 			})
 		}
 	}
@@ -93,19 +289,46 @@ func getAddOnsForUpgrade(in *ecv1beta1.Installation, meta *ectypes.ReleaseMetada
 		})
 	}
 
+	ingressSpec := GetIngressSpec(in.Spec.Config, in.Spec.EndUserConfig)
+	if ingressSpec.Enabled {
+		addOns = append(addOns, &ingress.Ingress{})
+	}
+
+	if monitoringSpec.Enabled {
+		addOns = append(addOns, &monitoring.Monitoring{
+			Retention:       monitoringSpec.Retention,
+			RetentionSize:   monitoringSpec.RetentionSize,
+			StorageProvider: storageProvider,
+		})
+	}
+
+	for _, manifest := range thirdPartyManifests(in.Spec.Config) {
+		addOns = append(addOns, &thirdparty.ThirdParty{
+			Manifest:    manifest,
+			IsAirgap:    in.Spec.AirGap,
+			IsHA:        in.Spec.HighAvailability,
+			Proxy:       in.Spec.Proxy,
+			ServiceCIDR: serviceCIDR,
+		})
+	}
+
 	addOns = append(addOns, &adminconsole.AdminConsole{
-		IsAirgap:    in.Spec.AirGap,
-		IsHA:        in.Spec.HighAvailability,
-		Proxy:       in.Spec.Proxy,
-		ServiceCIDR: serviceCIDR,
+		IsAirgap:        in.Spec.AirGap,
+		IsHA:            in.Spec.HighAvailability,
+		Proxy:           in.Spec.Proxy,
+		ServiceCIDR:     serviceCIDR,
+		Ingress:         adminConsoleIngress(ingressSpec),
+		StorageProvider: storageProvider,
 	})
 
+	setThirdPartyDependencies(addOns)
 	return addOns, nil
 }
 
 func upgradeAddOn(ctx context.Context, hcli helm.Client, kcli client.Client, in *ecv1beta1.Installation, addon types.AddOn) error {
 	// check if we already processed this addon
-	if kubeutils.CheckInstallationConditionStatus(in.Status, conditionName(addon)) == metav1.ConditionTrue {
+	processed := kubeutils.CheckInstallationConditionStatus(in.Status, conditionName(addon)) == metav1.ConditionTrue
+	if processed {
 		slog.Info(addon.Name() + " is ready!")
 		return nil
 	}
@@ -113,40 +336,78 @@ func upgradeAddOn(ctx context.Context, hcli helm.Client, kcli client.Client, in
 	slog.Info("Upgrading addon", "name", addon.Name(), "version", addon.Version())
 
 	// mark as processing
-	if err := setCondition(ctx, kcli, in, conditionName(addon), metav1.ConditionFalse, "Upgrading", ""); err != nil {
-		return errors.Wrap(err, "failed to set condition status")
+	if err := setAddOnStatus(ctx, kcli, in, addon, ecv1beta1.ComponentPhaseUpgrading, "", 0); err != nil {
+		return errors.Wrap(err, "failed to set addon status")
 	}
 
-	// TODO (@salah): add support for end user overrides
-	overrides := addOnOverrides(addon, in.Spec.Config, nil)
+	overrides := addOnOverrides(addon, in.Spec.Config, in.Spec.EndUserConfig)
 
 	err := addon.Upgrade(ctx, kcli, hcli, overrides)
 	if err != nil {
 		message := helpers.CleanErrorMessage(err)
-		if err := setCondition(ctx, kcli, in, conditionName(addon), metav1.ConditionFalse, "UpgradeFailed", message); err != nil {
-			slog.Error("Failed to set condition upgrade failed", "error", err)
+		if err := setAddOnStatus(ctx, kcli, in, addon, ecv1beta1.ComponentPhaseFailed, message, 0); err != nil {
+			slog.Error("Failed to set addon status upgrade failed", "error", err)
 		}
 		return errors.Wrap(err, "upgrade addon")
 	}
 
-	err = setCondition(ctx, kcli, in, conditionName(addon), metav1.ConditionTrue, "Upgraded", "")
+	revision, err := hcli.ReleaseRevision(ctx, addon.Namespace(), addon.ReleaseName())
+	if err != nil {
+		slog.Warn("Failed to get addon helm revision", "name", addon.Name(), "error", err)
+	}
+
+	err = setAddOnStatus(ctx, kcli, in, addon, ecv1beta1.ComponentPhaseUpgraded, "", revision)
 	if err != nil {
-		return errors.Wrap(err, "set condition upgrade succeeded")
+		return errors.Wrap(err, "set addon status upgrade succeeded")
 	}
 
 	slog.Info(addon.Name() + " is ready!")
 	return nil
 }
 
+func uninstallAddOn(ctx context.Context, hcli helm.Client, kcli client.Client, in *ecv1beta1.Installation, addon types.AddOn) error {
+	// check if we already processed this addon
+	processed := kubeutils.CheckInstallationConditionStatus(in.Status, conditionName(addon)) == metav1.ConditionTrue
+	if processed {
+		slog.Info(addon.Name() + " is uninstalled!")
+		return nil
+	}
+
+	slog.Info("Uninstalling addon", "name", addon.Name())
+
+	if err := setAddOnStatus(ctx, kcli, in, addon, ecv1beta1.ComponentPhaseUninstalling, "", 0); err != nil {
+		return errors.Wrap(err, "failed to set addon status")
+	}
+
+	if err := addon.Uninstall(ctx, kcli, hcli); err != nil {
+		message := helpers.CleanErrorMessage(err)
+		if err := setAddOnStatus(ctx, kcli, in, addon, ecv1beta1.ComponentPhaseFailed, message, 0); err != nil {
+			slog.Error("Failed to set addon status uninstall failed", "error", err)
+		}
+		return errors.Wrap(err, "uninstall addon")
+	}
+
+	if err := setAddOnStatus(ctx, kcli, in, addon, ecv1beta1.ComponentPhaseUninstalled, "", 0); err != nil {
+		return errors.Wrap(err, "set addon status uninstall succeeded")
+	}
+
+	slog.Info(addon.Name() + " is uninstalled!")
+	return nil
+}
+
 func conditionName(addon types.AddOn) string {
 	return fmt.Sprintf("%s-%s", addon.Namespace(), addon.ReleaseName())
 }
 
-func setCondition(ctx context.Context, kcli client.Client, in *ecv1beta1.Installation, conditionType string, status metav1.ConditionStatus, reason, message string) error {
-	return kubeutils.SetInstallationConditionStatus(ctx, kcli, in, metav1.Condition{
-		Type:    conditionType,
-		Status:  status,
-		Reason:  reason,
-		Message: message,
+func setAddOnStatus(ctx context.Context, kcli client.Client, in *ecv1beta1.Installation, addon types.AddOn, phase, lastError string, revision int) error {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+	return kubeutils.SetInstallationAddonStatus(ctx, kcli, in, ecv1beta1.ComponentStatus{
+		Name:         addon.ReleaseName(),
+		Namespace:    addon.Namespace(),
+		Version:      addon.Version(),
+		Phase:        phase,
+		LastError:    lastError,
+		HelmRevision: revision,
 	})
 }
@@ -1,22 +1,37 @@
 package addons
 
 import (
+	"context"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
 	ectypes "github.com/replicatedhq/embedded-cluster/kinds/types"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/adminconsole"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/cilium"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/embeddedclusteroperator"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/ingress"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/monitoring"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/openebs"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/registry"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/seaweedfs"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/thirdparty"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/types"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/velero"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
-func Test_getAddOnsForUpgrade(t *testing.T) {
+func Test_GetAddOnsForUpgrade(t *testing.T) {
 	meta := &ectypes.ReleaseMetadata{
 		Configs: ecv1beta1.Helm{
 			Charts: []ecv1beta1.Chart{
@@ -231,6 +246,98 @@ func Test_getAddOnsForUpgrade(t *testing.T) {
 				assert.Equal(t, "10.96.0.0/12", adminConsole.ServiceCIDR)
 			},
 		},
+		{
+			name: "airgap HA with an external registry",
+			in: &ecv1beta1.Installation{
+				Spec: ecv1beta1.InstallationSpec{
+					AirGap:           true,
+					HighAvailability: true,
+					ExternalRegistry: &ecv1beta1.ExternalRegistrySpec{Address: "harbor.example.com"},
+					BinaryName:       "test-binary-name",
+				},
+			},
+			meta: meta,
+			verify: func(t *testing.T, addons []types.AddOn, err error) {
+				assert.NoError(t, err)
+				assert.Len(t, addons, 3)
+
+				eco, ok := addons[1].(*embeddedclusteroperator.EmbeddedClusterOperator)
+				require.True(t, ok, "second addon should be EmbeddedClusterOperator")
+				assert.True(t, eco.IsAirgap, "ECO should be in airgap mode")
+
+				adminConsole, ok := addons[2].(*adminconsole.AdminConsole)
+				require.True(t, ok, "third addon should be AdminConsole")
+				assert.True(t, adminConsole.IsAirgap, "AdminConsole should be in airgap mode")
+			},
+		},
+		{
+			name: "ingress controller",
+			in: &ecv1beta1.Installation{
+				Spec: ecv1beta1.InstallationSpec{
+					Config: &ecv1beta1.ConfigSpec{
+						Ingress: &ecv1beta1.IngressSpec{
+							Enabled:      true,
+							AdminConsole: &ecv1beta1.AdminConsoleIngressSpec{Hostname: "console.example.com", TLSSecretName: "console-tls"},
+						},
+					},
+				},
+			},
+			meta: meta,
+			verify: func(t *testing.T, addons []types.AddOn, err error) {
+				assert.NoError(t, err)
+				assert.Len(t, addons, 4)
+
+				_, ok := addons[2].(*ingress.Ingress)
+				require.True(t, ok, "third addon should be Ingress")
+
+				adminConsole, ok := addons[3].(*adminconsole.AdminConsole)
+				require.True(t, ok, "fourth addon should be AdminConsole")
+				assert.Equal(t, &ecv1beta1.AdminConsoleIngressSpec{Hostname: "console.example.com", TLSSecretName: "console-tls"}, adminConsole.Ingress)
+			},
+		},
+		{
+			name: "monitoring",
+			in: &ecv1beta1.Installation{
+				Spec: ecv1beta1.InstallationSpec{
+					Config: &ecv1beta1.ConfigSpec{
+						Monitoring: &ecv1beta1.MonitoringSpec{Enabled: true, Retention: "30d"},
+					},
+				},
+			},
+			meta: meta,
+			verify: func(t *testing.T, addons []types.AddOn, err error) {
+				assert.NoError(t, err)
+				assert.Len(t, addons, 4)
+
+				eco, ok := addons[1].(*embeddedclusteroperator.EmbeddedClusterOperator)
+				require.True(t, ok, "second addon should be EmbeddedClusterOperator")
+				assert.True(t, eco.ExposeMetrics)
+
+				mon, ok := addons[2].(*monitoring.Monitoring)
+				require.True(t, ok, "third addon should be Monitoring")
+				assert.Equal(t, "30d", mon.Retention)
+			},
+		},
+		{
+			name: "cilium",
+			in: &ecv1beta1.Installation{
+				Spec: ecv1beta1.InstallationSpec{
+					Config: &ecv1beta1.ConfigSpec{
+						CNI: &ecv1beta1.CNISpec{Provider: ecv1beta1.CNIProviderCilium},
+					},
+				},
+			},
+			meta: meta,
+			verify: func(t *testing.T, addons []types.AddOn, err error) {
+				assert.NoError(t, err)
+				assert.Len(t, addons, 4)
+
+				_, ok := addons[0].(*cilium.Cilium)
+				require.True(t, ok, "first addon should be Cilium")
+				_, ok = addons[1].(*openebs.OpenEBS)
+				require.True(t, ok, "second addon should be OpenEBS")
+			},
+		},
 		{
 			name: "invalid metadata - missing chart",
 			in: &ecv1beta1.Installation{
@@ -265,8 +372,349 @@ func Test_getAddOnsForUpgrade(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			addons, err := getAddOnsForUpgrade(tt.in, tt.meta)
+			addons, err := GetAddOnsForUpgrade(tt.in, tt.meta)
 			tt.verify(t, addons, err)
 		})
 	}
 }
+
+func Test_GetAddOnsForRemoval(t *testing.T) {
+	meta := &ectypes.ReleaseMetadata{
+		Configs: ecv1beta1.Helm{
+			Charts: []ecv1beta1.Chart{
+				{
+					Name:      "embedded-cluster-operator",
+					ChartName: "replicated/embedded-cluster-operator",
+					Version:   "1.22.0+k8s-1.30",
+				},
+			},
+		},
+		Images: []string{
+			"proxy.replicated.com/anonymous/replicated/embedded-cluster-operator-image:1.22.0-k8s-1.30-amd64@sha256:929b6cb42add383a69e3b26790c06320bd4eac0ecd60b509212c1864d69c6a88",
+			"proxy.replicated.com/anonymous/replicated/ec-utils:latest-amd64@sha256:f499ed26bd5899bc5a1ae14d9d13853d1fc615ae21bde86fe250960772fd2c70",
+		},
+	}
+	drLicense := &ecv1beta1.LicenseInfo{IsDisasterRecoverySupported: true}
+
+	tests := []struct {
+		name string
+		prev *ecv1beta1.Installation
+		in   *ecv1beta1.Installation
+		want []string
+	}{
+		{
+			name: "no previous installation",
+			in:   &ecv1beta1.Installation{},
+			want: nil,
+		},
+		{
+			name: "nothing removed",
+			prev: &ecv1beta1.Installation{Spec: ecv1beta1.InstallationSpec{LicenseInfo: drLicense}},
+			in:   &ecv1beta1.Installation{Spec: ecv1beta1.InstallationSpec{LicenseInfo: drLicense}},
+			want: nil,
+		},
+		{
+			name: "disaster recovery no longer supported",
+			prev: &ecv1beta1.Installation{Spec: ecv1beta1.InstallationSpec{LicenseInfo: drLicense}},
+			in:   &ecv1beta1.Installation{Spec: ecv1beta1.InstallationSpec{LicenseInfo: &ecv1beta1.LicenseInfo{}}},
+			want: []string{"velero"},
+		},
+		{
+			name: "high availability air gap converted to online",
+			prev: &ecv1beta1.Installation{Spec: ecv1beta1.InstallationSpec{AirGap: true, HighAvailability: true}},
+			in:   &ecv1beta1.Installation{Spec: ecv1beta1.InstallationSpec{HighAvailability: true}},
+			want: []string{"docker-registry", "seaweedfs"},
+		},
+		{
+			name: "third party addon removed",
+			prev: &ecv1beta1.Installation{Spec: ecv1beta1.InstallationSpec{Config: &ecv1beta1.ConfigSpec{
+				Extensions: ecv1beta1.Extensions{AddOns: []ecv1beta1.AddOn{{Name: "Ingress", ReleaseName: "ingress-nginx", Namespace: "ingress-nginx"}}},
+			}}},
+			in:   &ecv1beta1.Installation{},
+			want: []string{"ingress-nginx"},
+		},
+		{
+			name: "ingress controller disabled by the end user",
+			prev: &ecv1beta1.Installation{Spec: ecv1beta1.InstallationSpec{Config: &ecv1beta1.ConfigSpec{
+				Ingress: &ecv1beta1.IngressSpec{Enabled: true},
+			}}},
+			in: &ecv1beta1.Installation{Spec: ecv1beta1.InstallationSpec{
+				Config:        &ecv1beta1.ConfigSpec{Ingress: &ecv1beta1.IngressSpec{Enabled: true}},
+				EndUserConfig: &ecv1beta1.ConfigSpec{Ingress: &ecv1beta1.IngressSpec{}},
+			}},
+			want: []string{"ingress-nginx"},
+		},
+		{
+			name: "monitoring disabled",
+			prev: &ecv1beta1.Installation{Spec: ecv1beta1.InstallationSpec{Config: &ecv1beta1.ConfigSpec{
+				Monitoring: &ecv1beta1.MonitoringSpec{Enabled: true},
+			}}},
+			in:   &ecv1beta1.Installation{Spec: ecv1beta1.InstallationSpec{Config: &ecv1beta1.ConfigSpec{}}},
+			want: []string{"prometheus"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			removed, err := GetAddOnsForRemoval(tt.prev, tt.in, meta)
+			require.NoError(t, err)
+
+			var got []string
+			for _, addon := range removed {
+				got = append(got, addon.ReleaseName())
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("storage provider changed", func(t *testing.T) {
+		prev := &ecv1beta1.Installation{}
+		in := &ecv1beta1.Installation{Spec: ecv1beta1.InstallationSpec{Config: &ecv1beta1.ConfigSpec{
+			Storage: &ecv1beta1.StorageSpec{Provider: ecv1beta1.StorageProviderLonghorn},
+		}}}
+		_, err := GetAddOnsForRemoval(prev, in, meta)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "storage provider can not be changed from openebs to longhorn")
+	})
+
+	t.Run("cni provider changed", func(t *testing.T) {
+		prev := &ecv1beta1.Installation{}
+		in := &ecv1beta1.Installation{Spec: ecv1beta1.InstallationSpec{Config: &ecv1beta1.ConfigSpec{
+			CNI: &ecv1beta1.CNISpec{Provider: ecv1beta1.CNIProviderCilium},
+		}}}
+		_, err := GetAddOnsForRemoval(prev, in, meta)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cni provider can not be changed from calico to cilium")
+	})
+
+	t.Run("external registry added", func(t *testing.T) {
+		prev := &ecv1beta1.Installation{Spec: ecv1beta1.InstallationSpec{AirGap: true}}
+		in := &ecv1beta1.Installation{Spec: ecv1beta1.InstallationSpec{
+			AirGap:           true,
+			ExternalRegistry: &ecv1beta1.ExternalRegistrySpec{Address: "harbor.example.com"},
+		}}
+		_, err := GetAddOnsForRemoval(prev, in, meta)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "external registry can not be added or removed")
+	})
+}
+
+func Test_registryRemoval(t *testing.T) {
+	meta := &ectypes.ReleaseMetadata{
+		Configs: ecv1beta1.Helm{
+			Charts: []ecv1beta1.Chart{
+				{
+					Name:      "embedded-cluster-operator",
+					ChartName: "replicated/embedded-cluster-operator",
+					Version:   "1.22.0+k8s-1.30",
+				},
+			},
+		},
+		Images: []string{
+			"proxy.replicated.com/anonymous/replicated/embedded-cluster-operator-image:1.22.0-k8s-1.30-amd64@sha256:929b6cb42add383a69e3b26790c06320bd4eac0ecd60b509212c1864d69c6a88",
+			"proxy.replicated.com/anonymous/replicated/ec-utils:latest-amd64@sha256:f499ed26bd5899bc5a1ae14d9d13853d1fc615ae21bde86fe250960772fd2c70",
+		},
+	}
+	network := &ecv1beta1.NetworkSpec{ServiceCIDR: "10.96.0.0/12"}
+	installation := func(name string, airgap bool) *ecv1beta1.Installation {
+		return &ecv1beta1.Installation{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Spec: ecv1beta1.InstallationSpec{
+				Config:           &ecv1beta1.ConfigSpec{Version: "2.0.0+k8s-1.30"},
+				AirGap:           airgap,
+				HighAvailability: true,
+				Network:          network,
+			},
+		}
+	}
+	appPod := func(image string) *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "app", Namespace: "kotsadm"},
+			Spec:       corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Image: image}}},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		in       *ecv1beta1.Installation
+		removed  []types.AddOn
+		objects  []client.Object
+		releases []string
+		want     []string
+	}{
+		{
+			name:     "air gap installation",
+			in:       installation("20241002000000", true),
+			removed:  []types.AddOn{&velero.Velero{}},
+			releases: []string{"registry/docker-registry"},
+			want:     []string{"velero"},
+		},
+		{
+			name:     "registry in use after conversion to online",
+			in:       installation("20241002000000", false),
+			removed:  []types.AddOn{&registry.Registry{}, &seaweedfs.SeaweedFS{}},
+			objects:  []client.Object{installation("20241001000000", true), appPod("10.96.0.11:5000/app/api:1.0.0")},
+			releases: []string{"registry/docker-registry", "seaweedfs/seaweedfs"},
+			want:     nil,
+		},
+		{
+			name:     "registry no longer in use",
+			in:       installation("20241002000000", false),
+			removed:  []types.AddOn{&registry.Registry{}, &seaweedfs.SeaweedFS{}},
+			objects:  []client.Object{installation("20241001000000", true), appPod("proxy.replicated.com/app/api:1.0.0")},
+			releases: []string{"registry/docker-registry", "seaweedfs/seaweedfs"},
+			want:     []string{"docker-registry", "seaweedfs"},
+		},
+		{
+			name:     "registry left by a previous upgrade",
+			in:       installation("20241003000000", false),
+			objects:  []client.Object{installation("20241001000000", true), installation("20241002000000", false)},
+			releases: []string{"registry/docker-registry"},
+			want:     []string{"docker-registry"},
+		},
+		{
+			name:    "registry already removed",
+			in:      installation("20241003000000", false),
+			objects: []client.Object{installation("20241001000000", true), installation("20241002000000", false)},
+			want:    nil,
+		},
+		{
+			name:     "never air gapped",
+			in:       installation("20241002000000", false),
+			objects:  []client.Object{installation("20241001000000", false)},
+			releases: []string{"registry/docker-registry", "seaweedfs/seaweedfs"},
+			want:     nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kcli := fake.NewClientBuilder().WithScheme(kubeutils.Scheme).WithObjects(append(tt.objects, tt.in)...).Build()
+			hcli := &helm.MockClient{}
+			for _, release := range []string{"registry/docker-registry", "seaweedfs/seaweedfs"} {
+				namespace, name, _ := strings.Cut(release, "/")
+				hcli.On("ReleaseExists", mock.Anything, namespace, name).Return(slices.Contains(tt.releases, release), nil).Maybe()
+			}
+
+			removed, err := registryRemoval(context.Background(), hcli, kcli, tt.in, meta, tt.removed)
+			require.NoError(t, err)
+
+			var got []string
+			for _, addon := range removed {
+				got = append(got, addon.ReleaseName())
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_reverseDependencies(t *testing.T) {
+	removed := reverseDependencies([]types.AddOn{&registry.Registry{}, &seaweedfs.SeaweedFS{}, &velero.Velero{}})
+
+	var got []string
+	for _, addon := range removed {
+		got = append(got, addon.ReleaseName())
+	}
+	assert.Equal(t, []string{"velero", "seaweedfs", "docker-registry"}, got)
+
+	// seaweedfs is uninstalled once the registry using it is gone.
+	assert.Equal(t, []string{"docker-registry"}, removed[1].Dependencies())
+	assert.Empty(t, removed[2].Dependencies())
+}
+
+// overridesRecorder records the overrides the addon is upgraded with.
+type overridesRecorder struct {
+	*openebs.OpenEBS
+	overrides []string
+}
+
+func (o *overridesRecorder) Upgrade(ctx context.Context, kcli client.Client, hcli helm.Client, overrides []string) error {
+	o.overrides = overrides
+	return nil
+}
+
+func Test_upgradeAddOn_endUserOverrides(t *testing.T) {
+	ctx := context.Background()
+	in := &ecv1beta1.Installation{
+		ObjectMeta: metav1.ObjectMeta{Name: "20241002205018"},
+		Spec: ecv1beta1.InstallationSpec{
+			Config: &ecv1beta1.ConfigSpec{
+				UnsupportedOverrides: ecv1beta1.UnsupportedOverrides{
+					BuiltInExtensions: []ecv1beta1.BuiltInExtension{{Name: "openebs", Values: "embedded: true\n"}},
+				},
+			},
+			EndUserConfig: &ecv1beta1.ConfigSpec{
+				UnsupportedOverrides: ecv1beta1.UnsupportedOverrides{
+					BuiltInExtensions: []ecv1beta1.BuiltInExtension{{Name: "openebs", Values: "endUser: true\n"}},
+				},
+			},
+		},
+	}
+	kcli := fake.NewClientBuilder().WithScheme(kubeutils.Scheme).WithObjects(in).WithStatusSubresource(in).Build()
+	hcli := &helm.MockClient{}
+	hcli.On("ReleaseRevision", mock.Anything, "openebs", "openebs").Return(2, nil)
+
+	addon := &overridesRecorder{OpenEBS: &openebs.OpenEBS{}}
+	require.NoError(t, upgradeAddOn(ctx, hcli, kcli, in, addon))
+
+	// the end user overrides are applied last so they take precedence.
+	assert.Equal(t, []string{"embedded: true\n", "endUser: true\n"}, addon.overrides)
+}
+
+// parallelUpgradeRecorder records the overrides it is upgraded with, holding the upgrade for a
+// while so the addons upgraded in parallel overlap.
+type parallelUpgradeRecorder struct {
+	*thirdparty.ThirdParty
+	overrides []string
+}
+
+func (p *parallelUpgradeRecorder) Upgrade(ctx context.Context, kcli client.Client, hcli helm.Client, overrides []string) error {
+	time.Sleep(10 * time.Millisecond)
+	p.overrides = overrides
+	return nil
+}
+
+// Test_upgradeAddOns_parallel is meant to be run with -race, the addons upgraded at the same
+// time must not share the installation.
+func Test_upgradeAddOns_parallel(t *testing.T) {
+	ctx := context.Background()
+	in := &ecv1beta1.Installation{
+		ObjectMeta: metav1.ObjectMeta{Name: "20241002205018"},
+		Spec: ecv1beta1.InstallationSpec{
+			Config: &ecv1beta1.ConfigSpec{
+				UnsupportedOverrides: ecv1beta1.UnsupportedOverrides{
+					BuiltInExtensions: []ecv1beta1.BuiltInExtension{{Name: "a", Values: "a: true\n"}},
+				},
+			},
+		},
+	}
+	kcli := fake.NewClientBuilder().WithScheme(kubeutils.Scheme).WithObjects(in).WithStatusSubresource(in).Build()
+	hcli := &helm.MockClient{}
+	hcli.On("ReleaseRevision", mock.Anything, mock.Anything, mock.Anything).Return(1, nil)
+
+	recorders := []*parallelUpgradeRecorder{}
+	addons := []types.AddOn{}
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		recorder := &parallelUpgradeRecorder{ThirdParty: &thirdparty.ThirdParty{
+			Manifest: ecv1beta1.AddOn{Name: name, ReleaseName: name, Namespace: name},
+		}}
+		recorders = append(recorders, recorder)
+		addons = append(addons, recorder)
+	}
+
+	var steps []string
+	checkpoint := func(ctx context.Context, step string) error {
+		steps = append(steps, step)
+		return nil
+	}
+	require.NoError(t, upgradeAddOns(ctx, hcli, kcli, in, nil, addons, checkpoint))
+
+	assert.Equal(t, []string{"a: true\n"}, recorders[0].overrides)
+	assert.ElementsMatch(t, []string{"addon a", "addon b", "addon c", "addon d", "addon e"}, steps)
+	assert.Empty(t, in.Status.Addons, "the provided installation is not modified")
+
+	var got ecv1beta1.Installation
+	require.NoError(t, kcli.Get(ctx, client.ObjectKeyFromObject(in), &got))
+	assert.Len(t, got.Status.Addons, 5)
+	for _, addon := range got.Status.Addons {
+		assert.Equal(t, ecv1beta1.ComponentPhaseUpgraded, addon.Phase, addon.Name)
+	}
+}
@@ -2,11 +2,19 @@ package addons
 
 import (
 	"errors"
+	"sort"
 	"strings"
 
 	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
 	ectypes "github.com/replicatedhq/embedded-cluster/kinds/types"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/adminconsole"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/cilium"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/longhorn"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/openebs"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/storage"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/thirdparty"
 	"github.com/replicatedhq/embedded-cluster/pkg/addons/types"
+	"github.com/replicatedhq/embedded-cluster/pkg/config"
 )
 
 func addOnOverrides(addon types.AddOn, embCfgSpec *ecv1beta1.ConfigSpec, euCfgSpec *ecv1beta1.ConfigSpec) []string {
@@ -20,6 +28,162 @@ func addOnOverrides(addon types.AddOn, embCfgSpec *ecv1beta1.ConfigSpec, euCfgSp
 	return overrides
 }
 
+// GetIngressSpec returns the configuration of the built-in ingress controller.
+func GetIngressSpec(embCfgSpec *ecv1beta1.ConfigSpec, euCfgSpec *ecv1beta1.ConfigSpec) ecv1beta1.IngressSpec {
+	return overriddenSpec(embCfgSpec, euCfgSpec, func(spec *ecv1beta1.ConfigSpec) *ecv1beta1.IngressSpec {
+		return spec.Ingress
+	})
+}
+
+// GetMonitoringSpec returns the configuration of the built-in monitoring stack.
+func GetMonitoringSpec(embCfgSpec *ecv1beta1.ConfigSpec, euCfgSpec *ecv1beta1.ConfigSpec) ecv1beta1.MonitoringSpec {
+	return overriddenSpec(embCfgSpec, euCfgSpec, func(spec *ecv1beta1.ConfigSpec) *ecv1beta1.MonitoringSpec {
+		return spec.Monitoring
+	})
+}
+
+// GetStorageSpec returns the storage provider configuration. The zero spec selects OpenEBS.
+func GetStorageSpec(embCfgSpec *ecv1beta1.ConfigSpec, euCfgSpec *ecv1beta1.ConfigSpec) ecv1beta1.StorageSpec {
+	return overriddenSpec(embCfgSpec, euCfgSpec, func(spec *ecv1beta1.ConfigSpec) *ecv1beta1.StorageSpec {
+		return spec.Storage
+	})
+}
+
+// overriddenSpec returns the section of the config spec selected by field. The end user config
+// takes precedence over the embedded one. A zero value is returned if neither sets the section.
+func overriddenSpec[T any](embCfgSpec, euCfgSpec *ecv1beta1.ConfigSpec, field func(*ecv1beta1.ConfigSpec) *T) T {
+	for _, spec := range []*ecv1beta1.ConfigSpec{euCfgSpec, embCfgSpec} {
+		if spec == nil {
+			continue
+		}
+		if value := field(spec); value != nil {
+			return *value
+		}
+	}
+	var zero T
+	return zero
+}
+
+// storageAddOn returns the addon implementing the storage provider selected by the spec.
+func storageAddOn(spec ecv1beta1.StorageSpec) types.StorageAddOn {
+	if storage.Provider(spec) == ecv1beta1.StorageProviderLonghorn {
+		return &longhorn.Longhorn{
+			Replicas: spec.Replicas,
+		}
+	}
+	return &openebs.OpenEBS{}
+}
+
+// cniAddOn returns the addon implementing the cni provider selected by the vendor, nil if the
+// cni is deployed by k0s.
+func cniAddOn(embCfgSpec *ecv1beta1.ConfigSpec) types.AddOn {
+	if config.CNIProvider(embCfgSpec) == ecv1beta1.CNIProviderCilium {
+		return &cilium.Cilium{}
+	}
+	return nil
+}
+
+// adminConsoleIngress returns how the admin console is exposed through the ingress
+// controller, nil if it is not.
+func adminConsoleIngress(spec ecv1beta1.IngressSpec) *ecv1beta1.AdminConsoleIngressSpec {
+	if !spec.Enabled || spec.AdminConsole == nil || spec.AdminConsole.Hostname == "" {
+		return nil
+	}
+	return spec.AdminConsole
+}
+
+// thirdPartyManifests returns the third party addons in the config spec sorted by their order.
+func thirdPartyManifests(cfgSpec *ecv1beta1.ConfigSpec) []ecv1beta1.AddOn {
+	if cfgSpec == nil {
+		return nil
+	}
+	manifests := make([]ecv1beta1.AddOn, len(cfgSpec.Extensions.AddOns))
+	copy(manifests, cfgSpec.Extensions.AddOns)
+	sort.SliceStable(manifests, func(i, j int) bool {
+		return manifests[i].Order < manifests[j].Order
+	})
+	return manifests
+}
+
+// setThirdPartyDependencies makes the third party addons depend on the built-in infrastructure
+// addons and on the third party addons with a lower order, and the admin console depend on the
+// third party addons.
+func setThirdPartyDependencies(addOns []types.AddOn) {
+	builtIn := []string{}
+	thirdParty := []*thirdparty.ThirdParty{}
+	var console *adminconsole.AdminConsole
+	for _, addon := range addOns {
+		switch a := addon.(type) {
+		case *thirdparty.ThirdParty:
+			thirdParty = append(thirdParty, a)
+		case *adminconsole.AdminConsole:
+			console = a
+		default:
+			builtIn = append(builtIn, addon.ReleaseName())
+		}
+	}
+
+	for _, addon := range thirdParty {
+		addon.DependsOn = append([]string{}, builtIn...)
+		for _, other := range thirdParty {
+			if other.Manifest.Order < addon.Manifest.Order {
+				addon.DependsOn = append(addon.DependsOn, other.ReleaseName())
+			}
+		}
+		if console != nil {
+			console.ExtraDependencies = append(console.ExtraDependencies, addon.ReleaseName())
+		}
+	}
+}
+
+// diffAddOns returns the addons in prevAddOns that are not in addOns. Addons are matched by
+// namespace and release name.
+func diffAddOns(prevAddOns, addOns []types.AddOn) []types.AddOn {
+	enabled := map[string]bool{}
+	for _, addon := range addOns {
+		enabled[addon.Namespace()+"/"+addon.ReleaseName()] = true
+	}
+
+	var removed []types.AddOn
+	for _, addon := range prevAddOns {
+		if !enabled[addon.Namespace()+"/"+addon.ReleaseName()] {
+			removed = append(removed, addon)
+		}
+	}
+	return removed
+}
+
+// removedAddOn is an addon being uninstalled. It depends on the addons that depend on it so
+// they are uninstalled first.
+type removedAddOn struct {
+	types.AddOn
+	dependents []string
+}
+
+func (r *removedAddOn) Dependencies() []string {
+	return r.dependents
+}
+
+// reverseDependencies returns the addons with their dependencies reversed, for them to be
+// uninstalled in the opposite order they are installed.
+func reverseDependencies(addOns []types.AddOn) []types.AddOn {
+	dependents := map[string][]string{}
+	for _, addon := range addOns {
+		for _, dep := range addon.Dependencies() {
+			dependents[dep] = append(dependents[dep], addon.ReleaseName())
+		}
+	}
+
+	reversed := []types.AddOn{}
+	for i := len(addOns) - 1; i >= 0; i-- {
+		reversed = append(reversed, &removedAddOn{
+			AddOn:      addOns[i],
+			dependents: dependents[addOns[i].ReleaseName()],
+		})
+	}
+	return reversed
+}
+
 func operatorChart(meta *ectypes.ReleaseMetadata) (string, string, error) {
 	// search through for the operator chart, and find the location
 	for _, chart := range meta.Configs.Charts {
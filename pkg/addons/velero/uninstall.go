@@ -0,0 +1,24 @@
+package velero
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Uninstall removes velero once disaster recovery is no longer supported. The backups stored
+// in the backup storage location are kept.
+func (v *Velero) Uninstall(ctx context.Context, kcli client.Client, hcli helm.Client) error {
+	if err := kubeutils.UninstallReleaseAndNamespace(ctx, kcli, hcli, releaseName, namespace); err != nil {
+		return errors.Wrap(err, "uninstall release")
+	}
+
+	if err := kubeutils.DeleteCRDsForGroup(ctx, kcli, "velero.io"); err != nil {
+		return errors.Wrap(err, "delete custom resource definitions")
+	}
+
+	return nil
+}
@@ -57,3 +57,7 @@ func (v *Velero) ReleaseName() string {
 func (v *Velero) Namespace() string {
 	return namespace
 }
+
+func (v *Velero) Dependencies() []string {
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
 	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
 )
 
@@ -15,6 +16,18 @@ const registryConfigTemplate = `
       insecure_skip_verify = true
 `
 
+const externalRegistryMirrorTemplate = `
+[plugins."io.containerd.grpc.v1.cri".registry]
+  [plugins."io.containerd.grpc.v1.cri".registry.mirrors]
+    [plugins."io.containerd.grpc.v1.cri".registry.mirrors."%[1]s"]
+      endpoint = ["https://%[1]s"]
+`
+
+const externalRegistryTLSTemplate = `  [plugins."io.containerd.grpc.v1.cri".registry.configs]
+    [plugins."io.containerd.grpc.v1.cri".registry.configs."%s".tls]
+      ca_file = "%s"
+`
+
 // AddInsecureRegistry adds a registry to the list of registries that
 // are allowed to be accessed over HTTP.
 func AddInsecureRegistry(registry string) error {
@@ -32,3 +45,38 @@ func AddInsecureRegistry(registry string) error {
 
 	return nil
 }
+
+// AddExternalRegistry configures containerd to pull from an external registry, used instead of
+// the bundled one. If the registry has a CA it is written next to the containerd configuration
+// and used to verify the registry certificate.
+func AddExternalRegistry(registry ecv1beta1.ExternalRegistrySpec) error {
+	parentDir := runtimeconfig.PathToK0sContainerdConfig()
+	if err := os.MkdirAll(parentDir, 0755); err != nil {
+		return fmt.Errorf("failed to ensure containerd directory exists: %w", err)
+	}
+
+	caFile := ""
+	if registry.CA != "" {
+		// containerd only imports the toml files in this directory.
+		caFile = filepath.Join(parentDir, "external-registry-ca.crt")
+		if err := os.WriteFile(caFile, []byte(registry.CA), 0644); err != nil {
+			return fmt.Errorf("failed to write external-registry-ca.crt: %w", err)
+		}
+	}
+
+	contents := externalRegistryConfig(registry.Address, caFile)
+	err := os.WriteFile(filepath.Join(parentDir, "external-registry.toml"), []byte(contents), 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write external-registry.toml: %w", err)
+	}
+
+	return nil
+}
+
+func externalRegistryConfig(address string, caFile string) string {
+	contents := fmt.Sprintf(externalRegistryMirrorTemplate, address)
+	if caFile != "" {
+		contents += fmt.Sprintf(externalRegistryTLSTemplate, address, caFile)
+	}
+	return contents
+}
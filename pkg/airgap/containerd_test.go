@@ -0,0 +1,46 @@
+package airgap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_externalRegistryConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		address string
+		caFile  string
+		want    string
+	}{
+		{
+			name:    "without ca",
+			address: "harbor.example.com",
+			want: `
+[plugins."io.containerd.grpc.v1.cri".registry]
+  [plugins."io.containerd.grpc.v1.cri".registry.mirrors]
+    [plugins."io.containerd.grpc.v1.cri".registry.mirrors."harbor.example.com"]
+      endpoint = ["https://harbor.example.com"]
+`,
+		},
+		{
+			name:    "with ca",
+			address: "harbor.example.com:8443",
+			caFile:  "/etc/k0s/containerd.d/external-registry-ca.crt",
+			want: `
+[plugins."io.containerd.grpc.v1.cri".registry]
+  [plugins."io.containerd.grpc.v1.cri".registry.mirrors]
+    [plugins."io.containerd.grpc.v1.cri".registry.mirrors."harbor.example.com:8443"]
+      endpoint = ["https://harbor.example.com:8443"]
+  [plugins."io.containerd.grpc.v1.cri".registry.configs]
+    [plugins."io.containerd.grpc.v1.cri".registry.configs."harbor.example.com:8443".tls]
+      ca_file = "/etc/k0s/containerd.d/external-registry-ca.crt"
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, externalRegistryConfig(tt.address, tt.caFile))
+		})
+	}
+}
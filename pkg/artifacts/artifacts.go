@@ -14,6 +14,13 @@ import (
 // PullOptions are options for pulling an artifact from a registry.
 type PullOptions struct {
 	PlainHTTP bool
+	// Peers is a list of base urls (e.g. http://10.0.0.1:50000) of local artifact mirrors
+	// running in other nodes. Layers are fetched from the peers before falling back to the
+	// registry.
+	Peers []string
+	// CacheDir, if set, is where the pulled layers are linked into (by digest) so they
+	// can later be served to other nodes.
+	CacheDir string
 }
 
 // Pull fetches an artifact from the registry pointed by 'from' and stores it in the 'dstDir' directory.
@@ -40,13 +47,20 @@ func Pull(ctx context.Context, cli client.Client, from string, dstDir string, op
 
 	repo.PlainHTTP = opts.PlainHTTP
 
+	tag := imgref.Reference
+	if len(opts.Peers) > 0 || opts.CacheDir != "" {
+		if err := pullLayers(ctx, repo, tag, dstDir, opts); err != nil {
+			return fmt.Errorf("pull layers: %w", err)
+		}
+		return nil
+	}
+
 	fs, err := file.New(dstDir)
 	if err != nil {
 		return fmt.Errorf("create file store: %w", err)
 	}
 	defer fs.Close()
 
-	tag := imgref.Reference
 	_, err = oras.Copy(ctx, repo, tag, fs, tag, oras.DefaultCopyOptions)
 	if err != nil {
 		return fmt.Errorf("registry copy: %w", err)
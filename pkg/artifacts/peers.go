@@ -0,0 +1,177 @@
+package artifacts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/sirupsen/logrus"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/registry/remote"
+)
+
+// PeerArtifactsPath is the path under which the local artifact mirror serves verified
+// artifacts, by digest, to other nodes in the cluster.
+const PeerArtifactsPath = "/artifacts"
+
+var (
+	// peerHTTPClient is the client used to fetch artifacts from other nodes. Peers that are
+	// not reachable must fail fast so we can move on to the next peer or to the registry.
+	peerHTTPClient = &http.Client{
+		Transport: &http.Transport{
+			DialContext:           (&net.Dialer{Timeout: 5 * time.Second}).DialContext,
+			ResponseHeaderTimeout: 10 * time.Second,
+		},
+	}
+)
+
+// PeerArtifactPath returns the path, relative to the cache directory, where the artifact
+// with the provided descriptor is stored.
+func PeerArtifactPath(desc ocispec.Descriptor) string {
+	return filepath.Join(desc.Digest.Algorithm().String(), desc.Digest.Encoded())
+}
+
+// pullLayers fetches the artifact manifest from the registry and then copies each one of
+// its layers into dstDir. Layers are first requested from the provided peers and, if none
+// of them can serve a layer, from the registry itself. Every layer is verified against the
+// digest present in the manifest before being moved into place. If a cache directory is
+// provided the verified layers are also linked into it so they can be served to peers.
+func pullLayers(ctx context.Context, repo *remote.Repository, tag string, dstDir string, opts PullOptions) error {
+	mdesc, rc, err := repo.FetchReference(ctx, tag)
+	if err != nil {
+		return fmt.Errorf("fetch manifest: %w", err)
+	}
+	defer rc.Close()
+
+	data, err := content.ReadAll(rc, mdesc)
+	if err != nil {
+		return fmt.Errorf("read manifest: %w", err)
+	}
+
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return fmt.Errorf("unmarshal manifest: %w", err)
+	}
+
+	for _, layer := range manifest.Layers {
+		name := layer.Annotations[ocispec.AnnotationTitle]
+		if name == "" {
+			return fmt.Errorf("layer %s has no title", layer.Digest)
+		}
+
+		dst := filepath.Join(dstDir, filepath.Base(name))
+		if err := pullLayer(ctx, repo, layer, dst, opts.Peers); err != nil {
+			return fmt.Errorf("pull layer %s: %w", name, err)
+		}
+
+		if opts.CacheDir == "" {
+			continue
+		}
+		if err := linkToCache(dst, filepath.Join(opts.CacheDir, PeerArtifactPath(layer))); err != nil {
+			// not being able to cache the layer only means we won't be able to serve
+			// it to other nodes, the pull itself has succeeded.
+			logrus.Warnf("unable to cache layer %s: %v", name, err)
+		}
+	}
+
+	return nil
+}
+
+// pullLayer fetches a single layer, trying the peers in order before falling back to the
+// registry.
+func pullLayer(ctx context.Context, repo *remote.Repository, layer ocispec.Descriptor, dst string, peers []string) error {
+	for _, peer := range peers {
+		err := fetchVerified(dst, layer, func() (io.ReadCloser, error) {
+			return fetchFromPeer(ctx, peer, layer)
+		})
+		if err == nil {
+			logrus.Infof("layer %s fetched from peer %s", layer.Digest, peer)
+			return nil
+		}
+		logrus.Warnf("unable to fetch layer %s from peer %s: %v", layer.Digest, peer, err)
+	}
+
+	err := fetchVerified(dst, layer, func() (io.ReadCloser, error) {
+		return repo.Blobs().Fetch(ctx, layer)
+	})
+	if err != nil {
+		return fmt.Errorf("fetch from registry: %w", err)
+	}
+	logrus.Infof("layer %s fetched from registry", layer.Digest)
+	return nil
+}
+
+// fetchFromPeer requests the layer from the local artifact mirror running in a peer node.
+func fetchFromPeer(ctx context.Context, peer string, layer ocispec.Descriptor) (io.ReadCloser, error) {
+	url := fmt.Sprintf("%s%s/%s", peer, PeerArtifactsPath, filepath.ToSlash(PeerArtifactPath(layer)))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	resp, err := peerHTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("get %s: %w", url, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("get %s: unexpected status code %d", url, resp.StatusCode)
+	}
+	return resp.Body, nil
+}
+
+// fetchVerified writes the content returned by open into dst, verifying its size and
+// digest against the provided descriptor. dst is only written if the content is valid.
+func fetchVerified(dst string, desc ocispec.Descriptor, open func() (io.ReadCloser, error)) error {
+	rc, err := open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".layer-*")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	vr := content.NewVerifyReader(rc, desc)
+	if _, err := io.Copy(tmp, vr); err != nil {
+		return fmt.Errorf("copy content: %w", err)
+	}
+	if err := vr.Verify(); err != nil {
+		return fmt.Errorf("verify content: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close temp file: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return fmt.Errorf("rename temp file: %w", err)
+	}
+	return nil
+}
+
+// linkToCache hard links src into dst. Hard links are used so a cached artifact does not
+// take additional disk space for as long as the original file exists.
+func linkToCache(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("create cache dir: %w", err)
+	}
+	if err := os.Remove(dst); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("remove cached file: %w", err)
+	}
+	if err := os.Link(src, dst); err != nil {
+		return fmt.Errorf("link file: %w", err)
+	}
+	return nil
+}
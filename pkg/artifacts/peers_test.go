@@ -0,0 +1,70 @@
+package artifacts
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_pullLayer_fromPeer(t *testing.T) {
+	data := []byte("artifact content")
+	layer := ocispec.Descriptor{
+		MediaType: ocispec.MediaTypeImageLayer,
+		Digest:    digest.FromBytes(data),
+		Size:      int64(len(data)),
+	}
+
+	var requested string
+	good := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.Path
+		w.Write(data)
+	}))
+	defer good.Close()
+
+	corrupted := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("corrupted content"))
+	}))
+	defer corrupted.Close()
+
+	missing := httptest.NewServer(http.NotFoundHandler())
+	defer missing.Close()
+
+	dst := filepath.Join(t.TempDir(), "artifact")
+	peers := []string{missing.URL, corrupted.URL, good.URL}
+	err := pullLayer(context.Background(), nil, layer, dst, peers)
+	require.NoError(t, err)
+
+	assert.Equal(t, "/artifacts/sha256/"+layer.Digest.Encoded(), requested)
+	content, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	assert.Equal(t, data, content)
+
+	// no temporary files must be left behind.
+	entries, err := os.ReadDir(filepath.Dir(dst))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func Test_linkToCache(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	require.NoError(t, os.WriteFile(src, []byte("new"), 0644))
+
+	dst := filepath.Join(dir, "cache", "sha256", "abc")
+	require.NoError(t, os.MkdirAll(filepath.Dir(dst), 0755))
+	require.NoError(t, os.WriteFile(dst, []byte("old"), 0644))
+
+	require.NoError(t, linkToCache(src, dst))
+
+	content, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(content))
+}
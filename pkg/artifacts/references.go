@@ -0,0 +1,158 @@
+package artifacts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+)
+
+// referencesFileName is the name of the file, inside the artifacts directory, where we keep
+// track of the installation versions referencing each artifact in the data directory.
+const referencesFileName = "references.json"
+
+// References tracks which installation versions need each one of the artifacts present in
+// the data directory. Artifacts not present here are never pruned.
+type References struct {
+	// Artifacts maps the path of each artifact, relative to the data directory, to the
+	// installation versions referencing it.
+	Artifacts map[string][]string `json:"artifacts"`
+}
+
+// PathToReferences returns the path to the artifact references file.
+func PathToReferences() string {
+	return filepath.Join(runtimeconfig.EmbeddedClusterArtifactsSubDir(), referencesFileName)
+}
+
+// ReadReferences reads the artifact references from disk. An empty set of references is
+// returned if the file does not exist.
+func ReadReferences() (*References, error) {
+	refs := &References{Artifacts: map[string][]string{}}
+	data, err := os.ReadFile(PathToReferences())
+	if errors.Is(err, os.ErrNotExist) {
+		return refs, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("read references: %w", err)
+	}
+	if err := json.Unmarshal(data, refs); err != nil {
+		return nil, fmt.Errorf("unmarshal references: %w", err)
+	}
+	if refs.Artifacts == nil {
+		refs.Artifacts = map[string][]string{}
+	}
+	return refs, nil
+}
+
+// WriteReferences writes the artifact references to disk.
+func WriteReferences(refs *References) error {
+	data, err := json.MarshalIndent(refs, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal references: %w", err)
+	}
+	fpath := PathToReferences()
+	tmp := fpath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("write references: %w", err)
+	}
+	if err := os.Rename(tmp, fpath); err != nil {
+		return fmt.Errorf("rename references: %w", err)
+	}
+	return nil
+}
+
+// Add marks the artifact in the provided path as referenced by the installation version.
+func (r *References) Add(path, version string) error {
+	rel, err := filepath.Rel(runtimeconfig.EmbeddedClusterHomeDirectory(), path)
+	if err != nil {
+		return fmt.Errorf("relative path for %s: %w", path, err)
+	}
+	if !slices.Contains(r.Artifacts[rel], version) {
+		r.Artifacts[rel] = append(r.Artifacts[rel], version)
+		sort.Strings(r.Artifacts[rel])
+	}
+	return nil
+}
+
+// TrackArtifacts marks all regular files, within the provided directories, that have been
+// modified after 'since' as referenced by the installation version. This is used after
+// pulling and materializing the artifacts for a given installation.
+func TrackArtifacts(version string, since time.Time, dirs ...string) error {
+	refs, err := ReadReferences()
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.Type().IsRegular() || path == PathToReferences() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if info.ModTime().Before(since) {
+				return nil
+			}
+			return refs.Add(path, version)
+		})
+		if err != nil {
+			return fmt.Errorf("walk %s: %w", dir, err)
+		}
+	}
+
+	return WriteReferences(refs)
+}
+
+// PruneArtifacts removes the tracked artifacts that are not referenced by any of the
+// provided installation versions. If dryRun is true nothing is removed. Returns the list
+// of artifacts, relative to the data directory, that have been (or would be) removed.
+func PruneArtifacts(keep []string, dryRun bool) ([]string, error) {
+	refs, err := ReadReferences()
+	if err != nil {
+		return nil, err
+	}
+
+	var pruned []string
+	for path, versions := range refs.Artifacts {
+		remaining := []string{}
+		for _, version := range versions {
+			if slices.Contains(keep, version) {
+				remaining = append(remaining, version)
+			}
+		}
+		if len(remaining) > 0 {
+			refs.Artifacts[path] = remaining
+			continue
+		}
+
+		pruned = append(pruned, path)
+		if dryRun {
+			continue
+		}
+		fpath := filepath.Join(runtimeconfig.EmbeddedClusterHomeDirectory(), path)
+		if err := os.Remove(fpath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("remove %s: %w", path, err)
+		}
+		delete(refs.Artifacts, path)
+	}
+	sort.Strings(pruned)
+
+	if dryRun {
+		return pruned, nil
+	}
+	if err := WriteReferences(refs); err != nil {
+		return nil, err
+	}
+	return pruned, nil
+}
@@ -0,0 +1,60 @@
+package artifacts
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPruneArtifacts(t *testing.T) {
+	runtimeconfig.SetDataDir(t.TempDir())
+	charts := runtimeconfig.EmbeddedClusterChartsSubDir()
+	bins := runtimeconfig.EmbeddedClusterBinsSubDir()
+
+	now := time.Now()
+	write := func(path string, mtime time.Time) {
+		require.NoError(t, os.WriteFile(path, []byte("content"), 0644))
+		require.NoError(t, os.Chtimes(path, mtime, mtime))
+	}
+
+	// an untracked file that must never be removed.
+	write(filepath.Join(bins, "untracked"), now.Add(-time.Hour))
+
+	write(filepath.Join(charts, "openebs-1.0.0.tgz"), now.Add(-30*time.Minute))
+	write(filepath.Join(bins, "k0s"), now.Add(-30*time.Minute))
+	require.NoError(t, TrackArtifacts("1.0.0", now.Add(-40*time.Minute), charts, filepath.Join(bins, "k0s")))
+
+	write(filepath.Join(charts, "openebs-2.0.0.tgz"), now.Add(-10*time.Minute))
+	require.NoError(t, TrackArtifacts("2.0.0", now.Add(-20*time.Minute), charts))
+	refs, err := ReadReferences()
+	require.NoError(t, err)
+	require.NoError(t, refs.Add(filepath.Join(bins, "k0s"), "2.0.0"))
+	require.NoError(t, WriteReferences(refs))
+
+	write(filepath.Join(charts, "openebs-3.0.0.tgz"), now)
+	require.NoError(t, TrackArtifacts("3.0.0", now.Add(-time.Minute), filepath.Join(charts, "openebs-3.0.0.tgz")))
+
+	pruned, err := PruneArtifacts([]string{"2.0.0", "3.0.0"}, true)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"charts/openebs-1.0.0.tgz"}, pruned)
+	assert.FileExists(t, filepath.Join(charts, "openebs-1.0.0.tgz"), "dry run must not remove files")
+
+	pruned, err = PruneArtifacts([]string{"3.0.0"}, false)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bin/k0s", "charts/openebs-1.0.0.tgz", "charts/openebs-2.0.0.tgz"}, pruned)
+
+	assert.NoFileExists(t, filepath.Join(charts, "openebs-1.0.0.tgz"))
+	assert.NoFileExists(t, filepath.Join(charts, "openebs-2.0.0.tgz"))
+	assert.NoFileExists(t, filepath.Join(bins, "k0s"))
+	assert.FileExists(t, filepath.Join(charts, "openebs-3.0.0.tgz"))
+	assert.FileExists(t, filepath.Join(bins, "untracked"))
+
+	refs, err = ReadReferences()
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{"charts/openebs-3.0.0.tgz": {"3.0.0"}}, refs.Artifacts)
+}
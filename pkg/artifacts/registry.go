@@ -0,0 +1,162 @@
+package artifacts
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/distribution/reference"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"oras.land/oras-go/v2/registry/remote"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// PruneRegistryOptions are options for pruning the images stored in a registry.
+type PruneRegistryOptions struct {
+	PlainHTTP bool
+	// DryRun only reports the images that would be removed.
+	DryRun bool
+	// Repositories are the images whose repositories can be pruned. Any other repository in
+	// the registry is left untouched.
+	Repositories []string
+}
+
+// PruneRegistry removes from the registry in addr the manifests of the images not matched by
+// any of the keep references. Only the repositories of opts.Repositories are pruned. The blobs
+// are not removed, that is up to the registry garbage collector. Images are pushed to the
+// registry under a different host and namespace so they are matched by the last element of
+// their repository and by their tag or digest. A manifest is only removed if none of its tags
+// is referenced. Returns the images (repository:tag) that have been (or would be) removed.
+func PruneRegistry(ctx context.Context, cli client.Client, addr string, keep []string, opts PruneRegistryOptions) ([]string, error) {
+	reg, err := remote.NewRegistry(addr)
+	if err != nil {
+		return nil, fmt.Errorf("new registry: %w", err)
+	}
+
+	authClient := newInsecureAuthClient()
+	store, err := registryAuth(ctx, cli)
+	if err != nil {
+		return nil, fmt.Errorf("get registry auth: %w", err)
+	}
+	authClient.Credential = store.Get
+
+	reg.Client = authClient
+	reg.PlainHTTP = opts.PlainHTTP
+
+	return pruneRegistry(ctx, reg, keep, opts)
+}
+
+func pruneRegistry(ctx context.Context, reg *remote.Registry, keep []string, opts PruneRegistryOptions) ([]string, error) {
+	if len(keep) == 0 {
+		return nil, fmt.Errorf("no images to keep")
+	}
+
+	prunable := map[string]bool{}
+	for _, image := range opts.Repositories {
+		named, err := reference.ParseNormalizedNamed(image)
+		if err != nil {
+			return nil, fmt.Errorf("parse image %s: %w", image, err)
+		}
+		prunable[path.Base(reference.Path(named))] = true
+	}
+
+	referenced := map[string]bool{}
+	for _, image := range keep {
+		keys, err := imageKeys(image)
+		if err != nil {
+			return nil, fmt.Errorf("parse image %s: %w", image, err)
+		}
+		for _, key := range keys {
+			referenced[key] = true
+		}
+	}
+
+	var repos []string
+	err := reg.Repositories(ctx, "", func(page []string) error {
+		repos = append(repos, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list repositories: %w", err)
+	}
+
+	var pruned []string
+	for _, name := range repos {
+		base := path.Base(name)
+		if !prunable[base] {
+			continue
+		}
+		repo, err := reg.Repository(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("get repository %s: %w", name, err)
+		}
+
+		var tags []string
+		err = repo.Tags(ctx, "", func(page []string) error {
+			tags = append(tags, page...)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("list tags of %s: %w", name, err)
+		}
+
+		// multiple tags may point to the same manifest and deleting it removes all of them,
+		// the manifest is kept if any of its tags is referenced.
+		tagsByDigest := map[string][]string{}
+		descs := map[string]ocispec.Descriptor{}
+		inUse := map[string]bool{}
+		for _, tag := range tags {
+			desc, err := repo.Resolve(ctx, tag)
+			if err != nil {
+				return nil, fmt.Errorf("resolve %s:%s: %w", name, tag, err)
+			}
+			dgst := desc.Digest.String()
+			tagsByDigest[dgst] = append(tagsByDigest[dgst], tag)
+			descs[dgst] = desc
+			if referenced[base+":"+tag] || referenced[base+"@"+dgst] {
+				inUse[dgst] = true
+			}
+		}
+
+		for dgst, dtags := range tagsByDigest {
+			if inUse[dgst] {
+				continue
+			}
+			for _, tag := range dtags {
+				pruned = append(pruned, name+":"+tag)
+			}
+			if opts.DryRun {
+				continue
+			}
+			if err := repo.Delete(ctx, descs[dgst]); err != nil {
+				return nil, fmt.Errorf("delete %s@%s: %w", name, dgst, err)
+			}
+		}
+	}
+	sort.Strings(pruned)
+
+	return pruned, nil
+}
+
+// imageKeys returns the keys an image is matched by in the registry, the last element of its
+// repository followed by its tag and by its digest.
+func imageKeys(image string) ([]string, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return nil, err
+	}
+	base := path.Base(reference.Path(named))
+
+	var keys []string
+	if tagged, ok := named.(reference.Tagged); ok {
+		keys = append(keys, base+":"+tagged.Tag())
+	}
+	if digested, ok := named.(reference.Digested); ok {
+		keys = append(keys, base+"@"+digested.Digest().String())
+	}
+	if len(keys) == 0 {
+		keys = append(keys, base+":latest")
+	}
+	return keys, nil
+}
@@ -0,0 +1,224 @@
+package artifacts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"oras.land/oras-go/v2/registry/remote"
+)
+
+// fakeRegistry serves the subset of the distribution api used when pruning a registry.
+type fakeRegistry struct {
+	mu sync.Mutex
+	// repos maps each repository to its tags and each tag to the manifest content.
+	repos   map[string]map[string][]byte
+	deleted []string
+}
+
+// fakeManifest returns the content of a manifest unique to the given name.
+func fakeManifest(name string) []byte {
+	return []byte(fmt.Sprintf(`{"schemaVersion":2,"mediaType":%q,"annotations":{"name":%q}}`, ocispec.MediaTypeImageManifest, name))
+}
+
+var fakeRegistryPath = regexp.MustCompile(`^/v2/(.+)/(tags/list|manifests/(.+))$`)
+
+func (f *fakeRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.URL.Path == "/v2/_catalog" {
+		repos := []string{}
+		for name := range f.repos {
+			repos = append(repos, name)
+		}
+		json.NewEncoder(w).Encode(map[string][]string{"repositories": repos})
+		return
+	}
+
+	matches := fakeRegistryPath.FindStringSubmatch(r.URL.Path)
+	if matches == nil {
+		http.NotFound(w, r)
+		return
+	}
+	name, ref := matches[1], matches[3]
+	tags, ok := f.repos[name]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if matches[2] == "tags/list" {
+		list := []string{}
+		for tag := range tags {
+			list = append(list, tag)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"name": name, "tags": list})
+		return
+	}
+
+	var manifest []byte
+	for tag, content := range tags {
+		if tag == ref || digest.FromBytes(content).String() == ref {
+			manifest = content
+		}
+	}
+	if manifest == nil {
+		http.NotFound(w, r)
+		return
+	}
+	dgst := digest.FromBytes(manifest).String()
+
+	switch r.Method {
+	case http.MethodHead, http.MethodGet:
+		w.Header().Set("Content-Type", ocispec.MediaTypeImageManifest)
+		w.Header().Set("Docker-Content-Digest", dgst)
+		w.Header().Set("Content-Length", strconv.Itoa(len(manifest)))
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			w.Write(manifest)
+		}
+	case http.MethodDelete:
+		for tag, content := range tags {
+			if digest.FromBytes(content).String() == dgst {
+				delete(tags, tag)
+			}
+		}
+		f.deleted = append(f.deleted, name+"@"+dgst)
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func Test_pruneRegistry(t *testing.T) {
+	dgst := func(name string) string {
+		return digest.FromBytes(fakeManifest(name)).String()
+	}
+
+	tests := []struct {
+		name        string
+		repos       map[string]map[string][]byte
+		keep        []string
+		prunable    []string
+		dryRun      bool
+		wantPruned  []string
+		wantDeleted []string
+	}{
+		{
+			name: "removes the images not referenced",
+			repos: map[string]map[string][]byte{
+				"my-app/nginx": {"1.25": fakeManifest("nginx-1.25"), "1.24": fakeManifest("nginx-1.24")},
+				"my-app/redis": {"7": fakeManifest("redis-7")},
+			},
+			keep: []string{
+				"docker.io/library/nginx:1.25",
+				"registry.example.com/cache/redis:7",
+			},
+			prunable:    []string{"nginx", "redis"},
+			wantPruned:  []string{"my-app/nginx:1.24"},
+			wantDeleted: []string{"my-app/nginx@" + dgst("nginx-1.24")},
+		},
+		{
+			name: "matches images by digest",
+			repos: map[string]map[string][]byte{
+				"my-app/nginx": {"1.25": fakeManifest("nginx-1.25"), "1.24": fakeManifest("nginx-1.24")},
+			},
+			keep: []string{
+				"proxy.replicated.com/anonymous/nginx:unknown@" + dgst("nginx-1.24"),
+			},
+			prunable:    []string{"nginx"},
+			wantPruned:  []string{"my-app/nginx:1.25"},
+			wantDeleted: []string{"my-app/nginx@" + dgst("nginx-1.25")},
+		},
+		{
+			name: "keeps manifests with any of their tags referenced",
+			repos: map[string]map[string][]byte{
+				"my-app/nginx": {"1.25": fakeManifest("nginx-1.25"), "stable": fakeManifest("nginx-1.25")},
+			},
+			keep:        []string{"nginx:stable"},
+			prunable:    []string{"nginx"},
+			wantPruned:  nil,
+			wantDeleted: nil,
+		},
+		{
+			name: "dry run does not delete anything",
+			repos: map[string]map[string][]byte{
+				"my-app/nginx": {"1.25": fakeManifest("nginx-1.25"), "1.24": fakeManifest("nginx-1.24")},
+			},
+			keep:        []string{"nginx:1.25"},
+			prunable:    []string{"nginx"},
+			dryRun:      true,
+			wantPruned:  []string{"my-app/nginx:1.24"},
+			wantDeleted: nil,
+		},
+		{
+			name: "leaves alone the repositories that can not be pruned",
+			repos: map[string]map[string][]byte{
+				"my-app/nginx":   {"1.25": fakeManifest("nginx-1.25"), "1.24": fakeManifest("nginx-1.24")},
+				"my-app/coredns": {"1.11": fakeManifest("coredns-1.11"), "1.10": fakeManifest("coredns-1.10")},
+			},
+			keep:        []string{"registry.k8s.io/coredns/coredns:1.11"},
+			prunable:    []string{"registry.k8s.io/coredns/coredns:1.11"},
+			wantPruned:  []string{"my-app/coredns:1.10"},
+			wantDeleted: []string{"my-app/coredns@" + dgst("coredns-1.10")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeRegistry{repos: tt.repos}
+			server := httptest.NewServer(fake)
+			defer server.Close()
+
+			u, err := url.Parse(server.URL)
+			require.NoError(t, err)
+			reg, err := remote.NewRegistry(u.Host)
+			require.NoError(t, err)
+			reg.PlainHTTP = true
+
+			opts := PruneRegistryOptions{DryRun: tt.dryRun, Repositories: tt.prunable}
+			pruned, err := pruneRegistry(context.Background(), reg, tt.keep, opts)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantPruned, pruned)
+			assert.Equal(t, tt.wantDeleted, fake.deleted)
+		})
+	}
+}
+
+func Test_pruneRegistry_noImagesToKeep(t *testing.T) {
+	reg, err := remote.NewRegistry("127.0.0.1:5000")
+	require.NoError(t, err)
+	_, err = pruneRegistry(context.Background(), reg, nil, PruneRegistryOptions{})
+	assert.EqualError(t, err, "no images to keep")
+}
+
+func Test_imageKeys(t *testing.T) {
+	dgst := digest.FromString("image").String()
+	tests := []struct {
+		image string
+		want  []string
+	}{
+		{"nginx", []string{"nginx:latest"}},
+		{"docker.io/library/nginx:1.25", []string{"nginx:1.25"}},
+		{"10.96.0.11:5000/my-app/nginx:1.25", []string{"nginx:1.25"}},
+		{fmt.Sprintf("proxy.replicated.com/anonymous/replicated/ec-calico-cni:v3.28.0@%s", dgst), []string{"ec-calico-cni:v3.28.0", "ec-calico-cni@" + dgst}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			got, err := imageKeys(tt.image)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
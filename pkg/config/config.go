@@ -18,16 +18,44 @@ import (
 const (
 	DefaultServiceNodePortRange = "80-32767"
 	DefaultVendorChartOrder     = 10
+	// CustomNetworkProvider is the k0s network provider used when the cni is not deployed by
+	// k0s but installed as an addon.
+	CustomNetworkProvider = "custom"
 )
 
-// RenderK0sConfig renders a k0s cluster configuration.
-func RenderK0sConfig() *k0sconfig.ClusterConfig {
+// CNIProvider returns the cni provider selected in the config, Calico if none is. The cni is
+// chosen by the vendor, it can not be changed through the end user config.
+func CNIProvider(spec *embeddedclusterv1beta1.ConfigSpec) string {
+	if spec == nil || spec.CNI == nil || spec.CNI.Provider == "" {
+		return embeddedclusterv1beta1.CNIProviderCalico
+	}
+	return spec.CNI.Provider
+}
+
+// ValidateCNIProvider returns an error if the cni provider selected in the config is not
+// supported. An unknown provider would be rendered as a custom k0s network with no cni to
+// install.
+func ValidateCNIProvider(spec *embeddedclusterv1beta1.ConfigSpec) error {
+	switch provider := CNIProvider(spec); provider {
+	case embeddedclusterv1beta1.CNIProviderCalico, embeddedclusterv1beta1.CNIProviderCilium:
+		return nil
+	default:
+		return fmt.Errorf("unsupported cni provider %q, must be %q or %q", provider, embeddedclusterv1beta1.CNIProviderCalico, embeddedclusterv1beta1.CNIProviderCilium)
+	}
+}
+
+// RenderK0sConfig renders a k0s cluster configuration for the provided cni provider. Calico
+// is deployed by k0s, any other provider is installed as an addon.
+func RenderK0sConfig(cniProvider string) *k0sconfig.ClusterConfig {
 	cfg := k0sconfig.DefaultClusterConfig()
 	// Customize the default k0s configuration to our taste.
 	cfg.Name = runtimeconfig.BinaryName()
 	cfg.Spec.Konnectivity = nil
 	cfg.Spec.Network.KubeRouter = nil
 	cfg.Spec.Network.Provider = "calico"
+	if cniProvider != "" && cniProvider != embeddedclusterv1beta1.CNIProviderCalico {
+		cfg.Spec.Network.Provider = CustomNetworkProvider
+	}
 	cfg.Spec.Telemetry.Enabled = false
 	if cfg.Spec.API.ExtraArgs == nil {
 		cfg.Spec.API.ExtraArgs = map[string]string{}
@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	k0sconfig "github.com/k0sproject/k0s/pkg/apis/k0s/v1beta1"
+	embeddedclusterv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"gopkg.in/yaml.v2"
@@ -94,9 +95,44 @@ func Test_extractK0sConfigPatch(t *testing.T) {
 }
 
 func TestRenderK0sConfig(t *testing.T) {
-	cfg := RenderK0sConfig()
+	cfg := RenderK0sConfig(CNIProvider(nil))
 
 	assert.Equal(t, "calico", cfg.Spec.Network.Provider)
 	assert.Equal(t, DefaultServiceNodePortRange, cfg.Spec.API.ExtraArgs["service-node-port-range"])
 	assert.Contains(t, cfg.Spec.API.SANs, "kubernetes.default.svc.cluster.local")
 }
+
+func TestRenderK0sConfigCilium(t *testing.T) {
+	spec := &embeddedclusterv1beta1.ConfigSpec{
+		CNI: &embeddedclusterv1beta1.CNISpec{Provider: embeddedclusterv1beta1.CNIProviderCilium},
+	}
+	cfg := RenderK0sConfig(CNIProvider(spec))
+
+	assert.Equal(t, CustomNetworkProvider, cfg.Spec.Network.Provider)
+	assert.NotNil(t, cfg.Spec.Network.KubeProxy)
+}
+
+func TestValidateCNIProvider(t *testing.T) {
+	for _, tt := range []struct {
+		provider string
+		wantErr  bool
+	}{
+		{provider: ""},
+		{provider: embeddedclusterv1beta1.CNIProviderCalico},
+		{provider: embeddedclusterv1beta1.CNIProviderCilium},
+		{provider: "Cilium", wantErr: true},
+		{provider: "flannel", wantErr: true},
+	} {
+		t.Run(tt.provider, func(t *testing.T) {
+			spec := &embeddedclusterv1beta1.ConfigSpec{
+				CNI: &embeddedclusterv1beta1.CNISpec{Provider: tt.provider},
+			}
+			err := ValidateCNIProvider(spec)
+			if tt.wantErr {
+				assert.ErrorContains(t, err, "unsupported cni provider")
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
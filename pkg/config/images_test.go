@@ -8,7 +8,7 @@ import (
 )
 
 func TestListK0sImages(t *testing.T) {
-	original := airgap.GetImageURIs(RenderK0sConfig().Spec, true)
+	original := airgap.GetImageURIs(RenderK0sConfig(CNIProvider(nil)).Spec, true)
 	if len(original) == 0 {
 		t.Errorf("airgap.GetImageURIs() = %v, want not empty", original)
 	}
@@ -40,7 +40,7 @@ func TestListK0sImages(t *testing.T) {
 		t.Errorf("airgap.GetImageURIs() = %v, want to contain envoy-distroless", original)
 	}
 
-	filtered := ListK0sImages(RenderK0sConfig())
+	filtered := ListK0sImages(RenderK0sConfig(CNIProvider(nil)))
 	if len(filtered) == 0 {
 		t.Errorf("ListK0sImages() = %v, want not empty", filtered)
 	}
@@ -0,0 +1,316 @@
+package configutils
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/cilium"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons/ingress"
+	"github.com/replicatedhq/embedded-cluster/pkg/helpers/firewalld"
+	"github.com/replicatedhq/embedded-cluster/pkg/netutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"github.com/sirupsen/logrus"
+	"go.uber.org/multierr"
+)
+
+// ConfigureFirewalld configures firewalld for the cluster. It adds the ec-net zone for pod and
+// service communication with default target ACCEPT, and opens the necessary ports in the default
+// zone for k0s and k8s components and the cni provider on the host network, and for the built-in
+// ingress controller when it is enabled. The local artifact mirror port is only opened to the
+// network of the provided interface.
+func ConfigureFirewalld(ctx context.Context, podNetwork, serviceNetwork, networkInterface, cniProvider string, ingressEnabled bool) error {
+	isActive, err := firewalld.IsFirewalldActive(ctx)
+	if err != nil {
+		return fmt.Errorf("check if firewalld is active: %w", err)
+	}
+	if !isActive {
+		logrus.Debugf("firewalld is not active, skipping configuration")
+		return nil
+	}
+
+	logrus.Debugf("firewalld is active, configuring")
+
+	cmdExists, err := firewalld.FirewallCmdExists(ctx)
+	if err != nil {
+		return fmt.Errorf("check if firewall-cmd exists: %w", err)
+	}
+	if !cmdExists {
+		logrus.Debugf("firewall-cmd not found but firewalld is active, skipping firewalld configuration")
+		return nil
+	}
+
+	err = ensureFirewalldECNetZone(ctx, podNetwork, serviceNetwork, cniProvider)
+	if err != nil {
+		return fmt.Errorf("ensure ec-net zone: %w", err)
+	}
+
+	nodeNetwork, err := FirewalldNodeNetwork(networkInterface)
+	if err != nil {
+		return fmt.Errorf("get node network: %w", err)
+	}
+
+	err = EnsureFirewalldDefaultZone(ctx, nodeNetwork, cniProvider, ingressEnabled)
+	if err != nil {
+		return fmt.Errorf("ensure default zone: %w", err)
+	}
+
+	err = firewalld.Reload(ctx)
+	if err != nil {
+		return fmt.Errorf("reload firewalld: %w", err)
+	}
+
+	return nil
+}
+
+// EnsureLocalArtifactMirrorFirewalldRule opens the local artifact mirror port to the node network
+// if firewalld is active. New nodes get the rule from ConfigureFirewalld, this is used to migrate
+// the nodes installed before the mirror served the other nodes.
+func EnsureLocalArtifactMirrorFirewalldRule(ctx context.Context, nodeNetwork string) error {
+	isActive, err := firewalld.IsFirewalldActive(ctx)
+	if err != nil {
+		return fmt.Errorf("check if firewalld is active: %w", err)
+	}
+	if !isActive {
+		return nil
+	}
+
+	cmdExists, err := firewalld.FirewallCmdExists(ctx)
+	if err != nil {
+		return fmt.Errorf("check if firewall-cmd exists: %w", err)
+	}
+	if !cmdExists {
+		return nil
+	}
+
+	err = firewalld.AddRichRuleToZone(ctx, localArtifactMirrorRichRule(nodeNetwork), firewalld.IsPermanent())
+	if err != nil {
+		return fmt.Errorf("add local artifact mirror rule: %w", err)
+	}
+
+	err = firewalld.Reload(ctx)
+	if err != nil {
+		return fmt.Errorf("reload firewalld: %w", err)
+	}
+
+	return nil
+}
+
+// ResetFirewalld removes all firewalld configuration added by the installer.
+func ResetFirewalld(ctx context.Context) (finalErr error) {
+	cmdExists, err := firewalld.FirewallCmdExists(ctx)
+	if err != nil {
+		return fmt.Errorf("check if firewall-cmd exists: %w", err)
+	}
+	if !cmdExists {
+		return nil
+	}
+
+	err = resetFirewalldECNetZone(ctx)
+	if err != nil {
+		finalErr = multierr.Append(finalErr, fmt.Errorf("reset ec-net zone: %w", err))
+	}
+
+	err = resetFirewalldDefaultZone(ctx)
+	if err != nil {
+		finalErr = multierr.Append(finalErr, fmt.Errorf("reset default zone: %w", err))
+	}
+
+	err = firewalld.Reload(ctx)
+	if err != nil {
+		return fmt.Errorf("reload firewalld: %w", err)
+	}
+
+	return
+}
+
+func ensureFirewalldECNetZone(ctx context.Context, podNetwork, serviceNetwork, cniProvider string) error {
+	opts := []firewalld.Option{
+		firewalld.IsPermanent(),
+		firewalld.WithZone("ec-net"),
+	}
+
+	exists, err := firewalld.ZoneExists(ctx, "ec-net")
+	if err != nil {
+		return fmt.Errorf("check if ec-net zone exists: %w", err)
+	} else if !exists {
+		err = firewalld.NewZone(ctx, "ec-net", opts...)
+		if err != nil {
+			return fmt.Errorf("create ec-net zone: %w", err)
+		}
+	}
+
+	// Set the default target to ACCEPT for pod and service networks
+	err = firewalld.SetZoneTarget(ctx, "ACCEPT", opts...)
+	if err != nil {
+		return fmt.Errorf("set target to ACCEPT: %w", err)
+	}
+
+	err = firewalld.AddSourceToZone(ctx, podNetwork, opts...)
+	if err != nil {
+		return fmt.Errorf("add pod network source: %w", err)
+	}
+
+	err = firewalld.AddSourceToZone(ctx, serviceNetwork, opts...)
+	if err != nil {
+		return fmt.Errorf("add service network source: %w", err)
+	}
+
+	// Add the cni interfaces
+	// This is redundant and overlaps with the pod network but we add it anyway
+	for _, iface := range cniInterfaces(cniProvider) {
+		err = firewalld.AddInterfaceToZone(ctx, iface, opts...)
+		if err != nil {
+			return fmt.Errorf("add %s interface: %w", iface, err)
+		}
+	}
+
+	return nil
+}
+
+func resetFirewalldECNetZone(ctx context.Context) (finalErr error) {
+	opts := []firewalld.Option{
+		firewalld.IsPermanent(),
+	}
+
+	exists, err := firewalld.ZoneExists(ctx, "ec-net")
+	if err != nil {
+		return fmt.Errorf("check if ec-net zone exists: %w", err)
+	} else if !exists {
+		return nil
+	}
+
+	err = firewalld.DeleteZone(ctx, "ec-net", opts...)
+	if err != nil {
+		return fmt.Errorf("delete ec-net zone: %w", err)
+	}
+
+	return
+}
+
+// EnsureFirewalldDefaultZone opens the ports returned by FirewalldDefaultZonePorts in the default
+// zone, and the local artifact mirror port to the node network only.
+func EnsureFirewalldDefaultZone(ctx context.Context, nodeNetwork, cniProvider string, ingressEnabled bool) error {
+	opts := []firewalld.Option{
+		firewalld.IsPermanent(),
+	}
+
+	ports := FirewalldDefaultZonePorts(cniProvider, ingressEnabled)
+	for _, port := range ports {
+		err := firewalld.AddPortToZone(ctx, port, opts...)
+		if err != nil {
+			return fmt.Errorf("add %s port: %w", port, err)
+		}
+	}
+
+	err := firewalld.AddRichRuleToZone(ctx, localArtifactMirrorRichRule(nodeNetwork), opts...)
+	if err != nil {
+		return fmt.Errorf("add local artifact mirror rule: %w", err)
+	}
+
+	return nil
+}
+
+func resetFirewalldDefaultZone(ctx context.Context) (finalErr error) {
+	opts := []firewalld.Option{
+		firewalld.IsPermanent(),
+	}
+
+	// the ports of all the cni providers and the ingress ports are removed as there is no
+	// telling which cni provider was used or if the ingress controller was enabled
+	ports := FirewalldDefaultZonePorts(ecv1beta1.CNIProviderCalico, true)
+	ports = append(ports, cniPorts(ecv1beta1.CNIProviderCilium)...)
+	for _, port := range ports {
+		err := firewalld.RemovePortFromZone(ctx, port, opts...)
+		if err != nil {
+			finalErr = multierr.Append(finalErr, fmt.Errorf("remove %s port: %w", port, err))
+		}
+	}
+
+	// the node network may have changed since the installation, the rule is found by port.
+	rules, err := firewalld.ListRichRules(ctx, opts...)
+	if err != nil {
+		return multierr.Append(finalErr, fmt.Errorf("list rich rules: %w", err))
+	}
+	for _, rule := range rules {
+		if !strings.Contains(rule, localArtifactMirrorRichRulePort()) {
+			continue
+		}
+		err := firewalld.RemoveRichRuleFromZone(ctx, rule, opts...)
+		if err != nil {
+			finalErr = multierr.Append(finalErr, fmt.Errorf("remove local artifact mirror rule: %w", err))
+		}
+	}
+
+	return
+}
+
+// FirewalldDefaultZonePorts returns the ports other nodes need to connect to. These are the
+// k0s core components and the cni provider. The ports of the built-in ingress controller,
+// served on every node, are included when it is enabled.
+func FirewalldDefaultZonePorts(cniProvider string, ingressEnabled bool) []string {
+	ports := []string{"6443/tcp", "10250/tcp", "9443/tcp", "2380/tcp"}
+	ports = append(ports, cniPorts(cniProvider)...)
+	if ingressEnabled {
+		ports = append(ports,
+			fmt.Sprintf("%d/tcp", ingress.HTTPPort),
+			fmt.Sprintf("%d/tcp", ingress.HTTPSPort),
+		)
+	}
+	return ports
+}
+
+// localArtifactMirrorRichRule returns the rule allowing the nodes in the node network to fetch
+// the airgap artifacts other nodes serve through their local artifact mirror.
+func localArtifactMirrorRichRule(nodeNetwork string) string {
+	return fmt.Sprintf(`rule family="ipv4" source address="%s" %s accept`, nodeNetwork, localArtifactMirrorRichRulePort())
+}
+
+func localArtifactMirrorRichRulePort() string {
+	return fmt.Sprintf(`port port="%d" protocol="tcp"`, runtimeconfig.LocalArtifactMirrorPort())
+}
+
+// FirewalldNodeNetwork returns the network, in cidr notation, of the address the node uses in
+// the cluster.
+func FirewalldNodeNetwork(networkInterface string) (string, error) {
+	ipnet, err := netutils.FirstValidIPNet(networkInterface)
+	if err != nil {
+		return "", fmt.Errorf("get node ipnet: %w", err)
+	}
+	return ipNetNetwork(ipnet), nil
+}
+
+// FirewalldAddressNetwork returns the network, in cidr notation, of the node address.
+func FirewalldAddressNetwork(address string) (string, error) {
+	ipnet, err := netutils.IPNetForAddress(address)
+	if err != nil {
+		return "", fmt.Errorf("get address ipnet: %w", err)
+	}
+	return ipNetNetwork(ipnet), nil
+}
+
+func ipNetNetwork(ipnet *net.IPNet) string {
+	network := net.IPNet{IP: ipnet.IP.Mask(ipnet.Mask), Mask: ipnet.Mask}
+	return network.String()
+}
+
+// cniPorts returns the ports the cni provider uses to carry the pod network between the nodes.
+func cniPorts(cniProvider string) []string {
+	if cniProvider == ecv1beta1.CNIProviderCilium {
+		return []string{
+			fmt.Sprintf("%d/udp", cilium.VXLANPort),
+			fmt.Sprintf("%d/tcp", cilium.HealthPort),
+		}
+	}
+	return []string{"4789/udp"}
+}
+
+// cniInterfaces returns the network interfaces created on the host by the cni provider.
+func cniInterfaces(cniProvider string) []string {
+	if cniProvider == ecv1beta1.CNIProviderCilium {
+		return cilium.Interfaces
+	}
+	return []string{"cali+", "tunl+", "vxlan-v6.calico", "vxlan.calico", "wg-v6.cali", "wireguard.cali"}
+}
@@ -0,0 +1,37 @@
+package configutils
+
+import (
+	"fmt"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/helpers/systemd"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+)
+
+const (
+	localArtifactMirrorDropInFileContents = `[Service]
+Environment="LOCAL_ARTIFACT_MIRROR_PORT=%d"
+Environment="LOCAL_ARTIFACT_MIRROR_DATA_DIR=%s"
+Environment="LOCAL_ARTIFACT_MIRROR_PEER_ADDRESS=%s"
+# Empty ExecStart= will clear out the previous ExecStart value
+ExecStart=
+ExecStart=%s serve
+`
+)
+
+// WriteLocalArtifactMirrorDropInFile writes the systemd drop-in file configuring the port and
+// data directory of the local artifact mirror, and the node address it serves the other nodes
+// on. The systemd daemon must be reloaded for the changes to take effect.
+func WriteLocalArtifactMirrorDropInFile(peerAddress string) error {
+	contents := fmt.Sprintf(
+		localArtifactMirrorDropInFileContents,
+		runtimeconfig.LocalArtifactMirrorPort(),
+		runtimeconfig.EmbeddedClusterHomeDirectory(),
+		peerAddress,
+		runtimeconfig.PathToEmbeddedClusterBinary("local-artifact-mirror"),
+	)
+	err := systemd.WriteDropInFile("local-artifact-mirror.service", "embedded-cluster.conf", []byte(contents))
+	if err != nil {
+		return fmt.Errorf("write drop-in file: %w", err)
+	}
+	return nil
+}
@@ -24,6 +24,16 @@ var sysctlConfigPath = "/etc/sysctl.d/99-embedded-cluster.conf"
 // the embedded cluster.
 const dynamicSysctlConfigPath = "/etc/sysctl.d/99-dynamic-embedded-cluster.conf"
 
+// remediationsSysctlConfigPath is the path to the sysctl config file holding the settings fixed
+// by the host preflight remediations. It sorts after the other config files, including the
+// 99-sysctl.conf link to /etc/sysctl.conf, so it is applied last. This could have been a constant
+// but we want to be able to override it for testing purposes.
+var remediationsSysctlConfigPath = "/etc/sysctl.d/99-zz-embedded-cluster-remediations.conf"
+
+// selinuxConfigPath is the path to the SELinux config file. This could have been a constant but
+// we want to be able to override it for testing purposes.
+var selinuxConfigPath = "/etc/selinux/config"
+
 // modulesLoadConfigPath is the path to the kernel modules config file that is used to configure
 // the embedded cluster.
 const modulesLoadConfigPath = "/etc/modules-load.d/99-embedded-cluster.conf"
@@ -197,3 +207,78 @@ func modprobe(module string) error {
 	_, err := helpers.RunCommand("modprobe", module)
 	return err
 }
+
+// SetSysctl sets the kernel parameter to the provided value and persists it in the remediations
+// sysctl config file so it survives reboots.
+func SetSysctl(key string, value int64) error {
+	if _, err := helpers.RunCommand("sysctl", "-w", fmt.Sprintf("%s=%d", key, value)); err != nil {
+		return fmt.Errorf("set sysctl %s: %w", key, err)
+	}
+	if err := persistSysctl(remediationsSysctlConfigPath, key, value); err != nil {
+		return fmt.Errorf("persist sysctl %s: %w", key, err)
+	}
+	return nil
+}
+
+// persistSysctl sets the value of the key in the sysctl config file, replacing any previous
+// value.
+func persistSysctl(configPath, key string, value int64) error {
+	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+	content, err := os.ReadFile(configPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("read file: %w", err)
+	}
+
+	lines := []string{}
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		if k, _, ok := strings.Cut(line, "="); ok && strings.TrimSpace(k) == key {
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	lines = append(lines, fmt.Sprintf("%s = %d", key, value))
+
+	if err := os.WriteFile(configPath, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		return fmt.Errorf("write file: %w", err)
+	}
+	return nil
+}
+
+// LoadKernelModule loads the kernel module and makes sure the kernel modules config file is in
+// place so it is loaded on boot.
+func LoadKernelModule(module string) error {
+	if err := kernelModulesConfig(); err != nil {
+		return fmt.Errorf("materialize kernel modules config: %w", err)
+	}
+	if err := modprobe(module); err != nil {
+		return fmt.Errorf("modprobe %s: %w", module, err)
+	}
+	return nil
+}
+
+// SetSELinuxPermissive switches SELinux to permissive mode and persists the mode in the SELinux
+// config file.
+func SetSELinuxPermissive() error {
+	if _, err := helpers.RunCommand("setenforce", "0"); err != nil {
+		return fmt.Errorf("set selinux permissive: %w", err)
+	}
+
+	content, err := os.ReadFile(selinuxConfigPath)
+	if err != nil {
+		return fmt.Errorf("read selinux config: %w", err)
+	}
+	lines := strings.Split(string(content), "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "SELINUX=") {
+			lines[i] = "SELINUX=permissive"
+		}
+	}
+	if err := os.WriteFile(selinuxConfigPath, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+		return fmt.Errorf("write selinux config: %w", err)
+	}
+	return nil
+}
@@ -174,3 +174,55 @@ func Test_ensureKernelModulesLoaded(t *testing.T) {
 		}
 	}
 }
+
+func TestSetSysctl(t *testing.T) {
+	mock := &helpers.MockHelpers{}
+	helpers.Set(mock)
+	t.Cleanup(func() {
+		helpers.Set(&helpers.Helpers{})
+	})
+
+	orig := remediationsSysctlConfigPath
+	t.Cleanup(func() {
+		remediationsSysctlConfigPath = orig
+	})
+	remediationsSysctlConfigPath = filepath.Join(t.TempDir(), "sysctl.d", "remediations.conf")
+
+	require.NoError(t, SetSysctl("net.ipv4.ip_forward", 1))
+	require.NoError(t, SetSysctl("fs.inotify.max_user_watches", 65536))
+	// setting a key again replaces its previous value.
+	require.NoError(t, SetSysctl("net.ipv4.ip_forward", 0))
+
+	assert.Equal(t, []string{
+		"sysctl -w net.ipv4.ip_forward=1",
+		"sysctl -w fs.inotify.max_user_watches=65536",
+		"sysctl -w net.ipv4.ip_forward=0",
+	}, mock.Commands)
+
+	content, err := os.ReadFile(remediationsSysctlConfigPath)
+	require.NoError(t, err)
+	assert.Equal(t, "fs.inotify.max_user_watches = 65536\nnet.ipv4.ip_forward = 0\n", string(content))
+}
+
+func TestSetSELinuxPermissive(t *testing.T) {
+	mock := &helpers.MockHelpers{}
+	helpers.Set(mock)
+	t.Cleanup(func() {
+		helpers.Set(&helpers.Helpers{})
+	})
+
+	orig := selinuxConfigPath
+	t.Cleanup(func() {
+		selinuxConfigPath = orig
+	})
+	selinuxConfigPath = filepath.Join(t.TempDir(), "config")
+	err := os.WriteFile(selinuxConfigPath, []byte("# comment\nSELINUX=enforcing\nSELINUXTYPE=targeted\n"), 0644)
+	require.NoError(t, err)
+
+	require.NoError(t, SetSELinuxPermissive())
+	assert.Equal(t, []string{"setenforce 0"}, mock.Commands)
+
+	content, err := os.ReadFile(selinuxConfigPath)
+	require.NoError(t, err)
+	assert.Equal(t, "# comment\nSELINUX=permissive\nSELINUXTYPE=targeted\n", string(content))
+}
@@ -68,7 +68,7 @@ func (k *KubeUtils) IsJobComplete(ctx context.Context, cli client.Client, ns, na
 	return true, nil
 }
 
-func (k *KubeUtils) WaitForKubernetes(ctx context.Context, cli client.Client) <-chan error {
+func (k *KubeUtils) WaitForKubernetes(ctx context.Context, cli client.Client, cniProvider string) <-chan error {
 	errCh := make(chan error)
 	close(errCh)
 	return errCh
@@ -32,7 +32,7 @@ func Install(ctx context.Context, hcli helm.Client) error {
 	for _, ext := range sorted {
 		loading.Infof("Installing %s", ext.Name)
 
-		if err := install(ctx, hcli, ext); err != nil {
+		if _, err := install(ctx, hcli, ext); err != nil {
 			return errors.Wrapf(err, "install extension %s", ext.Name)
 		}
 	}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/pkg/errors"
 	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/addons"
 	"github.com/replicatedhq/embedded-cluster/pkg/helm"
 	"github.com/replicatedhq/embedded-cluster/pkg/helpers"
 	"github.com/replicatedhq/embedded-cluster/pkg/kubeutils"
@@ -22,7 +23,7 @@ const (
 
 type helmAction string
 
-func Upgrade(ctx context.Context, kcli client.Client, hcli helm.Client, prev *ecv1beta1.Installation, in *ecv1beta1.Installation) error {
+func Upgrade(ctx context.Context, kcli client.Client, hcli helm.Client, prev *ecv1beta1.Installation, in *ecv1beta1.Installation, checkpoint addons.CheckpointFunc) error {
 	// add new helm repos
 	if in.Spec.Config.Extensions.Helm != nil {
 		if err := addRepos(hcli, in.Spec.Config.Extensions.Helm.Repositories); err != nil {
@@ -48,6 +49,9 @@ func Upgrade(ctx context.Context, kcli client.Client, hcli helm.Client, prev *ec
 			if err := handleExtensionUninstall(ctx, kcli, hcli, in, result.Ext); err != nil {
 				return errors.Wrapf(err, "uninstall extension %s", result.Ext.Name)
 			}
+			if err := runCheckpoint(ctx, checkpoint, result.Ext); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -69,62 +73,74 @@ func Upgrade(ctx context.Context, kcli client.Client, hcli helm.Client, prev *ec
 		case actionUninstall:
 			continue
 		}
+		if err := runCheckpoint(ctx, checkpoint, result.Ext); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+func runCheckpoint(ctx context.Context, checkpoint addons.CheckpointFunc, ext ecv1beta1.Chart) error {
+	if checkpoint == nil {
+		return nil
+	}
+	return checkpoint(ctx, "extension "+ext.Name)
+}
+
 func handleExtensionInstall(ctx context.Context, kcli client.Client, hcli helm.Client, in *ecv1beta1.Installation, ext ecv1beta1.Chart) error {
-	return handleExtension(ctx, kcli, in, ext, actionInstall, func() error {
+	return handleExtension(ctx, kcli, in, ext, actionInstall, func() (int, error) {
 		exists, err := hcli.ReleaseExists(ctx, ext.TargetNS, ext.Name)
 		if err != nil {
-			return errors.Wrap(err, "check if release exists")
+			return 0, errors.Wrap(err, "check if release exists")
 		}
 		if exists {
 			slog.Info("Extension already installed", "name", ext.Name)
-			return nil
+			return 0, nil
 		}
-		if err := install(ctx, hcli, ext); err != nil {
-			return errors.Wrap(err, "install")
+		revision, err := install(ctx, hcli, ext)
+		if err != nil {
+			return 0, errors.Wrap(err, "install")
 		}
-		return nil
+		return revision, nil
 	})
 }
 
 func handleExtensionUpgrade(ctx context.Context, kcli client.Client, hcli helm.Client, in *ecv1beta1.Installation, ext ecv1beta1.Chart) error {
-	return handleExtension(ctx, kcli, in, ext, actionUpgrade, func() error {
-		if err := upgrade(ctx, hcli, ext); err != nil {
-			return errors.Wrap(err, "upgrade")
+	return handleExtension(ctx, kcli, in, ext, actionUpgrade, func() (int, error) {
+		revision, err := upgrade(ctx, hcli, ext)
+		if err != nil {
+			return 0, errors.Wrap(err, "upgrade")
 		}
-		return nil
+		return revision, nil
 	})
 }
 
 func handleExtensionNoop(ctx context.Context, kcli client.Client, in *ecv1beta1.Installation, ext ecv1beta1.Chart) error {
-	return handleExtension(ctx, kcli, in, ext, actionUpgrade, func() error {
+	return handleExtension(ctx, kcli, in, ext, actionUpgrade, func() (int, error) {
 		slog.Info("Extension is up to date", "name", ext.Name)
-		return nil
+		return 0, nil
 	})
 }
 
 func handleExtensionUninstall(ctx context.Context, kcli client.Client, hcli helm.Client, in *ecv1beta1.Installation, ext ecv1beta1.Chart) error {
-	return handleExtension(ctx, kcli, in, ext, actionUninstall, func() error {
+	return handleExtension(ctx, kcli, in, ext, actionUninstall, func() (int, error) {
 		exists, err := hcli.ReleaseExists(ctx, ext.TargetNS, ext.Name)
 		if err != nil {
-			return errors.Wrap(err, "check if release exists")
+			return 0, errors.Wrap(err, "check if release exists")
 		}
 		if !exists {
 			slog.Info("Extension already uninstalled", "name", ext.Name)
-			return nil
+			return 0, nil
 		}
 		if err := uninstall(ctx, hcli, ext); err != nil {
-			return errors.Wrap(err, "uninstall")
+			return 0, errors.Wrap(err, "uninstall")
 		}
-		return nil
+		return 0, nil
 	})
 }
 
-func handleExtension(ctx context.Context, kcli client.Client, in *ecv1beta1.Installation, ext ecv1beta1.Chart, action helmAction, processFn func() error) error {
+func handleExtension(ctx context.Context, kcli client.Client, in *ecv1beta1.Installation, ext ecv1beta1.Chart, action helmAction, processFn func() (int, error)) error {
 	slogArgs := slogArgs(ext, action)
 
 	if extensionAlreadyProcessed(in, ext) {
@@ -141,7 +157,7 @@ func handleExtension(ctx context.Context, kcli client.Client, in *ecv1beta1.Inst
 		}
 	}
 
-	err := processFn()
+	revision, err := processFn()
 	if err != nil {
 		if err := markExtensionAsFailed(ctx, kcli, in, ext, action, err); err != nil {
 			slog.Error("Failed to mark extension as failed", append(slogArgs, "error", err)...)
@@ -149,7 +165,7 @@ func handleExtension(ctx context.Context, kcli client.Client, in *ecv1beta1.Inst
 		return errors.Wrap(err, "process extension")
 	}
 
-	err = markExtensionAsProcessed(ctx, kcli, in, ext, action)
+	err = markExtensionAsProcessed(ctx, kcli, in, ext, action, revision)
 	if err != nil {
 		return errors.Wrap(err, "mark extension as processed")
 	}
@@ -165,47 +181,51 @@ func extensionAlreadyProcessed(in *ecv1beta1.Installation, ext ecv1beta1.Chart)
 }
 
 func markExtensionAsProcessing(ctx context.Context, kcli client.Client, in *ecv1beta1.Installation, ext ecv1beta1.Chart, action helmAction) error {
-	actionIng, _ := formatAction(action)
-	if err := setCondition(ctx, kcli, in, conditionName(ext), metav1.ConditionFalse, actionIng, ""); err != nil {
-		return errors.Wrap(err, "failed to set condition status")
+	processing, _ := actionPhases(action)
+	if err := setExtensionStatus(ctx, kcli, in, ext, processing, "", 0); err != nil {
+		return errors.Wrap(err, "failed to set extension status")
 	}
 	return nil
 }
 
-func markExtensionAsProcessed(ctx context.Context, kcli client.Client, in *ecv1beta1.Installation, ext ecv1beta1.Chart, action helmAction) error {
-	_, actionEd := formatAction(action)
-	if err := setCondition(ctx, kcli, in, conditionName(ext), metav1.ConditionTrue, actionEd, ""); err != nil {
-		return errors.Wrap(err, "failed to set condition status")
+func markExtensionAsProcessed(ctx context.Context, kcli client.Client, in *ecv1beta1.Installation, ext ecv1beta1.Chart, action helmAction, revision int) error {
+	_, processed := actionPhases(action)
+	if err := setExtensionStatus(ctx, kcli, in, ext, processed, "", revision); err != nil {
+		return errors.Wrap(err, "failed to set extension status")
 	}
 	return nil
 }
 
 func markExtensionAsFailed(ctx context.Context, kcli client.Client, in *ecv1beta1.Installation, ext ecv1beta1.Chart, action helmAction, finalErr error) error {
-	reason := string(action) + "Failed"
 	message := helpers.CleanErrorMessage(finalErr)
-	if err := setCondition(ctx, kcli, in, conditionName(ext), metav1.ConditionFalse, reason, message); err != nil {
-		return errors.Wrap(err, "failed to set condition status")
+	if err := setExtensionStatus(ctx, kcli, in, ext, ecv1beta1.ComponentPhaseFailed, message, 0); err != nil {
+		return errors.Wrap(err, "failed to set extension status")
 	}
 	return nil
 }
 
-func formatAction(action helmAction) (ing, ed string) {
+// actionPhases returns the phases of an extension while and after the action is performed.
+func actionPhases(action helmAction) (processing, processed string) {
 	switch action {
-	case actionInstall, actionUninstall:
-		return string(action) + "ing", string(action) + "ed"
+	case actionInstall:
+		return ecv1beta1.ComponentPhaseInstalling, ecv1beta1.ComponentPhaseInstalled
 	case actionUpgrade:
-		return "Upgrading", "Upgraded"
+		return ecv1beta1.ComponentPhaseUpgrading, ecv1beta1.ComponentPhaseUpgraded
+	case actionUninstall:
+		return ecv1beta1.ComponentPhaseUninstalling, ecv1beta1.ComponentPhaseUninstalled
 	default:
-		return "Processing", "Processed"
+		return ecv1beta1.ComponentPhaseInstalling, ecv1beta1.ComponentPhaseInstalled
 	}
 }
 
-func setCondition(ctx context.Context, kcli client.Client, in *ecv1beta1.Installation, conditionType string, status metav1.ConditionStatus, reason, message string) error {
-	return kubeutils.SetInstallationConditionStatus(ctx, kcli, in, metav1.Condition{
-		Type:    conditionType,
-		Status:  status,
-		Reason:  reason,
-		Message: message,
+func setExtensionStatus(ctx context.Context, kcli client.Client, in *ecv1beta1.Installation, ext ecv1beta1.Chart, phase, lastError string, revision int) error {
+	return kubeutils.SetInstallationExtensionStatus(ctx, kcli, in, ecv1beta1.ComponentStatus{
+		Name:         ext.Name,
+		Namespace:    ext.TargetNS,
+		Version:      ext.Version,
+		Phase:        phase,
+		LastError:    lastError,
+		HelmRevision: revision,
 	})
 }
 
@@ -518,7 +518,7 @@ func TestUpgrade(t *testing.T) {
 				Build()
 			mockHelmCli := tt.setupMockHelmCli(t)
 
-			err := Upgrade(context.Background(), kcli, mockHelmCli, tt.prev, tt.in)
+			err := Upgrade(context.Background(), kcli, mockHelmCli, tt.prev, tt.in, nil)
 			if tt.wantErr {
 				assert.Error(t, err)
 			} else {
@@ -11,6 +11,7 @@ import (
 	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
 	"github.com/replicatedhq/embedded-cluster/pkg/helm"
 	"github.com/sirupsen/logrus"
+	"helm.sh/helm/v3/pkg/release"
 	helmrepo "helm.sh/helm/v3/pkg/repo"
 )
 
@@ -38,13 +39,14 @@ func addRepos(hcli helm.Client, repos []k0sv1beta1.Repository) error {
 	return nil
 }
 
-func install(ctx context.Context, hcli helm.Client, ext ecv1beta1.Chart) error {
+// install installs the extension and returns the revision of the helm release.
+func install(ctx context.Context, hcli helm.Client, ext ecv1beta1.Chart) (int, error) {
 	values, err := helm.UnmarshalValues(ext.Values)
 	if err != nil {
-		return errors.Wrap(err, "unmarshal values")
+		return 0, errors.Wrap(err, "unmarshal values")
 	}
 
-	_, err = hcli.Install(ctx, helm.InstallOptions{
+	rel, err := hcli.Install(ctx, helm.InstallOptions{
 		ReleaseName:  ext.Name,
 		ChartPath:    ext.ChartName,
 		ChartVersion: ext.Version,
@@ -53,16 +55,17 @@ func install(ctx context.Context, hcli helm.Client, ext ecv1beta1.Chart) error {
 		Timeout:      ext.Timeout.Duration,
 	})
 	if err != nil {
-		return errors.Wrap(err, "helm install")
+		return 0, errors.Wrap(err, "helm install")
 	}
 
-	return nil
+	return releaseRevision(rel), nil
 }
 
-func upgrade(ctx context.Context, hcli helm.Client, ext ecv1beta1.Chart) error {
+// upgrade upgrades the extension and returns the revision of the helm release.
+func upgrade(ctx context.Context, hcli helm.Client, ext ecv1beta1.Chart) (int, error) {
 	values, err := helm.UnmarshalValues(ext.Values)
 	if err != nil {
-		return errors.Wrap(err, "unmarshal values")
+		return 0, errors.Wrap(err, "unmarshal values")
 	}
 
 	opts := helm.UpgradeOptions{
@@ -77,12 +80,19 @@ func upgrade(ctx context.Context, hcli helm.Client, ext ecv1beta1.Chart) error {
 	if ext.ForceUpgrade != nil {
 		opts.Force = *ext.ForceUpgrade
 	}
-	_, err = hcli.Upgrade(ctx, opts)
+	rel, err := hcli.Upgrade(ctx, opts)
 	if err != nil {
-		return errors.Wrap(err, "helm upgrade")
+		return 0, errors.Wrap(err, "helm upgrade")
 	}
 
-	return nil
+	return releaseRevision(rel), nil
+}
+
+func releaseRevision(rel *release.Release) int {
+	if rel == nil {
+		return 0
+	}
+	return rel.Version
 }
 
 func uninstall(ctx context.Context, hcli helm.Client, ext ecv1beta1.Chart) error {
@@ -9,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Masterminds/semver/v3"
@@ -127,7 +128,18 @@ type UninstallOptions struct {
 	IgnoreNotFound bool
 }
 
+type RollbackOptions struct {
+	ReleaseName string
+	Namespace   string
+	Revision    int
+	Timeout     time.Duration
+	Force       bool
+}
+
 type HelmClient struct {
+	// mu guards the repositories, the client is used by the addons installed and upgraded in
+	// parallel.
+	mu            sync.Mutex
 	tmpdir        string
 	kversion      *semver.Version
 	kubeconfig    string
@@ -140,6 +152,8 @@ type HelmClient struct {
 	airgapPath    string
 }
 
+// prepare writes the repositories config and downloads their indexes if the repositories
+// changed. The caller must hold mu.
 func (h *HelmClient) prepare() error {
 	// NOTE: this is a hack and should be refactored
 	if !h.reposChanged {
@@ -179,12 +193,16 @@ func (h *HelmClient) Close() error {
 }
 
 func (h *HelmClient) AddRepo(repo *repo.Entry) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 	h.repos = append(h.repos, repo)
 	h.reposChanged = true
 	return nil
 }
 
 func (h *HelmClient) Latest(reponame, chart string) (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 	for _, repository := range h.repos {
 		if repository.Name != reponame {
 			continue
@@ -225,16 +243,20 @@ func (h *HelmClient) Pull(reponame, chart string, version string) (string, error
 }
 
 func (h *HelmClient) PullByRef(ref string, version string) (string, error) {
+	h.mu.Lock()
 	if !isOCIChart(ref) {
 		if err := h.prepare(); err != nil {
+			h.mu.Unlock()
 			return "", fmt.Errorf("prepare: %w", err)
 		}
 	}
+	repocfg := h.repocfg
+	h.mu.Unlock()
 
 	dl := downloader.ChartDownloader{
 		Out:              io.Discard,
 		Options:          []getter.Option{},
-		RepositoryConfig: h.repocfg,
+		RepositoryConfig: repocfg,
 		RepositoryCache:  h.tmpdir,
 		Getters:          getters,
 	}
@@ -433,6 +455,64 @@ func (h *HelmClient) Uninstall(ctx context.Context, opts UninstallOptions) error
 	return nil
 }
 
+// ReleaseRevision returns the latest revision of a release. Zero is returned if the release
+// does not exist or has been uninstalled.
+func (h *HelmClient) ReleaseRevision(ctx context.Context, namespace string, releaseName string) (int, error) {
+	cfg, err := h.getActionCfg(namespace)
+	if err != nil {
+		return 0, fmt.Errorf("get action configuration: %w", err)
+	}
+
+	client := action.NewHistory(cfg)
+
+	versions, err := client.Run(releaseName)
+	if errors.Is(err, driver.ErrReleaseNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("get release history: %w", err)
+	}
+
+	var latest *release.Release
+	for _, version := range versions {
+		if latest == nil || version.Version > latest.Version {
+			latest = version
+		}
+	}
+	if latest == nil || latest.Info.Status == release.StatusUninstalled {
+		return 0, nil
+	}
+
+	return latest.Version, nil
+}
+
+// Rollback rolls a release back to the provided revision, waiting for the resources to be
+// ready.
+func (h *HelmClient) Rollback(ctx context.Context, opts RollbackOptions) error {
+	cfg, err := h.getActionCfg(opts.Namespace)
+	if err != nil {
+		return fmt.Errorf("get action configuration: %w", err)
+	}
+
+	client := action.NewRollback(cfg)
+	client.Version = opts.Revision
+	client.Wait = true
+	client.WaitForJobs = true
+	client.Force = opts.Force
+
+	if opts.Timeout != 0 {
+		client.Timeout = opts.Timeout
+	} else {
+		client.Timeout = 5 * time.Minute
+	}
+
+	if err := client.Run(opts.ReleaseName); err != nil {
+		return fmt.Errorf("helm rollback: %w", err)
+	}
+
+	return nil
+}
+
 func (h *HelmClient) Render(releaseName string, chartPath string, values map[string]interface{}, namespace string, labels map[string]string) ([][]byte, error) {
 	cfg := &action.Configuration{}
 
@@ -25,6 +25,8 @@ type Client interface {
 	Install(ctx context.Context, opts InstallOptions) (*release.Release, error)
 	Upgrade(ctx context.Context, opts UpgradeOptions) (*release.Release, error)
 	Uninstall(ctx context.Context, opts UninstallOptions) error
+	ReleaseRevision(ctx context.Context, namespace string, releaseName string) (int, error)
+	Rollback(ctx context.Context, opts RollbackOptions) error
 	Render(releaseName string, chartPath string, values map[string]interface{}, namespace string, labels map[string]string) ([][]byte, error)
 }
 
@@ -84,6 +84,16 @@ func (m *MockClient) Uninstall(ctx context.Context, opts UninstallOptions) error
 	return args.Error(0)
 }
 
+func (m *MockClient) ReleaseRevision(ctx context.Context, namespace string, releaseName string) (int, error) {
+	args := m.Called(ctx, namespace, releaseName)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockClient) Rollback(ctx context.Context, opts RollbackOptions) error {
+	args := m.Called(ctx, opts)
+	return args.Error(0)
+}
+
 func (m *MockClient) Render(releaseName string, chartPath string, values map[string]interface{}, namespace string, labels map[string]string) ([][]byte, error) {
 	args := m.Called(releaseName, chartPath, values, namespace, labels)
 	if args.Get(0) == nil {
@@ -1,9 +1,11 @@
 package firewalld
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"os/exec"
+	"strings"
 
 	"github.com/replicatedhq/embedded-cluster/pkg/helpers"
 )
@@ -110,6 +112,39 @@ func (c *Client) RemovePortFromZone(ctx context.Context, port string, opts ...Op
 	return helpers.RunCommandWithOptions(commandOptions(ctx), firewallCmd, args...)
 }
 
+// AddRichRuleToZone adds a rich rule to a zone.
+func (c *Client) AddRichRuleToZone(ctx context.Context, rule string, opts ...Option) error {
+	args := []string{"--add-rich-rule", rule}
+	args = append(args, buildContext(opts...).Args()...)
+	return helpers.RunCommandWithOptions(commandOptions(ctx), firewallCmd, args...)
+}
+
+// RemoveRichRuleFromZone removes a rich rule from a zone.
+func (c *Client) RemoveRichRuleFromZone(ctx context.Context, rule string, opts ...Option) error {
+	args := []string{"--remove-rich-rule", rule}
+	args = append(args, buildContext(opts...).Args()...)
+	return helpers.RunCommandWithOptions(commandOptions(ctx), firewallCmd, args...)
+}
+
+// ListRichRules lists the rich rules of a zone.
+func (c *Client) ListRichRules(ctx context.Context, opts ...Option) ([]string, error) {
+	args := []string{"--list-rich-rules"}
+	args = append(args, buildContext(opts...).Args()...)
+	var out bytes.Buffer
+	cmdOpts := commandOptions(ctx)
+	cmdOpts.Stdout = &out
+	if err := helpers.RunCommandWithOptions(cmdOpts, firewallCmd, args...); err != nil {
+		return nil, err
+	}
+	rules := []string{}
+	for _, line := range strings.Split(out.String(), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			rules = append(rules, line)
+		}
+	}
+	return rules, nil
+}
+
 // Reload reloads the firewalld configuration.
 func (c *Client) Reload(ctx context.Context) error {
 	opts := commandOptions(ctx)
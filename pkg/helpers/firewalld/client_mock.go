@@ -54,6 +54,24 @@ func (m *MockClient) RemovePortFromZone(ctx context.Context, port string, opts .
 	return args.Error(0)
 }
 
+func (m *MockClient) AddRichRuleToZone(ctx context.Context, rule string, opts ...Option) error {
+	args := m.Called(ctx, rule, opts)
+	return args.Error(0)
+}
+
+func (m *MockClient) RemoveRichRuleFromZone(ctx context.Context, rule string, opts ...Option) error {
+	args := m.Called(ctx, rule, opts)
+	return args.Error(0)
+}
+
+func (m *MockClient) ListRichRules(ctx context.Context, opts ...Option) ([]string, error) {
+	args := m.Called(ctx, opts)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
 func (m *MockClient) Reload(ctx context.Context) error {
 	args := m.Called(ctx)
 	return args.Error(0)
@@ -33,6 +33,12 @@ type Interface interface {
 	AddPortToZone(ctx context.Context, port string, opts ...Option) error
 	// RemovePortFromZone removes a port from a zone.
 	RemovePortFromZone(ctx context.Context, port string, opts ...Option) error
+	// AddRichRuleToZone adds a rich rule to a zone.
+	AddRichRuleToZone(ctx context.Context, rule string, opts ...Option) error
+	// RemoveRichRuleFromZone removes a rich rule from a zone.
+	RemoveRichRuleFromZone(ctx context.Context, rule string, opts ...Option) error
+	// ListRichRules lists the rich rules of a zone.
+	ListRichRules(ctx context.Context, opts ...Option) ([]string, error)
 	// Reload reloads the firewalld configuration.
 	Reload(ctx context.Context) error
 }
@@ -77,6 +83,21 @@ func RemovePortFromZone(ctx context.Context, port string, opts ...Option) error
 	return _f.RemovePortFromZone(ctx, port, opts...)
 }
 
+// AddRichRuleToZone adds a rich rule to a zone.
+func AddRichRuleToZone(ctx context.Context, rule string, opts ...Option) error {
+	return _f.AddRichRuleToZone(ctx, rule, opts...)
+}
+
+// RemoveRichRuleFromZone removes a rich rule from a zone.
+func RemoveRichRuleFromZone(ctx context.Context, rule string, opts ...Option) error {
+	return _f.RemoveRichRuleFromZone(ctx, rule, opts...)
+}
+
+// ListRichRules lists the rich rules of a zone.
+func ListRichRules(ctx context.Context, opts ...Option) ([]string, error) {
+	return _f.ListRichRules(ctx, opts...)
+}
+
 // Reload reloads the firewalld configuration.
 func Reload(ctx context.Context) error {
 	return _f.Reload(ctx)
@@ -0,0 +1,117 @@
+package kubeutils
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// protectedNamespaces are never deleted when an addon is uninstalled as they hold workloads
+// not owned by any addon.
+var protectedNamespaces = map[string]bool{
+	"default":         true,
+	"kube-system":     true,
+	"kube-public":     true,
+	"kube-node-lease": true,
+
+	runtimeconfig.KotsadmNamespace:         true,
+	runtimeconfig.EmbeddedClusterNamespace: true,
+	runtimeconfig.LonghornNamespace:        true,
+	"openebs":                              true,
+}
+
+// UninstallReleaseAndNamespace uninstalls the helm release, if it exists, and then deletes its
+// namespace as DeleteNamespace does. This is how addons owning their namespace are removed.
+func UninstallReleaseAndNamespace(ctx context.Context, kcli client.Client, hcli helm.Client, releaseName, ns string) error {
+	err := hcli.Uninstall(ctx, helm.UninstallOptions{
+		ReleaseName:    releaseName,
+		Namespace:      ns,
+		Wait:           true,
+		IgnoreNotFound: true,
+	})
+	if err != nil {
+		return fmt.Errorf("helm uninstall: %w", err)
+	}
+	if err := DeleteNamespace(ctx, kcli, ns, nil); err != nil {
+		return fmt.Errorf("delete namespace: %w", err)
+	}
+	return nil
+}
+
+// DeleteNamespace deletes the namespace, and everything in it, and waits for it to be gone.
+// Protected namespaces are left untouched. Namespaces that do not exist are ignored.
+func DeleteNamespace(ctx context.Context, cli client.Client, ns string, opts *WaitOptions) error {
+	if protectedNamespaces[ns] {
+		return nil
+	}
+
+	namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: ns}}
+	if err := cli.Delete(ctx, namespace); err != nil {
+		if k8serrors.IsNotFound(err) {
+			return nil
+		}
+		return fmt.Errorf("delete namespace %s: %w", ns, err)
+	}
+
+	if err := wait.ExponentialBackoffWithContext(ctx, opts.GetBackoff(), func(ctx context.Context) (bool, error) {
+		err := cli.Get(ctx, client.ObjectKey{Name: ns}, namespace)
+		if k8serrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, nil
+	}); err != nil {
+		return fmt.Errorf("timed out waiting for namespace %s to be deleted", ns)
+	}
+	return nil
+}
+
+// DeleteNamespaceIfUnused deletes the namespace, as DeleteNamespace does, only if no pods are
+// left running in it.
+func DeleteNamespaceIfUnused(ctx context.Context, cli client.Client, ns string, opts *WaitOptions) error {
+	var pods corev1.PodList
+	if err := cli.List(ctx, &pods, client.InNamespace(ns), client.Limit(1)); err != nil {
+		return fmt.Errorf("list pods in namespace %s: %w", ns, err)
+	}
+	if len(pods.Items) > 0 {
+		return nil
+	}
+	return DeleteNamespace(ctx, cli, ns, opts)
+}
+
+// DeletePVCs deletes the persistent volume claims matching the labels in the namespace.
+func DeletePVCs(ctx context.Context, cli client.Client, ns string, matchLabels map[string]string) error {
+	if len(matchLabels) == 0 {
+		return fmt.Errorf("refusing to delete all persistent volume claims in namespace %s", ns)
+	}
+	err := cli.DeleteAllOf(ctx, &corev1.PersistentVolumeClaim{}, client.InNamespace(ns), client.MatchingLabels(matchLabels))
+	if err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("delete persistent volume claims in namespace %s: %w", ns, err)
+	}
+	return nil
+}
+
+// DeleteCRDsForGroup deletes the custom resource definitions, and with them all the custom
+// resources, of the api group.
+func DeleteCRDsForGroup(ctx context.Context, cli client.Client, group string) error {
+	var crds apiextensionsv1.CustomResourceDefinitionList
+	if err := cli.List(ctx, &crds); err != nil {
+		return fmt.Errorf("list custom resource definitions: %w", err)
+	}
+	for _, crd := range crds.Items {
+		if crd.Spec.Group != group {
+			continue
+		}
+		if err := cli.Delete(ctx, &crd); err != nil && !k8serrors.IsNotFound(err) {
+			return fmt.Errorf("delete custom resource definition %s: %w", crd.Name, err)
+		}
+	}
+	return nil
+}
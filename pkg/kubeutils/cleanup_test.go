@@ -0,0 +1,84 @@
+package kubeutils
+
+import (
+	"context"
+	"testing"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/helm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestAddonCleanup(t *testing.T) {
+	ctx := context.Background()
+	cli := fake.NewClientBuilder().
+		WithScheme(Scheme).
+		WithObjects(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kotsadm"}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "velero"}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "monitoring"}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "shared"}},
+			&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "shared"}},
+			&corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{
+				Name: "data", Namespace: "shared", Labels: map[string]string{"app.kubernetes.io/instance": "addon"},
+			}},
+			&corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "shared"}},
+			&apiextensionsv1.CustomResourceDefinition{
+				ObjectMeta: metav1.ObjectMeta{Name: "backups.velero.io"},
+				Spec:       apiextensionsv1.CustomResourceDefinitionSpec{Group: "velero.io"},
+			},
+			&apiextensionsv1.CustomResourceDefinition{
+				ObjectMeta: metav1.ObjectMeta{Name: "installations.embeddedcluster.replicated.com"},
+				Spec:       apiextensionsv1.CustomResourceDefinitionSpec{Group: "embeddedcluster.replicated.com"},
+			},
+		).
+		Build()
+
+	exists := func(obj client.Object, name, namespace string) bool {
+		err := cli.Get(ctx, client.ObjectKey{Name: name, Namespace: namespace}, obj)
+		if k8serrors.IsNotFound(err) {
+			return false
+		}
+		require.NoError(t, err)
+		return true
+	}
+
+	// protected and missing namespaces are left alone.
+	require.NoError(t, DeleteNamespace(ctx, cli, "kotsadm", nil))
+	assert.True(t, exists(&corev1.Namespace{}, "kotsadm", ""))
+	require.NoError(t, DeleteNamespace(ctx, cli, "missing", nil))
+
+	require.NoError(t, DeleteNamespace(ctx, cli, "velero", nil))
+	assert.False(t, exists(&corev1.Namespace{}, "velero", ""))
+
+	hcli := &helm.MockClient{}
+	hcli.On("Uninstall", mock.Anything, helm.UninstallOptions{
+		ReleaseName:    "prometheus",
+		Namespace:      "monitoring",
+		Wait:           true,
+		IgnoreNotFound: true,
+	}).Return(nil).Once()
+	require.NoError(t, UninstallReleaseAndNamespace(ctx, cli, hcli, "prometheus", "monitoring"))
+	assert.False(t, exists(&corev1.Namespace{}, "monitoring", ""))
+	hcli.AssertExpectations(t)
+
+	// namespaces with pods left running are kept.
+	require.NoError(t, DeleteNamespaceIfUnused(ctx, cli, "shared", nil))
+	assert.True(t, exists(&corev1.Namespace{}, "shared", ""))
+
+	require.NoError(t, DeletePVCs(ctx, cli, "shared", map[string]string{"app.kubernetes.io/instance": "addon"}))
+	assert.False(t, exists(&corev1.PersistentVolumeClaim{}, "data", "shared"))
+	assert.True(t, exists(&corev1.PersistentVolumeClaim{}, "other", "shared"))
+	assert.Error(t, DeletePVCs(ctx, cli, "shared", nil))
+
+	require.NoError(t, DeleteCRDsForGroup(ctx, cli, "velero.io"))
+	assert.False(t, exists(&apiextensionsv1.CustomResourceDefinition{}, "backups.velero.io", ""))
+	assert.True(t, exists(&apiextensionsv1.CustomResourceDefinition{}, "installations.embeddedcluster.replicated.com", ""))
+}
@@ -4,10 +4,13 @@ import (
 	"fmt"
 
 	autopilotv1beta2 "github.com/k0sproject/k0s/pkg/apis/autopilot/v1beta2"
+	etcdv1beta1 "github.com/k0sproject/k0s/pkg/apis/etcd/v1beta1"
 	k0shelmv1beta1 "github.com/k0sproject/k0s/pkg/apis/helm/v1beta1"
 	k0sv1beta1 "github.com/k0sproject/k0s/pkg/apis/k0s/v1beta1"
 	embeddedclusterv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	embeddedclusterv1beta2 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta2"
 	velerov1 "github.com/vmware-tanzu/velero/pkg/apis/velero/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/cli-runtime/pkg/genericclioptions"
 	"k8s.io/client-go/kubernetes/scheme"
@@ -21,10 +24,13 @@ var ParameterCodec = scheme.ParameterCodec
 
 func init() {
 	utilruntime.Must(embeddedclusterv1beta1.AddToScheme(Scheme))
+	utilruntime.Must(embeddedclusterv1beta2.AddToScheme(Scheme))
 	utilruntime.Must(autopilotv1beta2.AddToScheme(Scheme))
+	utilruntime.Must(etcdv1beta1.AddToScheme(Scheme))
 	utilruntime.Must(k0sv1beta1.AddToScheme(Scheme))
 	utilruntime.Must(k0shelmv1beta1.AddToScheme(Scheme))
 	utilruntime.Must(velerov1.AddToScheme(Scheme))
+	utilruntime.Must(apiextensionsv1.AddToScheme(Scheme))
 }
 
 // KubeClient returns a new kubernetes client.
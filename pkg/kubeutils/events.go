@@ -0,0 +1,88 @@
+package kubeutils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// EventSourceComponent is the component reported as the source of the events we record on
+// the installation objects.
+const EventSourceComponent = "embedded-cluster"
+
+// RecordInstallationEvent creates a Kubernetes Event for the provided installation. Events are
+// created directly through the client instead of an event broadcaster so they are not lost when
+// short lived processes (e.g. the upgrade job) exit. Failing to record an event is logged and
+// otherwise ignored as events are informational only.
+func RecordInstallationEvent(ctx context.Context, cli client.Client, in *ecv1beta1.Installation, eventType, reason, message string) {
+	now := metav1.NewTime(time.Now())
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			// installations are cluster scoped so, as the client-go event recorder does, we
+			// store their events in the default namespace.
+			Name:      fmt.Sprintf("%s.%x", in.Name, now.UnixNano()),
+			Namespace: metav1.NamespaceDefault,
+		},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion:      ecv1beta1.GroupVersion.String(),
+			Kind:            "Installation",
+			Name:            in.Name,
+			UID:             in.UID,
+			ResourceVersion: in.ResourceVersion,
+		},
+		Reason:         reason,
+		Message:        message,
+		Type:           eventType,
+		Source:         corev1.EventSource{Component: EventSourceComponent},
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Count:          1,
+	}
+	if err := cli.Create(ctx, event); err != nil {
+		log := ctrl.LoggerFrom(ctx)
+		log.Error(err, "Failed to record installation event", "installation", in.Name, "reason", reason)
+	}
+}
+
+// installationStateEventType returns the event type for a transition into the provided state.
+func installationStateEventType(state string) string {
+	switch state {
+	case ecv1beta1.InstallationStateFailed, ecv1beta1.InstallationStateHelmChartUpdateFailure:
+		return corev1.EventTypeWarning
+	default:
+		return corev1.EventTypeNormal
+	}
+}
+
+// installationConditionEventType returns the event type for a condition change. Conditions
+// whose reason indicates a failure are reported as warnings.
+func installationConditionEventType(condition metav1.Condition) string {
+	if strings.HasSuffix(condition.Reason, "Failed") {
+		return corev1.EventTypeWarning
+	}
+	return corev1.EventTypeNormal
+}
+
+// installationStateEventMessage returns the message for a transition into the provided state.
+func installationStateEventMessage(state, reason string) string {
+	if reason == "" {
+		return fmt.Sprintf("Installation state changed to %s", state)
+	}
+	return fmt.Sprintf("Installation state changed to %s: %s", state, reason)
+}
+
+// installationConditionEventMessage returns the message for a condition change.
+func installationConditionEventMessage(condition metav1.Condition) string {
+	msg := fmt.Sprintf("Condition %s changed to %s", condition.Type, condition.Status)
+	if condition.Message != "" {
+		msg = fmt.Sprintf("%s: %s", msg, condition.Message)
+	}
+	return msg
+}
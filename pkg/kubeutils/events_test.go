@@ -0,0 +1,50 @@
+package kubeutils
+
+import (
+	"context"
+	"testing"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestInstallationEvents(t *testing.T) {
+	ctx := context.Background()
+	in := &ecv1beta1.Installation{ObjectMeta: metav1.ObjectMeta{Name: "20241002205018"}}
+	cli := fake.NewClientBuilder().
+		WithScheme(Scheme).
+		WithObjects(in).
+		WithStatusSubresource(in).
+		Build()
+
+	require.NoError(t, SetInstallationState(ctx, cli, in, ecv1beta1.InstallationStateInstalling, "Upgrading Kubernetes"))
+	// setting the same state and reason again must not record a new event.
+	require.NoError(t, SetInstallationState(ctx, cli, in, ecv1beta1.InstallationStateInstalling, "Upgrading Kubernetes"))
+	require.NoError(t, SetInstallationState(ctx, cli, in, ecv1beta1.InstallationStateFailed, "something went wrong"))
+
+	require.NoError(t, SetInstallationConditionStatus(ctx, cli, in, metav1.Condition{
+		Type: "openebs-openebs", Status: metav1.ConditionFalse, Reason: "UpgradeFailed", Message: "timed out",
+	}))
+
+	var events corev1.EventList
+	require.NoError(t, cli.List(ctx, &events))
+	require.Len(t, events.Items, 3)
+
+	byReason := map[string]corev1.Event{}
+	for _, ev := range events.Items {
+		assert.Equal(t, "Installation", ev.InvolvedObject.Kind)
+		assert.Equal(t, in.Name, ev.InvolvedObject.Name)
+		assert.Equal(t, metav1.NamespaceDefault, ev.Namespace)
+		byReason[ev.Reason] = ev
+	}
+
+	assert.Equal(t, corev1.EventTypeNormal, byReason[ecv1beta1.InstallationStateInstalling].Type)
+	assert.Equal(t, "Installation state changed to Installing: Upgrading Kubernetes", byReason[ecv1beta1.InstallationStateInstalling].Message)
+	assert.Equal(t, corev1.EventTypeWarning, byReason[ecv1beta1.InstallationStateFailed].Type)
+	assert.Equal(t, corev1.EventTypeWarning, byReason["UpgradeFailed"].Type)
+	assert.Equal(t, "Condition openebs-openebs changed to False: timed out", byReason["UpgradeFailed"].Message)
+}
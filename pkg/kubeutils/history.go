@@ -0,0 +1,168 @@
+package kubeutils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// InstallationHistoryConfigMap is the name of the config map holding the history of the
+	// installation objects that have been deleted.
+	InstallationHistoryConfigMap = "embedded-cluster-installation-history"
+	installationHistoryKey       = "history.json"
+	// maxInstallationHistoryEntries caps the number of entries kept in the config map so it
+	// never reaches the object size limit.
+	maxInstallationHistoryEntries = 500
+)
+
+// InstallationHistoryEntry is a compact record of an installation object.
+type InstallationHistoryEntry struct {
+	Name      string       `json:"name"`
+	Version   string       `json:"version,omitempty"`
+	StartedAt metav1.Time  `json:"startedAt"`
+	EndedAt   *metav1.Time `json:"endedAt,omitempty"`
+	Result    string       `json:"result"`
+	Reason    string       `json:"reason,omitempty"`
+}
+
+// NewInstallationHistoryEntry returns the history entry for the provided installation. For
+// obsolete installations the result is the state the installation was in when it got
+// superseded. As installations do not record when they finished, the end time is the last time
+// one of their conditions changed.
+func NewInstallationHistoryEntry(in ecv1beta1.Installation) InstallationHistoryEntry {
+	entry := InstallationHistoryEntry{
+		Name:      in.Name,
+		StartedAt: in.CreationTimestamp,
+		Result:    in.Status.State,
+		Reason:    in.Status.Reason,
+	}
+	if in.Spec.Config != nil {
+		entry.Version = in.Spec.Config.Version
+	}
+	if in.Status.State == ecv1beta1.InstallationStateObsolete {
+		entry.Result = ecv1beta1.InstallationStateUnknown
+		entry.Reason = ""
+		if state, ok := in.Annotations[ecv1beta1.InstallationObsoletedStateAnnotation]; ok && state != "" {
+			entry.Result = state
+			entry.Reason = in.Annotations[ecv1beta1.InstallationObsoletedReasonAnnotation]
+		}
+	}
+	for _, cond := range in.Status.Conditions {
+		if entry.EndedAt == nil || cond.LastTransitionTime.After(entry.EndedAt.Time) {
+			entry.EndedAt = cond.LastTransitionTime.DeepCopy()
+		}
+	}
+	return entry
+}
+
+// RecordInstallationHistory adds the entries to the installation history config map.
+func RecordInstallationHistory(ctx context.Context, cli client.Client, entries ...InstallationHistoryEntry) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		cm, history, err := getInstallationHistory(ctx, cli)
+		if err != nil {
+			return err
+		}
+
+		history = mergeInstallationHistory(history, entries)
+		if len(history) > maxInstallationHistoryEntries {
+			history = history[:maxInstallationHistoryEntries]
+		}
+
+		data, err := json.Marshal(history)
+		if err != nil {
+			return fmt.Errorf("marshal installation history: %w", err)
+		}
+
+		if cm == nil {
+			cm = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      InstallationHistoryConfigMap,
+					Namespace: runtimeconfig.EmbeddedClusterNamespace,
+				},
+				Data: map[string]string{installationHistoryKey: string(data)},
+			}
+			if err := cli.Create(ctx, cm); err != nil {
+				return fmt.Errorf("create installation history: %w", err)
+			}
+			return nil
+		}
+
+		if cm.Data == nil {
+			cm.Data = map[string]string{}
+		}
+		cm.Data[installationHistoryKey] = string(data)
+		if err := cli.Update(ctx, cm); err != nil {
+			return fmt.Errorf("update installation history: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetInstallationHistory returns the history of all the installations, the ones still present
+// in the cluster and the ones that have been deleted, newest first.
+func GetInstallationHistory(ctx context.Context, cli client.Client) ([]InstallationHistoryEntry, error) {
+	_, history, err := getInstallationHistory(ctx, cli)
+	if err != nil {
+		return nil, err
+	}
+
+	installs, err := ListInstallations(ctx, cli)
+	if err != nil {
+		return nil, fmt.Errorf("list installations: %w", err)
+	}
+	current := []InstallationHistoryEntry{}
+	for _, in := range installs {
+		current = append(current, NewInstallationHistoryEntry(in))
+	}
+
+	return mergeInstallationHistory(history, current), nil
+}
+
+func getInstallationHistory(ctx context.Context, cli client.Client) (*corev1.ConfigMap, []InstallationHistoryEntry, error) {
+	var cm corev1.ConfigMap
+	nsn := client.ObjectKey{Namespace: runtimeconfig.EmbeddedClusterNamespace, Name: InstallationHistoryConfigMap}
+	if err := cli.Get(ctx, nsn, &cm); k8serrors.IsNotFound(err) {
+		return nil, nil, nil
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("get installation history: %w", err)
+	}
+
+	var history []InstallationHistoryEntry
+	if data := cm.Data[installationHistoryKey]; data != "" {
+		if err := json.Unmarshal([]byte(data), &history); err != nil {
+			return nil, nil, fmt.Errorf("unmarshal installation history: %w", err)
+		}
+	}
+	return &cm, history, nil
+}
+
+// mergeInstallationHistory merges the entries into the history, replacing the ones with the
+// same name. The result is sorted by name, newest first.
+func mergeInstallationHistory(history, entries []InstallationHistoryEntry) []InstallationHistoryEntry {
+	byName := map[string]InstallationHistoryEntry{}
+	for _, entry := range history {
+		byName[entry.Name] = entry
+	}
+	for _, entry := range entries {
+		byName[entry.Name] = entry
+	}
+
+	result := make([]InstallationHistoryEntry, 0, len(byName))
+	for _, entry := range byName {
+		result = append(result, entry)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[j].Name < result[i].Name
+	})
+	return result
+}
@@ -0,0 +1,76 @@
+package kubeutils
+
+import (
+	"testing"
+	"time"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNewInstallationHistoryEntry(t *testing.T) {
+	started := metav1.NewTime(time.Date(2024, 10, 2, 20, 50, 18, 0, time.UTC))
+	ended := metav1.NewTime(started.Add(10 * time.Minute))
+
+	tests := []struct {
+		name string
+		in   ecv1beta1.Installation
+		want InstallationHistoryEntry
+	}{
+		{
+			name: "current installation",
+			in: ecv1beta1.Installation{
+				ObjectMeta: metav1.ObjectMeta{Name: "20241002205018", CreationTimestamp: started},
+				Spec:       ecv1beta1.InstallationSpec{Config: &ecv1beta1.ConfigSpec{Version: "1.2.0"}},
+				Status: ecv1beta1.InstallationStatus{
+					State: ecv1beta1.InstallationStateInstalled,
+					Conditions: []metav1.Condition{
+						{Type: "openebs-openebs", LastTransitionTime: started},
+						{Type: "admin-console", LastTransitionTime: ended},
+					},
+				},
+			},
+			want: InstallationHistoryEntry{
+				Name: "20241002205018", Version: "1.2.0", StartedAt: started, EndedAt: &ended,
+				Result: ecv1beta1.InstallationStateInstalled,
+			},
+		},
+		{
+			name: "obsolete failed installation",
+			in: ecv1beta1.Installation{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "20241002205018",
+					CreationTimestamp: started,
+					Annotations: map[string]string{
+						ecv1beta1.InstallationObsoletedStateAnnotation:  ecv1beta1.InstallationStateFailed,
+						ecv1beta1.InstallationObsoletedReasonAnnotation: "autopilot plan failed",
+					},
+				},
+				Status: ecv1beta1.InstallationStatus{
+					State:  ecv1beta1.InstallationStateObsolete,
+					Reason: "This is not the most recent installation object",
+				},
+			},
+			want: InstallationHistoryEntry{
+				Name: "20241002205018", StartedAt: started,
+				Result: ecv1beta1.InstallationStateFailed, Reason: "autopilot plan failed",
+			},
+		},
+		{
+			name: "obsolete installation without annotations",
+			in: ecv1beta1.Installation{
+				ObjectMeta: metav1.ObjectMeta{Name: "20241002205018", CreationTimestamp: started},
+				Status:     ecv1beta1.InstallationStatus{State: ecv1beta1.InstallationStateObsolete},
+			},
+			want: InstallationHistoryEntry{
+				Name: "20241002205018", StartedAt: started, Result: ecv1beta1.InstallationStateUnknown,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NewInstallationHistoryEntry(tt.in))
+		})
+	}
+}
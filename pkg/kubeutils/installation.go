@@ -150,10 +150,21 @@ func UpdateInstallationStatus(ctx context.Context, cli client.Client, in *ecv1be
 	})
 }
 
+// SetInstallationState sets the installation state and reason. An event is recorded on the
+// installation every time its state or reason change.
 func SetInstallationState(ctx context.Context, cli client.Client, in *ecv1beta1.Installation, state string, reason string, pendingCharts ...string) error {
-	return UpdateInstallationStatus(ctx, cli, in, func(status *ecv1beta1.InstallationStatus) {
+	var changed bool
+	err := UpdateInstallationStatus(ctx, cli, in, func(status *ecv1beta1.InstallationStatus) {
+		changed = status.State != state || status.Reason != reason
 		status.SetState(state, reason, pendingCharts)
 	})
+	if err != nil {
+		return err
+	}
+	if changed {
+		RecordInstallationEvent(ctx, cli, in, installationStateEventType(state), state, installationStateEventMessage(state, reason))
+	}
+	return nil
 }
 
 func ListInstallations(ctx context.Context, cli client.Client) ([]ecv1beta1.Installation, error) {
@@ -292,10 +303,58 @@ func MaybeOverrideInstallationDataDirs(in ecv1beta1.Installation, previous *ecv1
 	return in, false, nil
 }
 
+// SetInstallationConditionStatus sets a condition in the installation status. An event is
+// recorded on the installation every time the condition changes.
 func SetInstallationConditionStatus(ctx context.Context, cli client.Client, in *ecv1beta1.Installation, condition metav1.Condition) error {
-	return UpdateInstallationStatus(ctx, cli, in, func(status *ecv1beta1.InstallationStatus) {
-		status.SetCondition(condition)
+	var changed bool
+	err := UpdateInstallationStatus(ctx, cli, in, func(status *ecv1beta1.InstallationStatus) {
+		changed = status.SetCondition(condition)
+	})
+	if err != nil {
+		return err
+	}
+	if changed {
+		RecordInstallationEvent(ctx, cli, in, installationConditionEventType(condition), condition.Reason, installationConditionEventMessage(condition))
+	}
+	return nil
+}
+
+// SetInstallationAddonStatus sets the status of an addon in the installation. The condition
+// tracking the addon is updated as well and an event is recorded every time it changes.
+func SetInstallationAddonStatus(ctx context.Context, cli client.Client, in *ecv1beta1.Installation, addon ecv1beta1.ComponentStatus) error {
+	var changed bool
+	err := UpdateInstallationStatus(ctx, cli, in, func(status *ecv1beta1.InstallationStatus) {
+		changed = status.SetAddonStatus(addon)
+	})
+	if err != nil {
+		return err
+	}
+	if changed {
+		recordComponentStatusEvent(ctx, cli, in, addon)
+	}
+	return nil
+}
+
+// SetInstallationExtensionStatus sets the status of an extension in the installation. The
+// condition tracking the extension is updated as well and an event is recorded every time it
+// changes.
+func SetInstallationExtensionStatus(ctx context.Context, cli client.Client, in *ecv1beta1.Installation, ext ecv1beta1.ComponentStatus) error {
+	var changed bool
+	err := UpdateInstallationStatus(ctx, cli, in, func(status *ecv1beta1.InstallationStatus) {
+		changed = status.SetExtensionStatus(ext)
 	})
+	if err != nil {
+		return err
+	}
+	if changed {
+		recordComponentStatusEvent(ctx, cli, in, ext)
+	}
+	return nil
+}
+
+func recordComponentStatusEvent(ctx context.Context, cli client.Client, in *ecv1beta1.Installation, component ecv1beta1.ComponentStatus) {
+	condition := component.Condition()
+	RecordInstallationEvent(ctx, cli, in, installationConditionEventType(condition), condition.Reason, installationConditionEventMessage(condition))
 }
 
 func CheckInstallationConditionStatus(inStat ecv1beta1.InstallationStatus, conditionName string) metav1.ConditionStatus {
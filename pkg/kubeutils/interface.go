@@ -34,7 +34,7 @@ type KubeUtilsInterface interface {
 	IsStatefulSetReady(ctx context.Context, cli client.Client, ns, name string) (bool, error)
 	IsDaemonsetReady(ctx context.Context, cli client.Client, ns, name string) (bool, error)
 	IsJobComplete(ctx context.Context, cli client.Client, ns, name string, completions int32) (bool, error)
-	WaitForKubernetes(ctx context.Context, cli client.Client) <-chan error
+	WaitForKubernetes(ctx context.Context, cli client.Client, cniProvider string) <-chan error
 	WaitForCRDToBeReady(ctx context.Context, cli client.Client, name string) error
 	KubeClient() (client.Client, error)
 }
@@ -110,8 +110,8 @@ func IsJobComplete(ctx context.Context, cli client.Client, ns, name string, comp
 	return kb.IsJobComplete(ctx, cli, ns, name, completions)
 }
 
-func WaitForKubernetes(ctx context.Context, cli client.Client) <-chan error {
-	return kb.WaitForKubernetes(ctx, cli)
+func WaitForKubernetes(ctx context.Context, cli client.Client, cniProvider string) <-chan error {
+	return kb.WaitForKubernetes(ctx, cli, cniProvider)
 }
 
 func WaitForCRDToBeReady(ctx context.Context, cli client.Client, name string) error {
@@ -6,6 +6,7 @@ import (
 	"sync"
 	"time"
 
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
 	appsv1 "k8s.io/api/apps/v1"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -305,8 +306,15 @@ func (k *KubeUtils) IsPodComplete(ctx context.Context, cli client.Client, ns, na
 }
 
 // WaitForKubernetes waits for all deployments to be ready in kube-system, and returns an error channel.
-// if either of them fails to become healthy, an error is returned via the channel.
-func (k *KubeUtils) WaitForKubernetes(ctx context.Context, cli client.Client) <-chan error {
+// if either of them fails to become healthy, an error is returned via the channel. The deployments
+// expected depend on the cni provider.
+func (k *KubeUtils) WaitForKubernetes(ctx context.Context, cli client.Client, cniProvider string) <-chan error {
+	// coredns and metrics-server, and calico-kube-controllers when k0s deploys calico.
+	expected := 2
+	if cniProvider == "" || cniProvider == ecv1beta1.CNIProviderCalico {
+		expected = 3
+	}
+
 	errch := make(chan error, 1)
 
 	// wait until there is at least one deployment in kube-system
@@ -317,7 +325,7 @@ func (k *KubeUtils) WaitForKubernetes(ctx context.Context, cli client.Client) <-
 			if err := cli.List(ctx, &deps, client.InNamespace("kube-system")); err != nil {
 				return false, nil
 			}
-			return len(deps.Items) >= 3, nil // coredns, metrics-server, and calico-kube-controllers
+			return len(deps.Items) >= expected, nil
 		}); err != nil {
 		errch <- fmt.Errorf("timed out waiting for deployments in kube-system: %w", err)
 		close(errch)
@@ -41,6 +41,25 @@ func FirstValidIPNet(networkInterface string) (*net.IPNet, error) {
 	return nil, fmt.Errorf("interface %s not found or is not valid. The following interfaces were detected: %s", networkInterface, strings.Join(ifNames, ", "))
 }
 
+// IPNetForAddress returns the address, along with the mask of its network, as configured in the
+// interface holding it.
+func IPNetForAddress(address string) (*net.IPNet, error) {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid address %s", address)
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, fmt.Errorf("list interface addresses: %w", err)
+	}
+	for _, a := range addrs {
+		if ipnet, ok := a.(*net.IPNet); ok && ipnet.IP.Equal(ip) {
+			return ipnet, nil
+		}
+	}
+	return nil, fmt.Errorf("no interface holds address %s", address)
+}
+
 // listValidInterfaces returns a list of valid network interfaces for the node.
 func listValidInterfaces() ([]net.Interface, error) {
 	ifs, err := net.Interfaces()
@@ -69,6 +88,10 @@ func isValidInterface(i net.Interface) bool {
 		return false
 	case strings.HasPrefix(i.Name, "cali"):
 		return false
+	case strings.HasPrefix(i.Name, "cilium_"):
+		return false
+	case strings.HasPrefix(i.Name, "lxc"):
+		return false
 	}
 	return hasValidIPNet(i)
 }
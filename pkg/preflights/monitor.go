@@ -0,0 +1,93 @@
+package preflights
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/replicatedhq/troubleshoot/pkg/apis/troubleshoot/v1beta2"
+	"github.com/replicatedhq/troubleshoot/pkg/loader"
+)
+
+//go:embed host-preflight-monitor.yaml
+var hostPreflightMonitorYAML string
+
+// DefaultMonitorChecks are the checks periodically run on the nodes when none is configured.
+// The filesystem write latency check is left out as it writes to the disk used by etcd.
+var DefaultMonitorChecks = []string{
+	ecv1beta1.HostPreflightCheckDiskSpace,
+	ecv1beta1.HostPreflightCheckClock,
+	ecv1beta1.HostPreflightCheckCgroups,
+	ecv1beta1.HostPreflightCheckKernelModules,
+}
+
+// MonitorChecks are all the checks that can be periodically run on the nodes.
+var MonitorChecks = []string{
+	ecv1beta1.HostPreflightCheckDiskSpace,
+	ecv1beta1.HostPreflightCheckFilesystemWriteLatency,
+	ecv1beta1.HostPreflightCheckClock,
+	ecv1beta1.HostPreflightCheckCgroups,
+	ecv1beta1.HostPreflightCheckKernelModules,
+}
+
+// MonitorOptions holds the options used to render the host preflights periodically run on a
+// node after the installation.
+type MonitorOptions struct {
+	DataDir    string
+	K0sDataDir string
+	// Checks are the checks to run. If empty DefaultMonitorChecks are run.
+	Checks []string
+	// IsController indicates if the spec is for a controller node. Some checks only apply to
+	// controllers.
+	IsController bool
+}
+
+type monitorTemplateData struct {
+	DataDir           string
+	K0sDataDir        string
+	IsController      bool
+	Checks            map[string]bool
+	CgroupControllers []string
+	KernelModules     []string
+}
+
+// GetHostPreflightMonitorSpec returns the host preflight spec periodically run on the nodes.
+// It is a subset of the host preflights run during the installation.
+func GetHostPreflightMonitorSpec(ctx context.Context, opts MonitorOptions) (*v1beta2.HostPreflightSpec, error) {
+	checks := opts.Checks
+	if len(checks) == 0 {
+		checks = DefaultMonitorChecks
+	}
+
+	data := monitorTemplateData{
+		DataDir:           opts.DataDir,
+		K0sDataDir:        opts.K0sDataDir,
+		IsController:      opts.IsController,
+		Checks:            map[string]bool{},
+		CgroupControllers: []string{"cpu", "cpuacct", "cpuset", "memory", "devices", "freezer", "pids"},
+		KernelModules:     []string{"overlay", "ip_tables", "br_netfilter", "nf_conntrack"},
+	}
+	for _, check := range checks {
+		data.Checks[check] = true
+	}
+
+	spec, err := renderTemplate(hostPreflightMonitorYAML, data)
+	if err != nil {
+		return nil, fmt.Errorf("render host preflight monitor template: %w", err)
+	}
+	kinds, err := loader.LoadSpecs(ctx, loader.LoadOptions{
+		RawSpecs: []string{spec},
+		Strict:   true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("load host preflight monitor spec: %w", err)
+	}
+
+	hpf := &v1beta2.HostPreflightSpec{}
+	for _, h := range kinds.HostPreflightsV1Beta2 {
+		hpf.Collectors = append(hpf.Collectors, h.Spec.Collectors...)
+		hpf.Analyzers = append(hpf.Analyzers, h.Spec.Analyzers...)
+	}
+	return hpf, nil
+}
@@ -0,0 +1,85 @@
+package preflights
+
+import (
+	"context"
+	"testing"
+
+	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetHostPreflightMonitorSpec(t *testing.T) {
+	tests := []struct {
+		name           string
+		opts           MonitorOptions
+		wantCollectors int
+		wantAnalyzers  []string
+	}{
+		{
+			name: "default checks",
+			opts: MonitorOptions{DataDir: "/var/lib/embedded-cluster", IsController: true},
+			// disk usage, time, cgroups and kernel modules.
+			wantCollectors: 4,
+			wantAnalyzers: []string{
+				"Embedded Cluster Disk Space",
+				"System Clock",
+				"Cgroups",
+				"'cpu' Cgroup Controller",
+				"'cpuacct' Cgroup Controller",
+				"'cpuset' Cgroup Controller",
+				"'memory' Cgroup Controller",
+				"'devices' Cgroup Controller",
+				"'freezer' Cgroup Controller",
+				"'pids' Cgroup Controller",
+				"overlay kernel module",
+				"ip_tables kernel module",
+				"br_netfilter kernel module",
+				"nf_conntrack kernel module",
+			},
+		},
+		{
+			name: "filesystem write latency on a controller",
+			opts: MonitorOptions{
+				K0sDataDir:   "/var/lib/embedded-cluster/k0s",
+				Checks:       []string{ecv1beta1.HostPreflightCheckFilesystemWriteLatency, ecv1beta1.HostPreflightCheckClock},
+				IsController: true,
+			},
+			wantCollectors: 2,
+			wantAnalyzers:  []string{"System Clock", "Filesystem Write Latency"},
+		},
+		{
+			name: "filesystem write latency on a worker",
+			opts: MonitorOptions{
+				K0sDataDir: "/var/lib/embedded-cluster/k0s",
+				Checks:     []string{ecv1beta1.HostPreflightCheckFilesystemWriteLatency, ecv1beta1.HostPreflightCheckClock},
+			},
+			wantCollectors: 1,
+			wantAnalyzers:  []string{"System Clock"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, err := GetHostPreflightMonitorSpec(context.Background(), tt.opts)
+			require.NoError(t, err)
+			assert.Len(t, spec.Collectors, tt.wantCollectors)
+
+			var analyzers []string
+			for _, a := range spec.Analyzers {
+				switch {
+				case a.DiskUsage != nil:
+					analyzers = append(analyzers, a.DiskUsage.CheckName)
+				case a.Time != nil:
+					analyzers = append(analyzers, a.Time.CheckName)
+				case a.JsonCompare != nil:
+					analyzers = append(analyzers, a.JsonCompare.CheckName)
+				case a.KernelModules != nil:
+					analyzers = append(analyzers, a.KernelModules.CheckName)
+				case a.FilesystemPerformance != nil:
+					analyzers = append(analyzers, a.FilesystemPerformance.CheckName)
+				}
+			}
+			assert.Equal(t, tt.wantAnalyzers, analyzers)
+		})
+	}
+}
@@ -0,0 +1,317 @@
+package preflights
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/configutils"
+	"github.com/replicatedhq/embedded-cluster/pkg/helpers"
+	"github.com/replicatedhq/embedded-cluster/pkg/helpers/firewalld"
+	"github.com/replicatedhq/embedded-cluster/pkg/helpers/systemd"
+	"github.com/replicatedhq/embedded-cluster/pkg/preflights/types"
+	"github.com/replicatedhq/embedded-cluster/pkg/runtimeconfig"
+	"github.com/sirupsen/logrus"
+)
+
+// Remediation is an action fixing one or more failing host preflights.
+type Remediation struct {
+	// Checks are the titles of the host preflights fixed by the remediation.
+	Checks []string
+	// Description describes what the remediation does. It is shown to the user before the
+	// remediation is applied.
+	Description string
+
+	apply func(ctx context.Context) error
+}
+
+// RemediationOptions holds the installation settings the remediations need to match the host
+// configuration applied by the installer.
+type RemediationOptions struct {
+	NetworkInterface string
+	CNIProvider      string
+	IngressEnabled   bool
+}
+
+func remediationOptions(opts PrepareAndRunOptions) RemediationOptions {
+	return RemediationOptions{
+		NetworkInterface: opts.NetworkInterface,
+		CNIProvider:      opts.CNIProvider,
+		IngressEnabled:   opts.IngressEnabled,
+	}
+}
+
+// Apply applies the remediation.
+func (r Remediation) Apply(ctx context.Context) error {
+	return r.apply(ctx)
+}
+
+type sysctlRemediation struct {
+	key   string
+	value int64
+}
+
+// sysctlRemediations maps the sysctl host preflights to the value they expect.
+var sysctlRemediations = map[string]sysctlRemediation{
+	"ARP Filter default value for newly created interfaces":             {"net.ipv4.conf.default.arp_filter", 0},
+	"ARP Filter value for all interfaces":                               {"net.ipv4.conf.all.arp_filter", 0},
+	"ARP Ignore default value for newly created interfaces":             {"net.ipv4.conf.default.arp_ignore", 0},
+	"ARP Ignore value for all interfaces":                               {"net.ipv4.conf.all.arp_ignore", 0},
+	"Reverse Path Filtering default value for newly created interfaces": {"net.ipv4.conf.default.rp_filter", 2},
+	"Reverse Path Filtering value for all interfaces":                   {"net.ipv4.conf.all.rp_filter", 2},
+	"IP forwarding":                                            {"net.ipv4.ip_forward", 1},
+	"IP forwarding for all interfaces":                         {"net.ipv4.conf.all.forwarding", 1},
+	"IP forwarding default value for newly created interfaces": {"net.ipv4.conf.default.forwarding", 1},
+	"Bridge netfilter call iptables":                           {"net.bridge.bridge-nf-call-iptables", 1},
+	"Maximum number of inotify instances per user":             {"fs.inotify.max_user_instances", 1024},
+	"Maximum number of inotify watches per user":               {"fs.inotify.max_user_watches", 65536},
+}
+
+// kernelModuleRemediations maps the kernel module host preflights to the module they expect.
+var kernelModuleRemediations = map[string]string{
+	"Overlay kernel module":      "overlay",
+	"IP tables kernel module":    "ip_tables",
+	"BR Netfilter kernel module": "br_netfilter",
+	"NF Conntrack kernel module": "nf_conntrack",
+}
+
+const (
+	selinuxCheck    = "SELinux Mode"
+	portCheckSuffix = " Port Availability"
+
+	firewalldRemediation = "Open the ports required by the cluster in the firewalld default zone"
+)
+
+var (
+	portRegex   = regexp.MustCompile(`Port (\d+)/(TCP|UDP)`)
+	ssPIDRegex  = regexp.MustCompile(`pid=(\d+)`)
+	cgroupRegex = regexp.MustCompile(`/system\.slice/([^/\s]+\.service)`)
+)
+
+// procFSRoot is the root of the proc filesystem. This could have been a constant but we want to
+// be able to override it for testing purposes.
+var procFSRoot = "/proc"
+
+// PlanRemediations returns the remediations for the failing and warning host preflights in the
+// output. Host preflights without a known remediation are left for the user to fix.
+func PlanRemediations(ctx context.Context, output types.Output, opts RemediationOptions) []Remediation {
+	remediations := []Remediation{}
+	byDescription := map[string]int{}
+	add := func(check string, r Remediation) {
+		if i, ok := byDescription[r.Description]; ok {
+			remediations[i].Checks = append(remediations[i].Checks, check)
+			return
+		}
+		r.Checks = []string{check}
+		byDescription[r.Description] = len(remediations)
+		remediations = append(remediations, r)
+	}
+
+	for _, record := range failedAndWarned(output) {
+		r, ok := planRemediation(ctx, record, opts)
+		if !ok {
+			logrus.Debugf("no remediation known for host preflight %q", record.Title)
+			continue
+		}
+		add(record.Title, r)
+	}
+	return remediations
+}
+
+// RemediationHints returns a description of the fix for each failing and warning host preflight
+// with a known remediation, indexed by the host preflight title. Unlike PlanRemediations the host
+// is not inspected so the hints for the port checks do not name the unit using the port and do
+// not depend on firewalld being active.
+func RemediationHints(output types.Output, opts RemediationOptions) map[string]string {
+	hints := map[string]string{}
+	for _, record := range failedAndWarned(output) {
+		if strings.HasSuffix(record.Title, portCheckSuffix) {
+			if hint, ok := portRemediationHint(record, opts); ok {
+				hints[record.Title] = hint
+			}
+			continue
+		}
+		if r, ok := hostConfigRemediation(record); ok {
+			hints[record.Title] = r.Description
+		}
+	}
+	return hints
+}
+
+func failedAndWarned(output types.Output) []types.Record {
+	records := []types.Record{}
+	records = append(records, output.Fail...)
+	records = append(records, output.Warn...)
+	return records
+}
+
+func planRemediation(ctx context.Context, record types.Record, opts RemediationOptions) (Remediation, bool) {
+	if strings.HasSuffix(record.Title, portCheckSuffix) {
+		return planPortRemediation(ctx, record, opts)
+	}
+	return hostConfigRemediation(record)
+}
+
+// hostConfigRemediation returns the remediation setting the kernel parameter, loading the kernel
+// module or changing the SELinux mode the host preflight expects.
+func hostConfigRemediation(record types.Record) (Remediation, bool) {
+	if s, ok := sysctlRemediations[record.Title]; ok {
+		return Remediation{
+			Description: fmt.Sprintf("Set the %s kernel parameter to %d", s.key, s.value),
+			apply: func(ctx context.Context) error {
+				return configutils.SetSysctl(s.key, s.value)
+			},
+		}, true
+	}
+
+	if module, ok := kernelModuleRemediations[record.Title]; ok {
+		return Remediation{
+			Description: fmt.Sprintf("Load the %s kernel module", module),
+			apply: func(ctx context.Context) error {
+				return configutils.LoadKernelModule(module)
+			},
+		}, true
+	}
+
+	if record.Title == selinuxCheck {
+		return Remediation{
+			Description: "Set SELinux to permissive mode",
+			apply: func(ctx context.Context) error {
+				return configutils.SetSELinuxPermissive()
+			},
+		}, true
+	}
+
+	return Remediation{}, false
+}
+
+// planPortRemediation stops the systemd unit using the port if another process is using it, or
+// configures the firewalld default zone as the installer does if the connection to the port timed
+// out. Only the ports the installer opens are fixed this way, the local artifact mirror port is
+// opened to the node network only. Other failures, a refused connection for instance, are not
+// caused by the firewall and have no fix.
+func planPortRemediation(ctx context.Context, record types.Record, opts RemediationOptions) (Remediation, bool) {
+	port, protocol, ok := parsePortRecord(record)
+	if !ok {
+		return Remediation{}, false
+	}
+
+	if portInUse(record) {
+		unit, err := portOwnerUnit(port, protocol)
+		if err != nil {
+			logrus.Debugf("unable to find the unit using port %s/%s: %v", port, protocol, err)
+			return Remediation{}, false
+		}
+		return Remediation{
+			Description: fmt.Sprintf("Stop and disable the %s unit using port %s/%s", unit, port, strings.ToUpper(protocol)),
+			apply: func(ctx context.Context) error {
+				if err := systemd.Stop(ctx, unit); err != nil {
+					return fmt.Errorf("stop %s: %w", unit, err)
+				}
+				if err := systemd.Disable(ctx, unit); err != nil {
+					return fmt.Errorf("disable %s: %w", unit, err)
+				}
+				return nil
+			},
+		}, true
+	}
+
+	if !portBlocked(record, port, protocol, opts) {
+		return Remediation{}, false
+	}
+	if active, err := firewalld.IsFirewalldActive(ctx); err != nil || !active {
+		return Remediation{}, false
+	}
+	return Remediation{
+		Description: firewalldRemediation,
+		apply: func(ctx context.Context) error {
+			nodeNetwork, err := configutils.FirewalldNodeNetwork(opts.NetworkInterface)
+			if err != nil {
+				return fmt.Errorf("get node network: %w", err)
+			}
+			if err := configutils.EnsureFirewalldDefaultZone(ctx, nodeNetwork, opts.CNIProvider, opts.IngressEnabled); err != nil {
+				return fmt.Errorf("ensure default zone: %w", err)
+			}
+			if err := firewalld.Reload(ctx); err != nil {
+				return fmt.Errorf("reload firewalld: %w", err)
+			}
+			return nil
+		},
+	}, true
+}
+
+// portRemediationHint describes the fix planPortRemediation would plan for the record without
+// inspecting the host.
+func portRemediationHint(record types.Record, opts RemediationOptions) (string, bool) {
+	port, protocol, ok := parsePortRecord(record)
+	if !ok {
+		return "", false
+	}
+	if portInUse(record) {
+		return fmt.Sprintf("Stop and disable the systemd unit using port %s/%s", port, strings.ToUpper(protocol)), true
+	}
+	if portBlocked(record, port, protocol, opts) {
+		return firewalldRemediation, true
+	}
+	return "", false
+}
+
+// parsePortRecord returns the port and the lower case protocol the port check is about.
+func parsePortRecord(record types.Record) (string, string, bool) {
+	matches := portRegex.FindStringSubmatch(record.Message)
+	if matches == nil {
+		return "", "", false
+	}
+	return matches[1], strings.ToLower(matches[2]), true
+}
+
+func portInUse(record types.Record) bool {
+	return strings.Contains(record.Message, "another process is already using it")
+}
+
+// portBlocked returns true if the connection to the port timed out and the port is one the
+// installer opens in the firewall.
+func portBlocked(record types.Record, port, protocol string, opts RemediationOptions) bool {
+	if !strings.Contains(record.Message, "the connection timed out") {
+		return false
+	}
+	return firewalldOpensPort(fmt.Sprintf("%s/%s", port, protocol), opts)
+}
+
+// firewalldOpensPort returns true if the installer opens the port in the firewalld default zone.
+func firewalldOpensPort(port string, opts RemediationOptions) bool {
+	if port == fmt.Sprintf("%d/tcp", runtimeconfig.LocalArtifactMirrorPort()) {
+		return true
+	}
+	return slices.Contains(configutils.FirewalldDefaultZonePorts(opts.CNIProvider, opts.IngressEnabled), port)
+}
+
+// portOwnerUnit returns the systemd service of the process listening on the port.
+func portOwnerUnit(port, protocol string) (string, error) {
+	flag := "-t"
+	if protocol == "udp" {
+		flag = "-u"
+	}
+	out, err := helpers.RunCommand("ss", "-H", "-l", "-n", "-p", flag, "sport", "=", ":"+port)
+	if err != nil {
+		return "", fmt.Errorf("list sockets: %w", err)
+	}
+	matches := ssPIDRegex.FindStringSubmatch(out)
+	if matches == nil {
+		return "", fmt.Errorf("no process found")
+	}
+	cgroup, err := os.ReadFile(filepath.Join(procFSRoot, matches[1], "cgroup"))
+	if err != nil {
+		return "", fmt.Errorf("read process cgroup: %w", err)
+	}
+	// processes in user sessions are left alone, only system services are stopped.
+	unit := cgroupRegex.FindStringSubmatch(string(cgroup))
+	if unit == nil {
+		return "", fmt.Errorf("process %s is not a systemd system service", matches[1])
+	}
+	return unit[1], nil
+}
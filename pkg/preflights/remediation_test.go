@@ -0,0 +1,116 @@
+package preflights
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/helpers"
+	"github.com/replicatedhq/embedded-cluster/pkg/helpers/firewalld"
+	"github.com/replicatedhq/embedded-cluster/pkg/preflights/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeHelpers struct {
+	helpers.MockHelpers
+	output map[string]string
+}
+
+func (f *fakeHelpers) RunCommand(bin string, args ...string) (string, error) {
+	f.MockHelpers.RunCommand(bin, args...)
+	return f.output[bin+" "+strings.Join(args, " ")], nil
+}
+
+type fakeFirewalldUtil struct {
+	active bool
+}
+
+func (f *fakeFirewalldUtil) IsFirewalldActive(ctx context.Context) (bool, error) {
+	return f.active, nil
+}
+
+func (f *fakeFirewalldUtil) FirewallCmdExists(ctx context.Context) (bool, error) {
+	return f.active, nil
+}
+
+func TestPlanRemediations(t *testing.T) {
+	procRoot := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(procRoot, "1234"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(procRoot, "1234", "cgroup"), []byte("0::/system.slice/etcd.service\n"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(procRoot, "4321"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(procRoot, "4321", "cgroup"), []byte("0::/user.slice/user-1000.slice/user@1000.service/app.slice/nc.service\n"), 0644))
+
+	orig := procFSRoot
+	procFSRoot = procRoot
+	t.Cleanup(func() { procFSRoot = orig })
+
+	helpers.Set(&fakeHelpers{output: map[string]string{
+		"ss -H -l -n -p -t sport = :2379":  `LISTEN 0 4096 127.0.0.1:2379 0.0.0.0:* users:(("etcd",pid=1234,fd=7))`,
+		"ss -H -l -n -p -t sport = :30000": `LISTEN 0 1 0.0.0.0:30000 0.0.0.0:* users:(("nc",pid=4321,fd=3))`,
+	}})
+	t.Cleanup(func() { helpers.Set(&helpers.Helpers{}) })
+
+	firewalld.SetUtil(&fakeFirewalldUtil{active: true})
+	t.Cleanup(func() { firewalld.SetUtil(&firewalld.Util{}) })
+
+	output := types.Output{
+		Fail: []types.Record{
+			{Title: "IP forwarding", Message: "IP forwarding must be enabled."},
+			{Title: "BR Netfilter kernel module", Message: "The 'br_netfilter' kernel module is not loaded or loadable"},
+			{Title: "SELinux Mode", Message: "SELinux must be disabled or run in permissive mode."},
+			{Title: "ETCD Internal Port Availability", Message: "Port 2379/TCP is required, but another process is already using it. Relocate the conflicting process to continue."},
+			{Title: "Kotsadm Node Port Availability", Message: "Port 30000/TCP is required, but another process is already using it. Relocate the conflicting process to continue."},
+			{Title: "Calico Communication Port Availability", Message: "Port 4789/UDP is required, but the connection timed out. Ensure that your firewall doesn't block port 4789/UDP."},
+			{Title: "Kube API Server Port Availability", Message: "Port 6443/TCP is required, but the connection timed out. Ensure that your firewall doesn't block port 6443/TCP."},
+			{Title: "Calico External TCP Port Availability", Message: "Port 9091/TCP is required, but the connection timed out. Ensure that your firewall doesn't block port 9091/TCP."},
+			{Title: "Kubelet Port Availability", Message: "Port 10250/TCP is required, but the connection to it was refused. Ensure port 10250/TCP is available."},
+			{Title: "Memory", Message: "At least 2GB of memory is required, but less is present"},
+		},
+		Warn: []types.Record{
+			{Title: "Maximum number of inotify watches per user", Message: "The system limit for inotify watches per user must be at least 65536."},
+		},
+	}
+
+	remediations := PlanRemediations(context.Background(), output, RemediationOptions{CNIProvider: "calico"})
+
+	got := map[string][]string{}
+	for _, r := range remediations {
+		got[r.Description] = r.Checks
+	}
+	assert.Equal(t, map[string][]string{
+		"Set the net.ipv4.ip_forward kernel parameter to 1":                    {"IP forwarding"},
+		"Load the br_netfilter kernel module":                                  {"BR Netfilter kernel module"},
+		"Set SELinux to permissive mode":                                       {"SELinux Mode"},
+		"Stop and disable the etcd.service unit using port 2379/TCP":           {"ETCD Internal Port Availability"},
+		"Open the ports required by the cluster in the firewalld default zone": {"Calico Communication Port Availability", "Kube API Server Port Availability"},
+		"Set the fs.inotify.max_user_watches kernel parameter to 65536":        {"Maximum number of inotify watches per user"},
+	}, got)
+}
+
+func TestRemediationHints(t *testing.T) {
+	// the host must not be inspected, commands would fail the test.
+	mock := &helpers.MockHelpers{}
+	helpers.Set(mock)
+	t.Cleanup(func() { helpers.Set(&helpers.Helpers{}) })
+
+	output := types.Output{
+		Fail: []types.Record{
+			{Title: "IP forwarding", Message: "IP forwarding must be enabled."},
+			{Title: "ETCD Internal Port Availability", Message: "Port 2379/TCP is required, but another process is already using it. Relocate the conflicting process to continue."},
+			{Title: "Kube API Server Port Availability", Message: "Port 6443/TCP is required, but the connection timed out. Ensure that your firewall doesn't block port 6443/TCP."},
+			{Title: "Calico External TCP Port Availability", Message: "Port 9091/TCP is required, but the connection timed out. Ensure that your firewall doesn't block port 9091/TCP."},
+			{Title: "Memory", Message: "At least 2GB of memory is required, but less is present"},
+		},
+	}
+
+	hints := RemediationHints(output, RemediationOptions{CNIProvider: "calico"})
+	assert.Equal(t, map[string]string{
+		"IP forwarding":                     "Set the net.ipv4.ip_forward kernel parameter to 1",
+		"ETCD Internal Port Availability":   "Stop and disable the systemd unit using port 2379/TCP",
+		"Kube API Server Port Availability": "Open the ports required by the cluster in the firewalld default zone",
+	}, hints)
+	assert.Empty(t, mock.Commands)
+}
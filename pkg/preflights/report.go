@@ -0,0 +1,456 @@
+package preflights
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/preflights/types"
+	"github.com/replicatedhq/embedded-cluster/pkg/versions"
+	"github.com/replicatedhq/troubleshoot/pkg/apis/troubleshoot/v1beta2"
+)
+
+const (
+	ReportFormatJSON  = "json"
+	ReportFormatJUnit = "junit"
+	ReportFormatSARIF = "sarif"
+)
+
+// ReportFormats are the formats a host preflight report can be written in.
+var ReportFormats = []string{ReportFormatJSON, ReportFormatJUnit, ReportFormatSARIF}
+
+const (
+	SeverityPass = "pass"
+	SeverityWarn = "warn"
+	SeverityFail = "fail"
+)
+
+// osReleasePath is the path to the os-release file. This could have been a constant but we
+// want to be able to override it for testing purposes.
+var osReleasePath = "/etc/os-release"
+
+// Report is a machine readable report of a host preflight run.
+type Report struct {
+	Timestamp  time.Time      `json:"timestamp"`
+	Host       HostFacts      `json:"host"`
+	Collectors []string       `json:"collectors"`
+	Results    []ReportResult `json:"results"`
+	Summary    ReportSummary  `json:"summary"`
+}
+
+// HostFacts holds information about the host the preflights ran on.
+type HostFacts struct {
+	Hostname     string `json:"hostname"`
+	Architecture string `json:"architecture"`
+	CPUs         int    `json:"cpus"`
+	MemoryBytes  int64  `json:"memoryBytes"`
+	Kernel       string `json:"kernel"`
+	Distribution string `json:"distribution"`
+	Version      string `json:"version"`
+}
+
+// ReportResult is the result of a single host preflight analyzer.
+type ReportResult struct {
+	Title       string `json:"title"`
+	Severity    string `json:"severity"`
+	Message     string `json:"message"`
+	Remediation string `json:"remediation,omitempty"`
+}
+
+// ReportSummary holds the number of results per severity.
+type ReportSummary struct {
+	Pass int `json:"pass"`
+	Warn int `json:"warn"`
+	Fail int `json:"fail"`
+}
+
+// HasFail returns true if any of the results in the report failed.
+func (r Report) HasFail() bool {
+	return r.Summary.Fail > 0
+}
+
+// NewReport builds a report out of the host preflight spec and its output. Remediation hints
+// are added to the results with a known fix, the host is not inspected to build them.
+func NewReport(hpf *v1beta2.HostPreflightSpec, output types.Output, opts RemediationOptions) Report {
+	hints := RemediationHints(output, opts)
+
+	report := Report{
+		Timestamp:  time.Now().UTC(),
+		Host:       GetHostFacts(),
+		Collectors: collectorNames(hpf),
+		Results:    []ReportResult{},
+		Summary: ReportSummary{
+			Pass: len(output.Pass),
+			Warn: len(output.Warn),
+			Fail: len(output.Fail),
+		},
+	}
+	add := func(severity string, records []types.Record) {
+		for _, record := range records {
+			report.Results = append(report.Results, ReportResult{
+				Title:       record.Title,
+				Severity:    severity,
+				Message:     record.Message,
+				Remediation: hints[record.Title],
+			})
+		}
+	}
+	add(SeverityFail, output.Fail)
+	add(SeverityWarn, output.Warn)
+	add(SeverityPass, output.Pass)
+	return report
+}
+
+// collectorNames returns the name of every collector in the spec. Collectors without an
+// explicit name are identified by their type.
+func collectorNames(hpf *v1beta2.HostPreflightSpec) []string {
+	names := []string{}
+	if hpf == nil {
+		return names
+	}
+	for _, collector := range hpf.Collectors {
+		data, err := json.Marshal(collector)
+		if err != nil {
+			continue
+		}
+		fields := map[string]struct {
+			CollectorName string `json:"collectorName"`
+		}{}
+		if err := json.Unmarshal(data, &fields); err != nil {
+			continue
+		}
+		kinds := []string{}
+		for kind := range fields {
+			kinds = append(kinds, kind)
+		}
+		sort.Strings(kinds)
+		for _, kind := range kinds {
+			name := kind
+			if fields[kind].CollectorName != "" {
+				name = fmt.Sprintf("%s/%s", kind, fields[kind].CollectorName)
+			}
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// GetHostFacts returns information about the host. Facts that can not be read are left empty.
+func GetHostFacts() HostFacts {
+	facts := HostFacts{
+		Architecture: runtime.GOARCH,
+		CPUs:         runtime.NumCPU(),
+	}
+	if hostname, err := os.Hostname(); err == nil {
+		facts.Hostname = hostname
+	}
+	if data, err := os.ReadFile(filepath.Join(procFSRoot, "sys", "kernel", "osrelease")); err == nil {
+		facts.Kernel = strings.TrimSpace(string(data))
+	}
+	if memory, err := readMemTotal(filepath.Join(procFSRoot, "meminfo")); err == nil {
+		facts.MemoryBytes = memory
+	}
+	if release, err := readOSRelease(osReleasePath); err == nil {
+		facts.Distribution = release["ID"]
+		facts.Version = release["VERSION_ID"]
+	}
+	return facts
+}
+
+var memTotalRegex = regexp.MustCompile(`^MemTotal:\s+(\d+)\s+kB`)
+
+// readMemTotal returns the total memory, in bytes, as reported by the meminfo file.
+func readMemTotal(path string) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		matches := memTotalRegex.FindStringSubmatch(scanner.Text())
+		if matches == nil {
+			continue
+		}
+		kb, err := strconv.ParseInt(matches[1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("parse memory: %w", err)
+		}
+		return kb * 1024, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("total memory not found in %s", path)
+}
+
+// readOSRelease parses the os-release file into a map.
+func readOSRelease(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	release := map[string]string{}
+	for _, line := range strings.Split(string(data), "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok || strings.HasPrefix(key, "#") {
+			continue
+		}
+		release[key] = strings.Trim(value, `"'`)
+	}
+	return release, nil
+}
+
+// ReportFileExtension returns the file extension used for reports in the format.
+func ReportFileExtension(format string) string {
+	switch format {
+	case ReportFormatJUnit:
+		return "xml"
+	case ReportFormatSARIF:
+		return "sarif"
+	default:
+		return "json"
+	}
+}
+
+// WriteFile writes the report in the format to the file at path.
+func (r Report) WriteFile(path, format string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("create directory: %w", err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create file: %w", err)
+	}
+	defer f.Close()
+	if err := r.Write(f, format); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// Write writes the report in the format to w.
+func (r Report) Write(w io.Writer, format string) error {
+	switch format {
+	case ReportFormatJSON:
+		return writeJSON(w, r)
+	case ReportFormatJUnit:
+		return r.writeJUnit(w)
+	case ReportFormatSARIF:
+		return writeJSON(w, r.sarif())
+	default:
+		return fmt.Errorf("unknown report format %q", format)
+	}
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Errorf("encode report: %w", err)
+	}
+	return nil
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Hostname   string          `xml:"hostname,attr,omitempty"`
+	Timestamp  string          `xml:"timestamp,attr"`
+	Properties []junitProperty `xml:"properties>property"`
+	TestCases  []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnit writes the report as a JUnit test suite. Failures are reported as failed test
+// cases, warnings as passed test cases with the warning in their output so they do not break
+// pipelines gating on the test results.
+func (r Report) writeJUnit(w io.Writer) error {
+	suite := junitTestSuite{
+		Name:      "host-preflights",
+		Tests:     len(r.Results),
+		Failures:  r.Summary.Fail,
+		Hostname:  r.Host.Hostname,
+		Timestamp: r.Timestamp.Format(time.RFC3339),
+		Properties: []junitProperty{
+			{Name: "architecture", Value: r.Host.Architecture},
+			{Name: "cpus", Value: strconv.Itoa(r.Host.CPUs)},
+			{Name: "memoryBytes", Value: strconv.FormatInt(r.Host.MemoryBytes, 10)},
+			{Name: "kernel", Value: r.Host.Kernel},
+			{Name: "distribution", Value: r.Host.Distribution},
+			{Name: "version", Value: r.Host.Version},
+		},
+	}
+	for _, collector := range r.Collectors {
+		suite.Properties = append(suite.Properties, junitProperty{Name: "collector", Value: collector})
+	}
+	for _, result := range r.Results {
+		tc := junitTestCase{Name: result.Title, ClassName: "host-preflights"}
+		text := result.Message
+		if result.Remediation != "" {
+			text = fmt.Sprintf("%s\nRemediation: %s", text, result.Remediation)
+		}
+		switch result.Severity {
+		case SeverityFail:
+			tc.Failure = &junitFailure{Message: result.Message, Type: result.Severity, Text: text}
+		case SeverityWarn:
+			tc.SystemOut = fmt.Sprintf("warn: %s", text)
+		default:
+			tc.SystemOut = text
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+
+	suites := junitTestSuites{
+		Name:     "host-preflights",
+		Tests:    suite.Tests,
+		Failures: suite.Failures,
+		Suites:   []junitTestSuite{suite},
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(suites); err != nil {
+		return fmt.Errorf("encode report: %w", err)
+	}
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return fmt.Errorf("write report: %w", err)
+	}
+	return nil
+}
+
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool       sarifTool              `json:"tool"`
+	Results    []sarifResult          `json:"results"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name    string      `json:"name"`
+	Version string      `json:"version,omitempty"`
+	Rules   []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string        `json:"id"`
+	Name             string        `json:"name"`
+	ShortDescription sarifMessage  `json:"shortDescription"`
+	Help             *sarifMessage `json:"help,omitempty"`
+}
+
+type sarifResult struct {
+	RuleID  string       `json:"ruleId"`
+	Kind    string       `json:"kind"`
+	Level   string       `json:"level"`
+	Message sarifMessage `json:"message"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+var nonAlphanumericRegex = regexp.MustCompile(`[^a-z0-9]+`)
+
+// sarifRuleID returns a stable rule id for the host preflight title.
+func sarifRuleID(title string) string {
+	return strings.Trim(nonAlphanumericRegex.ReplaceAllString(strings.ToLower(title), "-"), "-")
+}
+
+// sarif converts the report into a SARIF 2.1.0 log. Each host preflight is a rule, failures
+// are reported as errors, warnings as warnings and passes as results of kind pass.
+func (r Report) sarif() sarifLog {
+	run := sarifRun{
+		Tool: sarifTool{
+			Driver: sarifDriver{
+				Name:    "embedded-cluster-host-preflights",
+				Version: versions.Version,
+				Rules:   []sarifRule{},
+			},
+		},
+		Results: []sarifResult{},
+		Properties: map[string]interface{}{
+			"timestamp":  r.Timestamp,
+			"host":       r.Host,
+			"collectors": r.Collectors,
+		},
+	}
+
+	seen := map[string]bool{}
+	for _, result := range r.Results {
+		id := sarifRuleID(result.Title)
+		if !seen[id] {
+			seen[id] = true
+			rule := sarifRule{ID: id, Name: result.Title, ShortDescription: sarifMessage{Text: result.Title}}
+			if result.Remediation != "" {
+				rule.Help = &sarifMessage{Text: result.Remediation}
+			}
+			run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, rule)
+		}
+
+		sr := sarifResult{RuleID: id, Message: sarifMessage{Text: result.Message}}
+		switch result.Severity {
+		case SeverityFail:
+			sr.Kind, sr.Level = "fail", "error"
+		case SeverityWarn:
+			sr.Kind, sr.Level = "fail", "warning"
+		default:
+			sr.Kind, sr.Level = "pass", "none"
+		}
+		run.Results = append(run.Results, sr)
+	}
+
+	return sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{run},
+	}
+}
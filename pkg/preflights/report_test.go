@@ -0,0 +1,117 @@
+package preflights
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/replicatedhq/embedded-cluster/pkg/preflights/types"
+	"github.com/replicatedhq/troubleshoot/pkg/apis/troubleshoot/v1beta2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupReportHost(t *testing.T) {
+	procRoot := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(procRoot, "sys", "kernel"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(procRoot, "sys", "kernel", "osrelease"), []byte("6.8.0-45-generic\n"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(procRoot, "meminfo"), []byte("MemTotal:        8038072 kB\nMemFree:         1234567 kB\n"), 0644))
+	osRelease := filepath.Join(t.TempDir(), "os-release")
+	require.NoError(t, os.WriteFile(osRelease, []byte("# comment\nNAME=\"Ubuntu\"\nID=ubuntu\nVERSION_ID=\"24.04\"\n"), 0644))
+
+	origProc, origOSRelease := procFSRoot, osReleasePath
+	procFSRoot, osReleasePath = procRoot, osRelease
+	t.Cleanup(func() { procFSRoot, osReleasePath = origProc, origOSRelease })
+}
+
+func testReport(t *testing.T) Report {
+	setupReportHost(t)
+
+	hpf := &v1beta2.HostPreflightSpec{
+		Collectors: []*v1beta2.HostCollect{
+			{CPU: &v1beta2.CPU{}},
+			{DiskUsage: &v1beta2.DiskUsage{HostCollectorMeta: v1beta2.HostCollectorMeta{CollectorName: "embedded-cluster-path-usage"}}},
+		},
+	}
+	output := types.Output{
+		Fail: []types.Record{{Title: "IP forwarding", Message: "IP forwarding is disabled"}},
+		Warn: []types.Record{{Title: "System Clock", Message: "Unable to determine system clock status"}},
+		Pass: []types.Record{{Title: "CPU", Message: "At least 2 CPU cores are present"}},
+	}
+	return NewReport(hpf, output, RemediationOptions{})
+}
+
+func TestNewReport(t *testing.T) {
+	report := testReport(t)
+
+	assert.Equal(t, []string{"cpu", "diskUsage/embedded-cluster-path-usage"}, report.Collectors)
+	assert.Equal(t, ReportSummary{Pass: 1, Warn: 1, Fail: 1}, report.Summary)
+	assert.True(t, report.HasFail())
+	assert.Equal(t, []ReportResult{
+		{Title: "IP forwarding", Severity: SeverityFail, Message: "IP forwarding is disabled", Remediation: "Set the net.ipv4.ip_forward kernel parameter to 1"},
+		{Title: "System Clock", Severity: SeverityWarn, Message: "Unable to determine system clock status"},
+		{Title: "CPU", Severity: SeverityPass, Message: "At least 2 CPU cores are present"},
+	}, report.Results)
+
+	assert.Equal(t, "6.8.0-45-generic", report.Host.Kernel)
+	assert.Equal(t, int64(8038072*1024), report.Host.MemoryBytes)
+	assert.Equal(t, "ubuntu", report.Host.Distribution)
+	assert.Equal(t, "24.04", report.Host.Version)
+	assert.NotZero(t, report.Host.CPUs)
+}
+
+func TestReport_Write(t *testing.T) {
+	report := testReport(t)
+
+	t.Run("json", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		require.NoError(t, report.Write(buf, ReportFormatJSON))
+
+		var got Report
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+		assert.Equal(t, report.Results, got.Results)
+		assert.Equal(t, report.Host, got.Host)
+	})
+
+	t.Run("junit", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		require.NoError(t, report.Write(buf, ReportFormatJUnit))
+
+		var got junitTestSuites
+		require.NoError(t, xml.Unmarshal(buf.Bytes(), &got))
+		assert.Equal(t, 3, got.Tests)
+		assert.Equal(t, 1, got.Failures)
+		require.Len(t, got.Suites, 1)
+		require.Len(t, got.Suites[0].TestCases, 3)
+		require.NotNil(t, got.Suites[0].TestCases[0].Failure)
+		assert.Contains(t, got.Suites[0].TestCases[0].Failure.Text, "Remediation: Set the net.ipv4.ip_forward kernel parameter to 1")
+		assert.Nil(t, got.Suites[0].TestCases[1].Failure)
+		assert.Equal(t, "warn: Unable to determine system clock status", got.Suites[0].TestCases[1].SystemOut)
+		assert.Contains(t, got.Suites[0].Properties, junitProperty{Name: "kernel", Value: "6.8.0-45-generic"})
+	})
+
+	t.Run("sarif", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		require.NoError(t, report.Write(buf, ReportFormatSARIF))
+
+		var got sarifLog
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+		assert.Equal(t, "2.1.0", got.Version)
+		require.Len(t, got.Runs, 1)
+		require.Len(t, got.Runs[0].Tool.Driver.Rules, 3)
+		assert.Equal(t, "ip-forwarding", got.Runs[0].Tool.Driver.Rules[0].ID)
+		require.NotNil(t, got.Runs[0].Tool.Driver.Rules[0].Help)
+		assert.Equal(t, []sarifResult{
+			{RuleID: "ip-forwarding", Kind: "fail", Level: "error", Message: sarifMessage{Text: "IP forwarding is disabled"}},
+			{RuleID: "system-clock", Kind: "fail", Level: "warning", Message: sarifMessage{Text: "Unable to determine system clock status"}},
+			{RuleID: "cpu", Kind: "pass", Level: "none", Message: sarifMessage{Text: "At least 2 CPU cores are present"}},
+		}, got.Runs[0].Results)
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		assert.Error(t, report.Write(&bytes.Buffer{}, "yaml"))
+	})
+}
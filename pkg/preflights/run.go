@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"runtime"
+	"strings"
 
 	ecv1beta1 "github.com/replicatedhq/embedded-cluster/kinds/apis/v1beta1"
 	"github.com/replicatedhq/embedded-cluster/pkg/dryrun"
@@ -29,14 +30,21 @@ type PrepareAndRunOptions struct {
 	ServiceCIDR            string
 	GlobalCIDR             *string
 	NodeIP                 string
+	NetworkInterface       string
 	PrivateCAs             []string
 	IsAirgap               bool
 	SkipHostPreflights     bool
 	IgnoreHostPreflights   bool
+	FixHostPreflights      bool
 	AssumeYes              bool
 	TCPConnectionsRequired []string
 	MetricsReporter        MetricsReporter
 	IsJoin                 bool
+	IngressEnabled         bool
+	StorageProvider        string
+	CNIProvider            string
+	ReportFormat           string
+	ReportFile             string
 }
 
 type MetricsReporter interface {
@@ -70,6 +78,9 @@ func PrepareAndRun(ctx context.Context, opts PrepareAndRunOptions) error {
 		TCPConnectionsRequired:  opts.TCPConnectionsRequired,
 		NodeIP:                  opts.NodeIP,
 		IsJoin:                  opts.IsJoin,
+		IngressEnabled:          opts.IngressEnabled,
+		StorageProvider:         opts.StorageProvider,
+		CNIProvider:             opts.CNIProvider,
 	}.WithCIDRData(opts.PodCIDR, opts.ServiceCIDR, opts.GlobalCIDR)
 
 	if err != nil {
@@ -125,6 +136,28 @@ func runHostPreflights(ctx context.Context, hpf *v1beta2.HostPreflightSpec, opts
 		logrus.Debugf("preflight stderr: %s", stderr)
 	}
 
+	if opts.FixHostPreflights && (output.HasFail() || output.HasWarn()) {
+		if remediations := PlanRemediations(ctx, *output, remediationOptions(opts)); len(remediations) > 0 {
+			pb.Warnf("Host preflights found issues that can be fixed")
+			pb.Close()
+
+			applied := applyRemediations(ctx, remediations, opts.AssumeYes, opts.ReportFormat == "")
+
+			pb = spinner.Start()
+			if applied {
+				pb.Infof("Running host preflights again")
+				output, stderr, err = Run(ctx, hpf, opts.Proxy)
+				if err != nil {
+					pb.CloseWithError()
+					return fmt.Errorf("host preflights failed to run: %w", err)
+				}
+				if stderr != "" {
+					logrus.Debugf("preflight stderr: %s", stderr)
+				}
+			}
+		}
+	}
+
 	err = output.SaveToDisk(runtimeconfig.PathToEmbeddedClusterSupportFile("host-preflight-results.json"))
 	if err != nil {
 		logrus.Warnf("save preflights output: %v", err)
@@ -135,6 +168,19 @@ func runHostPreflights(ctx context.Context, hpf *v1beta2.HostPreflightSpec, opts
 		logrus.Warnf("copy preflight bundle to embedded-cluster support dir: %v", err)
 	}
 
+	if opts.ReportFormat != "" {
+		report := NewReport(hpf, *output, remediationOptions(opts))
+		if err := report.WriteFile(opts.ReportFile, opts.ReportFormat); err != nil {
+			pb.CloseWithError()
+			return fmt.Errorf("write host preflight report: %w", err)
+		}
+		logrus.Debugf("host preflight report written to %s", opts.ReportFile)
+	}
+
+	// automation gates on the exit code when a report is requested, the user is never prompted.
+	// failures can not be ignored along with a report, this is validated with the flags.
+	interactive := !opts.AssumeYes && opts.ReportFormat == ""
+
 	// Failures found
 	if output.HasFail() {
 		s := "preflights"
@@ -186,7 +232,7 @@ func runHostPreflights(ctx context.Context, hpf *v1beta2.HostPreflightSpec, opts
 		}
 
 		pb.Warnf("%d host %s warned", len(output.Warn), s)
-		if opts.AssumeYes {
+		if !interactive {
 			// We have warnings but we are not in interactive mode
 			// so we just print the warnings and continue
 			pb.Close()
@@ -219,3 +265,35 @@ func runHostPreflights(ctx context.Context, hpf *v1beta2.HostPreflightSpec, opts
 
 	return nil
 }
+
+// applyRemediations shows the remediations and applies them once the user confirms. Returns true
+// if any remediation was applied. Remediations failing to apply are reported and skipped, the
+// host preflights are run again anyway. When the user can not be prompted the remediations are
+// only applied if assumeYes is set.
+func applyRemediations(ctx context.Context, remediations []Remediation, assumeYes, canPrompt bool) bool {
+	logrus.Info("The following fixes will be applied:")
+	for _, r := range remediations {
+		logrus.Infof("  • %s (%s)", r.Description, strings.Join(r.Checks, ", "))
+	}
+
+	if !assumeYes {
+		if !canPrompt {
+			logrus.Info("Pass --yes to apply these fixes without being prompted.")
+			return false
+		}
+		if !prompts.New().Confirm("Do you want to apply these fixes?", false) {
+			return false
+		}
+	}
+
+	applied := false
+	for _, r := range remediations {
+		if err := r.Apply(ctx); err != nil {
+			logrus.Warnf("Unable to apply fix %q: %v", r.Description, err)
+			continue
+		}
+		logrus.Debugf("applied host preflight fix: %s", r.Description)
+		applied = true
+	}
+	return applied
+}
@@ -32,7 +32,7 @@ func GetClusterHostPreflights(ctx context.Context, data types.TemplateData) ([]v
 	return kinds.HostPreflightsV1Beta2, nil
 }
 
-func renderTemplate(spec string, data types.TemplateData) (string, error) {
+func renderTemplate(spec string, data interface{}) (string, error) {
 	tmpl, err := template.New("preflight").Parse(spec)
 	if err != nil {
 		return "", fmt.Errorf("parse template: %w", err)
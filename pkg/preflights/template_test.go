@@ -617,3 +617,188 @@ func TestTemplateTCPConnectionsRequired(t *testing.T) {
 		})
 	}
 }
+
+func getTCPPortStatusCollectorByName(name string, spec v1beta2.HostPreflightSpec) *v1beta2.TCPPortStatus {
+	for _, c := range spec.Collectors {
+		if c.TCPPortStatus == nil {
+			continue
+		}
+		if c.TCPPortStatus.CollectorName == name {
+			return c.TCPPortStatus
+		}
+	}
+	return nil
+}
+
+func getTCPPortStatusAnalyzerByName(name string, spec v1beta2.HostPreflightSpec) *v1beta2.TCPPortStatusAnalyze {
+	for _, c := range spec.Analyzers {
+		if c.TCPPortStatus == nil {
+			continue
+		}
+		if c.TCPPortStatus.CollectorName == name {
+			return c.TCPPortStatus
+		}
+	}
+	return nil
+}
+
+func TestTemplateIngressPorts(t *testing.T) {
+	tests := []struct {
+		name           string
+		ingressEnabled bool
+		wantExclude    string
+	}{
+		{
+			name:           "ingress controller enabled",
+			ingressEnabled: true,
+			wantExclude:    "false",
+		},
+		{
+			name:           "ingress controller disabled",
+			ingressEnabled: false,
+			wantExclude:    "true",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := require.New(t)
+			tl := types.TemplateData{IngressEnabled: tt.ingressEnabled}
+			hpfc, err := GetClusterHostPreflights(context.Background(), tl)
+			req.NoError(err)
+
+			spec := hpfc[0].Spec
+			for name, port := range map[string]int{"Ingress HTTP Port": 80, "Ingress HTTPS Port": 443} {
+				collector := getTCPPortStatusCollectorByName(name, spec)
+				req.NotNil(collector, "collector %s not found", name)
+				req.Equal(port, collector.Port)
+				req.Equal(tt.wantExclude, collector.Exclude.String())
+
+				analyzer := getTCPPortStatusAnalyzerByName(name, spec)
+				req.NotNil(analyzer, "analyzer %s not found", name)
+				req.Equal(tt.wantExclude, analyzer.Exclude.String())
+			}
+		})
+	}
+}
+
+func TestTemplateLonghornRequirements(t *testing.T) {
+	tests := []struct {
+		name            string
+		storageProvider string
+		wantExclude     string
+	}{
+		{
+			name:            "longhorn",
+			storageProvider: "longhorn",
+			wantExclude:     "false",
+		},
+		{
+			name:            "openebs",
+			storageProvider: "openebs",
+			wantExclude:     "true",
+		},
+		{
+			name:        "default storage provider",
+			wantExclude: "true",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := require.New(t)
+			tl := types.TemplateData{StorageProvider: tt.storageProvider}
+			hpfc, err := GetClusterHostPreflights(context.Background(), tl)
+			req.NoError(err)
+
+			spec := hpfc[0].Spec
+			collectors := map[string]bool{}
+			for _, c := range spec.Collectors {
+				if c.HostRun == nil || (c.HostRun.CollectorName != "check-iscsiadm" && c.HostRun.CollectorName != "check-iscsid") {
+					continue
+				}
+				collectors[c.HostRun.CollectorName] = true
+				req.Equal(tt.wantExclude, c.HostRun.Exclude.String())
+			}
+			req.Len(collectors, 2)
+
+			analyzers := map[string]bool{}
+			for _, a := range spec.Analyzers {
+				switch {
+				case a.TextAnalyze != nil && (a.TextAnalyze.CheckName == "'iscsiadm' Command" || a.TextAnalyze.CheckName == "iSCSI Daemon"):
+					analyzers[a.TextAnalyze.CheckName] = true
+					req.Equal(tt.wantExclude, a.TextAnalyze.Exclude.String())
+				case a.KernelModules != nil && a.KernelModules.CheckName == "iSCSI TCP kernel module":
+					analyzers[a.KernelModules.CheckName] = true
+					req.Equal(tt.wantExclude, a.KernelModules.Exclude.String())
+				}
+			}
+			req.Len(analyzers, 3)
+		})
+	}
+}
+
+func TestTemplateCNIPorts(t *testing.T) {
+	calico := []string{"Calico External TCP Port", "Calico Node Internal Port", "Calico Communication Port"}
+	cilium := []string{"Cilium Health Check Port", "Cilium Agent Health Port", "Cilium VXLAN Port"}
+	tests := []struct {
+		name        string
+		cniProvider string
+		wantCalico  string
+		wantCilium  string
+	}{
+		{
+			name:        "calico",
+			cniProvider: "calico",
+			wantCalico:  "false",
+			wantCilium:  "true",
+		},
+		{
+			name:        "cilium",
+			cniProvider: "cilium",
+			wantCalico:  "true",
+			wantCilium:  "false",
+		},
+		{
+			name:       "default cni provider",
+			wantCalico: "false",
+			wantCilium: "true",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := require.New(t)
+			tl := types.TemplateData{CNIProvider: tt.cniProvider}
+			hpfc, err := GetClusterHostPreflights(context.Background(), tl)
+			req.NoError(err)
+
+			spec := hpfc[0].Spec
+			excludes := map[string]string{}
+			for _, c := range spec.Collectors {
+				switch {
+				case c.TCPPortStatus != nil:
+					excludes[c.TCPPortStatus.CollectorName] = c.TCPPortStatus.Exclude.String()
+				case c.UDPPortStatus != nil:
+					excludes[c.UDPPortStatus.CollectorName] = c.UDPPortStatus.Exclude.String()
+				}
+			}
+			for _, name := range calico {
+				req.Equal(tt.wantCalico, excludes[name], name)
+			}
+			for _, name := range cilium {
+				req.Equal(tt.wantCilium, excludes[name], name)
+			}
+
+			analyzers := 0
+			for _, a := range spec.Analyzers {
+				switch {
+				case a.TCPPortStatus != nil && strings.HasPrefix(a.TCPPortStatus.CheckName, "Cilium"):
+					req.Equal(tt.wantCilium, a.TCPPortStatus.Exclude.String())
+					analyzers++
+				case a.UDPPortStatus != nil && strings.HasPrefix(a.UDPPortStatus.CheckName, "Cilium"):
+					req.Equal(tt.wantCilium, a.UDPPortStatus.Exclude.String())
+					analyzers++
+				}
+			}
+			req.Equal(3, analyzers)
+		})
+	}
+}
@@ -35,6 +35,9 @@ type TemplateData struct {
 	TCPConnectionsRequired  []string
 	NodeIP                  string
 	IsJoin                  bool
+	IngressEnabled          bool
+	StorageProvider         string
+	CNIProvider             string
 }
 
 // WithCIDRData sets the respective CIDR properties in the TemplateData struct based on the provided CIDR strings
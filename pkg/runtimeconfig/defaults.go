@@ -17,8 +17,16 @@ const KotsadmServiceAccount = "kotsadm"
 const SeaweedFSNamespace = "seaweedfs"
 const RegistryNamespace = "registry"
 const VeleroNamespace = "velero"
+const IngressNamespace = "ingress-nginx"
+const MonitoringNamespace = "monitoring"
+const LonghornNamespace = "longhorn-system"
 const EmbeddedClusterNamespace = "embedded-cluster"
 
+// LocalArtifactMirrorPeerAddressFile is the support file the artifacts job writes the node
+// address to during upgrades. Local artifact mirrors installed before they served the other
+// nodes read it to configure themselves.
+const LocalArtifactMirrorPeerAddressFile = "local-artifact-mirror-peer-address"
+
 // BinaryName returns the binary name, this is useful for places where we
 // need to present the name of the binary to the user (the name may vary if
 // the binary is renamed). We make sure the name does not contain invalid
@@ -88,6 +88,16 @@ func EmbeddedClusterImagesSubDir() string {
 	return path
 }
 
+// EmbeddedClusterArtifactsSubDir returns the path to the directory where verified airgap
+// artifacts are kept, by digest, so they can be served to other nodes in the cluster.
+func EmbeddedClusterArtifactsSubDir() string {
+	path := filepath.Join(EmbeddedClusterHomeDirectory(), "artifacts")
+	if err := os.MkdirAll(path, 0755); err != nil {
+		logrus.Fatalf("unable to create embedded-cluster artifacts dir: %s", err)
+	}
+	return path
+}
+
 // EmbeddedClusterK0sSubDir returns the path to the directory where k0s data is stored.
 func EmbeddedClusterK0sSubDir() string {
 	if runtimeConfig.K0sDataDirOverride != "" {
@@ -109,6 +119,12 @@ func EmbeddedClusterOpenEBSLocalSubDir() string {
 	return filepath.Join(EmbeddedClusterHomeDirectory(), "openebs-local")
 }
 
+// EmbeddedClusterLonghornSubDir returns the path to the directory where Longhorn replicas are
+// stored.
+func EmbeddedClusterLonghornSubDir() string {
+	return filepath.Join(EmbeddedClusterHomeDirectory(), "longhorn")
+}
+
 // PathToEmbeddedClusterBinary is an utility function that returns the full path to a
 // materialized binary that belongs to embedded-cluster. This function does not check
 // if the file exists.
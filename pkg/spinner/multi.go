@@ -0,0 +1,134 @@
+package spinner
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Multi displays the progress of concurrent tasks, one line per task. Each task reports its
+// progress through its own MessageWriter.
+type Multi struct {
+	mu     sync.Mutex
+	lines  []*multiLine
+	drawn  int
+	frame  int
+	printf WriteFn
+	tty    bool
+	end    chan struct{}
+	done   chan struct{}
+}
+
+type multiLine struct {
+	prefix  string
+	message string
+	closed  bool
+}
+
+// MultiOption is a function that sets an option on a Multi.
+type MultiOption func(*Multi)
+
+// WithMultiWriter sets the WriteFn on the Multi.
+func WithMultiWriter(w WriteFn) MultiOption {
+	return func(m *Multi) {
+		m.printf = w
+	}
+}
+
+// StartMulti starts a multi line progress display.
+func StartMulti(opts ...MultiOption) *Multi {
+	m := &Multi{
+		printf: fmt.Printf,
+		tty:    hasTTY,
+		end:    make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	go m.loop()
+	return m
+}
+
+// Start adds a line to the display and returns the MessageWriter used to report the progress
+// of the task shown on it. The line is final once the MessageWriter is closed.
+func (m *Multi) Start() *MessageWriter {
+	line := &multiLine{prefix: "○"}
+	m.mu.Lock()
+	m.lines = append(m.lines, line)
+	m.mu.Unlock()
+
+	mw := &MessageWriter{
+		ch:  make(chan string, 1024),
+		end: make(chan struct{}),
+		printf: func(format string, args ...any) (int, error) {
+			m.update(line, fmt.Sprintf(format, args...))
+			return 0, nil
+		},
+		// the message writer does not draw on the terminal itself, it reports each message
+		// once and the multi display takes care of drawing it.
+		tty: false,
+	}
+	go mw.loop()
+	return mw
+}
+
+// update records a message, as printed by a MessageWriter without a terminal, on the line.
+func (m *Multi) update(line *multiLine, printed string) {
+	prefix, message, _ := strings.Cut(strings.TrimSuffix(printed, "\n"), "  ")
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	line.message = message
+	line.closed = prefix != "○"
+	line.prefix = prefix
+	if !m.tty {
+		m.printf("%s  %s\n", prefix, message)
+	}
+}
+
+// Close stops the display once every line has been drawn in its final state. The
+// MessageWriters returned by Start must be closed first.
+func (m *Multi) Close() {
+	close(m.end)
+	<-m.done
+}
+
+func (m *Multi) loop() {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.end:
+			m.draw()
+			close(m.done)
+			return
+		case <-ticker.C:
+			m.draw()
+		}
+	}
+}
+
+// draw redraws all the lines in place. Nothing is drawn without a terminal as the lines are
+// printed as they are updated.
+func (m *Multi) draw() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.tty {
+		return
+	}
+
+	m.frame++
+	if m.drawn > 0 {
+		m.printf("\033[%dA", m.drawn)
+	}
+	for _, line := range m.lines {
+		prefix := line.prefix
+		if !line.closed {
+			prefix = blocks[m.frame%len(blocks)]
+		}
+		m.printf("\033[K\r%s  %s\n", prefix, line.message)
+	}
+	m.drawn = len(m.lines)
+}
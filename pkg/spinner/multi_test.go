@@ -0,0 +1,38 @@
+package spinner
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMulti(t *testing.T) {
+	for _, tty := range []bool{true, false} {
+		buf := bytes.NewBuffer(nil)
+		multi := StartMulti(WithMultiWriter(writeTo(buf)), func(m *Multi) {
+			m.tty = tty
+		})
+
+		first := multi.Start()
+		second := multi.Start()
+		first.Infof("Installing first")
+		second.Infof("Installing second")
+		first.Closef("first is ready!")
+		second.Errorf("second failed")
+		second.CloseWithError()
+		multi.Close()
+
+		out := buf.String()
+		assert.Contains(t, out, "✔  first is ready!")
+		assert.Contains(t, out, "✗  second failed")
+		if tty {
+			// the last frame holds both lines in their final state.
+			frames := strings.Split(out, "\033[2A")
+			last := frames[len(frames)-1]
+			assert.Contains(t, last, "first is ready!")
+			assert.Contains(t, last, "second failed")
+		}
+	}
+}